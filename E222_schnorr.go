@@ -1,79 +1,84 @@
-package main
+package sig
 
 import (
-	"crypto/rand"
-	"fmt"
 	"math/big"
-	"time"
 
 	"golang.org/x/crypto/sha3"
+
+	"sig/edwards"
 )
 
-func run_e222_schnorr() {
+/*
+Computes the challenge e = Hash(r || M) for the given signature version.
 
-	total := 0
-	loops := 100
-	rnd := rand.Reader
-	data := make([]byte, 5242880)
-	rnd.Read(data)
-	for i := 0; i < loops; i++ {
-		start := time.Now()
-		key, sig, e := sign_message_e222(&data)
-		verify_sig_e222(key, sig, e, &data)
-		elapsed := time.Since(start)
-		// fmt.Println(res)
-		total += int(elapsed.Microseconds())
+Version 1 hashed the variable length r.x.Bytes() and, by passing the
+input to Sum instead of Write, took e as the first 32 bytes of r || M
+rather than a digest. Version 2 hashes the fixed width encoding of r,
+with SHA3-256 in the empty domain and cSHAKE256 customized by domain
+otherwise.
+*/
+func e222_challenge(version byte, domain string, rx *big.Int, msg *[]byte) *big.Int {
+	if domain != "" {
+		return fromFixedBytes(domain_hash(domain, toFixedBytes(rx, e222Width), *msg))
+	}
+	if version == signatureVersionVariableWidth {
+		hash := sha3.New256()
+		e_hash := hash.Sum([]byte(append(rx.Bytes(), *msg...)))
+		return fromFixedBytes(e_hash[:32])
 	}
-	fmt.Println("avg μs to sign and verify e222: ", total/loops)
-}
-
-func sign_message_e222(msg *[]byte) (*E222, *big.Int, *big.Int) {
-
-	g := E222GenPoint()
-	n := g.n
-
-	// the secret key generated by the user
-	rnd := rand.Reader
-	x_bytes := make([]byte, 32)
-	rnd.Read(x_bytes)
-	x := big.NewInt(0).SetBytes(x_bytes)
-	y := g.SecMul(x)
-
-	// random k from allowed set [1..n-1]
-	k_read := rand.Reader
-	k_bytes := make([]byte, 32)
-	k_read.Read(k_bytes)
-	k := big.NewInt(0).SetBytes(k_bytes)
-	k.Add(k, big.NewInt(1))
-	k = k.Mod(k, &n)
-
-	r := g.SecMul(k)
 	hash := sha3.New256()
-	e_hash := hash.Sum([]byte(append(r.x.Bytes(), *msg...)))
-
-	e := big.NewInt(0).SetBytes(e_hash[:32])
-	xe := big.NewInt(0).Mul(x, e)
-
-	s := k.Sub(k, xe)
-	s = s.Mod(s, &n)
-	return y, s, e
+	hash.Write(toFixedBytes(rx, e222Width))
+	hash.Write(*msg)
+	return fromFixedBytes(hash.Sum(nil))
 }
 
 /*
-let r_v = g^s * y^e
+let r_v = g^s * (4y)^e
 let e_v Hash(r_v || M)
 
 return true iff e_v = e
+
+The signer uses the cofactor scaled key 4x, so the public key y is
+multiplied by the cofactor before use. s must be below r: G has order r,
+so s + r would verify like s.
+
+Keys with a small order component verify like their prime order part,
+since the cofactor is cleared; with edwards.RequirePrimeOrder they are
+refused outright, as strict verifiers do. Keys made by this package are
+s × G and always pass.
 */
-func verify_sig_e222(y *E222, s, e *big.Int, msg *[]byte) bool {
-	g := E222GenPoint()
+func verify_sig_e222(y *E222, s, e *big.Int, msg *[]byte, opts ...edwards.DecodeOption) bool {
+	return verify_sig_e222_version(signatureVersion, "", y, s, e, msg, opts...)
+}
 
-	gs := g.SecMul(s)
-	gy := y.SecMul(e)
+// Verifies a signature made in domain under the given signature version.
+func verify_sig_e222_version(version byte, domain string, y *E222, s, e *big.Int, msg *[]byte, opts ...edwards.DecodeOption) bool {
+	if validate_public_key(y) != nil || new(big.Int).Mod(e, e222Params.R).Sign() == 0 ||
+		s.Sign() < 0 || s.Cmp(e222Params.R) >= 0 {
+		return false
+	}
+	if y.Check(opts...) != nil {
+		return false
+	}
+	gs := e222Curve.BaseMul(s)
+	gy := y.ClearCofactor().SecMul(e)
 
 	r := gs.Add(gy)
 
+	if version == signatureVersionVariableWidth && domain == "" {
+		return verify_legacy_e222(r, e, msg) || verify_legacy_e222(gs.Add(y.SecMul(e)), e, msg)
+	}
+	return e222_challenge(version, domain, r.X(), msg).Cmp(e) == 0
+}
+
+/*
+Checks the version 1 challenge e against the commitment r. Version 1
+signatures were made with s = k − x·e before signers switched to the
+cofactor scaled key 4x and with s = k − 4x·e after, so they are checked
+against r = g^s * (4y)^e and, failing that, r = g^s * y^e.
+*/
+func verify_legacy_e222(r *E222, e *big.Int, msg *[]byte) bool {
 	hash := sha3.New256()
-	e_v := hash.Sum([]byte(append(r.x.Bytes(), *msg...)))
+	e_v := hash.Sum([]byte(append(r.X().Bytes(), *msg...)))
 	return Equal(e_v[:32], e.Bytes())
 }
@@ -0,0 +1,217 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+
+	"sig/kmac"
+)
+
+func run_e222_schnorr() {
+
+	total := 0
+	loops := 100
+	rnd := rand.Reader
+	data := make([]byte, 5242880)
+	rnd.Read(data)
+	for i := 0; i < loops; i++ {
+		start := time.Now()
+		key, sig, e := sign_message_e222(&data)
+		verify_sig_e222(key, sig, e, &data)
+		elapsed := time.Since(start)
+		// fmt.Println(res)
+		total += int(elapsed.Microseconds())
+	}
+	fmt.Println("avg μs to sign and verify e222: ", total/loops)
+}
+
+// Generates a fresh E222 key pair and signs msg with it in the empty domain.
+// Returns the public key y = x × G and the signature (s, e).
+func sign_message_e222(msg *[]byte) (*E222, *big.Int, *big.Int) {
+
+	// the secret key generated by the user
+	x, err := RandomSecretScalar(32)
+	if err != nil {
+		return nil, nil, nil
+	}
+	defer x.Destroy()
+
+	x_int, _ := x.Int()
+	y := e222Curve.BaseMul(x_int)
+	wipe_int(x_int)
+
+	s, e, err := sign_with_key_e222(x, "", msg)
+	if err != nil {
+		return nil, nil, nil
+	}
+	return y, s, e
+}
+
+/*
+Signs msg in domain under the E222 secret key x. E222 offers roughly 112 bits of
+security at a fraction of the signature size of larger curves, which
+makes it suitable for constrained devices. The nonce and the copy of
+the key used here are wiped before returning.
+
+	x: secret signing key, public key is x × G
+	return: signature (s, e), or ErrDestroyedSecret if x was destroyed
+*/
+func sign_with_key_e222(x *SecretScalar, domain string, msg *[]byte) (*big.Int, *big.Int, error) {
+
+	x_int, err := x.Int()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer wipe_int(x_int)
+	if err := check_scalar(x_int); err != nil {
+		return nil, nil, err
+	}
+
+	// random k from allowed set [1..r-1]
+	k, err := rand.Int(rand.Reader, new(big.Int).Sub(e222Params.R, big.NewInt(1)))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer wipe_int(k)
+	k.Add(k, big.NewInt(1))
+
+	s, e := sign_with_nonce_e222(x_int, k, domain, msg)
+	return s, e, nil
+}
+
+/*
+The deterministic nonce of the test vectors, k = KMACXOF256(x, msg, 448,
+S) mod r with the key as the KMAC key. S is "N" in the empty domain and
+"N" ‖ 0x00 ‖ domain otherwise, so that the nonce depends on the domain as
+the challenge does: signing the same message in two domains never reuses
+a nonce with two different challenges, which would reveal x. Returns the
+raw KMAC output along with k.
+*/
+func e222_kmac_nonce(x *big.Int, domain string, msg []byte) ([]byte, *big.Int) {
+	S := "N"
+	if domain != "" {
+		S += "\x00" + domain
+	}
+	x_bytes := toFixedBytes(x, e222Width)
+	defer wipe_bytes(x_bytes)
+	nonce_kmac := kmac.XOF256(x_bytes, msg, 448, S)
+	return nonce_kmac, new(big.Int).Mod(fromFixedBytes(nonce_kmac), e222Params.R)
+}
+
+/*
+Signs msg in domain under x with the nonce of e222_kmac_nonce instead of
+a random one, so that equal inputs give equal signatures and no random
+source is needed. The domain enters both the nonce and the challenge.
+*/
+func sign_deterministic_e222(x *SecretScalar, domain string, msg *[]byte) (*big.Int, *big.Int, error) {
+	x_int, err := x.Int()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer wipe_int(x_int)
+	if err := check_scalar(x_int); err != nil {
+		return nil, nil, err
+	}
+	nonce_kmac, k := e222_kmac_nonce(x_int, domain, *msg)
+	defer wipe_bytes(nonce_kmac)
+	defer wipe_int(k)
+	s, e := sign_with_nonce_e222(x_int, k, domain, msg)
+	return s, e, nil
+}
+
+/*
+Signs the message read from in, preceded by prefix, in domain under x
+without holding it in memory: the commitment r = k × G is fixed before
+the first byte is read, so the challenge is hashed as the message streams
+past, one chunk of readerChunkSize bytes at a time. The result is the
+signature that sign_with_key_e222 would make over prefix ‖ message. Read
+errors are returned before anything is signed.
+*/
+func sign_stream_e222(x *SecretScalar, domain string, prefix []byte, in io.Reader) (*big.Int, *big.Int, error) {
+	x_int, err := x.Int()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer wipe_int(x_int)
+	if err := check_scalar(x_int); err != nil {
+		return nil, nil, err
+	}
+	k, err := rand.Int(rand.Reader, new(big.Int).Sub(e222Params.R, big.NewInt(1)))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer wipe_int(k)
+	k.Add(k, big.NewInt(1))
+
+	r := e222Curve.BaseMul(k)
+	h, sum := e222_challenge_hasher(domain)
+	h.Write(toFixedBytes(r.X(), e222Width))
+	h.Write(prefix)
+	buf := make([]byte, readerChunkSize)
+	for {
+		m, err := in.Read(buf)
+		h.Write(buf[:m])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	e := fromFixedBytes(sum())
+	x4 := cofactor_scalar(x_int)
+	defer wipe_int(x4)
+	xe := new(big.Int).Mul(x4, e)
+	defer wipe_int(xe)
+	s := new(big.Int).Sub(k, xe)
+	return s.Mod(s, e222Params.R), e, nil
+}
+
+/*
+Computes the signature (s, e) over msg in domain under x with the given
+nonce k. s is reduced mod r, the order of G: reduced mod 4r it would
+equal k mod 4, since 4x·e is a multiple of 4, and publish the two low
+bits of every nonce.
+*/
+func sign_with_nonce_e222(x, k *big.Int, domain string, msg *[]byte) (*big.Int, *big.Int) {
+	r := e222Curve.BaseMul(k)
+	e := e222_challenge(signatureVersion, domain, r.X(), msg)
+	x4 := cofactor_scalar(x)
+	defer wipe_int(x4)
+	xe := big.NewInt(0).Mul(x4, e)
+	defer wipe_int(xe)
+
+	s := new(big.Int).Sub(k, xe)
+	s = s.Mod(s, e222Params.R)
+	return s, e
+}
+
+/*
+Signs msg in domain under x and returns the encoded signature, which
+records the domain label. In canonical text mode, meant for text typed or
+pasted by a user, the signature covers the canonical form of msg and is
+flagged so that verifiers apply the same transform; otherwise msg is
+signed byte for byte, as for files.
+*/
+func sign_text_e222(x *SecretScalar, domain string, msg []byte, canonical bool) ([]byte, error) {
+	sig := &SchnorrSignature{Curve: curveE222}
+	input := msg
+	if canonical {
+		input = canonical_text_input(msg)
+		sig.Meta = append(sig.Meta, SignatureField{Tag: metaCanonicalText, Value: []byte{1}})
+	}
+	if domain != "" {
+		sig.Meta = append(sig.Meta, SignatureField{Tag: metaDomain, Value: []byte(domain)})
+	}
+	s, e, err := sign_with_key_e222(x, domain, &input)
+	if err != nil {
+		return nil, err
+	}
+	sig.S, sig.E = s, e
+	return EncodeSignature(sig), nil
+}
@@ -0,0 +1,167 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+/*
+A signing agent in the manner of ssh-agent: it holds the secrets of a
+KeyStore in memory and signs on request of clients connecting over a Unix
+socket, see AgentClient, so that long-running services sign without ever
+reading a keystore or its master password themselves. Access is governed
+by the socket: whoever can connect can sign, so Listen makes it readable
+and writable by the owner only. Keys that are expired or revoked
+at the time of a request are refused.
+*/
+type Agent struct {
+	mu   sync.RWMutex
+	keys []*agentEntry
+	now  func() time.Time
+}
+
+type agentEntry struct {
+	key *KeyObj
+	ctx *SigningContext
+}
+
+/*
+Loads the secret of every key in store. The agent keeps its own copies,
+so the store can be closed, and its secrets destroyed, once this returns.
+*/
+func NewAgent(store KeyStore) (*Agent, error) {
+	a := &Agent{now: time.Now}
+	for _, key := range store.Keys() {
+		secret, err := store.Secret(key.Id)
+		if err != nil {
+			a.Destroy()
+			return nil, err
+		}
+		ctx, err := NewSigningContextFromScalar(secret)
+		secret.Destroy()
+		if err != nil {
+			a.Destroy()
+			return nil, err
+		}
+		a.keys = append(a.keys, &agentEntry{key: key, ctx: ctx})
+	}
+	return a, nil
+}
+
+/*
+Creates the Unix socket at path and restricts it to mode 0600, failing if
+a file is already there: a stale socket of an agent that died is for the
+caller to remove. As with ssh-agent, path belongs in a directory only the
+owner can enter, which also covers the moment before the chmod.
+*/
+func (a *Agent) Listen(path string) (net.Listener, error) {
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+/*
+Answers the clients of l, each connection in its own goroutine, until l
+is closed; then returns nil. Connections still open are served to their
+end.
+*/
+func (a *Agent) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		go a.serve_conn(conn)
+	}
+}
+
+// Answers requests until the client hangs up or sends a malformed message.
+func (a *Agent) serve_conn(conn net.Conn) {
+	defer conn.Close()
+	for {
+		req, err := read_agent_message(conn)
+		if err != nil {
+			return
+		}
+		reply, ok := a.handle(req)
+		if write_agent_message(conn, reply) != nil || !ok {
+			return
+		}
+	}
+}
+
+// The reply to req; false if req is malformed and the connection is to be dropped.
+func (a *Agent) handle(req []byte) ([]byte, bool) {
+	r := &sshReader{b: req[1:]}
+	switch req[0] {
+	case agentList:
+		if !r.done() {
+			break
+		}
+		a.mu.RLock()
+		defer a.mu.RUnlock()
+		out := binary.BigEndian.AppendUint32([]byte{agentKeys}, uint32(len(a.keys)))
+		for _, e := range a.keys {
+			out = ssh_string(ssh_string(out, []byte(e.key.Id)), []byte(e.key.Owner))
+		}
+		return out, true
+	case agentSign:
+		id, domain, msg := r.string(), r.string(), r.string()
+		if !r.done() {
+			break
+		}
+		sig, err := a.sign(string(id), string(domain), msg)
+		if err != nil {
+			return agent_failure(err.Error()), true
+		}
+		return ssh_string([]byte{agentSignature}, sig), true
+	}
+	return agent_failure(ErrAgentProtocol.Error()), false
+}
+
+func (a *Agent) sign(id, domain string, msg []byte) ([]byte, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	for _, e := range a.keys {
+		if e.key.Id != id {
+			continue
+		}
+		switch e.key.Status(a.now()) {
+		case KeyRevoked:
+			return nil, ErrKeyRevoked
+		case KeyExpired:
+			return nil, errors.New("signing key has expired")
+		}
+		return e.ctx.Sign(domain, msg)
+	}
+	return nil, ErrKeystoreMissing
+}
+
+func agent_failure(reason string) []byte {
+	return ssh_string([]byte{agentFailure}, []byte(reason))
+}
+
+// Destroys every secret the agent holds; later sign requests fail.
+func (a *Agent) Destroy() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, e := range a.keys {
+		e.ctx.Destroy()
+	}
+	a.keys = nil
+}
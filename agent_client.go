@@ -0,0 +1,152 @@
+package sig
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+/*
+Messages of the signing agent protocol, numbered as their counterparts in
+the ssh-agent protocol. Every message is framed as
+
+	uint32 length || type (1 byte) || fields
+
+with strings encoded as ssh_string does:
+
+	list      → keys       uint32 count || count × (string Id || string owner)
+	sign      → signature  string Id || string domain || string message
+	                       → string encoded signature
+	any       → failure    string reason
+*/
+const (
+	agentFailure   = 5
+	agentList      = 11
+	agentKeys      = 12
+	agentSign      = 13
+	agentSignature = 14
+)
+
+// Largest message either side accepts, which bounds what can be signed through the agent.
+const agentMaxMessage = 64 << 20
+
+var (
+	ErrAgentProtocol = errors.New("agent: malformed or unexpected message")
+	ErrAgentRefused  = errors.New("agent: request refused")
+)
+
+// A key the agent holds, as listed to clients.
+type AgentKey struct {
+	Id    string
+	Owner string
+}
+
+/*
+A connection to a signing agent, see Agent. The client never sees a
+private key: it names a key by Id and gets back the signature. Safe for
+concurrent use; requests are answered one at a time.
+*/
+type AgentClient struct {
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// Connects to the agent listening on the Unix socket at path.
+func DialAgent(path string) (*AgentClient, error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	return &AgentClient{conn: conn}, nil
+}
+
+// The keys the agent holds.
+func (c *AgentClient) List() ([]AgentKey, error) {
+	r, err := c.call([]byte{agentList}, agentKeys)
+	if err != nil {
+		return nil, err
+	}
+	count := r.uint32()
+	var keys []AgentKey
+	for i := uint32(0); i < count && !r.err; i++ {
+		keys = append(keys, AgentKey{Id: string(r.string()), Owner: string(r.string())})
+	}
+	if !r.done() {
+		return nil, ErrAgentProtocol
+	}
+	return keys, nil
+}
+
+/*
+Has the agent sign msg in domain with the key of Id id and returns the
+encoded Schnorr signature, which verifies as signatures of
+SigningContext.Sign do. Refusals, for an unknown, expired or revoked key,
+fail with ErrAgentRefused wrapped with the agent's reason.
+*/
+func (c *AgentClient) Sign(id, domain string, msg []byte) ([]byte, error) {
+	req := ssh_string(ssh_string([]byte{agentSign}, []byte(id)), []byte(domain))
+	r, err := c.call(ssh_string(req, msg), agentSignature)
+	if err != nil {
+		return nil, err
+	}
+	sig := r.string()
+	if !r.done() {
+		return nil, ErrAgentProtocol
+	}
+	return sig, nil
+}
+
+func (c *AgentClient) Close() error { return c.conn.Close() }
+
+// Sends the request and reads the reply, which must be of type want or a failure.
+func (c *AgentClient) call(req []byte, want byte) (*sshReader, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := write_agent_message(c.conn, req); err != nil {
+		return nil, err
+	}
+	reply, err := read_agent_message(c.conn)
+	if err != nil {
+		return nil, err
+	}
+	r := &sshReader{b: reply[1:]}
+	switch reply[0] {
+	case want:
+		return r, nil
+	case agentFailure:
+		reason := r.string()
+		if !r.done() {
+			return nil, ErrAgentProtocol
+		}
+		return nil, fmt.Errorf("%w: %s", ErrAgentRefused, reason)
+	}
+	return nil, ErrAgentProtocol
+}
+
+func write_agent_message(w io.Writer, body []byte) error {
+	if len(body) > agentMaxMessage {
+		return ErrAgentProtocol
+	}
+	_, err := w.Write(ssh_string(nil, body))
+	return err
+}
+
+// Reads one framed message; the body holds at least its type.
+func read_agent_message(r io.Reader) ([]byte, error) {
+	var length [4]byte
+	if _, err := io.ReadFull(r, length[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(length[:])
+	if n == 0 || n > agentMaxMessage {
+		return nil, ErrAgentProtocol
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
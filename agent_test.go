@@ -0,0 +1,170 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// An agent signs with the keys it holds over its socket and refuses expired, revoked and unknown keys.
+func TestAgentSigning(t *testing.T) {
+
+	dir := t.TempDir()
+	store, err := open_keychain_store(filepath.Join(dir, "keys.asc"), memoryKeychain{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []*KeyObj
+	for i, reason := range []string{"", "expires", "revoked"} {
+		pw := []byte(fmt.Sprint("agent passphrase ", i))
+		key, err := GenerateKeyObj(fmt.Sprint("owner ", i), pw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		secret, _ := e222_keypair_from_passphrase(pw)
+		switch reason {
+		case "expires":
+			err = SetKeyExpiry(key, secret, time.Now().Add(time.Hour))
+		case "revoked":
+			err = RevokeKey(key, secret, "key compromised")
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Add(key, secret); err != nil {
+			t.Fatal(err)
+		}
+		secret.Destroy()
+		keys = append(keys, key)
+	}
+	agent, err := NewAgent(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	socket := filepath.Join(dir, "agent.sock")
+	l, err := agent.Listen(socket)
+	if err != nil {
+		agent.Destroy()
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- agent.Serve(l) }()
+	defer l.Close()
+	defer agent.Destroy()
+	info, err := os.Stat(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Fatalf("socket mode %o, want 600", perm)
+	}
+	if _, err := agent.Listen(socket); err == nil {
+		t.Fatal("agent listens on a socket in use")
+	}
+
+	// clients list the keys and get signatures that verify under them
+	client, err := DialAgent(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	listed, err := client.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 3 {
+		t.Fatalf("agent lists %d keys, want 3", len(listed))
+	}
+	for i, k := range listed {
+		if k.Id != keys[i].Id || k.Owner != keys[i].Owner {
+			t.Fatalf("key %d listed as %s of %s, want %s of %s", i, k.Id, k.Owner, keys[i].Id, keys[i].Owner)
+		}
+	}
+	msg := []byte("signed by the agent")
+	sig, err := client.Sign(keys[0].Id, "agent-test", msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifySignature(keys[0].PubKey, "agent-test", sig, &msg) {
+		t.Fatal("agent signature does not verify")
+	}
+	if VerifySignature(keys[0].PubKey, "other-domain", sig, &msg) {
+		t.Fatal("agent signature verifies in another domain")
+	}
+	done := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		go func(i int) {
+			c, err := DialAgent(socket)
+			if err != nil {
+				done <- err
+				return
+			}
+			defer c.Close()
+			m := []byte(fmt.Sprint("concurrent message ", i))
+			s, err := c.Sign(keys[1].Id, "agent-test", m)
+			if err == nil && !VerifySignature(keys[1].PubKey, "agent-test", s, &m) {
+				err = fmt.Errorf("signature %d does not verify", i)
+			}
+			done <- err
+		}(i)
+	}
+	for i := 0; i < 4; i++ {
+		if err := <-done; err != nil {
+			t.Fatalf("concurrent client: %v", err)
+		}
+	}
+
+	// revoked, expired and unknown keys are refused without closing the connection
+	if _, err := client.Sign(keys[2].Id, "agent-test", msg); !errors.Is(err, ErrAgentRefused) {
+		t.Fatalf("revoked key: %v, want %v", err, ErrAgentRefused)
+	}
+	if _, err := client.Sign("no such key", "agent-test", msg); !errors.Is(err, ErrAgentRefused) {
+		t.Fatalf("unknown key: %v, want %v", err, ErrAgentRefused)
+	}
+	agent.mu.Lock()
+	agent.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	agent.mu.Unlock()
+	if _, err := client.Sign(keys[1].Id, "agent-test", msg); !errors.Is(err, ErrAgentRefused) {
+		t.Fatalf("expired key: %v, want %v", err, ErrAgentRefused)
+	}
+	if _, err := client.List(); err != nil {
+		t.Fatalf("list after refusals: %v", err)
+	}
+
+	// a malformed request draws a failure and a hang up
+	raw, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := write_agent_message(raw, []byte{agentSign, 0, 0}); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := read_agent_message(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply[0] != agentFailure {
+		t.Fatalf("reply %x to a malformed request, want a failure", reply)
+	}
+	if _, err := read_agent_message(raw); err == nil {
+		t.Fatal("connection stays open after a malformed request")
+	}
+	raw.Close()
+
+	// destroyed keys no longer sign; closing the listener ends Serve
+	agent.Destroy()
+	if _, err := client.Sign(keys[0].Id, "agent-test", msg); !errors.Is(err, ErrAgentRefused) {
+		t.Fatalf("destroyed agent: %v, want %v", err, ErrAgentRefused)
+	}
+	client.Close()
+	l.Close()
+	if err := <-served; err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+}
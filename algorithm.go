@@ -0,0 +1,65 @@
+package sig
+
+import (
+	"errors"
+)
+
+var ErrUnsupportedAlgorithm = errors.New("unsupported algorithm")
+
+/*
+Identifies the signature algorithm, curve and hash of a key or signature.
+The byte is stored in binary signature encodings, where it takes the place
+of the former curve id and keeps its values, and the name in armored
+headers.
+*/
+type AlgorithmID byte
+
+const (
+	algSchnorrE222    AlgorithmID = AlgorithmID(curveE222)
+	algSchnorrSecp256 AlgorithmID = AlgorithmID(curveSecp256)
+	algECDSAP256      AlgorithmID = 0x03
+	algEd25519        AlgorithmID = 0x04
+	algEd448          AlgorithmID = 0x05
+)
+
+var algorithmNames = map[AlgorithmID]string{
+	algSchnorrE222:    "schnorr-e222-sha3-256",
+	algSchnorrSecp256: "schnorr-secp256-sha256",
+	algECDSAP256:      "ecdsa-p256-sha256",
+	algEd25519:        "ed25519-sha512",
+	algEd448:          "ed448-shake256",
+}
+
+// Container scheme names predating the registry.
+var algorithmSchemes = map[AlgorithmID]string{
+	algSchnorrE222:    schemeSchnorrE222,
+	algSchnorrSecp256: schemeSchnorrSecp256,
+	algECDSAP256:      schemeECDSAP256,
+	algEd25519:        schemeEd25519,
+	algEd448:          schemeEd448,
+}
+
+// Registered name of id, or "" if id is unknown.
+func (id AlgorithmID) String() string {
+	return algorithmNames[id]
+}
+
+// Looks up a registered algorithm name.
+func parse_algorithm(name string) (AlgorithmID, error) {
+	for id, n := range algorithmNames {
+		if n == name {
+			return id, nil
+		}
+	}
+	return 0, ErrUnsupportedAlgorithm
+}
+
+// Algorithm of a container scheme name.
+func SchemeAlgorithm(scheme string) (AlgorithmID, error) {
+	for id, s := range algorithmSchemes {
+		if s == scheme {
+			return id, nil
+		}
+	}
+	return 0, ErrUnsupportedAlgorithm
+}
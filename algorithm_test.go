@@ -0,0 +1,106 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Artifacts name their algorithm, and unknown algorithms fail with a typed error.
+func TestAlgorithmIdentifiers(t *testing.T) {
+
+	for id, name := range algorithmNames {
+		if parsed, err := parse_algorithm(name); err != nil || parsed != id {
+			t.Fatalf("parse_algorithm(%q) = %v, %v, want %v", name, parsed, err, id)
+		}
+		if scheme, err := SchemeAlgorithm(algorithmSchemes[id]); err != nil || scheme != id {
+			t.Fatalf("SchemeAlgorithm(%q) = %v, %v, want %v", algorithmSchemes[id], scheme, err, id)
+		}
+	}
+	if _, err := parse_algorithm("schnorr-e521-kmac256"); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Fatalf("unknown algorithm: %v, want %v", err, ErrUnsupportedAlgorithm)
+	}
+	if name := AlgorithmID(0x7f).String(); name != "" {
+		t.Fatalf("unknown algorithm is named %q", name)
+	}
+
+	// binary signatures
+	msg := []byte("agility")
+	y, s, e := sign_message_e222(&msg)
+	sig := EncodeSignature(&SchnorrSignature{Curve: curveE222, S: s, E: e})
+	if sig[1] != byte(algSchnorrE222) {
+		t.Fatalf("algorithm byte %#x, want %#x", sig[1], byte(algSchnorrE222))
+	}
+	for _, unknown := range []byte{0x00, 0x04, 0xff} {
+		forged := append([]byte{}, sig...)
+		forged[1] = unknown
+		if _, err := DecodeSignature(forged); !errors.Is(err, ErrUnsupportedAlgorithm) {
+			t.Fatalf("algorithm %#x: %v, want %v", unknown, err, ErrUnsupportedAlgorithm)
+		}
+		if VerifySignature(y, "", forged, &msg) {
+			t.Fatalf("signature with algorithm %#x verifies", unknown)
+		}
+	}
+
+	// armored containers
+	armored := EncodeContainer(e222_container(y, s, e))
+	if !strings.Contains(armored, "Algorithm: schnorr-e222-sha3-256") {
+		t.Fatalf("container names no algorithm:\n%s", armored)
+	}
+	for _, forged := range []string{
+		strings.Replace(armored, "schnorr-e222-sha3-256", "schnorr-e521-kmac256", 1),
+		strings.Replace(armored, "Scheme: schnorr-e222", "Scheme: schnorr-e521", 1),
+	} {
+		if _, err := DecodeContainer(forged); !errors.Is(err, ErrUnsupportedAlgorithm) {
+			t.Fatalf("%v, want %v for\n%s", err, ErrUnsupportedAlgorithm, forged)
+		}
+	}
+	if _, err := DecodeContainer(strings.Replace(armored, "schnorr-e222-sha3-256", "ecdsa-p256-sha256", 1)); err == nil {
+		t.Fatal("container with a mismatched algorithm decodes")
+	}
+	legacy, err := DecodeContainer(strings.Replace(armored, "Algorithm: schnorr-e222-sha3-256\n", "", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := VerifyContainer(legacy, "", &msg); !ok {
+		t.Fatalf("container without an algorithm does not verify: %v", err)
+	}
+	if _, err := VerifyContainer(&SignatureContainer{Scheme: "schnorr-e521"}, "", &msg); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Fatalf("unknown scheme: %v, want %v", err, ErrUnsupportedAlgorithm)
+	}
+
+	// JSON
+	text, err := render_signature(e222_container(y, s, e), formatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := DetectSignature(strings.Replace(text, "schnorr-e222-sha3-256", "schnorr-e521-kmac256", 1)); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Fatalf("JSON with an unknown algorithm: %v, want %v", err, ErrUnsupportedAlgorithm)
+	}
+
+	// exported keys
+	key, err := GenerateKeyObj("agility", []byte("agility passphrase"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported, err := ExportKeys([]*KeyObj{key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(exported, []byte("Algorithm: schnorr-e222-sha3-256")) {
+		t.Fatalf("exported key names no algorithm:\n%s", exported)
+	}
+	if _, err := ImportKeys(nil, bytes.Replace(exported, []byte("schnorr-e222-sha3-256"), []byte("ecdsa-p256-sha256"), 1)); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Fatalf("key with another algorithm: %v, want %v", err, ErrUnsupportedAlgorithm)
+	}
+	table, err := ImportKeys(nil, bytes.Replace(exported, []byte("Algorithm: schnorr-e222-sha3-256\n"), nil, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table) != 1 || table[0].Untrusted {
+		t.Fatalf("key without an algorithm imports as %d keys, untrusted %v", len(table), len(table) == 1 && table[0].Untrusted)
+	}
+}
@@ -0,0 +1,296 @@
+package sig
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"time"
+
+	"sig/edwards"
+)
+
+// Signature schemes understood by the armored container.
+const (
+	schemeECDSAP256      = "ecdsa-p256"
+	schemeSchnorrE222    = "schnorr-e222"
+	schemeSchnorrSecp256 = "schnorr-secp256"
+	schemeEd25519        = "ed25519"
+	schemeEd448          = "ed448"
+)
+
+const armorType = "SIGNATURE"
+
+/*
+Armored container shared by the ECDSA, Schnorr and EdDSA signature schemes:
+
+	-----BEGIN SIGNATURE-----
+	Algorithm: ecdsa-p256-sha256
+	Scheme: ecdsa-p256
+	Key: <hex encoded public key>
+
+	<base64 encoded signature bytes>
+	-----END SIGNATURE-----
+
+The Algorithm header names a registered AlgorithmID; Scheme is kept for
+older readers and must agree with it. ECDSA signatures are stored as
+r || s, Schnorr signatures in the format produced by EncodeSignature,
+and Ed25519 and Ed448 keys and signatures as RFC 8032 defines them, so that
+other EdDSA implementations can check them. Detached signatures additionally
+carry Filename and Fingerprint headers and, when made by a version that
+records it, a Created header with the signing time, all of which the
+signature covers, see detached_input and detached_input_created. A Domain
+header records the signing domain for information; verification always
+uses the verifier's domain.
+*/
+type SignatureContainer struct {
+	Scheme      string
+	PublicKey   []byte
+	Signature   []byte
+	Filename    string    // detached signatures only
+	Fingerprint string    // detached signatures only
+	Created     time.Time // detached signatures only, zero if not recorded
+	Domain      string
+}
+
+// Encodes c as a PEM style armored block.
+func EncodeContainer(c *SignatureContainer) string {
+	block := pem.Block{
+		Type: armorType,
+		Headers: map[string]string{
+			"Scheme": c.Scheme,
+			"Key":    hex.EncodeToString(c.PublicKey),
+		},
+		Bytes: c.Signature,
+	}
+	if id, err := SchemeAlgorithm(c.Scheme); err == nil {
+		block.Headers["Algorithm"] = id.String()
+	}
+	if c.IsDetached() {
+		block.Headers["Filename"] = c.Filename
+		block.Headers["Fingerprint"] = c.Fingerprint
+		if !c.Created.IsZero() {
+			block.Headers["Created"] = FormatCreated(c.Created)
+		}
+	}
+	if c.Domain != "" {
+		block.Headers["Domain"] = c.Domain
+	}
+	return string(pem.EncodeToMemory(&block))
+}
+
+/*
+Decodes an armored block produced by EncodeContainer. Blocks naming an
+unknown algorithm or scheme fail with ErrUnsupportedAlgorithm; blocks
+without an Algorithm header predate it and are identified by Scheme.
+*/
+func DecodeContainer(armored string) (*SignatureContainer, error) {
+	block, _ := pem.Decode([]byte(armored))
+	if block == nil || block.Type != armorType {
+		return nil, errors.New("no armored signature found")
+	}
+	id, err := SchemeAlgorithm(block.Headers["Scheme"])
+	if err != nil {
+		return nil, err
+	}
+	if name, ok := block.Headers["Algorithm"]; ok {
+		named, err := parse_algorithm(name)
+		if err != nil {
+			return nil, err
+		}
+		if named != id {
+			return nil, errors.New("container algorithm does not match its scheme")
+		}
+	}
+	key, err := hex.DecodeString(block.Headers["Key"])
+	if err != nil {
+		return nil, errors.New("malformed public key in container")
+	}
+	var created time.Time
+	if value, ok := block.Headers["Created"]; ok {
+		if created, err = time.Parse(time.RFC3339, value); err != nil {
+			return nil, errors.New("malformed signing time in container")
+		}
+	}
+	return &SignatureContainer{
+		Scheme:      block.Headers["Scheme"],
+		PublicKey:   key,
+		Signature:   block.Bytes,
+		Filename:    block.Headers["Filename"],
+		Fingerprint: block.Headers["Fingerprint"],
+		Created:     created,
+		Domain:      block.Headers["Domain"],
+	}, nil
+}
+
+/*
+Wraps an ECDSA signature (r, s) made by Q_a in a container. Containers
+carry P-256 keys only; keys on other curves fail with
+ErrUnsupportedAlgorithm, as in sign_detached_ecdsa.
+*/
+func ecdsa_container(Q_a *ecdsa.PublicKey, r, s *big.Int) (*SignatureContainer, error) {
+	if ecdsa_curve(Q_a.Curve) != elliptic.P256() {
+		return nil, ErrUnsupportedAlgorithm
+	}
+	sig := append(toFixedBytes(r, p256Width), toFixedBytes(s, p256Width)...)
+	return &SignatureContainer{
+		Scheme:    schemeECDSAP256,
+		PublicKey: elliptic.Marshal(elliptic.P256(), Q_a.X, Q_a.Y),
+		Signature: sig,
+	}, nil
+}
+
+// Wraps an E222 Schnorr signature (s, e) made by y in a container.
+func e222_container(y *E222, s, e *big.Int) *SignatureContainer {
+	return &SignatureContainer{
+		Scheme:    schemeSchnorrE222,
+		PublicKey: e222_to_bytes(y),
+		Signature: EncodeSignature(&SchnorrSignature{Curve: curveE222, S: s, E: e}),
+	}
+}
+
+// Wraps a secp256 Schnorr signature (s, e) made by y in a container.
+func secp256_container(y *ecdsa.PublicKey, s, e *big.Int) *SignatureContainer {
+	return &SignatureContainer{
+		Scheme:    schemeSchnorrSecp256,
+		PublicKey: elliptic.Marshal(elliptic.P256(), y.X, y.Y),
+		Signature: EncodeSignature(&SchnorrSignature{Curve: curveSecp256, S: s, E: e}),
+	}
+}
+
+// Wraps an RFC 8032 Ed25519 signature made by the encoded key pub in a container.
+func ed25519_container(pub, sig []byte) *SignatureContainer {
+	return &SignatureContainer{Scheme: schemeEd25519, PublicKey: pub, Signature: sig}
+}
+
+// Wraps an RFC 8032 Ed448 signature made by the encoded key pub in a container.
+func ed448_container(pub, sig []byte) *SignatureContainer {
+	return &SignatureContainer{Scheme: schemeEd448, PublicKey: pub, Signature: sig}
+}
+
+/*
+Verifies the signature held in c over msg in domain, dispatching on its
+scheme. For detached signatures msg is the document and the embedded
+metadata is verified along with it. Ed448 takes the domain as its RFC 8032
+context; pure Ed25519 has none, so Ed25519 signatures are only checked in
+the empty domain and fail with ErrEd25519Domain in any other. opts are
+applied to E222 keys, see VerifySignature.
+*/
+func VerifyContainer(c *SignatureContainer, domain string, msg *[]byte, opts ...edwards.DecodeOption) (bool, error) {
+	if c.IsDetached() {
+		input := c.detached_input(*msg)
+		msg = &input
+	}
+	switch c.Scheme {
+	case schemeECDSAP256:
+		Q_a, err := p256_key_from_bytes(c.PublicKey)
+		if err != nil {
+			return false, err
+		}
+		if len(c.Signature) != 2*p256Width {
+			return false, errors.New("malformed ECDSA signature")
+		}
+		r := fromFixedBytes(c.Signature[:p256Width])
+		s := fromFixedBytes(c.Signature[p256Width:])
+		return verify_ecdsa_sig_domain(Q_a, domain, r, s, msg), nil
+	case schemeSchnorrE222:
+		y, err := e222_from_bytes(c.PublicKey, opts...)
+		if err != nil {
+			return false, err
+		}
+		return VerifySignature(y, domain, c.Signature, msg, opts...), nil
+	case schemeSchnorrSecp256:
+		y, err := p256_key_from_bytes(c.PublicKey)
+		if err != nil {
+			return false, err
+		}
+		return VerifySignature(y, domain, c.Signature, msg), nil
+	case schemeEd25519:
+		if domain != "" {
+			return false, ErrEd25519Domain
+		}
+		return VerifyEd25519(c.PublicKey, *msg, c.Signature), nil
+	case schemeEd448:
+		return VerifyEd448(c.PublicKey, *msg, c.Signature, []byte(domain)), nil
+	}
+	return false, ErrUnsupportedAlgorithm
+}
+
+/*
+Verifies the signature held in c over the message read from in, as
+VerifyContainer does over a message in memory. E222 Schnorr signatures
+of the current version without canonical text, and ECDSA signatures, are
+checked in one pass over in; the rest read it whole first.
+*/
+func verify_container_reader(c *SignatureContainer, domain string, in io.Reader) (bool, error) {
+	var prefix []byte
+	if c.IsDetached() {
+		prefix = c.detached_input(nil)
+	}
+	switch c.Scheme {
+	case schemeECDSAP256:
+		Q_a, err := p256_key_from_bytes(c.PublicKey)
+		if err != nil {
+			return false, err
+		}
+		if len(c.Signature) != 2*p256Width {
+			return false, errors.New("malformed ECDSA signature")
+		}
+		r := fromFixedBytes(c.Signature[:p256Width])
+		s := fromFixedBytes(c.Signature[p256Width:])
+		return VerifyReader(Q_a, domain, r, s, io.MultiReader(bytes.NewReader(prefix), in))
+	case schemeSchnorrE222:
+		sig, err := DecodeSignature(c.Signature)
+		if err != nil || sig.Version != signatureVersion || sig.is_canonical_text() {
+			break
+		}
+		y, err := e222_from_bytes(c.PublicKey)
+		if err != nil {
+			return false, err
+		}
+		return verify_stream_e222(context.Background(), y, domain, c.Signature, prefix, in, -1, discardReporter{})
+	}
+	msg, err := io.ReadAll(in)
+	if err != nil {
+		return false, err
+	}
+	return VerifyContainer(c, domain, &msg)
+}
+
+// Parses an uncompressed P-256 public key.
+func p256_key_from_bytes(b []byte) (*ecdsa.PublicKey, error) {
+	x, y := elliptic.Unmarshal(elliptic.P256(), b)
+	if x == nil {
+		return nil, errors.New("malformed P-256 public key")
+	}
+	return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+}
+
+// Encodes an E222 point as fixed width x || y.
+func e222_to_bytes(p *E222) []byte {
+	return append(toFixedBytes(p.X(), e222Width), toFixedBytes(p.Y(), e222Width)...)
+}
+
+/*
+Parses a point encoded by e222_to_bytes, rejecting points not on the curve
+and, as for any public key, the identity and small order points. With
+edwards.RequirePrimeOrder, keys with a small order component are rejected too.
+*/
+func e222_from_bytes(b []byte, opts ...edwards.DecodeOption) (*E222, error) {
+	if len(b) != 2*e222Width {
+		return nil, errors.New("malformed E222 public key")
+	}
+	p := e222Curve.NewPointXY(*fromFixedBytes(b[:e222Width]), *fromFixedBytes(b[e222Width:]))
+	if err := validate_public_key(p); err != nil {
+		return nil, err
+	}
+	if err := p.Check(opts...); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
@@ -0,0 +1,157 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sig/secp256k1"
+)
+
+// ECDSA and Schnorr signatures both survive the armored container codec
+// and verify with the backend named by their scheme.
+func TestContainerRoundTrip(t *testing.T) {
+
+	msg := []byte("short message")
+
+	d_a := generateRandomBigInt()
+	d_a.Mod(d_a, elliptic.P256().Params().N)
+	Q_a := ecdsa.PublicKey{Curve: elliptic.P256()}
+	Q_a.X, Q_a.Y = elliptic.P256().ScalarBaseMult(d_a.Bytes())
+	r, s := sign_message_ecdsa(elliptic.P256(), &msg, d_a)
+
+	y, s2, e := sign_message_e222(&msg)
+
+	ec, err := ecdsa_container(&Q_a, r, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []*SignatureContainer{ec, e222_container(y, s2, e)} {
+		decoded, err := DecodeContainer(EncodeContainer(c))
+		if err != nil {
+			t.Fatalf("%s container: %v", c.Scheme, err)
+		}
+		if decoded.Scheme != c.Scheme {
+			t.Fatalf("%s container decodes as %s", c.Scheme, decoded.Scheme)
+		}
+		if ok, err := VerifyContainer(decoded, "", &msg); !ok || err != nil {
+			t.Fatalf("%s container: VerifyContainer = %v, %v", c.Scheme, ok, err)
+		}
+	}
+
+	// other ECDSA curves have no container scheme
+	for _, curve := range []elliptic.Curve{elliptic.P384(), elliptic.P521(), secp256k1.Curve()} {
+		Q := &ecdsa.PublicKey{Curve: curve}
+		Q.X, Q.Y = curve.ScalarBaseMult(d_a.Bytes())
+		if c, err := ecdsa_container(Q, r, s); c != nil || err != ErrUnsupportedAlgorithm {
+			t.Fatalf("%s: ecdsa_container = %v, %v, want %v", curve.Params().Name, c, err, ErrUnsupportedAlgorithm)
+		}
+	}
+}
+
+// Ed25519 and Ed448 signatures travel in containers and detached files like the other schemes.
+func TestEdDSAContainers(t *testing.T) {
+
+	msg := []byte("short message")
+	seed25519 := make([]byte, Ed25519SeedSize)
+	seed448 := make([]byte, Ed448SeedSize)
+	rand.Read(seed25519)
+	rand.Read(seed448)
+	pub25519, err := Ed25519PublicKey(seed25519)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub448, err := Ed448PublicKey(seed448)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig25519, err := SignEd25519(seed25519, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig448, err := SignEd448(seed448, msg, []byte("app"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []*SignatureContainer{ed25519_container(pub25519, sig25519), ed448_container(pub448, sig448)} {
+		for _, format := range []string{formatArmor, formatJSON} {
+			text, err := render_signature(c, format)
+			if err != nil {
+				t.Fatalf("%s as %s: %v", c.Scheme, format, err)
+			}
+			decoded, _, err := DetectSignature(text)
+			if err != nil {
+				t.Fatalf("%s as %s: %v", c.Scheme, format, err)
+			}
+			if decoded.Scheme != c.Scheme {
+				t.Fatalf("%s as %s decodes as %s", c.Scheme, format, decoded.Scheme)
+			}
+		}
+	}
+	for c, algorithm := range map[*SignatureContainer]string{
+		ed25519_container(pub25519, sig25519): "Algorithm: ed25519-sha512",
+		ed448_container(pub448, sig448):       "Algorithm: ed448-shake256",
+	} {
+		if armored := EncodeContainer(c); !strings.Contains(armored, algorithm) {
+			t.Fatalf("%s container lacks %q:\n%s", c.Scheme, algorithm, armored)
+		}
+	}
+
+	// Ed448 binds the domain as its context, Ed25519 has none
+	if ok, err := VerifyContainer(ed448_container(pub448, sig448), "app", &msg); !ok || err != nil {
+		t.Fatalf("Ed448 in its domain: %v, %v", ok, err)
+	}
+	if ok, err := VerifyContainer(ed448_container(pub448, sig448), "", &msg); ok || err != nil {
+		t.Fatalf("Ed448 outside its domain: %v, %v", ok, err)
+	}
+	if ok, err := VerifyContainer(ed25519_container(pub25519, sig25519), "", &msg); !ok || err != nil {
+		t.Fatalf("Ed25519: %v, %v", ok, err)
+	}
+	if _, err := VerifyContainer(ed25519_container(pub25519, sig25519), "app", &msg); err != ErrEd25519Domain {
+		t.Fatalf("Ed25519 in a domain: %v, want %v", err, ErrEd25519Domain)
+	}
+
+	// signatures of crypto/ed25519 verify as they are
+	std := ed25519.NewKeyFromSeed(seed25519)
+	if ok, err := VerifyContainer(ed25519_container(std.Public().(ed25519.PublicKey), ed25519.Sign(std, msg)), "", &msg); !ok || err != nil {
+		t.Fatalf("crypto/ed25519 signature: %v, %v", ok, err)
+	}
+
+	doc := filepath.Join(t.TempDir(), "document.txt")
+	if err := os.WriteFile(doc, []byte("detached document"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sign_detached_ed25519(seed25519, "app", doc); err != ErrEd25519Domain {
+		t.Fatalf("detached Ed25519 in a domain: %v, want %v", err, ErrEd25519Domain)
+	}
+	c1, err := sign_detached_ed25519(seed25519, "", doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := sign_detached_ed448(seed448, "app", doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, c := range []*SignatureContainer{c1, c2} {
+		domain := []string{"", "app"}[i]
+		sigPath := DetachedSigPath(doc)
+		if err := WriteDetachedSignature(sigPath, c); err != nil {
+			t.Fatal(err)
+		}
+		res, err := VerifyDetachedSignature(doc, sigPath, c.PublicKey, domain)
+		if err != nil {
+			t.Fatalf("detached %s: %v", c.Scheme, err)
+		}
+		if !res.Valid {
+			t.Fatalf("detached %s does not verify", c.Scheme)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package sig
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+
+	"sig/kmac"
+)
+
+const (
+	authTagSalt   = 16  // bytes of random salt prepended to each tag
+	authTagLength = 256 // bits of KMAC output following the salt
+)
+
+/*
+Computes a message authentication tag over msg under the shared passphrase pw:
+
+	salt ← random 16 bytes
+	tag = salt || KMACXOF256(pw, salt || msg, 256, "AUTH_TAG")
+
+The random salt makes two tags over the same message differ.
+*/
+func auth_tag(pw, msg []byte) ([]byte, error) {
+	salt := make([]byte, authTagSalt)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return append(salt, kmac.XOF256(pw, append(salt, msg...), authTagLength, "AUTH_TAG")...), nil
+}
+
+// Checks a tag produced by auth_tag in constant time.
+func check_auth_tag(pw, msg, tag []byte) (bool, error) {
+	if len(tag) != authTagSalt+authTagLength/8 {
+		return false, errors.New("malformed authentication tag")
+	}
+	salt := tag[:authTagSalt]
+	expected := kmac.XOF256(pw, append(append([]byte{}, salt...), msg...), authTagLength, "AUTH_TAG")
+	return subtle.ConstantTimeCompare(expected, tag[authTagSalt:]) == 1, nil
+}
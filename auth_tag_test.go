@@ -0,0 +1,35 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAuthTagChecks(t *testing.T) {
+
+	pw := []byte("correct horse")
+	msg := []byte("short message")
+	tag1, err := auth_tag(pw, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag2, err := auth_tag(pw, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(tag1, tag2) {
+		t.Fatalf("two tags of the same message are both %x", tag1)
+	}
+
+	if ok, err := check_auth_tag(pw, msg, tag1); !ok || err != nil {
+		t.Fatalf("check_auth_tag = %v, %v", ok, err)
+	}
+	if ok, _ := check_auth_tag([]byte("battery staple"), msg, tag1); ok {
+		t.Fatal("tag checks with the wrong passphrase")
+	}
+	if _, err := check_auth_tag(pw, msg, tag1[:len(tag1)-1]); err == nil {
+		t.Fatal("truncated tag gives no error")
+	}
+}
@@ -0,0 +1,172 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"sig/secp256k1"
+)
+
+// Iteration counts for each operation timed by the benchmark suite.
+type BenchmarkWorkload struct {
+	SecMuls       int // E222 scalar multiplications
+	SchnorrCycles int // E222 Schnorr sign and verify cycles
+	ECDSACycles   int // P-256 ECDSA sign and verify cycles
+	HashBytes     int // bytes hashed with SHA3-256
+	ContextSigns  int // E222 signatures from a passphrase, once re-deriving the key per message and once via a SigningContext
+	JointMults    int // u₁ × G + u₂ × Q as in verification: on secp256k1 by Strauss–Shamir and as two multiplications, on P-256 as two multiplications
+	Doublings     int // E222 point doublings, once as Add(P, P) and once by Double
+}
+
+var defaultBenchmarkWorkload = BenchmarkWorkload{
+	SecMuls:       100,
+	SchnorrCycles: 50,
+	ECDSACycles:   50,
+	HashBytes:     10 * 1024 * 1024,
+	ContextSigns:  1000,
+	JointMults:    50,
+	Doublings:     1000,
+}
+
+// Timing of one operation in the suite.
+type BenchmarkRow struct {
+	Name       string
+	Iterations int
+	Total      time.Duration
+}
+
+type BenchmarkReport struct {
+	Rows      []BenchmarkRow
+	Cancelled bool
+}
+
+// Formats the report as a table of total and per-operation times.
+func (r BenchmarkReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%-24s %10s %14s %14s\n", "operation", "iterations", "total", "per op")
+	for _, row := range r.Rows {
+		per := time.Duration(0)
+		if row.Iterations > 0 {
+			per = row.Total / time.Duration(row.Iterations)
+		}
+		fmt.Fprintf(&sb, "%-24s %10d %14s %14s\n", row.Name, row.Iterations, row.Total.Round(time.Microsecond), per.Round(time.Microsecond))
+	}
+	if r.Cancelled {
+		sb.WriteString("(cancelled)\n")
+	}
+	return sb.String()
+}
+
+/*
+Runs the default workload, reporting progress and the finished report to
+reporter, which may be nil. Cancelling ctx stops the suite as described
+at run_benchmark_suite.
+*/
+func RunBenchmarkSuite(ctx context.Context, reporter UIReporter) BenchmarkReport {
+	return run_benchmark_suite(ctx, defaultBenchmarkWorkload, reporter)
+}
+
+/*
+Times each operation of w in turn. Progress is reported after every
+iteration; if ctx is cancelled the suite stops after the current iteration
+and returns the rows completed so far with Cancelled set. A nil reporter
+discards the reports.
+*/
+func run_benchmark_suite(ctx context.Context, w BenchmarkWorkload, reporter UIReporter) BenchmarkReport {
+	if reporter == nil {
+		reporter = discardReporter{}
+	}
+	var report BenchmarkReport
+	total := int64(w.SecMuls + w.SchnorrCycles + w.ECDSACycles + 1 + 2*w.ContextSigns + 3*w.JointMults + 2*w.Doublings)
+	done := int64(0)
+
+	msg := make([]byte, 1024)
+	rand.Read(msg)
+	d_a := generateRandomBigInt()
+	d_a.Mod(d_a, elliptic.P256().Params().N)
+	Q_a := &ecdsa.PublicKey{Curve: elliptic.P256()}
+	Q_a.X, Q_a.Y = elliptic.P256().ScalarBaseMult(d_a.Bytes())
+	pw := []byte("benchmark passphrase")
+	signer := NewSigningContext(pw)
+	defer signer.Destroy()
+	k1 := secp256k1.Curve()
+	Q_k1 := &ecdsa.PublicKey{Curve: k1}
+	Q_k1.X, Q_k1.Y = k1.ScalarBaseMult(toFixedBytes(d_a, p256Width))
+	u1 := toFixedBytes(new(big.Int).Mod(generateRandomBigInt(), k1.Params().N), p256Width)
+	u2 := toFixedBytes(new(big.Int).Mod(generateRandomBigInt(), k1.Params().N), p256Width)
+	P := e222Curve.GenPoint().SecMul(generateRandomBigInt())
+
+	ops := []struct {
+		name string
+		n    int
+		op   func()
+	}{
+		{"E222 SecMul", w.SecMuls, func() { e222Curve.GenPoint().SecMul(generateRandomBigInt()) }},
+		{"E222 Schnorr sign+verify", w.SchnorrCycles, func() {
+			y, s, e := sign_message_e222(&msg)
+			verify_sig_e222(y, s, e, &msg)
+		}},
+		{"ECDSA P-256 sign+verify", w.ECDSACycles, func() {
+			r, s := sign_message_ecdsa(elliptic.P256(), &msg, d_a)
+			verify_ecdsa_sig(Q_a, r, s, &msg)
+		}},
+		{fmt.Sprintf("SHA3-256 %d bytes", w.HashBytes), 1, func() {
+			ComputeSHA3HASH(make([]byte, w.HashBytes), 256)
+		}},
+		{"E222 sign, derive per msg", w.ContextSigns, func() { sign_with_passphrase_e222(pw, "", msg[:64]) }},
+		{"E222 sign, SigningContext", w.ContextSigns, func() { signer.Sign("", msg[:64]) }},
+		{"secp256k1 Shamir u1G+u2Q", w.JointMults, func() { ecdsa_combined_mult(k1, Q_k1, u1, u2) }},
+		{"secp256k1 separate mults", w.JointMults, func() { ecdsa_separate_mult(k1, Q_k1, u1, u2) }},
+		{"P-256 separate mults", w.JointMults, func() { ecdsa_combined_mult(elliptic.P256(), Q_a, u1, u2) }},
+		{"E222 Add(P, P)", w.Doublings, func() { P.Add(P) }},
+		{"E222 Double", w.Doublings, func() { P.Double() }},
+	}
+
+	for _, o := range ops {
+		row := BenchmarkRow{Name: o.name}
+		for i := 0; i < o.n; i++ {
+			if ctx.Err() != nil {
+				report.Cancelled = true
+				break
+			}
+			start := time.Now()
+			o.op()
+			row.Total += time.Since(start)
+			row.Iterations++
+			done++
+			reporter.Progress(done, total)
+		}
+		report.Rows = append(report.Rows, row)
+		if report.Cancelled {
+			break
+		}
+	}
+
+	result := "completed"
+	if report.Cancelled {
+		result = "cancelled"
+	}
+	reporter.Record(LogEntry{Operation: "benchmark", Result: result})
+	return report
+}
+
+// gengerates random 512 bit integer
+func generateRandomBigInt() *big.Int {
+	b := make([]byte, 64)
+	_, err := rand.Read(b)
+	if err != nil {
+		fmt.Println("error:", err)
+		return nil
+	}
+	random := big.NewInt(0)
+	random.SetBytes(b)
+	return random
+}
@@ -0,0 +1,65 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type progressCounter struct {
+	OperationLog
+	calls int64
+	last  int64
+}
+
+func (p *progressCounter) Progress(done, total int64) { p.calls++; p.last = done }
+
+func TestBenchmarkSuiteSmall(t *testing.T) {
+
+	reporter := &progressCounter{}
+	w := BenchmarkWorkload{SecMuls: 2, SchnorrCycles: 1, ECDSACycles: 1, HashBytes: 1024, ContextSigns: 2, JointMults: 1, Doublings: 1}
+	report := run_benchmark_suite(context.Background(), w, reporter)
+	if len(report.Rows) != 11 || report.Cancelled {
+		t.Fatalf("%d rows, cancelled %v, want 11 rows", len(report.Rows), report.Cancelled)
+	}
+	for row, want := range map[int]int{0: 2, 5: 2, 10: 1} {
+		if n := report.Rows[row].Iterations; n != want {
+			t.Fatalf("row %d ran %d iterations, want %d", row, n, want)
+		}
+	}
+	if reporter.calls != 14 || reporter.last != 14 {
+		t.Fatalf("%d progress calls, last at %d, want 14", reporter.calls, reporter.last)
+	}
+	if n := len(reporter.Entries()); n != 1 {
+		t.Fatalf("%d log entries, want 1", n)
+	}
+	if s := report.String(); !strings.Contains(s, "E222 SecMul") {
+		t.Fatalf("report lacks E222 SecMul:\n%s", s)
+	}
+
+	quiet := run_benchmark_suite(context.Background(), BenchmarkWorkload{SecMuls: 1}, nil)
+	if len(quiet.Rows) != 11 || quiet.Rows[0].Iterations != 1 {
+		t.Fatalf("without a reporter: %d rows, %d iterations, want 11 rows, 1 iteration", len(quiet.Rows), quiet.Rows[0].Iterations)
+	}
+}
+
+// A cancelled suite stops promptly and says so.
+func TestBenchmarkSuiteCancel(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	reporter := &progressCounter{}
+	report := RunBenchmarkSuite(ctx, reporter)
+	if !report.Cancelled || len(report.Rows) != 1 || report.Rows[0].Iterations != 0 {
+		t.Fatalf("cancelled %v, %d rows, want cancelled with one empty row", report.Cancelled, len(report.Rows))
+	}
+	if reporter.calls != 0 {
+		t.Fatalf("%d progress calls after cancelling", reporter.calls)
+	}
+	report = RunBenchmarkSuite(ctx, nil)
+	if !report.Cancelled || len(report.Rows) != 1 {
+		t.Fatalf("without a reporter: cancelled %v, %d rows", report.Cancelled, len(report.Rows))
+	}
+}
@@ -0,0 +1,104 @@
+package sig
+
+import (
+	"crypto/sha256"
+	"errors"
+	"math/big"
+
+	"sig/secp256k1"
+)
+
+// Lengths of BIP-340 x-only public keys, secret keys and signatures.
+const (
+	BIP340PublicKeySize = 32
+	BIP340SecretKeySize = 32
+	BIP340SignatureSize = 64
+)
+
+var (
+	ErrBIP340SecretKey = errors.New("bip340: secret key must be 32 bytes encoding an integer in [1, n−1]")
+	ErrBIP340PublicKey = errors.New("bip340: public key is not the x coordinate of a secp256k1 point")
+	ErrBIP340AuxRand   = errors.New("bip340: auxiliary randomness must be 32 bytes")
+	ErrBIP340Nonce     = errors.New("bip340: derived nonce is zero")
+	ErrBIP340Fault     = errors.New("bip340: produced signature does not verify")
+)
+
+// The curve of BIP-340 and its domain parameters.
+var (
+	k1       = secp256k1.Curve()
+	k1Params = k1.Params()
+)
+
+/*
+The tagged hash of BIP-340, SHA-256(SHA-256(tag) ‖ SHA-256(tag) ‖ parts…),
+which keeps the hashes of the nonce, the challenge and the auxiliary
+randomness, and those of other protocols, apart.
+*/
+func bip340_tagged_hash(tag string, parts ...[]byte) []byte {
+	tag_hash := sha256.Sum256([]byte(tag))
+	h := sha256.New()
+	h.Write(tag_hash[:])
+	h.Write(tag_hash[:])
+	for _, part := range parts {
+		h.Write(part)
+	}
+	return h.Sum(nil)
+}
+
+/*
+lift_x of BIP-340: the point of secp256k1 with x coordinate x and even
+y, found as the square root c^((p+1)/4) of c = x³ + 7, which exists iff it
+squares back to c. Returns nil for x ≥ p or x not on the curve.
+*/
+func bip340_lift_x(x *big.Int) (*big.Int, *big.Int) {
+	p := k1Params.P
+	if x.Sign() < 0 || x.Cmp(p) >= 0 {
+		return nil, nil
+	}
+	c := new(big.Int).Exp(x, big.NewInt(3), p)
+	c.Add(c, k1Params.B).Mod(c, p)
+	e := new(big.Int).Add(p, big.NewInt(1))
+	y := new(big.Int).Exp(c, e.Rsh(e, 2), p)
+	if new(big.Int).Exp(y, big.NewInt(2), p).Cmp(c) != 0 {
+		return nil, nil
+	}
+	if y.Bit(0) == 1 {
+		y.Sub(p, y)
+	}
+	return new(big.Int).Set(x), y
+}
+
+// The challenge e = int(hash_BIP0340/challenge(R ‖ P ‖ m)) mod n.
+func bip340_challenge(rx, px []byte, msg []byte) *big.Int {
+	e := new(big.Int).SetBytes(bip340_tagged_hash("BIP0340/challenge", rx, px, msg))
+	return e.Mod(e, k1Params.N)
+}
+
+/*
+Verifies a BIP-340 Schnorr signature r ‖ s on secp256k1 over msg, of any
+length, under the 32 byte x-only public key pub, as Taproot does: P is
+lift_x(pub), and the signature is valid iff r < p, s < n and
+R = s × G − e × P is not the point at infinity, has even y and has x = r.
+*/
+func VerifyBIP340(pub, msg, sig []byte) bool {
+	if len(pub) != BIP340PublicKeySize || len(sig) != BIP340SignatureSize {
+		return false
+	}
+	px, py := bip340_lift_x(new(big.Int).SetBytes(pub))
+	if px == nil {
+		return false
+	}
+	curve := k1Params
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+	if r.Cmp(curve.P) >= 0 || s.Cmp(curve.N) >= 0 {
+		return false
+	}
+	e := bip340_challenge(sig[:32], pub, msg)
+	neg_e := e.Sub(curve.N, e).Mod(e, curve.N)
+	rx, ry := k1.(combinedMultCurve).CombinedMult(px, py, toFixedBytes(s, 32), toFixedBytes(neg_e, 32))
+	if rx.Sign() == 0 && ry.Sign() == 0 {
+		return false
+	}
+	return ry.Bit(0) == 0 && rx.Cmp(r) == 0
+}
@@ -0,0 +1,97 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto/rand"
+	"math/big"
+)
+
+// The secret scalar d' of a BIP-340 secret key, or ErrBIP340SecretKey.
+func bip340_secret(seckey []byte) (*big.Int, error) {
+	if len(seckey) != BIP340SecretKeySize {
+		return nil, ErrBIP340SecretKey
+	}
+	d := new(big.Int).SetBytes(seckey)
+	if d.Sign() == 0 || d.Cmp(k1Params.N) >= 0 {
+		wipe_int(d)
+		return nil, ErrBIP340SecretKey
+	}
+	return d, nil
+}
+
+// The 32 byte x-only public key bytes(d' × G) of seckey.
+func BIP340PublicKey(seckey []byte) ([]byte, error) {
+	d, err := bip340_secret(seckey)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe_int(d)
+	px, _ := k1.ScalarBaseMult(toFixedBytes(d, 32))
+	return toFixedBytes(px, 32), nil
+}
+
+/*
+Signs msg with seckey as in the default signing algorithm of BIP-340.
+With P = d' × G, d is d' or n − d' so that P has even y; the nonce k' is
+hash_BIP0340/nonce((d ⊕ hash_BIP0340/aux(a)) ‖ bytes(P) ‖ msg) mod n for
+the 32 bytes a of auxRand, also negated if R = k' × G has odd y, and the
+signature is bytes(R) ‖ bytes(k + e·d mod n). A nil auxRand draws a from
+crypto/rand; a fixed a, such as 32 zero bytes, makes signing
+deterministic. The signature is verified before it is returned, which
+guards against faults in the computation.
+
+Security Remark: the secp256k1 arithmetic uses big.Int and is not
+constant time, see package secp256k1.
+*/
+func SignBIP340(seckey, msg, auxRand []byte) ([]byte, error) {
+	d, err := bip340_secret(seckey)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe_int(d)
+	if auxRand == nil {
+		auxRand = make([]byte, 32)
+		if _, err := rand.Read(auxRand); err != nil {
+			return nil, err
+		}
+	}
+	if len(auxRand) != 32 {
+		return nil, ErrBIP340AuxRand
+	}
+	n := k1Params.N
+	d_bytes := toFixedBytes(d, 32)
+	defer wipe_bytes(d_bytes)
+	px, py := k1.ScalarBaseMult(d_bytes)
+	if py.Bit(0) == 1 {
+		d.Sub(n, d)
+	}
+	pub := toFixedBytes(px, 32)
+
+	t := toFixedBytes(d, 32)
+	defer wipe_bytes(t)
+	for i, b := range bip340_tagged_hash("BIP0340/aux", auxRand) {
+		t[i] ^= b
+	}
+	k_bytes := bip340_tagged_hash("BIP0340/nonce", t, pub, msg)
+	defer wipe_bytes(k_bytes)
+	k := new(big.Int).SetBytes(k_bytes)
+	defer wipe_int(k)
+	if k.Mod(k, n).Sign() == 0 {
+		return nil, ErrBIP340Nonce
+	}
+	rx, ry := k1.ScalarBaseMult(toFixedBytes(k, 32))
+	if ry.Bit(0) == 1 {
+		k.Sub(n, k)
+	}
+	r := toFixedBytes(rx, 32)
+
+	e := bip340_challenge(r, pub, msg)
+	s := e.Mul(e, d)
+	s.Add(s, k).Mod(s, n)
+	sig := append(r, toFixedBytes(s, 32)...)
+	if !VerifyBIP340(pub, msg, sig) {
+		return nil, ErrBIP340Fault
+	}
+	return sig, nil
+}
@@ -0,0 +1,85 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"sig/secp256k1"
+)
+
+// SignBIP340 reproduces the BIP-340 vectors, round trips for random keys and rejects bad keys and auxiliary data.
+func TestBIP340Signatures(t *testing.T) {
+
+	for i, v := range bip340KnownAnswers {
+		if v.seckey == "" {
+			continue
+		}
+		sk, _ := hex.DecodeString(v.seckey)
+		aux, _ := hex.DecodeString(v.aux)
+		msg, _ := hex.DecodeString(v.msg)
+		pub, err := BIP340PublicKey(sk)
+		if err != nil {
+			t.Fatalf("vector %d: %v", i, err)
+		}
+		if got := hex.EncodeToString(pub); got != v.pub {
+			t.Fatalf("vector %d: public key %s, want %s", i, got, v.pub)
+		}
+		sig, err := SignBIP340(sk, msg, aux)
+		if err != nil {
+			t.Fatalf("vector %d: %v", i, err)
+		}
+		if got := hex.EncodeToString(sig); got != v.sig {
+			t.Fatalf("vector %d: signature %s, want %s", i, got, v.sig)
+		}
+	}
+
+	n := secp256k1.Curve().Params().N
+	for i := 0; i < 4; i++ {
+		d, err := rand.Int(rand.Reader, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sk := toFixedBytes(d.Add(d, big.NewInt(1)).Mod(d, n), BIP340SecretKeySize)
+		msg := make([]byte, i*23)
+		rand.Read(msg)
+		pub, err := BIP340PublicKey(sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := SignBIP340(sk, msg, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig2, err := SignBIP340(sk, msg, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sig) != BIP340SignatureSize {
+			t.Fatalf("signature is %d bytes, want %d", len(sig), BIP340SignatureSize)
+		}
+		if bytes.Equal(sig, sig2) {
+			t.Fatalf("two signatures with fresh auxiliary data are both %x", sig)
+		}
+		if !VerifyBIP340(pub, msg, sig) || !VerifyBIP340(pub, msg, sig2) {
+			t.Fatalf("key %x: signature does not verify", sk)
+		}
+	}
+
+	msg := []byte("short message")
+	for _, sk := range [][]byte{make([]byte, 32), toFixedBytes(n, 32), make([]byte, 31)} {
+		if _, err := BIP340PublicKey(sk); err != ErrBIP340SecretKey {
+			t.Fatalf("public key of %x: %v, want %v", sk, err, ErrBIP340SecretKey)
+		}
+		if _, err := SignBIP340(sk, msg, nil); err != ErrBIP340SecretKey {
+			t.Fatalf("signing with %x: %v, want %v", sk, err, ErrBIP340SecretKey)
+		}
+	}
+	if _, err := SignBIP340(toFixedBytes(big.NewInt(3), 32), msg, make([]byte, 31)); err != ErrBIP340AuxRand {
+		t.Fatalf("31 bytes of auxiliary data: %v, want %v", err, ErrBIP340AuxRand)
+	}
+}
@@ -0,0 +1,229 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	ErrMnemonic         = errors.New("bip39: unknown word or wrong number of words")
+	ErrMnemonicChecksum = errors.New("bip39: mnemonic checksum mismatch")
+)
+
+var bip39Index = bip39_index(bip39English)
+
+func bip39_index(words []string) map[string]int {
+	index := make(map[string]int, len(words))
+	for i, w := range words {
+		index[w] = i
+	}
+	return index
+}
+
+/*
+Encodes entropy of 16 to 32 bytes, in steps of 4, as a BIP39 mnemonic:
+entropy ‖ the first |entropy|/32 bits of SHA-256(entropy), split into 11
+bit indices into the English wordlist, the words joined by single spaces.
+32 bytes give 24 words. The result is as secret as the entropy: the
+caller wipes it once shown or written down.
+*/
+func EntropyToMnemonic(entropy []byte) ([]byte, error) {
+	n := len(entropy)
+	if n < 16 || n > 32 || n%4 != 0 {
+		return nil, errors.New("bip39: entropy must be 16 to 32 bytes in steps of 4")
+	}
+	h := sha256.Sum256(entropy)
+	bits := append(append(make([]byte, 0, n+1), entropy...), h[0])
+	defer wipe_bytes(bits)
+	count := (8*n + n/4) / 11
+	var out []byte
+	for i := 0; i < count; i++ {
+		idx := 0
+		for j := i * 11; j < (i+1)*11; j++ {
+			idx = idx<<1 | int(bits[j/8]>>(7-j%8)&1)
+		}
+		if i > 0 {
+			out = append(out, ' ')
+		}
+		out = append(out, bip39English[idx]...)
+	}
+	return out, nil
+}
+
+/*
+Decodes a mnemonic of EntropyToMnemonic back into its entropy, checking
+the word count and the checksum. Words may be separated by any white
+space and are matched after NFKD normalization and lower casing. Unknown
+words and wrong counts fail with ErrMnemonic, a typo that still yields
+known words almost always with ErrMnemonicChecksum.
+*/
+func MnemonicToEntropy(mnemonic []byte) (*SecretBytes, error) {
+	words := bip39_words(mnemonic)
+	count := len(words)
+	if count < 12 || count > 24 || count%3 != 0 {
+		return nil, ErrMnemonic
+	}
+	n := 4 * count / 3
+	bits := make([]byte, n+1)
+	defer wipe_bytes(bits[n:])
+	for i, w := range words {
+		idx, ok := bip39Index[w]
+		if !ok {
+			wipe_bytes(bits)
+			return nil, ErrMnemonic
+		}
+		for j := 0; j < 11; j++ {
+			k := i*11 + j
+			bits[k/8] |= byte(idx>>(10-j)&1) << (7 - k%8)
+		}
+	}
+	entropy := bits[:n]
+	h := sha256.Sum256(entropy)
+	shift := 8 - n/4
+	if h[0]>>shift != bits[n]>>shift {
+		wipe_bytes(entropy)
+		return nil, ErrMnemonicChecksum
+	}
+	return NewSecretBytes(entropy), nil
+}
+
+// The words of a mnemonic, normalized to NFKD and lower case.
+func bip39_words(mnemonic []byte) []string {
+	return strings.Fields(strings.ToLower(norm.NFKD.String(string(mnemonic))))
+}
+
+// A fresh 24 word mnemonic of 32 bytes from the system CSPRNG.
+func NewMnemonic() ([]byte, error) {
+	entropy := make([]byte, 32)
+	defer wipe_bytes(entropy)
+	if _, err := rand.Read(entropy); err != nil {
+		return nil, err
+	}
+	return EntropyToMnemonic(entropy)
+}
+
+/*
+Derives the 64 byte BIP39 seed of a mnemonic and an optional passphrase,
+
+	PBKDF2-HMAC-SHA512(NFKD(mnemonic), "mnemonic" ‖ NFKD(passphrase), 2048)
+
+as wallets do, for keys derived from the seed rather than encoded in the
+mnemonic. Unlike BIP39, which derives a seed from any sentence, the
+mnemonic must pass MnemonicToEntropy, so that a mistyped backup fails
+instead of silently restoring a different key.
+*/
+func MnemonicToSeed(mnemonic, passphrase []byte) (*SecretBytes, error) {
+	entropy, err := MnemonicToEntropy(mnemonic)
+	if err != nil {
+		return nil, err
+	}
+	entropy.Wipe()
+	sentence := []byte(strings.Join(bip39_words(mnemonic), " "))
+	defer wipe_bytes(sentence)
+	salt := append([]byte("mnemonic"), norm.NFKD.Bytes(passphrase)...)
+	defer wipe_bytes(salt)
+	return NewSecretBytes(pbkdf2.Key(sentence, salt, 2048, 64, sha512.New)), nil
+}
+
+/*
+Encodes the secret scalar as a 24 word mnemonic, a backup that can be
+written down and read back with ECDSAPrivateKeyFromMnemonic. Only curves
+with 32 byte scalars, P-256 and secp256k1, fit a mnemonic; others fail
+with ErrUnsupportedAlgorithm. The caller wipes the result.
+*/
+func (key *ECDSAPrivateKey) Mnemonic() ([]byte, error) {
+	key.mu.Lock()
+	wiped := key.wiped
+	key.mu.Unlock()
+	if wiped {
+		return nil, ErrDestroyedSecret
+	}
+	curve := ecdsa_curve(key.Curve)
+	if curve.Params().N.BitLen() > 256 {
+		return nil, ErrUnsupportedAlgorithm
+	}
+	if err := check_ecdsa_scalar(curve, key.D); err != nil {
+		return nil, err
+	}
+	scalar := toFixedBytes(key.D, 32)
+	defer wipe_bytes(scalar)
+	return EntropyToMnemonic(scalar)
+}
+
+/*
+Restores the key of Mnemonic on curve, P-256 when nil, with the nonce
+cache enabled. The mnemonic must hold 24 words and a scalar in [1, n−1].
+*/
+func ECDSAPrivateKeyFromMnemonic(curve elliptic.Curve, mnemonic []byte) (*ECDSAPrivateKey, error) {
+	curve = ecdsa_curve(curve)
+	if curve.Params().N.BitLen() > 256 {
+		return nil, ErrUnsupportedAlgorithm
+	}
+	entropy, err := MnemonicToEntropy(mnemonic)
+	if err != nil {
+		return nil, err
+	}
+	defer entropy.Wipe()
+	if len(entropy.b) != 32 {
+		return nil, ErrMnemonic
+	}
+	d := fromFixedBytes(entropy.b)
+	if err := check_ecdsa_scalar(curve, d); err != nil {
+		wipe_int(d)
+		return nil, err
+	}
+	key := NewECDSAPrivateKey(d)
+	key.Curve = curve
+	return key, nil
+}
+
+/*
+Encodes an E222 secret scalar, reduced mod r, as a mnemonic of its
+e222Width bytes: 21 words, since the scalar has 224 bits rather than 256.
+The caller wipes the result.
+*/
+func E222Mnemonic(secret *SecretScalar) ([]byte, error) {
+	s, err := secret.Int()
+	if err != nil {
+		return nil, err
+	}
+	defer wipe_int(s)
+	s.Mod(s, e222Params.R)
+	if s.Sign() == 0 {
+		return nil, ErrZeroScalar
+	}
+	scalar := toFixedBytes(s, e222Width)
+	defer wipe_bytes(scalar)
+	return EntropyToMnemonic(scalar)
+}
+
+/*
+Restores the secret scalar of E222Mnemonic and its public key s × G,
+which the caller compares with the KeyObj being restored. Scalars not in
+[1, r−1] fail with ErrMnemonic.
+*/
+func E222KeyFromMnemonic(mnemonic []byte) (*SecretScalar, *E222, error) {
+	entropy, err := MnemonicToEntropy(mnemonic)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer entropy.Wipe()
+	if len(entropy.b) != e222Width {
+		return nil, nil, ErrMnemonic
+	}
+	s := fromFixedBytes(entropy.b)
+	defer wipe_int(s)
+	if s.Sign() == 0 || s.Cmp(e222Params.R) >= 0 {
+		return nil, nil, ErrMnemonic
+	}
+	return NewSecretScalar(s), e222Curve.BaseMul(s), nil
+}
@@ -0,0 +1,138 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+
+	"sig/secp256k1"
+)
+
+// BIP39 mnemonics match the vectors of BIP39 and restore ECDSA and E222 keys.
+func TestMnemonicBackup(t *testing.T) {
+
+	vectors := []struct {
+		entropy []byte
+		words   string
+	}{
+		{make([]byte, 32), strings.Repeat("abandon ", 23) + "art"},
+		{bytes.Repeat([]byte{0x7f}, 16), "legal winner thank year wave sausage worth useful legal winner thank yellow"},
+		{bytes.Repeat([]byte{0x80}, 16), "letter advice cage absurd amount doctor acoustic avoid letter advice cage above"},
+	}
+	for _, v := range vectors {
+		words, err := EntropyToMnemonic(v.entropy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(words) != v.words {
+			t.Fatalf("entropy %x: %q, want %q", v.entropy, words, v.words)
+		}
+	}
+	zero, legal := []byte(vectors[0].words), []byte(vectors[1].words)
+	seed, err := MnemonicToSeed([]byte(strings.Repeat("abandon ", 11)+"about"), []byte("TREZOR"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := hex.EncodeToString(seed.b), "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04"; got != want {
+		t.Fatalf("seed %s, want %s", got, want)
+	}
+	seed.Wipe()
+
+	// spacing and case do not matter; typos, unknown words and wrong counts do
+	entropy, err := MnemonicToEntropy([]byte("  Legal winner thank year\twave sausage worth useful legal winner thank YELLOW\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(entropy.b, bytes.Repeat([]byte{0x7f}, 16)) {
+		t.Fatalf("entropy %x", entropy.b)
+	}
+	refused := []struct {
+		words string
+		want  error
+	}{
+		{"legal winner thank year wave sausage worth useful legal winner thank year", ErrMnemonicChecksum},
+		{"legal winner thank year wave sausage worth useful legal winner thank yelow", ErrMnemonic},
+		{"legal winner thank year wave sausage worth useful legal winner thank", ErrMnemonic},
+	}
+	for _, r := range refused {
+		if _, err := MnemonicToEntropy([]byte(r.words)); err != r.want {
+			t.Fatalf("%q: %v, want %v", r.words, err, r.want)
+		}
+	}
+	if _, err := EntropyToMnemonic(make([]byte, 15)); err == nil {
+		t.Fatal("15 bytes of entropy make a mnemonic")
+	}
+
+	fresh, err := NewMnemonic()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(strings.Fields(string(fresh))); n != 24 {
+		t.Fatalf("new mnemonic of %d words, want 24", n)
+	}
+
+	// ECDSA keys on 32 byte curves round trip through 24 words
+	for _, curve := range []elliptic.Curve{elliptic.P256(), secp256k1.Curve()} {
+		d, _ := rand.Int(rand.Reader, curve.Params().N)
+		key := NewECDSAPrivateKey(d.Add(d, big.NewInt(1)))
+		key.Curve = curve
+		words, err := key.Mnemonic()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := len(strings.Fields(string(words))); n != 24 {
+			t.Fatalf("%s: mnemonic of %d words, want 24", curve.Params().Name, n)
+		}
+		restored, err := ECDSAPrivateKeyFromMnemonic(curve, words)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if restored.D.Cmp(key.D) != 0 || restored.Curve != curve {
+			t.Fatalf("%s: key does not round trip", curve.Params().Name)
+		}
+	}
+	p384 := NewECDSAPrivateKey(big.NewInt(5))
+	p384.Curve = elliptic.P384()
+	if _, err := p384.Mnemonic(); err != ErrUnsupportedAlgorithm {
+		t.Fatalf("P-384 key: %v, want %v", err, ErrUnsupportedAlgorithm)
+	}
+	if _, err := ECDSAPrivateKeyFromMnemonic(nil, legal); err != ErrMnemonic {
+		t.Fatalf("12 words: %v, want %v", err, ErrMnemonic)
+	}
+	if _, err := ECDSAPrivateKeyFromMnemonic(nil, zero); err != ErrZeroScalar {
+		t.Fatalf("zero key: %v, want %v", err, ErrZeroScalar)
+	}
+
+	// an E222 key restores from 21 words to the same public key
+	secret, V := e222_keypair_from_passphrase([]byte("mnemonic backup test"))
+	defer secret.Destroy()
+	words, err := E222Mnemonic(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(strings.Fields(string(words))); n != 21 {
+		t.Fatalf("E222 mnemonic of %d words, want 21", n)
+	}
+	restored, W, err := E222KeyFromMnemonic(words)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restored.Destroy()
+	if !W.Equals(V) {
+		t.Fatal("E222 key restores to another public key")
+	}
+	a, _ := secret.Int()
+	b, _ := restored.Int()
+	if a.Cmp(b) != 0 {
+		t.Fatalf("E222 key restores as %v, want %v", b, a)
+	}
+	if _, _, err := E222KeyFromMnemonic(zero); err != ErrMnemonic {
+		t.Fatalf("24 words for an E222 key: %v, want %v", err, ErrMnemonic)
+	}
+}
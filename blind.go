@@ -0,0 +1,93 @@
+package sig
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+
+	"sig/edwards"
+)
+
+var (
+	ErrBlindCommitment = errors.New("blind: invalid signer commitment")
+	ErrBlindSignature  = errors.New("blind: signer response does not match the commitment")
+)
+
+/*
+Blind E222 Schnorr signatures: a signer with key y issues a signature on
+a message it never sees, and cannot later link the signature to the
+session that produced it, as token and credential issuance need.
+
+	signer                              user
+	k random, R' = k × G     ── R' ──▶   α, β random
+	                                     R = R' + α × G − β × 4y
+	                                     e = H(R.x ‖ M), e' = e + β
+	s' = k − e'·4x           ◀── e' ──
+	                         ── s' ──▶   check s' × G + e' × 4y = R'
+	                                     s = s' + α
+
+(s, e) is an ordinary signature on M under y, checked by
+verify_sig_e222_version in the domain given to BlindE222. The signer only
+sees R', e' and s', which α and β make independent of (s, e).
+
+The signer must not run sessions concurrently: with many sessions open at
+once a user can combine their challenges into one more signature than the
+signer issued (the ROS attack of Benhamouda et al., 2021). BlindSigner
+enforces one session at a time.
+*/
+type BlindRequest struct {
+	Challenge  *big.Int // e', sent to the signer
+	key        *E222
+	commitment *edwards.Point
+	alpha      *big.Int
+	e          *big.Int
+}
+
+/*
+Blinds msg for signing in domain by the holder of y, who sent commitment
+R' to open the session. The request's Challenge goes to the signer; the
+rest stays with the user until Unblind.
+*/
+func BlindE222(domain string, y *E222, commitment *edwards.Point, msg []byte) (*BlindRequest, error) {
+	if y == nil || y.Curve() != e222Curve || validate_public_key(y) != nil {
+		return nil, edwards.ErrInvalidPoint
+	}
+	if commitment == nil || commitment.Curve() != e222Curve || !commitment.InPrimeSubgroup() {
+		return nil, ErrBlindCommitment
+	}
+	alpha, err := rand.Int(rand.Reader, e222Params.R)
+	if err != nil {
+		return nil, err
+	}
+	beta, err := rand.Int(rand.Reader, e222Params.R)
+	if err != nil {
+		return nil, err
+	}
+	R := commitment.Add(e222Curve.BaseMul(alpha)).Add(y.ClearCofactor().SecMul(beta).Neg())
+	e := e222_challenge(signatureVersion, domain, R.X(), &msg)
+	challenge := new(big.Int).Add(e, beta)
+	return &BlindRequest{
+		Challenge:  challenge.Mod(challenge, e222Params.R),
+		key:        y,
+		commitment: commitment,
+		alpha:      alpha,
+		e:          e,
+	}, nil
+}
+
+/*
+Turns the signer's response s' into the signature (s, e) on the message,
+after checking s' × G + e' × 4y = R'. Fails with ErrBlindSignature if the
+signer answered with anything else, which would yield no valid signature.
+*/
+func (req *BlindRequest) Unblind(response *big.Int) (*big.Int, *big.Int, error) {
+	if response == nil || response.Sign() < 0 || response.Cmp(e222Params.R) >= 0 {
+		return nil, nil, ErrBlindSignature
+	}
+	check := e222Curve.BaseMul(response).Add(req.key.ClearCofactor().SecMul(req.Challenge))
+	if !check.Equals(req.commitment) {
+		return nil, nil, ErrBlindSignature
+	}
+	s := new(big.Int).Add(response, req.alpha)
+	return s.Mod(s, e222Params.R), new(big.Int).Set(req.e), nil
+}
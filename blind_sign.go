@@ -0,0 +1,105 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"sync"
+
+	"sig/edwards"
+)
+
+var (
+	ErrBlindSessionOpen = errors.New("blind: a signing session is already open")
+	ErrBlindNoSession   = errors.New("blind: no signing session is open")
+)
+
+/*
+The signer's side of blind E222 Schnorr signatures, see BlindRequest.
+Holds a copy of the key and at most one open session, so that sessions
+run one after another; Commit refuses to open a second one until Sign or
+Abort closes the first. Safe for concurrent use.
+*/
+type BlindSigner struct {
+	mu    sync.Mutex
+	x     *SecretScalar
+	nonce *big.Int // k of the open session, nil if none
+}
+
+// Wraps a copy of x, which the caller remains responsible for.
+func NewBlindSigner(x *SecretScalar) (*BlindSigner, error) {
+	x_int, err := x.Int()
+	if err != nil {
+		return nil, err
+	}
+	defer wipe_int(x_int)
+	if err := check_scalar(x_int); err != nil {
+		return nil, err
+	}
+	return &BlindSigner{x: NewSecretScalar(x_int)}, nil
+}
+
+// Opens a session with a fresh nonce k in [1, r−1] and returns R' = k × G for the user.
+func (bs *BlindSigner) Commit() (*edwards.Point, error) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if bs.nonce != nil {
+		return nil, ErrBlindSessionOpen
+	}
+	k, err := rand.Int(rand.Reader, new(big.Int).Sub(e222Params.R, big.NewInt(1)))
+	if err != nil {
+		return nil, err
+	}
+	bs.nonce = k.Add(k, big.NewInt(1))
+	return e222Curve.BaseMul(bs.nonce), nil
+}
+
+/*
+Answers the blinded challenge e' of the open session with s' = k − e'·4x
+mod r and closes the session, wiping k. Fails with ErrBlindNoSession if
+no session is open.
+*/
+func (bs *BlindSigner) Sign(challenge *big.Int) (*big.Int, error) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if bs.nonce == nil {
+		return nil, ErrBlindNoSession
+	}
+	k := bs.nonce
+	bs.nonce = nil
+	defer wipe_int(k)
+	x_int, err := bs.x.Int()
+	if err != nil {
+		return nil, err
+	}
+	defer wipe_int(x_int)
+	x4 := cofactor_scalar(x_int)
+	defer wipe_int(x4)
+	xe := new(big.Int).Mul(x4, new(big.Int).Mod(challenge, e222Params.R))
+	defer wipe_int(xe)
+	s := new(big.Int).Sub(k, xe)
+	return s.Mod(s, e222Params.R), nil
+}
+
+// Closes the open session without signing, wiping its nonce.
+func (bs *BlindSigner) Abort() {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if bs.nonce != nil {
+		wipe_int(bs.nonce)
+		bs.nonce = nil
+	}
+}
+
+// Wipes the key and any open session. Sessions opened later fail in Sign with ErrDestroyedSecret.
+func (bs *BlindSigner) Destroy() {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bs.x.Destroy()
+	if bs.nonce != nil {
+		wipe_int(bs.nonce)
+		bs.nonce = nil
+	}
+}
@@ -0,0 +1,102 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestBlindSignatures(t *testing.T) {
+
+	x_int := generateRandomBigInt()
+	y := e222Curve.BaseMul(x_int)
+	signer, err := NewBlindSigner(NewSecretScalar(x_int))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("token 7f3a")
+
+	R, err := signer.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := signer.Commit(); err != ErrBlindSessionOpen {
+		t.Fatalf("second commit: %v, want %v", err, ErrBlindSessionOpen)
+	}
+	req, err := BlindE222("tokens", y, R, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := signer.Sign(req.Challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, e, err := req.Unblind(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verify_sig_e222_version(signatureVersion, "tokens", y, s, e, &msg) {
+		t.Fatal("unblinded signature does not verify")
+	}
+	if verify_sig_e222_version(signatureVersion, "", y, s, e, &msg) {
+		t.Fatal("unblinded signature verifies without its domain")
+	}
+
+	// the signer saw neither the challenge nor the response of the signature
+	if req.Challenge.Cmp(e) == 0 || response.Cmp(s) == 0 {
+		t.Fatal("signer saw the signature's challenge or response")
+	}
+
+	// the session is closed after signing and may be aborted before
+	if _, err := signer.Sign(req.Challenge); err != ErrBlindNoSession {
+		t.Fatalf("sign after signing: %v, want %v", err, ErrBlindNoSession)
+	}
+	if _, err := signer.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	signer.Abort()
+	if _, err := signer.Sign(req.Challenge); err != ErrBlindNoSession {
+		t.Fatalf("sign after abort: %v, want %v", err, ErrBlindNoSession)
+	}
+
+	// a response that does not match the commitment is caught before unblinding
+	R, err = signer.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err = BlindE222("", y, R, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err = signer.Sign(req.Challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := req.Unblind(new(big.Int).Add(response, big.NewInt(1))); err != ErrBlindSignature {
+		t.Fatalf("wrong response: %v, want %v", err, ErrBlindSignature)
+	}
+	s, e, err = req.Unblind(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verify_sig_e222_version(signatureVersion, "", y, s, e, &msg) {
+		t.Fatal("unblinded signature does not verify")
+	}
+
+	// commitments outside the prime order subgroup are refused
+	small := e222Curve.NewPointXY(*big.NewInt(0), *new(big.Int).Sub(e222Curve.Params().P, big.NewInt(1)))
+	if _, err := BlindE222("", y, R.Add(small), msg); err != ErrBlindCommitment {
+		t.Fatalf("small order commitment: %v, want %v", err, ErrBlindCommitment)
+	}
+
+	signer.Commit()
+	signer.Destroy()
+	if _, err := signer.Sign(big.NewInt(1)); err != ErrBlindNoSession {
+		t.Fatalf("sign after destroy: %v, want %v", err, ErrBlindNoSession)
+	}
+	signer.Commit()
+	if _, err := signer.Sign(big.NewInt(1)); err != ErrDestroyedSecret {
+		t.Fatalf("sign with a destroyed key: %v, want %v", err, ErrDestroyedSecret)
+	}
+}
@@ -1,5 +1,5 @@
 #!/bin/bash
 # go build run_tests.go secp256r1_ecdsa.go E222.go E222Tests.go E222_schnorr.go secp256r1_sig_Schnorr.go
-go build secp256r1_ecdsa.go
+go build -o secp256r1_ecdsa ./cmd/secp256r1_ecdsa
 # # Run the executable
 ./secp256r1_ecdsa
\ No newline at end of file
@@ -0,0 +1,162 @@
+//go:build !verifyonly
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"sig"
+)
+
+/*
+Program entry point. Arguments are dispatched to the subcommands before
+anything else runs; without arguments the signing demo is run.
+*/
+func main() {
+	if len(os.Args) > 1 {
+		os.Exit(run_command(os.Args[1:]))
+	}
+	run_demo()
+}
+
+/*
+Runs the subcommand named by args[0] and returns the exit code:
+
+	verify                       see run_verify_command
+	selftest                     run the ECDSA self test
+	vectors <seed> <count> [out] write Schnorr test vectors
+	agent <socket> <keystore>    serve the keystore's keys, see run_agent_command
+
+Unknown subcommands print the usage and return 2.
+*/
+func run_command(args []string) int {
+	if handled, code := run_verify_command(args); handled {
+		return code
+	}
+	switch args[0] {
+	case "selftest":
+		report := sig.SelfTest(100)
+		fmt.Print(report)
+		if !report.OK() {
+			return 1
+		}
+		return 0
+	case "vectors":
+		return run_vectors_command(args[1:])
+	case "agent":
+		return run_agent_command(args[1:])
+	}
+	fmt.Fprintln(os.Stderr, sig.T("usage.full"))
+	return 2
+}
+
+// Signs a random 5 MB message with ECDSA and verifies it after a bit flip.
+func run_demo() {
+	rnd := rand.Reader
+
+	// Generate a 256 bit random secret key
+	d_a_bytes := make([]byte, 32)
+	rnd.Read(d_a_bytes)
+	key := sig.NewECDSAPrivateKey(new(big.Int).SetBytes(d_a_bytes))
+	defer key.Wipe()
+
+	message := make([]byte, 5242880) //random 5mb
+	rnd.Read(message)
+
+	// Sign data using private signing key
+	r, s, err := key.Sign("", &message)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	message[0] ^= 1 // bit flip test
+	res, _ := sig.VerifyReader(key.PublicKey(), "", r, s, bytes.NewReader(message))
+	println("Verified: ", res)
+}
+
+// Writes test vectors for vectors <seed> <count> [output file], to stdout by default.
+func run_vectors_command(args []string) int {
+	if len(args) < 2 || len(args) > 3 {
+		fmt.Fprintln(os.Stderr, sig.T("usage.vectors"))
+		return 2
+	}
+	seed, err1 := strconv.ParseInt(args[0], 10, 64)
+	count, err2 := strconv.Atoi(args[1])
+	if err1 != nil || err2 != nil {
+		fmt.Fprintln(os.Stderr, sig.T("vectors.not_integers"))
+		return 2
+	}
+	out, err := sig.GenerateTestVectors(seed, count)
+	if err == nil && len(args) == 3 {
+		err = os.WriteFile(args[2], out, 0o644)
+	} else if err == nil {
+		_, err = os.Stdout.Write(out)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+/*
+Runs a signing agent on the Unix socket at args[0] for the keys of the
+keystore at args[1], whose master password is the first line of stdin, so
+that it can be piped from a secret manager rather than given on the
+command line. Serves until interrupted, then destroys the keys and
+removes the socket.
+*/
+func run_agent_command(args []string) int {
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, sig.T("usage.agent"))
+		return 2
+	}
+	pw, err := bufio.NewReader(os.Stdin).ReadBytes('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && len(pw) > 0) {
+		fmt.Fprintln(os.Stderr, sig.T("agent.no_password"))
+		return 2
+	}
+	pw = bytes.TrimRight(pw, "\r\n")
+	ks, err := sig.OpenKeystore(args[1], pw)
+	for i := range pw {
+		pw[i] = 0
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	agent, err := sig.NewAgent(ks)
+	ks.Destroy()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer agent.Destroy()
+	l, err := agent.Listen(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+	go func() {
+		<-stop
+		l.Close()
+	}()
+	if err := agent.Serve(l); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
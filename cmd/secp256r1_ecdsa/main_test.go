@@ -0,0 +1,46 @@
+//go:build !verifyonly
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sig"
+)
+
+// Subcommands run headless and report failures through their exit code.
+func TestCommandDispatch(t *testing.T) {
+
+	out := filepath.Join(t.TempDir(), "dispatch_vectors.json")
+	for _, c := range []struct {
+		args []string
+		code int
+	}{
+		{[]string{"no-such-command"}, 2},
+		{[]string{"verify"}, 2},
+		{[]string{"verify", "--bogus", "a", "b"}, 2},
+		{[]string{"verify", "missing.sig", "missing.txt"}, 2},
+		{[]string{"vectors", "seed", "1"}, 2},
+		{[]string{"vectors", "1", "1", out}, 0},
+		{[]string{"agent", "socket-without-keystore"}, 2},
+	} {
+		if code := run_command(c.args); code != c.code {
+			t.Fatalf("%s: exit code %d, want %d", strings.Join(c.args, " "), code, c.code)
+		}
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := sig.GenerateTestVectors(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, want) {
+		t.Fatalf("vectors command wrote %d bytes that differ from GenerateTestVectors", len(data))
+	}
+}
@@ -0,0 +1,19 @@
+//go:build verifyonly
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"sig"
+)
+
+/** Verify-only entry point: no key generation, signing or encryption is linked in */
+func main() {
+	if handled, code := run_verify_command(os.Args[1:]); handled {
+		os.Exit(code)
+	}
+	fmt.Fprintln(os.Stderr, sig.T("usage.main"))
+	os.Exit(2)
+}
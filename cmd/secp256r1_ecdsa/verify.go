@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"sig"
+)
+
+/*
+Runs the subcommands available in every build configuration:
+
+	verify <signature file> <message file>   verify an armored or JSON signature
+	verify <document>                        verify <document>.sig over the document
+
+verify takes --context <label> to verify in a signing domain other than
+the empty one, and --keys <file> to check the signer against a file of
+exported keys: signatures by revoked keys, and by keys expired when they
+signed, then fail; see sig.CheckSignerInKeyFile.
+
+Returns false if args name no such subcommand, otherwise the exit code.
+*/
+func run_verify_command(args []string) (bool, int) {
+	if len(args) == 0 {
+		return false, 0
+	}
+	switch args[0] {
+	case "verify":
+		flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+		flags.SetOutput(io.Discard)
+		domain := flags.String("context", "", "signing domain")
+		keys := flags.String("keys", "", "file of exported keys")
+		if err := flags.Parse(args[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, sig.T("usage.verify"))
+			return true, 2
+		}
+		args = append([]string{args[0]}, flags.Args()...)
+		if len(args) == 2 {
+			args = []string{args[0], sig.DetachedSigPath(args[1]), args[1]}
+		}
+		if len(args) != 3 {
+			fmt.Fprintln(os.Stderr, sig.T("usage.verify"))
+			return true, 2
+		}
+		armored, err := os.ReadFile(args[1])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return true, 2
+		}
+		c, _, err := sig.DetectSignature(string(armored))
+		if err == nil && c.Scheme == "" {
+			err = errors.New(sig.T("verify.no_key"))
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return true, 2
+		}
+		var ok bool
+		if c.IsDetached() {
+			res, err := sig.VerifyDetachedSignature(args[2], args[1], nil, *domain)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return true, 2
+			}
+			for _, w := range res.Warnings {
+				fmt.Fprintln(os.Stderr, sig.T("verify.warning", w))
+			}
+			ok = res.Valid
+			if ok {
+				fmt.Println(sig.T("verify.signed_by", res.Fingerprint))
+				fmt.Println(sig.T("verify.fingerprint_words", res.Words))
+				if !res.Created.IsZero() {
+					fmt.Println(sig.T("verify.signed_at", sig.FormatCreated(res.Created)))
+				}
+			}
+		} else {
+			msg, err := os.ReadFile(args[2])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return true, 2
+			}
+			if ok, err = sig.VerifyContainer(c, *domain, &msg); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return true, 2
+			}
+		}
+		if ok && *keys != "" {
+			warnings, err := sig.CheckSignerInKeyFile(*keys, c, time.Now())
+			for _, w := range warnings {
+				fmt.Fprintln(os.Stderr, sig.T("verify.warning", w))
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				ok = false
+			}
+		}
+		if id, err := sig.SchemeAlgorithm(c.Scheme); err == nil {
+			fmt.Println(sig.T("verify.algorithm", id))
+		}
+		fmt.Println(sig.T("verify.result", ok))
+		if !ok {
+			return true, 1
+		}
+		return true, 0
+	}
+	return false, 0
+}
@@ -0,0 +1,204 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"math/big"
+	"math/bits"
+)
+
+/*
+Constant time arithmetic modulo an odd public modulus n, used for the
+secret scalars of ECDSA signing in place of big.Int, whose running time
+depends on the values it holds. Residues are fixed length little endian
+slices of 64-bit limbs, kept in Montgomery form a·R mod n with R = 2^(64L).
+Loops run over all limbs and results are chosen with masks, never with
+branches on secret data. Only n and public exponents steer control flow.
+*/
+type ctModulus struct {
+	n     []uint64
+	n0inv uint64   // −n⁻¹ mod 2⁶⁴
+	rr    []uint64 // R² mod n, to enter Montgomery form
+	big   *big.Int
+}
+
+func new_ct_modulus(n *big.Int) *ctModulus {
+	L := (n.BitLen() + 63) / 64
+	m := &ctModulus{big: n}
+	m.n = m.limbs(n)
+
+	// Newton iteration for n⁻¹ mod 2⁶⁴, doubling the correct bits each step
+	inv := uint64(1)
+	for i := 0; i < 6; i++ {
+		inv *= 2 - m.n[0]*inv
+	}
+	m.n0inv = -inv
+
+	rr := new(big.Int).Lsh(big.NewInt(1), uint(128*L))
+	m.rr = m.limbs(rr.Mod(rr, n))
+	return m
+}
+
+// Fixed width limbs of v, which must be below R.
+func (m *ctModulus) limbs(v *big.Int) []uint64 {
+	L := (m.big.BitLen() + 63) / 64
+	b := toFixedBytes(v, 8*L)
+	out := make([]uint64, L)
+	for i := range out {
+		for j := 0; j < 8; j++ {
+			out[i] |= uint64(b[len(b)-1-8*i-j]) << uint(8*j)
+		}
+	}
+	return out
+}
+
+// The integer held by the limbs a.
+func (m *ctModulus) to_int(a []uint64) *big.Int {
+	b := make([]byte, 8*len(a))
+	for i, w := range a {
+		for j := 0; j < 8; j++ {
+			b[len(b)-1-8*i-j] = byte(w >> uint(8*j))
+		}
+	}
+	defer wipe_bytes(b)
+	return new(big.Int).SetBytes(b)
+}
+
+// Returns a if choice is 1 and b if it is 0, without branching.
+func ct_select(choice uint64, a, b []uint64) []uint64 {
+	mask := -choice
+	out := make([]uint64, len(a))
+	for i := range out {
+		out[i] = b[i] ^ (mask & (a[i] ^ b[i]))
+	}
+	return out
+}
+
+// a − n and the borrow out, for the conditional subtractions.
+func (m *ctModulus) sub_n(a []uint64) ([]uint64, uint64) {
+	out := make([]uint64, len(m.n))
+	var borrow uint64
+	for i := range out {
+		out[i], borrow = bits.Sub64(a[i], m.n[i], borrow)
+	}
+	return out, borrow
+}
+
+// 1 if a < n, else 0.
+func (m *ctModulus) less(a []uint64) uint64 {
+	_, borrow := m.sub_n(a)
+	return borrow
+}
+
+// a + b mod n for a, b < n.
+func (m *ctModulus) add(a, b []uint64) []uint64 {
+	sum := make([]uint64, len(m.n))
+	var carry uint64
+	for i := range sum {
+		sum[i], carry = bits.Add64(a[i], b[i], carry)
+	}
+	diff, borrow := m.sub_n(sum)
+	// keep the difference if the sum overflowed R or is at least n
+	return ct_select(carry|(1^borrow), diff, sum)
+}
+
+/*
+Montgomery product a·b·R⁻¹ mod n by coarsely integrated operand scanning.
+Requires a·b < n·R, which holds when one factor is below n and the other
+below R, and returns a value below n.
+*/
+func (m *ctModulus) mul(a, b []uint64) []uint64 {
+	L := len(m.n)
+	t := make([]uint64, L+2)
+	for i := 0; i < L; i++ {
+		// t += a · b[i]
+		var c uint64
+		for j := 0; j < L; j++ {
+			hi, lo := bits.Mul64(a[j], b[i])
+			lo, c1 := bits.Add64(lo, t[j], 0)
+			lo, c2 := bits.Add64(lo, c, 0)
+			t[j], c = lo, hi+c1+c2
+		}
+		var c3 uint64
+		t[L], c3 = bits.Add64(t[L], c, 0)
+		t[L+1] += c3
+
+		// t = (t + q·n) / 2⁶⁴ with q chosen to clear the low limb
+		q := t[0] * m.n0inv
+		hi, lo := bits.Mul64(q, m.n[0])
+		_, c1 := bits.Add64(lo, t[0], 0)
+		c = hi + c1
+		for j := 1; j < L; j++ {
+			hi, lo := bits.Mul64(q, m.n[j])
+			lo, c1 := bits.Add64(lo, t[j], 0)
+			lo, c2 := bits.Add64(lo, c, 0)
+			t[j-1], c = lo, hi+c1+c2
+		}
+		t[L-1], c3 = bits.Add64(t[L], c, 0)
+		t[L] = t[L+1] + c3
+		t[L+1] = 0
+	}
+	diff, borrow := m.sub_n(t[:L])
+	// t < 2n: subtract n unless t had no overflow limb and was already below n
+	return ct_select(t[L]|(1^borrow), diff, t[:L])
+}
+
+// a·R mod n for a < R.
+func (m *ctModulus) to_mont(a []uint64) []uint64 { return m.mul(a, m.rr) }
+
+// a·R⁻¹ mod n, leaving Montgomery form.
+func (m *ctModulus) from_mont(a []uint64) []uint64 {
+	one := make([]uint64, len(m.n))
+	one[0] = 1
+	return m.mul(a, one)
+}
+
+// a^e in Montgomery form for a public exponent e, by square-and-multiply.
+func (m *ctModulus) exp(a []uint64, e *big.Int) []uint64 {
+	one := make([]uint64, len(m.n))
+	one[0] = 1
+	res := m.to_mont(one)
+	for i := e.BitLen() - 1; i >= 0; i-- {
+		res = m.mul(res, res)
+		if e.Bit(i) == 1 {
+			res = m.mul(res, a)
+		}
+	}
+	return res
+}
+
+// Zeroes the limbs of secret values.
+func wipe_limbs(vs ...[]uint64) {
+	for _, v := range vs {
+		for i := range v {
+			v[i] = 0
+		}
+	}
+}
+
+/*
+s = k⁻¹(z + r·d) mod n in constant time with respect to the secrets d and
+k. k⁻¹ is k^(n−2) by Fermat, n being prime, whose exponent is public. d
+is reduced with big.Int first only if it is wider than n, which keys in
+range never are.
+*/
+func ct_ecdsa_s(n, z, r, d, k *big.Int) *big.Int {
+	m := new_ct_modulus(n)
+	if d.BitLen() > 64*len(m.n) {
+		d = new(big.Int).Mod(d, n)
+	}
+	if z.BitLen() > 64*len(m.n) {
+		z = new(big.Int).Mod(z, n)
+	}
+	d_m := m.to_mont(m.limbs(d))
+	k_m := m.to_mont(m.limbs(k))
+	r_m := m.to_mont(m.limbs(r))
+	z_m := m.to_mont(m.limbs(z))
+	defer wipe_limbs(d_m, k_m)
+
+	t := m.add(m.mul(r_m, d_m), z_m) // z + r·d
+	k_inv := m.exp(k_m, new(big.Int).Sub(n, big.NewInt(2)))
+	s_m := m.mul(k_inv, t)
+	defer wipe_limbs(t, k_inv, s_m)
+	return m.to_int(m.from_mont(s_m))
+}
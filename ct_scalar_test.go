@@ -0,0 +1,72 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"sig/secp256k1"
+)
+
+// The constant time scalar arithmetic agrees with big.Int on every supported order.
+func TestConstantTimeScalars(t *testing.T) {
+
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521(), secp256k1.Curve()} {
+		name := curve.Params().Name
+		n := curve.Params().N
+		m := new_ct_modulus(n)
+		nm1 := new(big.Int).Sub(n, big.NewInt(1))
+		values := []*big.Int{big.NewInt(0), big.NewInt(1), nm1}
+		for i := 0; i < 6; i++ {
+			v, err := rand.Int(rand.Reader, n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			values = append(values, v)
+		}
+		for i, a := range values {
+			b := values[(i+4)%len(values)]
+			am, bm := m.to_mont(m.limbs(a)), m.to_mont(m.limbs(b))
+			prod := new(big.Int).Mul(a, b)
+			prod.Mod(prod, n)
+			if got := m.to_int(m.from_mont(m.mul(am, bm))); got.Cmp(prod) != 0 {
+				t.Fatalf("%s: %x · %x = %x, want %x", name, a, b, got, prod)
+			}
+			sum := new(big.Int).Add(a, b)
+			sum.Mod(sum, n)
+			if got := m.to_int(m.from_mont(m.add(am, bm))); got.Cmp(sum) != 0 {
+				t.Fatalf("%s: %x + %x = %x, want %x", name, a, b, got, sum)
+			}
+			if m.less(m.limbs(a)) != 1 {
+				t.Fatalf("%s: %x is not less than n", name, a)
+			}
+			if a.Sign() != 0 {
+				inv := m.to_int(m.from_mont(m.exp(am, new(big.Int).Sub(n, big.NewInt(2)))))
+				if want := new(big.Int).ModInverse(a, n); inv.Cmp(want) != 0 {
+					t.Fatalf("%s: 1/%x = %x, want %x", name, a, inv, want)
+				}
+			}
+		}
+		if m.less(m.limbs(n)) != 0 {
+			t.Fatalf("%s: n is less than n", name)
+		}
+
+		// s = k⁻¹(z + r·d) against big.Int, z as wide as a SHA-256 hash
+		d, _ := rand.Int(rand.Reader, n)
+		r, _ := rand.Int(rand.Reader, n)
+		k, _ := rand.Int(rand.Reader, n)
+		if k.Sign() == 0 {
+			k.SetInt64(1)
+		}
+		z := new(big.Int).SetBytes(bytes.Repeat([]byte{0xff}, 32))
+		want := new(big.Int).Mul(r, d)
+		want.Add(want, z).Mul(want, new(big.Int).ModInverse(k, n)).Mod(want, n)
+		if s := ct_ecdsa_s(n, z, r, d, k); s.Cmp(want) != 0 {
+			t.Fatalf("%s: s = %x, want %x", name, s, want)
+		}
+	}
+}
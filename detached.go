@@ -0,0 +1,156 @@
+package sig
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Extension of a detached signature file next to its document.
+const detachedSigExt = ".sig"
+
+// Domain prefix of the input signed by a detached signature.
+var detachedPrefix = []byte("detached-signature\x00")
+
+// Domain prefix of the input signed by a detached signature that records its signing time.
+var detachedCreatedPrefix = []byte("detached-signature-created\x00")
+
+/*
+Outcome of verifying a detached signature. A document whose name differs
+from the one embedded at signing time still verifies, with a warning.
+*/
+type VerifyResult struct {
+	Valid       bool
+	Scheme      string
+	Algorithm   AlgorithmID
+	Fingerprint string    // of the signing key
+	Words       string    // Fingerprint.Words of the signing key, once its fingerprint checks out
+	Filename    string    // as embedded at signing time
+	Created     time.Time // signing time, zero for signatures that predate it
+	Warnings    []string
+}
+
+// Path of the detached signature for the document at docPath.
+func DetachedSigPath(docPath string) string {
+	return docPath + detachedSigExt
+}
+
+// Fingerprint of an encoded public key: hex of the first 16 bytes of its SHA3-256, as key_id.
+func key_fingerprint(pub []byte) string {
+	return fingerprint_of(pub).Short()
+}
+
+/*
+Input signed by a detached signature, binding the document to the signer
+fingerprint and the original filename:
+
+	"detached-signature" 0x00 || len-prefixed filename || len-prefixed fingerprint || document
+*/
+func detached_input(filename, fingerprint string, doc []byte) []byte {
+	out := append([]byte{}, detachedPrefix...)
+	out = append(out, length_prefixed([]byte(filename))...)
+	out = append(out, length_prefixed([]byte(fingerprint))...)
+	return append(out, doc...)
+}
+
+/*
+Input signed by a detached signature that records its signing time, which
+the signature then covers as well:
+
+	"detached-signature-created" 0x00 || len-prefixed filename ||
+	len-prefixed fingerprint || len-prefixed RFC 3339 time || document
+
+The distinct prefix keeps the time from being stripped to pass the
+signature off as one over detached_input.
+*/
+func detached_input_created(filename, fingerprint string, created time.Time, doc []byte) []byte {
+	out := append([]byte{}, detachedCreatedPrefix...)
+	out = append(out, length_prefixed([]byte(filename))...)
+	out = append(out, length_prefixed([]byte(fingerprint))...)
+	out = append(out, length_prefixed([]byte(FormatCreated(created)))...)
+	return append(out, doc...)
+}
+
+// Input signed by the detached signature c over doc, with or without its signing time.
+func (c *SignatureContainer) detached_input(doc []byte) []byte {
+	if c.Created.IsZero() {
+		return detached_input(c.Filename, c.Fingerprint, doc)
+	}
+	return detached_input_created(c.Filename, c.Fingerprint, c.Created, doc)
+}
+
+// Signing time as recorded in containers: RFC 3339 in UTC to the second, as key export writes it.
+func FormatCreated(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// True iff c carries detached signature metadata.
+func (c *SignatureContainer) IsDetached() bool {
+	return c.Filename != "" || c.Fingerprint != ""
+}
+
+// Writes the armored detached signature c to sigPath.
+func WriteDetachedSignature(sigPath string, c *SignatureContainer) error {
+	if !c.IsDetached() {
+		return errors.New("container holds no detached signature metadata")
+	}
+	return os.WriteFile(sigPath, []byte(EncodeContainer(c)), 0o644)
+}
+
+/*
+Verifies the detached signature at sigPath over the document at docPath
+in domain. If pub is not nil the signature must have been made by that encoded
+public key. Errors are returned for unreadable or malformed input only;
+a bad signature yields a result with Valid unset.
+*/
+func VerifyDetachedSignature(docPath, sigPath string, pub []byte, domain string) (VerifyResult, error) {
+	armored, err := os.ReadFile(sigPath)
+	if err != nil {
+		return VerifyResult{}, err
+	}
+	c, err := DecodeContainer(string(armored))
+	if err != nil {
+		return VerifyResult{}, err
+	}
+	if !c.IsDetached() {
+		return VerifyResult{}, errors.New("not a detached signature")
+	}
+	doc, err := os.Open(docPath)
+	if err != nil {
+		return VerifyResult{}, err
+	}
+	defer doc.Close()
+	return VerifyDetachedReader(doc, filepath.Base(docPath), c, pub, domain)
+}
+
+/*
+Verifies the detached signature c over the document read from doc, named
+docName, as VerifyDetachedSignature does for files. E222 Schnorr and ECDSA
+signatures are checked as the document streams past, so it never has to
+fit in memory; the other schemes read it whole, see verify_container_reader.
+*/
+func VerifyDetachedReader(doc io.Reader, docName string, c *SignatureContainer, pub []byte, domain string) (VerifyResult, error) {
+	var res VerifyResult
+	if !c.IsDetached() {
+		return res, errors.New("not a detached signature")
+	}
+	res.Scheme, res.Filename, res.Fingerprint, res.Created = c.Scheme, c.Filename, c.Fingerprint, c.Created
+	res.Algorithm, _ = SchemeAlgorithm(c.Scheme)
+	if c.Fingerprint != key_fingerprint(c.PublicKey) {
+		return res, nil
+	}
+	if pub != nil && key_fingerprint(pub) != c.Fingerprint {
+		return res, nil
+	}
+	res.Words = fingerprint_of(c.PublicKey).Words()
+	var err error
+	if res.Valid, err = verify_container_reader(c, domain, doc); err != nil {
+		return res, err
+	}
+	if res.Valid && docName != c.Filename {
+		res.Warnings = append(res.Warnings, T("verify.renamed", docName, c.Filename))
+	}
+	return res, nil
+}
@@ -0,0 +1,120 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Time recorded in new detached signatures, truncated to the second the Created header keeps.
+func signing_time() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// Signs the document at docPath in domain under x as a detached E222 Schnorr signature, streaming the document from disk.
+func sign_detached_e222(x *SecretScalar, domain string, docPath string) (*SignatureContainer, error) {
+	doc, err := os.Open(docPath)
+	if err != nil {
+		return nil, err
+	}
+	defer doc.Close()
+	x_int, err := x.Int()
+	if err != nil {
+		return nil, err
+	}
+	c := &SignatureContainer{
+		Scheme:    schemeSchnorrE222,
+		PublicKey: e222_to_bytes(e222Curve.BaseMul(x_int)),
+		Filename:  filepath.Base(docPath),
+		Created:   signing_time(),
+		Domain:    domain,
+	}
+	wipe_int(x_int)
+	c.Fingerprint = key_fingerprint(c.PublicKey)
+	s, e, err := sign_stream_e222(x, domain, c.detached_input(nil), doc)
+	if err != nil {
+		return nil, err
+	}
+	c.Signature = EncodeSignature(&SchnorrSignature{Curve: curveE222, S: s, E: e})
+	return c, nil
+}
+
+// Signs the document at docPath in domain under key as a detached ECDSA signature, streaming the document from disk. Containers carry P-256 keys only.
+func sign_detached_ecdsa(key *ECDSAPrivateKey, domain string, docPath string) (*SignatureContainer, error) {
+	doc, err := os.Open(docPath)
+	if err != nil {
+		return nil, err
+	}
+	defer doc.Close()
+	curve := elliptic.P256()
+	if ecdsa_curve(key.Curve) != curve {
+		return nil, ErrUnsupportedAlgorithm
+	}
+	qx, qy := curve.ScalarBaseMult(toFixedBytes(key.D, p256Width))
+	c := &SignatureContainer{
+		Scheme:    schemeECDSAP256,
+		PublicKey: elliptic.Marshal(curve, qx, qy),
+		Filename:  filepath.Base(docPath),
+		Created:   signing_time(),
+		Domain:    domain,
+	}
+	c.Fingerprint = key_fingerprint(c.PublicKey)
+	prefix := c.detached_input(nil)
+	r, s, err := key.SignReader(domain, io.MultiReader(bytes.NewReader(prefix), doc))
+	if err != nil {
+		return nil, err
+	}
+	c.Signature = append(toFixedBytes(r, p256Width), toFixedBytes(s, p256Width)...)
+	return c, nil
+}
+
+// Signs the document at docPath as a detached Ed25519 signature under the key of seed. Ed25519 signs in the empty domain only.
+func sign_detached_ed25519(seed []byte, domain string, docPath string) (*SignatureContainer, error) {
+	if domain != "" {
+		return nil, ErrEd25519Domain
+	}
+	pub, err := Ed25519PublicKey(seed)
+	if err != nil {
+		return nil, err
+	}
+	return sign_detached_eddsa(ed25519_container(pub, nil), docPath, func(input []byte) ([]byte, error) {
+		return SignEd25519(seed, input)
+	})
+}
+
+// Signs the document at docPath in domain, the RFC 8032 context, as a detached Ed448 signature under the key of seed.
+func sign_detached_ed448(seed []byte, domain string, docPath string) (*SignatureContainer, error) {
+	pub, err := Ed448PublicKey(seed)
+	if err != nil {
+		return nil, err
+	}
+	c := ed448_container(pub, nil)
+	c.Domain = domain
+	return sign_detached_eddsa(c, docPath, func(input []byte) ([]byte, error) {
+		return SignEd448(seed, input, []byte(domain))
+	})
+}
+
+/*
+Fills in the detached metadata of c for docPath and the signature made by
+sign over it. Pure EdDSA hashes the message twice, for the nonce and for
+the challenge, so the document is read into memory here.
+*/
+func sign_detached_eddsa(c *SignatureContainer, docPath string, sign func(input []byte) ([]byte, error)) (*SignatureContainer, error) {
+	doc, err := os.ReadFile(docPath)
+	if err != nil {
+		return nil, err
+	}
+	c.Filename = filepath.Base(docPath)
+	c.Created = signing_time()
+	c.Fingerprint = key_fingerprint(c.PublicKey)
+	if c.Signature, err = sign(c.detached_input(doc)); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,280 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// Detached .sig files verify their document, warn on rename and fail on corruption.
+func TestDetachedSignatureFiles(t *testing.T) {
+
+	dir := t.TempDir()
+	doc := filepath.Join(dir, "document.pdf")
+	if err := os.WriteFile(doc, []byte("%PDF-1.4 not really a pdf"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	x := NewSecretScalar(generateRandomBigInt())
+	key := NewECDSAPrivateKey(new(big.Int).Mod(generateRandomBigInt(), elliptic.P256().Params().N))
+	c1, err := sign_detached_e222(x, "", doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := sign_detached_ecdsa(key, "", doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []*SignatureContainer{c1, c2} {
+		sigPath := DetachedSigPath(doc)
+		if err := WriteDetachedSignature(sigPath, c); err != nil {
+			t.Fatal(err)
+		}
+
+		// matching document
+		res, err := VerifyDetachedSignature(doc, sigPath, c.PublicKey, "")
+		if err != nil {
+			t.Fatalf("%s: %v", c.Scheme, err)
+		}
+		if !res.Valid || len(res.Warnings) != 0 {
+			t.Fatalf("%s: valid %v, warnings %v", c.Scheme, res.Valid, res.Warnings)
+		}
+		if res.Filename != "document.pdf" || res.Fingerprint != key_fingerprint(c.PublicKey) {
+			t.Fatalf("%s: signed %s by %s", c.Scheme, res.Filename, res.Fingerprint)
+		}
+
+		// renamed document verifies with a warning
+		renamed := filepath.Join(dir, "renamed.pdf")
+		if err := os.Rename(doc, renamed); err != nil {
+			t.Fatal(err)
+		}
+		res, err = VerifyDetachedSignature(renamed, sigPath, nil, "")
+		if err != nil {
+			t.Fatalf("%s renamed: %v", c.Scheme, err)
+		}
+		if !res.Valid || len(res.Warnings) != 1 {
+			t.Fatalf("%s renamed: valid %v, warnings %v, want valid with one warning", c.Scheme, res.Valid, res.Warnings)
+		}
+		if err := os.Rename(renamed, doc); err != nil {
+			t.Fatal(err)
+		}
+
+		// corrupted document
+		data, err := os.ReadFile(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data[0] ^= 1
+		if err := os.WriteFile(doc, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		res, err = VerifyDetachedSignature(doc, sigPath, nil, "")
+		if err != nil || res.Valid {
+			t.Fatalf("%s corrupted: valid %v, %v", c.Scheme, res.Valid, err)
+		}
+		data[0] ^= 1
+		if err := os.WriteFile(doc, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		// the embedded filename is authenticated
+		forged := *c
+		forged.Filename = "invoice.pdf"
+		if err := WriteDetachedSignature(sigPath, &forged); err != nil {
+			t.Fatal(err)
+		}
+		res, err = VerifyDetachedSignature(doc, sigPath, nil, "")
+		if err != nil || res.Valid {
+			t.Fatalf("%s with a forged filename: valid %v, %v", c.Scheme, res.Valid, err)
+		}
+
+		// wrong expected signer
+		if err := WriteDetachedSignature(sigPath, c); err != nil {
+			t.Fatal(err)
+		}
+		res, _ = VerifyDetachedSignature(doc, sigPath, e222_to_bytes(e222Curve.GenPoint()), "")
+		if res.Valid {
+			t.Fatalf("%s verifies for the wrong signer", c.Scheme)
+		}
+	}
+}
+
+// Detached signatures are made and checked from streams and agree with the in-memory path.
+func TestDetachedStreaming(t *testing.T) {
+
+	doc := filepath.Join(t.TempDir(), "large.bin")
+	data := make([]byte, 3*verifyChunkSize+12345)
+	rand.Read(data)
+	if err := os.WriteFile(doc, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	x := NewSecretScalar(generateRandomBigInt())
+	key := NewECDSAPrivateKey(new(big.Int).Mod(generateRandomBigInt(), elliptic.P256().Params().N))
+	seed := make([]byte, Ed448SeedSize)
+	rand.Read(seed)
+	c1, err := sign_detached_e222(x, "app", doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := sign_detached_ecdsa(key, "app", doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c3, err := sign_detached_ed448(seed, "app", doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []*SignatureContainer{c1, c2, c3} {
+		// the in-memory path accepts what was signed from a stream
+		if ok, err := VerifyContainer(c, "app", &data); !ok || err != nil {
+			t.Fatalf("%s in memory: %v, %v", c.Scheme, ok, err)
+		}
+
+		res, err := VerifyDetachedReader(bytes.NewReader(data), "large.bin", c, c.PublicKey, "app")
+		if err != nil {
+			t.Fatalf("%s: %v", c.Scheme, err)
+		}
+		if !res.Valid || len(res.Warnings) != 0 {
+			t.Fatalf("%s: valid %v, warnings %v", c.Scheme, res.Valid, res.Warnings)
+		}
+		res, err = VerifyDetachedReader(iotest.HalfReader(bytes.NewReader(data)), "copy.bin", c, nil, "app")
+		if err != nil {
+			t.Fatalf("%s renamed: %v", c.Scheme, err)
+		}
+		if !res.Valid || len(res.Warnings) != 1 {
+			t.Fatalf("%s renamed: valid %v, warnings %v, want valid with one warning", c.Scheme, res.Valid, res.Warnings)
+		}
+		if res, _ := VerifyDetachedReader(bytes.NewReader(data), "large.bin", c, nil, "other"); res.Valid {
+			t.Fatalf("%s verifies in another domain", c.Scheme)
+		}
+
+		tampered := append([]byte(nil), data...)
+		tampered[len(tampered)-1] ^= 1
+		res, err = VerifyDetachedReader(bytes.NewReader(tampered), "large.bin", c, nil, "app")
+		if err != nil || res.Valid {
+			t.Fatalf("%s tampered: valid %v, %v", c.Scheme, res.Valid, err)
+		}
+
+		failing := io.MultiReader(bytes.NewReader(data[:1000]), iotest.ErrReader(errors.New("disk")))
+		if _, err := VerifyDetachedReader(failing, "large.bin", c, nil, "app"); err == nil {
+			t.Fatalf("%s: failing reader gives no error", c.Scheme)
+		}
+	}
+
+	// signing from a stream fails on read errors and signs nothing
+	if _, _, err := sign_stream_e222(x, "", nil, iotest.ErrReader(errors.New("disk"))); err == nil {
+		t.Fatal("signing a failing stream gives no error")
+	}
+}
+
+func TestDetachedSigningTime(t *testing.T) {
+
+	doc := filepath.Join(t.TempDir(), "doc.txt")
+	data := []byte("signed at a known time")
+	if err := os.WriteFile(doc, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	x_int := generateRandomBigInt()
+	x := NewSecretScalar(x_int)
+	key := NewECDSAPrivateKey(new(big.Int).Mod(generateRandomBigInt(), elliptic.P256().Params().N))
+	seed := make([]byte, Ed25519SeedSize)
+	rand.Read(seed)
+	before := time.Now().UTC().Truncate(time.Second)
+	c1, err := sign_detached_e222(x, "", doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := sign_detached_ecdsa(key, "", doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c3, err := sign_detached_ed25519(seed, "", doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := time.Now().UTC()
+
+	for _, c := range []*SignatureContainer{c1, c2, c3} {
+		// the time, algorithm and fingerprint survive armoring and are reported
+		decoded, err := DecodeContainer(EncodeContainer(c))
+		if err != nil {
+			t.Fatalf("%s: %v", c.Scheme, err)
+		}
+		if !decoded.Created.Equal(c.Created) {
+			t.Fatalf("%s: created %v, decodes as %v", c.Scheme, c.Created, decoded.Created)
+		}
+		res, err := VerifyDetachedReader(bytes.NewReader(data), "doc.txt", decoded, nil, "")
+		if err != nil {
+			t.Fatalf("%s: %v", c.Scheme, err)
+		}
+		if !res.Valid {
+			t.Fatalf("%s does not verify", c.Scheme)
+		}
+		id, err := SchemeAlgorithm(c.Scheme)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Algorithm != id || res.Fingerprint != key_fingerprint(c.PublicKey) {
+			t.Fatalf("%s: reported %v by %s", c.Scheme, res.Algorithm, res.Fingerprint)
+		}
+		if res.Created.Before(before) || res.Created.After(after) {
+			t.Fatalf("%s: created %v, signed between %v and %v", c.Scheme, res.Created, before, after)
+		}
+
+		// the signature covers the time: changing or removing it fails
+		moved := *decoded
+		moved.Created = moved.Created.Add(-time.Hour)
+		if res, _ := VerifyDetachedReader(bytes.NewReader(data), "doc.txt", &moved, nil, ""); res.Valid {
+			t.Fatalf("%s verifies with an earlier time", c.Scheme)
+		}
+		moved.Created = time.Time{}
+		if res, _ := VerifyDetachedReader(bytes.NewReader(data), "doc.txt", &moved, nil, ""); res.Valid {
+			t.Fatalf("%s verifies without its time", c.Scheme)
+		}
+	}
+
+	// signatures from before signing times were recorded still verify
+	y := e222Curve.BaseMul(x_int)
+	input := detached_input("doc.txt", key_fingerprint(e222_to_bytes(y)), data)
+	s, e, err := sign_with_key_e222(x, "", &input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := e222_container(y, s, e)
+	old.Filename, old.Fingerprint = "doc.txt", key_fingerprint(old.PublicKey)
+	decoded, err := DecodeContainer(EncodeContainer(old))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Created.IsZero() {
+		t.Fatalf("container without a time decodes as created %v", decoded.Created)
+	}
+	res, err := VerifyDetachedReader(bytes.NewReader(data), "doc.txt", decoded, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Valid || !res.Created.IsZero() {
+		t.Fatalf("container without a time: valid %v, created %v", res.Valid, res.Created)
+	}
+
+	// a malformed Created header is refused
+	armored := strings.Replace(EncodeContainer(c1), "Created: ", "Created: yesterday ", 1)
+	if _, err := DecodeContainer(armored); err == nil {
+		t.Fatal("malformed Created header decodes")
+	}
+}
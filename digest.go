@@ -0,0 +1,81 @@
+package sig
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Encodings accepted by formatDigest.
+const (
+	digestHex    = "hex"
+	digestBase64 = "base64"
+	digestRaw    = "raw"
+)
+
+// Computes the SHA3 digest of data with an output length of 224, 256, 384 or 512 bits.
+func ComputeSHA3HASH(data []byte, bits int) ([]byte, error) {
+	switch bits {
+	case 224:
+		d := sha3.Sum224(data)
+		return d[:], nil
+	case 256:
+		d := sha3.Sum256(data)
+		return d[:], nil
+	case 384:
+		d := sha3.Sum384(data)
+		return d[:], nil
+	case 512:
+		d := sha3.Sum512(data)
+		return d[:], nil
+	}
+	return nil, fmt.Errorf("unsupported SHA3 output length: %d", bits)
+}
+
+/*
+Renders a digest as hex, base64, or raw bytes in which printable ASCII is
+kept and every other byte (and the backslash) is escaped as \xNN.
+*/
+func formatDigest(d []byte, enc string) (string, error) {
+	switch enc {
+	case digestHex:
+		return hex.EncodeToString(d), nil
+	case digestBase64:
+		return base64.StdEncoding.EncodeToString(d), nil
+	case digestRaw:
+		var sb strings.Builder
+		for _, b := range d {
+			if b >= 0x20 && b < 0x7f && b != '\\' {
+				sb.WriteByte(b)
+			} else {
+				fmt.Fprintf(&sb, "\\x%02x", b)
+			}
+		}
+		return sb.String(), nil
+	}
+	return "", errors.New("unknown digest encoding: " + enc)
+}
+
+/*
+Hashes text and returns the new notepad contents: the formatted digest, or
+text followed by the digest on a new line when appending. The digest is
+always computed over the text as given, never over previously appended output.
+*/
+func hash_notepad(text string, bits int, enc string, appendDigest bool) (string, error) {
+	d, err := ComputeSHA3HASH([]byte(text), bits)
+	if err != nil {
+		return "", err
+	}
+	formatted, err := formatDigest(d, enc)
+	if err != nil {
+		return "", err
+	}
+	if appendDigest {
+		return text + "\n" + formatted, nil
+	}
+	return formatted, nil
+}
@@ -0,0 +1,60 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestDigestFormatting(t *testing.T) {
+
+	for _, bits := range []int{224, 256, 384, 512} {
+		d, err := ComputeSHA3HASH([]byte("abc"), bits)
+		if err != nil {
+			t.Fatalf("SHA3-%d: %v", bits, err)
+		}
+		if len(d) != bits/8 {
+			t.Fatalf("SHA3-%d digest is %d bytes", bits, len(d))
+		}
+	}
+	if _, err := ComputeSHA3HASH([]byte("abc"), 100); err == nil {
+		t.Fatal("SHA3-100 gives no error")
+	}
+
+	d := []byte{0x41, 0x00, 0x5c, 0xff}
+	for format, want := range map[string]string{
+		digestHex:    "41005cff",
+		digestBase64: "QQBc/w==",
+		digestRaw:    `A\x00\x5c\xff`,
+	} {
+		got, err := formatDigest(d, format)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if got != want {
+			t.Fatalf("%s: %q, want %q", format, got, want)
+		}
+	}
+	if _, err := formatDigest(d, "rot13"); err == nil {
+		t.Fatal("unknown format gives no error")
+	}
+
+	// appending twice hashes the text as given each time
+	once, err := hash_notepad("hello", 256, digestHex, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	twice, err := hash_notepad(once, 256, digestHex, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d1, _ := ComputeSHA3HASH([]byte("hello"), 256)
+	d2, _ := ComputeSHA3HASH([]byte(once), 256)
+	if want := "hello\n" + hex.EncodeToString(d1); once != want {
+		t.Fatalf("once: %q, want %q", once, want)
+	}
+	if want := once + "\n" + hex.EncodeToString(d2); twice != want {
+		t.Fatalf("twice: %q, want %q", twice, want)
+	}
+}
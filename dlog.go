@@ -0,0 +1,94 @@
+package sig
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"sig/edwards"
+	"sig/kmac"
+)
+
+var ErrDlogProofEncoding = errors.New("dlog: malformed proof encoding")
+
+/*
+A non-interactive Schnorr proof of knowledge of the discrete logarithm a
+of a public key A = a × G (RFC 8235): the commitment V = v × G for a random
+v and the response r = v − a·c mod r for the Fiat–Shamir challenge c of
+dlog_challenge. It proves possession of the secret key, as key
+registration needs, without revealing anything about it.
+*/
+type DlogProof struct {
+	V *edwards.Point
+	R *big.Int
+}
+
+/*
+The challenge c = H(G ‖ V ‖ A ‖ UserID ‖ OtherInfo) mod r of RFC 8235
+Section 2.3, with H = KMACXOF256 under the customization string "DLOG"
+and an empty key, giving twice the bits of r so that c is close to
+uniform. Points are in the compressed encoding of Bytes, prefixed with
+the curve name, and every item carries a 4 byte big endian length as the
+RFC requires, so that no two inputs share an encoding. userID identifies
+the prover and otherInfo the context, binding the proof to both.
+*/
+func dlog_challenge(V, A *edwards.Point, userID, otherInfo []byte) *big.Int {
+	c := A.Curve()
+	var input []byte
+	for _, item := range [][]byte{[]byte(c.Name()), c.GenPoint().Bytes(), V.Bytes(), A.Bytes(), userID, otherInfo} {
+		input = binary.BigEndian.AppendUint32(input, uint32(len(item)))
+		input = append(input, item...)
+	}
+	h := kmac.XOF256(nil, input, 2*c.Order().BitLen(), "DLOG")
+	return new(big.Int).Mod(fromFixedBytes(h), c.Order())
+}
+
+/*
+Verifies a proof made by ProveDlog for the public key A, userID and
+otherInfo as in RFC 8235 Section 3.2: A must be a valid public key of the
+prime order subgroup, V a point of the curve of A, r in [0, r−1], and
+V = r × G + c × A.
+*/
+func VerifyDlog(A *edwards.Point, proof *DlogProof, userID, otherInfo []byte) bool {
+	if A == nil || proof == nil || proof.V == nil || proof.R == nil {
+		return false
+	}
+	c := A.Curve()
+	if validate_public_key(A) != nil || !A.InPrimeSubgroup() {
+		return false
+	}
+	if proof.V.Curve() != c || proof.V.Validate() != nil {
+		return false
+	}
+	if proof.R.Sign() < 0 || proof.R.Cmp(c.Order()) >= 0 {
+		return false
+	}
+	ch := dlog_challenge(proof.V, A, userID, otherInfo)
+	return c.BaseMul(proof.R).Add(A.SecMul(ch)).Equals(proof.V)
+}
+
+// V in the compressed encoding of Bytes followed by r in encoded_len big endian bytes.
+func (proof *DlogProof) Bytes() []byte {
+	V := proof.V.Bytes()
+	if V == nil {
+		return nil
+	}
+	return append(V, toFixedBytes(proof.R, proof.V.Curve().EncodedLen())...)
+}
+
+// Decodes a proof on curve c encoded by Bytes, or fails with ErrDlogProofEncoding.
+func ParseDlogProof(c *edwards.Curve, b []byte) (*DlogProof, error) {
+	w := c.EncodedLen()
+	if len(b) != 2*w {
+		return nil, ErrDlogProofEncoding
+	}
+	V, err := c.FromBytes(b[:w])
+	if err != nil {
+		return nil, ErrDlogProofEncoding
+	}
+	r := fromFixedBytes(b[w:])
+	if r.Cmp(c.Order()) >= 0 {
+		return nil, ErrDlogProofEncoding
+	}
+	return &DlogProof{V: V, R: r}, nil
+}
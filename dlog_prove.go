@@ -0,0 +1,42 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto/rand"
+	"math/big"
+
+	"sig/edwards"
+)
+
+/*
+Proves knowledge of the secret key x of the public key x × G on curve c
+for the prover userID in the context otherInfo, as in RFC 8235 Section 3.1.
+E521 keys are the intended use, but any curve of this package works. The
+nonce v is drawn from crypto/rand in [1, r−1] and wiped before returning.
+*/
+func ProveDlog(c *edwards.Curve, x *SecretScalar, userID, otherInfo []byte) (*DlogProof, error) {
+	a, err := x.Int()
+	if err != nil {
+		return nil, err
+	}
+	defer wipe_int(a)
+	a.Mod(a, c.Order())
+	if a.Sign() == 0 {
+		return nil, ErrZeroScalar
+	}
+	v, err := rand.Int(rand.Reader, new(big.Int).Sub(c.Order(), big.NewInt(1)))
+	if err != nil {
+		return nil, err
+	}
+	defer wipe_int(v)
+	v.Add(v, big.NewInt(1))
+
+	A := c.BaseMul(a)
+	V := c.BaseMul(v)
+	ch := dlog_challenge(V, A, userID, otherInfo)
+	ac := new(big.Int).Mul(a, ch)
+	defer wipe_int(ac)
+	r := new(big.Int).Sub(v, ac)
+	return &DlogProof{V: V, R: r.Mod(r, c.Order())}, nil
+}
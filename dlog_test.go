@@ -0,0 +1,77 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"sig/edwards"
+)
+
+// RFC 8235 proofs verify for their key, prover and context only, and survive encoding.
+func TestDlogProofs(t *testing.T) {
+
+	user, info := []byte("alice"), []byte("key registration 2026")
+	for _, c := range []*edwards.Curve{e521Curve, e222Curve} {
+		a, err := rand.Int(rand.Reader, c.Params().R)
+		if err != nil {
+			t.Fatal(err)
+		}
+		x := NewSecretScalar(a)
+		A := c.BaseMul(a)
+		proof, err := ProveDlog(c, x, user, info)
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name(), err)
+		}
+		if !VerifyDlog(A, proof, user, info) {
+			t.Fatalf("%s: proof does not verify", c.Name())
+		}
+		if VerifyDlog(A, proof, []byte("mallory"), info) || VerifyDlog(A, proof, user, nil) {
+			t.Fatalf("%s: proof verifies for another prover or context", c.Name())
+		}
+		if VerifyDlog(c.BaseMul(new(big.Int).Add(a, big.NewInt(1))), proof, user, info) {
+			t.Fatalf("%s: proof verifies for another key", c.Name())
+		}
+
+		decoded, err := ParseDlogProof(c, proof.Bytes())
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name(), err)
+		}
+		if !VerifyDlog(A, decoded, user, info) {
+			t.Fatalf("%s: decoded proof does not verify", c.Name())
+		}
+		if _, err := ParseDlogProof(c, proof.Bytes()[1:]); err != ErrDlogProofEncoding {
+			t.Fatalf("%s: truncated proof: %v, want %v", c.Name(), err, ErrDlogProofEncoding)
+		}
+
+		// altered responses, small order keys and missing parts are rejected
+		T := small_order_points(c)[1]
+		for name, bad := range map[string]bool{
+			"r + 1":           VerifyDlog(A, &DlogProof{V: proof.V, R: new(big.Int).Add(proof.R, big.NewInt(1))}, user, info),
+			"r + order":       VerifyDlog(A, &DlogProof{V: proof.V, R: new(big.Int).Add(proof.R, c.Params().R)}, user, info),
+			"small order key": VerifyDlog(A.Add(T), proof, user, info),
+			"no proof":        VerifyDlog(A, nil, user, info),
+			"no key":          VerifyDlog(nil, proof, user, info),
+		} {
+			if bad {
+				t.Fatalf("%s: proof verifies with %s", c.Name(), name)
+			}
+		}
+
+		// a proof for one curve says nothing on another
+		other := e222Curve
+		if c == other {
+			other = e521Curve
+		}
+		if VerifyDlog(other.BaseMul(a), proof, user, info) {
+			t.Fatalf("%s proof verifies on %s", c.Name(), other.Name())
+		}
+
+		x.Destroy()
+		if _, err := ProveDlog(c, x, user, info); err != ErrDestroyedSecret {
+			t.Fatalf("%s: proving with a destroyed secret: %v, want %v", c.Name(), err, ErrDestroyedSecret)
+		}
+	}
+}
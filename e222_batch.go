@@ -0,0 +1,83 @@
+package sig
+
+import (
+	"math/big"
+
+	"sig/edwards"
+)
+
+/*
+Verifies the E222 Schnorr signatures sigs[i] against keys[i] and msgs[i]
+for all i in domain, and returns true iff every signature is valid,
+exactly as VerifySignature would for each.
+
+A signature (s, e) carries no commitment R, only the hash e of it, so the
+random linear combination that VerifyBatch uses for ECDSA does not apply:
+every Rᵢ = sᵢ × G + eᵢ × 4yᵢ has to be recomputed and hashed. The batch
+does so in one pass of edwards.Curve.BatchMulAdd, which shares the window
+table of each distinct key and a single inversion. Since every input is
+public the arithmetic need not be constant time. Version 1 signatures in
+the empty domain, which compare e differently, are verified one by one.
+opts apply to every key, as for VerifySignature.
+*/
+func VerifyBatchE222(domain string, sigs []SchnorrSignature, keys []*E222, msgs [][]byte, opts ...edwards.DecodeOption) bool {
+	if len(sigs) != len(keys) || len(sigs) != len(msgs) {
+		return false
+	}
+	c := e222Curve
+	type pending struct {
+		version byte
+		e       *big.Int
+		msg     []byte
+	}
+	var batch []pending
+	var ss, es []*big.Int
+	var ys []*edwards.Point
+	cleared := make(map[string]*edwards.Point) // 4y by encoded key
+	for i := range sigs {
+		sig, y := &sigs[i], keys[i]
+		if sig.Curve != curveE222 || y == nil || sig.S == nil || sig.E == nil {
+			return false
+		}
+		msg := msgs[i]
+		if sig.is_canonical_text() {
+			msg = canonical_text_input(msg)
+		}
+		version := sig.Version
+		if version == 0 {
+			version = signatureVersion
+		}
+		if version == signatureVersionVariableWidth && domain == "" {
+			if !verify_sig_e222_version(version, domain, y, sig.S, sig.E, &msg, opts...) {
+				return false
+			}
+			continue
+		}
+
+		// the checks of verify_sig_e222_version
+		e := new(big.Int).Mod(sig.E, e222Params.R)
+		if validate_public_key(y) != nil || e.Sign() == 0 || sig.S.Sign() < 0 || sig.S.Cmp(e222Params.R) >= 0 {
+			return false
+		}
+		if y.Check(opts...) != nil {
+			return false
+		}
+		id := string(e222_to_bytes(y))
+		if cleared[id] == nil {
+			cleared[id] = y.ClearCofactor()
+		}
+		batch = append(batch, pending{version, sig.E, msg})
+		ss, es, ys = append(ss, sig.S), append(es, e), append(ys, cleared[id])
+	}
+
+	for i, R := range c.BatchMulAdd(ss, es, ys) {
+		p := batch[i]
+		if R.Validate() != nil {
+			return false // only possible off the curve
+		}
+		if e222_challenge(p.version, domain, R.X(), &p.msg).Cmp(p.e) != 0 {
+			return false
+		}
+	}
+	return true
+}
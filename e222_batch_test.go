@@ -0,0 +1,103 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+// VerifyBatchE222 accepts a batch iff VerifySignature accepts every signature in it.
+func TestE222BatchVerification(t *testing.T) {
+
+	var sigs []SchnorrSignature
+	var keys []*E222
+	var msgs [][]byte
+	for k := 0; k < 3; k++ {
+		x := NewSecretScalar(generateRandomBigInt())
+		x_int, err := x.Int()
+		if err != nil {
+			t.Fatal(err)
+		}
+		y := e222Curve.BaseMul(x_int)
+		for i := 0; i <= k; i++ {
+			msg := []byte(fmt.Sprintf("message %d of key %d", i, k))
+			s, e, err := sign_with_key_e222(x, "app", &msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sigs, keys, msgs = append(sigs, SchnorrSignature{Curve: curveE222, S: s, E: e}), append(keys, y), append(msgs, msg)
+		}
+		// canonical text mode
+		text := []byte("line one\r\nline two\n")
+		encoded, err := sign_text_e222(x, "app", text, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := DecodeSignature(encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sigs, keys, msgs = append(sigs, *sig), append(keys, y), append(msgs, text)
+	}
+
+	for i := range sigs {
+		if !VerifySignature(keys[i], "app", EncodeSignature(&sigs[i]), &msgs[i]) {
+			t.Fatalf("signature %d does not verify alone", i)
+		}
+	}
+	if !VerifyBatchE222("app", sigs, keys, msgs) {
+		t.Fatalf("batch of %d signatures does not verify", len(sigs))
+	}
+	if !VerifyBatchE222("app", nil, nil, nil) {
+		t.Fatal("empty batch does not verify")
+	}
+	if VerifyBatchE222("other", sigs, keys, msgs) {
+		t.Fatal("batch verifies in another domain")
+	}
+	if VerifyBatchE222("app", sigs, keys[1:], msgs) {
+		t.Fatal("batch verifies with one key too few")
+	}
+
+	// a single bad signature, message or key fails the batch
+	bad := append([]SchnorrSignature(nil), sigs...)
+	bad[2].S = new(big.Int).Add(bad[2].S, big.NewInt(1))
+	if VerifyBatchE222("app", bad, keys, msgs) {
+		t.Fatal("batch verifies with a changed signature")
+	}
+	badMsgs := append([][]byte(nil), msgs...)
+	badMsgs[len(badMsgs)-1] = []byte("forged")
+	if VerifyBatchE222("app", sigs, keys, badMsgs) {
+		t.Fatal("batch verifies with a changed message")
+	}
+	swapped := append([]*E222(nil), keys...)
+	swapped[0], swapped[len(swapped)-1] = swapped[len(swapped)-1], swapped[0]
+	if VerifyBatchE222("app", sigs, swapped, msgs) {
+		t.Fatal("batch verifies with two keys swapped")
+	}
+	if VerifyBatchE222("app", sigs[:1], []*E222{e222Curve.IdPoint()}, msgs[:1]) {
+		t.Fatal("batch verifies with the identity as key")
+	}
+
+	// version 1 signatures in the empty domain, here under the unscaled key, are checked one by one
+	msg := []byte("short message")
+	x := generateRandomBigInt()
+	k := generateRandomBigInt()
+	e := e222_challenge(signatureVersionVariableWidth, "", e222Curve.GenPoint().SecMul(k).X(), &msg)
+	s := new(big.Int).Sub(k, new(big.Int).Mul(x, e))
+	s.Mod(s, e222Params.R)
+	s2, e2, err := sign_with_key_e222(NewSecretScalar(x), "", &msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mixed := []SchnorrSignature{{Version: signatureVersionVariableWidth, Curve: curveE222, S: s, E: e}, {Curve: curveE222, S: s2, E: e2}}
+	y := e222Curve.GenPoint().SecMul(x)
+	if !VerifyBatchE222("", mixed, []*E222{y, y}, [][]byte{msg, msg}) {
+		t.Fatal("batch of a version 1 and a version 2 signature does not verify")
+	}
+	mixed[0].S = new(big.Int).Add(s, big.NewInt(1))
+	if VerifyBatchE222("", mixed, []*E222{y, y}, [][]byte{msg, msg}) {
+		t.Fatal("batch verifies with a changed version 1 signature")
+	}
+}
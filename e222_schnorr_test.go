@@ -0,0 +1,143 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+// A public key with a small order component T must verify the same as
+// the clean key once the cofactor is cleared.
+func TestCofactorClearing(t *testing.T) {
+
+	T := e222Curve.NewPointXY(*big.NewInt(1), *big.NewInt(0)) // order 4
+	if !T.IsOnCurve() || !T.ClearCofactor().Equals(e222Curve.IdPoint()) {
+		t.Fatalf("T = (%v, %v) is not a point of order 4", T.X(), T.Y())
+	}
+
+	msg := []byte("short message")
+	x := generateRandomBigInt()
+	y := e222Curve.GenPoint().SecMul(x)
+	if !y.Add(T).ClearCofactor().Equals(y.ClearCofactor()) {
+		t.Fatal("clearing the cofactor of y + T differs from clearing it of y")
+	}
+	s, e, err := sign_with_key_e222(NewSecretScalar(x), "", &msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verify_sig_e222(y, s, e, &msg) {
+		t.Fatal("signature does not verify against y")
+	}
+	if !verify_sig_e222(y.Add(T), s, e, &msg) {
+		t.Fatal("signature does not verify against y + T")
+	}
+}
+
+// The domain enters the deterministic nonce as well as the challenge, so signatures do not carry over between domains.
+func TestDomainBoundNonces(t *testing.T) {
+
+	x_int := new(big.Int).Mod(generateRandomBigInt(), e222Params.R)
+	x := NewSecretScalar(x_int)
+	y := e222Curve.BaseMul(x_int)
+	msg := []byte("short message")
+
+	_, k0 := e222_kmac_nonce(x_int, "", msg)
+	_, k1 := e222_kmac_nonce(x_int, "app-a", msg)
+	_, k2 := e222_kmac_nonce(x_int, "app-b", msg)
+	if k0.Cmp(k1) == 0 || k1.Cmp(k2) == 0 || k0.Cmp(k2) == 0 {
+		t.Fatalf("nonces %v, %v and %v in three domains", k0, k1, k2)
+	}
+
+	s1, e1, err := sign_deterministic_e222(x, "app-a", &msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2, e2, err := sign_deterministic_e222(x, "app-a", &msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s3, e3, err := sign_deterministic_e222(x, "app-b", &msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s1.Cmp(s2) != 0 || e1.Cmp(e2) != 0 {
+		t.Fatalf("signing twice in app-a gives (%v, %v) and (%v, %v)", s1, e1, s2, e2)
+	}
+	if s1.Cmp(s3) == 0 || e1.Cmp(e3) == 0 {
+		t.Fatalf("app-a and app-b share s = %v or e = %v", s1, e1)
+	}
+	if !verify_sig_e222_version(signatureVersion, "app-a", y, s1, e1, &msg) ||
+		!verify_sig_e222_version(signatureVersion, "app-b", y, s3, e3, &msg) {
+		t.Fatal("signature does not verify in its domain")
+	}
+	if verify_sig_e222_version(signatureVersion, "app-b", y, s1, e1, &msg) || verify_sig_e222(y, s1, e1, &msg) {
+		t.Fatal("signature from app-a verifies in another domain")
+	}
+
+	// vector files record their domain and verify only in it
+	out, err := GenerateDomainTestVectors(7, 2, "app-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file TestVectorFile
+	if err := json.Unmarshal(out, &file); err != nil {
+		t.Fatal(err)
+	}
+	if file.Domain != "app-a" || len(file.Vectors) != 2 {
+		t.Fatalf("%d vectors in domain %q, want 2 in app-a", len(file.Vectors), file.Domain)
+	}
+	for i, v := range file.Vectors {
+		vx, _ := hex.DecodeString(v.PublicX)
+		vy, _ := hex.DecodeString(v.PublicY)
+		sig, _ := hex.DecodeString(v.Signature)
+		vmsg, _ := hex.DecodeString(v.Message)
+		V, err := e222_from_bytes(append(vx, vy...))
+		if err != nil {
+			t.Fatalf("vector %d: %v", i, err)
+		}
+		if !VerifySignature(V, "app-a", sig, &vmsg) || VerifySignature(V, "", sig, &vmsg) {
+			t.Fatalf("vector %d does not verify in app-a only", i)
+		}
+	}
+	plain, err := GenerateTestVectors(7, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(plain, []byte(`"domain"`)) {
+		t.Fatalf("vectors without a domain record one:\n%s", plain)
+	}
+}
+
+// s is reduced mod r on every signing path, so it carries no bits of the nonce mod 4.
+func TestE222ResponseBelowOrder(t *testing.T) {
+
+	x_int := new(big.Int).Mod(generateRandomBigInt(), e222Params.R)
+	x := NewSecretScalar(x_int)
+	for i := 0; i < 64; i++ {
+		msg := []byte{byte(i)}
+		if _, k := e222_kmac_nonce(x_int, "", msg); k.Cmp(e222Params.R) >= 0 {
+			t.Fatalf("message %d: nonce %v is not below r", i, k)
+		}
+		s1, _, err := sign_deterministic_e222(x, "", &msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s2, _, err := sign_with_key_e222(x, "", &msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s3, _, err := sign_stream_e222(x, "", nil, bytes.NewReader(msg))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for path, s := range map[string]*big.Int{"deterministic": s1, "random": s2, "stream": s3} {
+			if s.Cmp(e222Params.R) >= 0 {
+				t.Fatalf("message %d, %s signing: s = %v is not below r", i, path, s)
+			}
+		}
+	}
+}
@@ -0,0 +1,596 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"sig/edwards"
+)
+
+func TestZero(t *testing.T) {
+
+	passedTestCount := 0
+	numberOfTests := 100
+	for i := 0; i < numberOfTests; i++ {
+		G := e222Curve.IdPoint()
+		if G.SecMul(big.NewInt(0)).Equals(e222Curve.IdPoint()) {
+			passedTestCount++
+		} else {
+			break
+		}
+	}
+	if passedTestCount != numberOfTests {
+		t.Fatalf("%d of %d passed", passedTestCount, numberOfTests)
+	}
+}
+
+func TestOne(t *testing.T) {
+
+	passedTestCount := 0
+	numberOfTests := 100
+	for i := 0; i < numberOfTests; i++ {
+		G := e222Curve.GenPoint()
+		if G.SecMul(big.NewInt(1)).Equals(e222Curve.GenPoint()) {
+			passedTestCount++
+		} else {
+			break
+		}
+	}
+	if passedTestCount != numberOfTests {
+		t.Fatalf("%d of %d passed", passedTestCount, numberOfTests)
+	}
+}
+
+func TestGPlusMinusG(t *testing.T) {
+
+	passedTestCount := 0
+	numberOfTests := 100
+	for i := 0; i < numberOfTests; i++ {
+		G := e222Curve.GenPoint()
+		if G.Add(e222Curve.GenPoint().Neg()).Equals(e222Curve.IdPoint()) {
+			passedTestCount++
+		} else {
+			break
+		}
+	}
+	if passedTestCount != numberOfTests {
+		t.Fatalf("%d of %d passed", passedTestCount, numberOfTests)
+	}
+}
+
+func TestTwoTimesG(t *testing.T) {
+
+	passedTestCount := 0
+	numberOfTests := 1
+	for i := 0; i < numberOfTests; i++ {
+		G := e222Curve.GenPoint()
+		p := G.SecMul(big.NewInt(2))
+		t.Log(p.X().String(), p.Y().String())
+		if G.SecMul(big.NewInt(2)).Equals(G.Add(G)) {
+			passedTestCount++
+		} else {
+			break
+		}
+	}
+	if passedTestCount != numberOfTests {
+		t.Fatalf("%d of %d passed", passedTestCount, numberOfTests)
+	}
+}
+
+func TestFourTimesG(t *testing.T) {
+
+	passedTestCount := 0
+	numberOfTests := 100
+	for i := 0; i < numberOfTests; i++ {
+		G := e222Curve.GenPoint()
+		if G.SecMul(big.NewInt(4)).Equals(G.SecMul(big.NewInt(2)).SecMul(big.NewInt(2))) {
+			passedTestCount++
+		} else {
+			break
+		}
+	}
+	if passedTestCount != numberOfTests {
+		t.Fatalf("%d of %d passed", passedTestCount, numberOfTests)
+	}
+}
+
+func TestNotZero(t *testing.T) {
+
+	passedTestCount := 0
+	numberOfTests := 100
+	for i := 0; i < numberOfTests; i++ {
+		G := e222Curve.GenPoint()
+		if !G.SecMul(big.NewInt(4)).Equals(e222Curve.IdPoint()) {
+			passedTestCount++
+		} else {
+			break
+		}
+	}
+	if passedTestCount != numberOfTests {
+		t.Fatalf("%d of %d passed", passedTestCount, numberOfTests)
+	}
+
+}
+
+func TestRTimesG(t *testing.T) {
+
+	passedTestCount := 0
+	numberOfTests := 100
+	for i := 0; i < numberOfTests; i++ {
+		G := e222Curve.GenPoint()
+		if G.SecMul(e222Params.R).Equals(e222Curve.IdPoint()) {
+			passedTestCount++
+		} else {
+			break
+		}
+	}
+	if passedTestCount != numberOfTests {
+		t.Fatalf("%d of %d passed", passedTestCount, numberOfTests)
+	}
+}
+
+func TestKTimesGAndKModRTimesG(t *testing.T) {
+	G := e222Curve.GenPoint()
+	R := *e222Curve.Order()
+
+	passedTestCount := 0
+	numberOfTests := 50
+	for i := 0; i < numberOfTests; i++ {
+		k := generateRandomBigInt()
+		G1 := G.SecMul(k)
+		G2 := G.SecMul(k.Mod(k, &R))
+		if G1.Equals(G2) {
+			passedTestCount++
+		} else {
+			break
+		}
+	}
+	if passedTestCount != numberOfTests {
+		t.Fatalf("%d of %d passed", passedTestCount, numberOfTests)
+	}
+}
+
+func TestKPlus1TimesG(t *testing.T) {
+
+	passedTestCount := 0
+	numberOfTests := 50
+	for i := 0; i < numberOfTests; i++ {
+		k := generateRandomBigInt()
+		G2 := e222Curve.GenPoint().SecMul(k)
+		G2 = G2.Add(e222Curve.GenPoint())
+		k = k.Add(k, big.NewInt(1))
+		G1 := e222Curve.GenPoint().SecMul(k)
+		if G1.Equals(G2) {
+			passedTestCount++
+		} else {
+			break
+		}
+	}
+	if passedTestCount != numberOfTests {
+		t.Fatalf("%d of %d passed", passedTestCount, numberOfTests)
+	}
+}
+
+func TestKTTimesGEqualsKGTG(t *testing.T) {
+
+	passedTestCount := 0
+	numberOfTests := 50
+	for i := 0; i < numberOfTests; i++ {
+		k := generateRandomBigInt()
+		t := generateRandomBigInt()
+
+		G2 := e222Curve.GenPoint().SecMul(k)
+		G2 = G2.Add(e222Curve.GenPoint().SecMul(t))
+
+		x := new(big.Int).Add(k, t)
+		G1 := e222Curve.GenPoint().SecMul(x)
+
+		if G1.Equals(G2) {
+			passedTestCount++
+		} else {
+			break
+		}
+	}
+	if passedTestCount != numberOfTests {
+		t.Fatalf("%d of %d passed", passedTestCount, numberOfTests)
+	}
+}
+
+func TestKTPEqualsTKGEqualsKTModRG(t *testing.T) {
+
+	passedTestCount := 0
+	numberOfTests := 50
+	for i := 0; i < numberOfTests; i++ {
+		k := generateRandomBigInt()
+		t := generateRandomBigInt()
+
+		ktP := e222Curve.GenPoint().SecMul(t).SecMul(k)
+		tkG := e222Curve.GenPoint().SecMul(k).SecMul(t)
+
+		ktmodr := k.Mul(k, t)
+		ktmodr = ktmodr.Mod(ktmodr, e222Params.R)
+		ktmodrG := e222Curve.GenPoint().SecMul(ktmodr)
+
+		if ktP.Equals(tkG) && ktP.Equals(ktmodrG) {
+			passedTestCount++
+		} else {
+			break
+		}
+	}
+	if passedTestCount != numberOfTests {
+		t.Fatalf("%d of %d passed", passedTestCount, numberOfTests)
+	}
+}
+
+func TestParamsInvariants(t *testing.T) {
+
+	params := e222Curve.Params()
+	if n := new(big.Int).Mul(params.Cofactor, params.R); params.N.Cmp(n) != 0 {
+		t.Fatalf("N = %v, want cofactor * R = %v", params.N, n)
+	}
+	G := e222Curve.NewPointXY(*params.Gx, *params.Gy)
+	if !G.IsOnCurve() || !G.Equals(e222Curve.GenPoint()) {
+		t.Fatalf("(Gx, Gy) = (%v, %v) is not the generator", params.Gx, params.Gy)
+	}
+	if rG := G.SecMul(params.R); !rG.Equals(e222Curve.IdPoint()) {
+		t.Fatalf("R * G = (%v, %v), want the identity", rG.X(), rG.Y())
+	}
+}
+
+// Modifying the values returned by E222Params must not change the curve.
+func TestParamsImmutable(t *testing.T) {
+
+	params := e222Curve.Params()
+	params.P.SetInt64(7)
+	params.R.SetInt64(7)
+	params.Gx.SetInt64(0)
+	params.Gy.SetInt64(1)
+
+	fresh := e222Curve.Params()
+	if fresh.P.Cmp(big.NewInt(7)) == 0 || fresh.R.Cmp(big.NewInt(7)) == 0 {
+		t.Fatalf("P = %v, R = %v after modifying a copy", fresh.P, fresh.R)
+	}
+	if G := e222Curve.GenPoint(); G.Equals(e222Curve.IdPoint()) || !G.IsOnCurve() {
+		t.Fatalf("generator is (%v, %v) after modifying a copy", G.X(), G.Y())
+	}
+}
+
+func TestOppositeDoesNotMutate(t *testing.T) {
+
+	G := e222Curve.GenPoint()
+	neg := G.Neg()
+	if !G.Equals(e222Curve.GenPoint()) {
+		t.Fatalf("Neg changed G to (%v, %v)", G.X(), G.Y())
+	}
+	if !neg.IsOnCurve() || !neg.Neg().Equals(G) {
+		t.Fatalf("-G = (%v, %v) is not the opposite of G", neg.X(), neg.Y())
+	}
+	if sum := G.Add(neg); !sum.Equals(e222Curve.IdPoint()) {
+		t.Fatalf("G + -G = (%v, %v), want the identity", sum.X(), sum.Y())
+	}
+}
+
+// Reference scalar multiplication: plain right-to-left double-and-add.
+// Slow and not side channel safe, but simple enough to trust.
+func naiveMul(P *E222, k *big.Int) *E222 {
+	result := e222Curve.IdPoint()
+	addend := e222Curve.NewPointXY(*P.X(), *P.Y())
+	for i := 0; i < k.BitLen(); i++ {
+		if k.Bit(i) == 1 {
+			result = result.Add(addend)
+		}
+		addend = addend.Add(addend)
+	}
+	return result
+}
+
+// Shrinks a scalar on which SecMul and naiveMul disagree by clearing
+// bits while the disagreement persists.
+func minimizeDivergence(P *E222, k *big.Int) *big.Int {
+	min := new(big.Int).Set(k)
+	for i := min.BitLen() - 1; i >= 0; i-- {
+		if min.Bit(i) == 0 {
+			continue
+		}
+		candidate := new(big.Int).SetBit(min, i, 0)
+		if !P.SecMul(candidate).Equals(naiveMul(P, candidate)) {
+			min = candidate
+		}
+	}
+	return min
+}
+
+// Differential test of SecMul against naiveMul on random scalars and points.
+func TestSecMulDifferential(t *testing.T) {
+
+	T := e222Curve.NewPointXY(*big.NewInt(1), *big.NewInt(0)) // order 4
+	for i := 0; i < 100; i++ {
+		P := e222Curve.GenPoint().SecMul(generateRandomBigInt())
+		if i%2 == 1 {
+			P = P.Add(T)
+		}
+		k := new(big.Int).Rsh(generateRandomBigInt(), uint(i%512))
+		if !P.SecMul(k).Equals(naiveMul(P, k)) {
+			t.Fatalf("SecMul diverges: P = (%v, %v), k = %v", P.X(), P.Y(), minimizeDivergence(P, k))
+		}
+	}
+}
+
+// Associativity, commutativity and distributivity over 1000 random triples.
+func TestGroupLawIdentities(t *testing.T) {
+
+	pool := make([]*E222, 20)
+	for i := range pool {
+		pool[i] = e222Curve.GenPoint().SecMul(generateRandomBigInt())
+	}
+	pick := func() *E222 {
+		i, _ := rand.Int(rand.Reader, big.NewInt(int64(len(pool))))
+		return pool[i.Int64()]
+	}
+
+	for i := 0; i < 1000; i++ {
+		P, Q, R := pick(), pick(), pick()
+		k := new(big.Int).Rsh(generateRandomBigInt(), 480) // 32 bit scalars keep this fast
+		if !P.Add(Q).Add(R).Equals(P.Add(Q.Add(R))) {
+			t.Fatalf("(P + Q) + R != P + (Q + R) for P = %v, Q = %v, R = %v", P.X(), Q.X(), R.X())
+		}
+		if !P.Add(Q).Equals(Q.Add(P)) {
+			t.Fatalf("P + Q != Q + P for P = %v, Q = %v", P.X(), Q.X())
+		}
+		if !P.Add(Q).SecMul(k).Equals(P.SecMul(k).Add(Q.SecMul(k))) {
+			t.Fatalf("k(P + Q) != kP + kQ for P = %v, Q = %v, k = %v", P.X(), Q.X(), k)
+		}
+	}
+}
+
+// Off-curve input making 1 + d·x₁x₂y₁y₂ ≡ 0 used to panic inside Add.
+func TestNonInvertibleDenominator(t *testing.T) {
+
+	P := *e222Params.P
+	d := big.NewInt(160102)
+	x2 := new(big.Int).ModInverse(d, &P)
+	x2.Sub(&P, x2) // -1/d
+
+	A := e222Curve.NewPointXY(*big.NewInt(1), *big.NewInt(1))
+	B := e222Curve.NewPointXY(*x2, *big.NewInt(1))
+	sum := A.Add(B)
+	if err := sum.Validate(); err != edwards.ErrInvalidPoint {
+		t.Fatalf("A + B: %v, want %v", err, edwards.ErrInvalidPoint)
+	}
+	if err := sum.Add(e222Curve.GenPoint()).Validate(); err != edwards.ErrInvalidPoint {
+		t.Fatalf("A + B + G: %v, want %v", err, edwards.ErrInvalidPoint)
+	}
+	if sum.Equals(sum) {
+		t.Fatal("an invalid point equals itself")
+	}
+	if err := B.SecMul(big.NewInt(12345)).Validate(); err != edwards.ErrInvalidPoint {
+		t.Fatalf("12345 B: %v, want %v", err, edwards.ErrInvalidPoint)
+	}
+
+	msg := []byte("short message")
+	if _, s, e := sign_message_e222(&msg); verify_sig_e222(B, s, e, &msg) {
+		t.Fatal("signature verifies against an invalid key")
+	}
+	if _, err := e222_from_bytes(e222_to_bytes(B)); err != edwards.ErrInvalidPoint {
+		t.Fatalf("decoding B: %v, want %v", err, edwards.ErrInvalidPoint)
+	}
+	if _, err := ecies_wrap(B, make([]byte, contentKeyLength)); err != edwards.ErrInvalidPoint {
+		t.Fatalf("wrapping to B: %v, want %v", err, edwards.ErrInvalidPoint)
+	}
+}
+
+/*
+Public keys whose x or y is written as the coordinate plus p would be a
+second encoding of the same point; e222_from_bytes rejects them.
+*/
+func TestNonCanonicalCoordinates(t *testing.T) {
+
+	p := e222Params.P
+	G := e222Curve.GenPoint()
+	x_p := new(big.Int).Add(G.X(), p)
+	y_p := new(big.Int).Add(G.Y(), p)
+	for _, enc := range [][2]*big.Int{{x_p, G.Y()}, {G.X(), y_p}, {x_p, y_p}} {
+		b := append(toFixedBytes(enc[0], e222Width), toFixedBytes(enc[1], e222Width)...)
+		if _, err := e222_from_bytes(b); err != edwards.ErrInvalidPoint {
+			t.Fatalf("e222_from_bytes(%x): %v, want %v", b, err, edwards.ErrInvalidPoint)
+		}
+		if err := e222Curve.NewPointXY(*enc[0], *enc[1]).Validate(); err != edwards.ErrInvalidPoint {
+			t.Fatalf("(%v, %v): %v, want %v", enc[0], enc[1], err, edwards.ErrInvalidPoint)
+		}
+	}
+	if _, err := e222_from_bytes(e222_to_bytes(G)); err != nil {
+		t.Fatalf("e222_from_bytes(G): %v", err)
+	}
+}
+
+/*
+For random points both parities of y are recoverable from x, recompressing
+gives back the parity bit and the two solutions are P and T − P for the
+order 2 point T = (0, −1). For random x either both parities or neither exist.
+*/
+func TestSolveForYParity(t *testing.T) {
+
+	p := e222Curve.Params().P
+	T := e222Curve.NewPointXY(*big.NewInt(0), *new(big.Int).Sub(p, big.NewInt(1)))
+	for i := 0; i < 16; i++ {
+		P := e222Curve.GenPoint().SecMul(generateRandomBigInt())
+		for parity := uint(0); parity < 2; parity++ {
+			Q := e222Curve.NewPointX(*P.X(), parity)
+			if !Q.IsOnCurve() || Q.Y().Bit(0) != parity {
+				t.Fatalf("x = %v, parity %d gives y = %v", P.X(), parity, Q.Y())
+			}
+		}
+		if Q := e222Curve.NewPointX(*P.X(), P.Y().Bit(0)); !Q.Equals(P) {
+			t.Fatalf("x = %v recompresses to y = %v, want %v", P.X(), Q.Y(), P.Y())
+		}
+
+		even, odd := e222Curve.NewPointX(*P.X(), 0), e222Curve.NewPointX(*P.X(), 1)
+		if !T.Add(even.Neg()).Equals(odd) {
+			t.Fatalf("x = %v: T − even != odd", P.X())
+		}
+		if sum := new(big.Int).Add(even.Y(), odd.Y()); sum.Cmp(p) != 0 {
+			t.Fatalf("x = %v: the two solutions sum to %v, want p", P.X(), sum)
+		}
+	}
+
+	missing := 0
+	for i := 0; i < 32; i++ {
+		x := new(big.Int).Mod(generateRandomBigInt(), p)
+		even, odd := e222Curve.NewPointX(*x, 0), e222Curve.NewPointX(*x, 1)
+		if even.IsOnCurve() != odd.IsOnCurve() {
+			t.Fatalf("x = %v has a point for only one parity", x)
+		}
+		if !even.IsOnCurve() {
+			if err := even.Validate(); err != edwards.ErrInvalidPoint {
+				t.Fatalf("x = %v, even: %v, want %v", x, err, edwards.ErrInvalidPoint)
+			}
+			if err := odd.Validate(); err != edwards.ErrInvalidPoint {
+				t.Fatalf("x = %v, odd: %v, want %v", x, err, edwards.ErrInvalidPoint)
+			}
+			missing++
+		}
+	}
+	if missing == 0 {
+		t.Fatal("all 32 random x values have points")
+	}
+}
+
+func TestEdwardsCurveInstances(t *testing.T) {
+
+	for _, c := range []*edwards.Curve{e222Curve, e521Curve, ed448Curve, ed25519Curve} {
+		G := c.GenPoint()
+		params := c.Params()
+		if !G.IsOnCurve() || G.Curve() != c || G.IsIdentity() {
+			t.Fatalf("%s: generator (%v, %v)", c.Name(), G.X(), G.Y())
+		}
+		if rG := G.SecMul(params.R); !rG.IsIdentity() {
+			t.Fatalf("%s: R · G = (%v, %v), want the identity", c.Name(), rG.X(), rG.Y())
+		}
+		if n := new(big.Int).Mul(params.R, params.Cofactor); params.N.Cmp(n) != 0 {
+			t.Fatalf("%s: N = %v, want R · cofactor = %v", c.Name(), params.N, n)
+		}
+
+		// the generator is recovered from x and the parity of y
+		if P := c.NewPointX(*params.Gx, params.Gy.Bit(0)); !P.Equals(G) {
+			t.Fatalf("%s: Gx recovers y = %v, want %v", c.Name(), P.Y(), params.Gy)
+		}
+
+		// k × G + (−k) × G = O and (a + b) × G = a × G + b × G
+		k, _ := rand.Int(rand.Reader, params.R)
+		a, _ := rand.Int(rand.Reader, params.R)
+		kG := G.SecMul(k)
+		if !kG.Add(kG.Neg()).IsIdentity() {
+			t.Fatalf("%s: kG − kG is not the identity for k = %v", c.Name(), k)
+		}
+		if !G.SecMul(new(big.Int).Add(k, a)).Equals(kG.Add(G.SecMul(a))) {
+			t.Fatalf("%s: (k + a)G != kG + aG for k = %v, a = %v", c.Name(), k, a)
+		}
+
+		// the point of order 2 is cleared by the cofactor
+		T := c.NewPointXY(*big.NewInt(0), *new(big.Int).Sub(params.P, big.NewInt(1)))
+		if !T.IsOnCurve() {
+			t.Fatalf("%s: (0, −1) is not on the curve", c.Name())
+		}
+		if err := validate_public_key(T); err != ErrIdentityPoint {
+			t.Fatalf("%s: (0, −1) as a public key: %v, want %v", c.Name(), err, ErrIdentityPoint)
+		}
+	}
+
+	// curves do not mix
+	mixed := e222Curve.GenPoint().Add(e521Curve.GenPoint())
+	if err := mixed.Validate(); err != edwards.ErrInvalidPoint {
+		t.Fatalf("E222 G + E521 G: %v, want %v", err, edwards.ErrInvalidPoint)
+	}
+	if e222Curve.IdPoint().Equals(e521Curve.IdPoint()) {
+		t.Fatal("the E222 identity equals the E521 identity")
+	}
+}
+
+/*
+Small order points of curve c: r × Q for points Q off the prime order
+subgroup, collected until all cofactor many have been found.
+*/
+func small_order_points(c *edwards.Curve) []*E222 {
+	params := c.Params()
+	torsion := []*E222{c.IdPoint()}
+	for x := int64(2); len(torsion) < int(params.Cofactor.Int64()) && x < 1000; x++ {
+		for parity := uint(0); parity < 2; parity++ {
+			Q := c.NewPointX(*big.NewInt(x), parity)
+			if Q.Validate() != nil {
+				continue
+			}
+			T := Q.SecMul(params.R)
+			for M := T; !M.IsIdentity(); M = M.Add(T) {
+				known := false
+				for _, U := range torsion {
+					known = known || U.Equals(M)
+				}
+				if !known {
+					torsion = append(torsion, M)
+				}
+			}
+		}
+	}
+	return torsion
+}
+
+// Decoders and Schnorr verification reject small order components only when asked to.
+func TestSubgroupMembership(t *testing.T) {
+
+	// decoders reject small order components only when asked to
+	T := e222Curve.NewPointXY(*big.NewInt(0), *new(big.Int).Sub(e222Curve.Params().P, big.NewInt(1)))
+	mixed := e222Curve.GenPoint().Add(T)
+	if _, err := e222Curve.FromBytes(mixed.Bytes()); err != nil {
+		t.Fatalf("FromBytes(G + T): %v", err)
+	}
+	if _, err := e222Curve.FromBytes(mixed.Bytes(), edwards.RequirePrimeOrder); err != edwards.ErrSmallSubgroup {
+		t.Fatalf("FromBytes(G + T) of prime order: %v, want %v", err, edwards.ErrSmallSubgroup)
+	}
+	G, err := e222Curve.FromBytes(e222Curve.GenPoint().Bytes(), edwards.RequirePrimeOrder)
+	if err != nil {
+		t.Fatalf("FromBytes(G) of prime order: %v", err)
+	}
+	if !G.Equals(e222Curve.GenPoint()) {
+		t.Fatalf("FromBytes(G) = (%v, %v)", G.X(), G.Y())
+	}
+	if _, err := e222_from_bytes(e222_to_bytes(mixed)); err != nil {
+		t.Fatalf("e222_from_bytes(G + T): %v", err)
+	}
+	if _, err := e222_from_bytes(e222_to_bytes(mixed), edwards.RequirePrimeOrder); err != edwards.ErrSmallSubgroup {
+		t.Fatalf("e222_from_bytes(G + T) of prime order: %v, want %v", err, edwards.ErrSmallSubgroup)
+	}
+
+	// Schnorr verification clears the cofactor unless keys must have prime order
+	msg := []byte("subgroup")
+	y, sig_s, sig_e := sign_message_e222(&msg)
+	tainted := y.Add(T)
+	strict := edwards.RequirePrimeOrder
+	if !verify_sig_e222(y, sig_s, sig_e, &msg) || !verify_sig_e222(y, sig_s, sig_e, &msg, strict) {
+		t.Fatal("signature does not verify against y")
+	}
+	if !verify_sig_e222(tainted, sig_s, sig_e, &msg) {
+		t.Fatal("signature does not verify against y + T")
+	}
+	if verify_sig_e222(tainted, sig_s, sig_e, &msg, strict) {
+		t.Fatal("signature verifies against y + T with keys of prime order required")
+	}
+	encoded := EncodeSignature(&SchnorrSignature{Curve: curveE222, S: sig_s, E: sig_e})
+	if !VerifySignature(tainted, "", encoded, &msg) || VerifySignature(tainted, "", encoded, &msg, strict) {
+		t.Fatal("VerifySignature against y + T ignores RequirePrimeOrder")
+	}
+	batch := []SchnorrSignature{{Curve: curveE222, S: sig_s, E: sig_e}}
+	if !VerifyBatchE222("", batch, []*E222{tainted}, [][]byte{msg}) ||
+		VerifyBatchE222("", batch, []*E222{tainted}, [][]byte{msg}, strict) {
+		t.Fatal("VerifyBatchE222 against y + T ignores RequirePrimeOrder")
+	}
+	c := &SignatureContainer{Scheme: schemeSchnorrE222, PublicKey: e222_to_bytes(tainted), Signature: encoded}
+	if ok, err := VerifyContainer(c, "", &msg); !ok || err != nil {
+		t.Fatalf("container with y + T: %v, %v", ok, err)
+	}
+	if _, err := VerifyContainer(c, "", &msg, strict); err != edwards.ErrSmallSubgroup {
+		t.Fatalf("container with y + T of prime order: %v, want %v", err, edwards.ErrSmallSubgroup)
+	}
+}
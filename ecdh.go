@@ -0,0 +1,115 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto/ecdsa"
+	"errors"
+
+	"sig/edwards"
+	"sig/kmac"
+)
+
+// Length in bytes of the keys DeriveSharedSecret derives.
+const SharedSecretSize = 32
+
+var ErrNoSharedSecret = errors.New("ecdh: key agreement yields the identity")
+
+/*
+Derives a symmetric key shared with the holder of peerPub from the private
+key priv, which must be one of
+
+	*SecretScalar    with peerPub an *edwards.Point on any curve of this package
+	*ECDSAPrivateKey with peerPub an *ecdsa.PublicKey on the key's curve
+
+The peer key is validated first, so invalid points fail with
+edwards.ErrInvalidPoint and never reach the scalar multiplication. On Edwards
+curves the peer key's cofactor is cleared, Z = x × ClearCofactor(peer),
+so that a small order component cannot reveal x mod the cofactor; both
+sides compute the same Z because each clears the cofactor of the other's
+key. A result that is the identity fails with ErrNoSharedSecret.
+
+The key is KMACXOF256(Z, C ‖ info, 256, "ECDH"), keyed by the encoding
+of Z and bound to the length-prefixed curve name C and to info, the
+application's context, as derive_keys binds its keys to a purpose. The
+caller wipes it once no longer needed.
+*/
+func DeriveSharedSecret(priv, peerPub interface{}, info []byte) (*SecretBytes, error) {
+	switch x := priv.(type) {
+	case *SecretScalar:
+		peer, ok := peerPub.(*edwards.Point)
+		if !ok || peer == nil {
+			return nil, edwards.ErrInvalidPoint
+		}
+		return ecdh_edwards(x, peer, info)
+	case *ECDSAPrivateKey:
+		peer, ok := peerPub.(*ecdsa.PublicKey)
+		if !ok || peer == nil {
+			return nil, edwards.ErrInvalidPoint
+		}
+		return ecdh_weierstrass(x, peer, info)
+	}
+	return nil, ErrUnsupportedAlgorithm
+}
+
+// Z = x × ClearCofactor(peer) on the curve of peer, see DeriveSharedSecret.
+func ecdh_edwards(x *SecretScalar, peer *edwards.Point, info []byte) (*SecretBytes, error) {
+	if err := validate_public_key(peer); err != nil {
+		return nil, err
+	}
+	c := peer.Curve()
+	x_int, err := x.Int()
+	if err != nil {
+		return nil, err
+	}
+	defer wipe_int(x_int)
+	x_int.Mod(x_int, c.Order())
+	if x_int.Sign() == 0 {
+		return nil, ErrZeroScalar
+	}
+	Z := peer.ClearCofactor().SecMul(x_int)
+	if Z.IsIdentity() {
+		return nil, ErrNoSharedSecret
+	}
+	shared := Z.Bytes()
+	defer wipe_bytes(shared)
+	return ecdh_kdf(shared, c.Name(), info), nil
+}
+
+/*
+Z = d × peer on the short Weierstrass curve of key, whose cofactor is 1.
+Only the x-coordinate of Z enters the KDF, as in SEC 1 Section 3.3.1.
+*/
+func ecdh_weierstrass(key *ECDSAPrivateKey, peer *ecdsa.PublicKey, info []byte) (*SecretBytes, error) {
+	curve := ecdsa_curve(key.Curve)
+	if peer.Curve == nil || peer.Curve.Params().Name != curve.Params().Name ||
+		peer.X == nil || peer.Y == nil || !curve.IsOnCurve(peer.X, peer.Y) {
+		return nil, edwards.ErrInvalidPoint
+	}
+	key.mu.Lock()
+	wiped := key.wiped
+	key.mu.Unlock()
+	if wiped {
+		return nil, ErrDestroyedSecret
+	}
+	if err := check_ecdsa_scalar(curve, key.D); err != nil {
+		return nil, err
+	}
+	width := curve_width(curve)
+	d := toFixedBytes(key.D, width)
+	defer wipe_bytes(d)
+	zx, zy := curve.ScalarMult(peer.X, peer.Y, d)
+	if zx.Sign() == 0 && zy.Sign() == 0 {
+		return nil, ErrNoSharedSecret
+	}
+	shared := toFixedBytes(zx, width)
+	defer wipe_bytes(shared)
+	defer wipe_int(zx)
+	return ecdh_kdf(shared, curve.Params().Name, info), nil
+}
+
+// The key derivation of DeriveSharedSecret.
+func ecdh_kdf(shared []byte, curve string, info []byte) *SecretBytes {
+	input := append(length_prefixed([]byte(curve)), info...)
+	return NewSecretBytes(kmac.XOF256(shared, input, 8*SharedSecretSize, "ECDH"))
+}
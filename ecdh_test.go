@@ -0,0 +1,115 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"sig/edwards"
+	"sig/secp256k1"
+)
+
+func TestECDHSharedSecrets(t *testing.T) {
+
+	info := []byte("session keys")
+	for _, c := range []*edwards.Curve{e222Curve, e521Curve, ed448Curve, ed25519Curve} {
+		a_int, _ := rand.Int(rand.Reader, c.Order())
+		b_int, _ := rand.Int(rand.Reader, c.Order())
+		a, b := NewSecretScalar(a_int), NewSecretScalar(b_int)
+		A, B := c.BaseMul(a_int), c.BaseMul(b_int)
+
+		k1, err := DeriveSharedSecret(a, B, info)
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name(), err)
+		}
+		k2, err := DeriveSharedSecret(b, A, info)
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name(), err)
+		}
+		k3, err := DeriveSharedSecret(a, B, []byte("other"))
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name(), err)
+		}
+		b1, _ := k1.Bytes()
+		b2, _ := k2.Bytes()
+		b3, _ := k3.Bytes()
+		if len(b1) != SharedSecretSize {
+			t.Fatalf("%s: secret of %d bytes, want %d", c.Name(), len(b1), SharedSecretSize)
+		}
+		if !bytes.Equal(b1, b2) {
+			t.Fatalf("%s: parties derive %x and %x", c.Name(), b1, b2)
+		}
+		if bytes.Equal(b1, b3) {
+			t.Fatalf("%s: secret does not depend on the info", c.Name())
+		}
+
+		// a small order component on the peer key does not change the secret
+		T := c.NewPointXY(*big.NewInt(0), *new(big.Int).Sub(c.Params().P, big.NewInt(1)))
+		k4, err := DeriveSharedSecret(a, B.Add(T), info)
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name(), err)
+		}
+		if b4, _ := k4.Bytes(); !bytes.Equal(b1, b4) {
+			t.Fatalf("%s: small order component changes the secret to %x", c.Name(), b4)
+		}
+
+		// the identity and small order keys are refused
+		if _, err := DeriveSharedSecret(a, c.IdPoint(), info); err != ErrIdentityPoint {
+			t.Fatalf("%s identity key: %v, want %v", c.Name(), err, ErrIdentityPoint)
+		}
+		if _, err := DeriveSharedSecret(a, T, info); err != ErrIdentityPoint {
+			t.Fatalf("%s small order key: %v, want %v", c.Name(), err, ErrIdentityPoint)
+		}
+	}
+
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), secp256k1.Curve()} {
+		name := curve.Params().Name
+		a, _ := rand.Int(rand.Reader, curve.Params().N)
+		b, _ := rand.Int(rand.Reader, curve.Params().N)
+		ka, kb := NewECDSAPrivateKey(a.Add(a, big.NewInt(1))), NewECDSAPrivateKey(b.Add(b, big.NewInt(1)))
+		ka.Curve, kb.Curve = curve, curve
+		k1, err := DeriveSharedSecret(ka, kb.PublicKey(), info)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		k2, err := DeriveSharedSecret(kb, ka.PublicKey(), info)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		b1, _ := k1.Bytes()
+		b2, _ := k2.Bytes()
+		if !bytes.Equal(b1, b2) {
+			t.Fatalf("%s: parties derive %x and %x", name, b1, b2)
+		}
+
+		off := *kb.PublicKey()
+		off.Y = new(big.Int).Add(off.Y, big.NewInt(1))
+		if _, err := DeriveSharedSecret(ka, &off, info); err != edwards.ErrInvalidPoint {
+			t.Fatalf("%s off-curve key: %v, want %v", name, err, edwards.ErrInvalidPoint)
+		}
+	}
+
+	// keys of different curves and kinds do not agree
+	p256 := NewECDSAPrivateKey(big.NewInt(7))
+	p384 := NewECDSAPrivateKey(big.NewInt(7))
+	p384.Curve = elliptic.P384()
+	if _, err := DeriveSharedSecret(p256, p384.PublicKey(), nil); err != edwards.ErrInvalidPoint {
+		t.Fatalf("P-384 peer for a P-256 key: %v, want %v", err, edwards.ErrInvalidPoint)
+	}
+	if _, err := DeriveSharedSecret(p256, e222Curve.GenPoint(), nil); err != edwards.ErrInvalidPoint {
+		t.Fatalf("E222 peer for a P-256 key: %v, want %v", err, edwards.ErrInvalidPoint)
+	}
+	if _, err := DeriveSharedSecret(big.NewInt(7), e222Curve.GenPoint(), nil); err != ErrUnsupportedAlgorithm {
+		t.Fatalf("unsupported key: %v, want %v", err, ErrUnsupportedAlgorithm)
+	}
+
+	peer := p256.PublicKey()
+	p256.Wipe()
+	if _, err := DeriveSharedSecret(p256, peer, nil); err != ErrDestroyedSecret {
+		t.Fatalf("wiped key: %v, want %v", err, ErrDestroyedSecret)
+	}
+}
@@ -0,0 +1,161 @@
+package sig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"math/big"
+
+	"sig/edwards"
+)
+
+// Signatures checked by one randomized linear combination.
+const ecdsaBatchSize = 8
+
+// An ECDSA signature (r, s).
+type ECDSASignature struct {
+	R, S *big.Int
+}
+
+/*
+Verifies sigs[i] against keys[i] and msgs[i] for all i, in the empty
+domain, and returns true iff every signature is valid. Signatures are
+checked in groups of ecdsaBatchSize: with random 128-bit weights aᵢ
+(a₀ = 1) and uᵢ, vᵢ the scalars of verify_ecdsa_sig, a group is accepted iff
+
+	(Σ aᵢuᵢ) × G + Σ_Q (Σ_{Qᵢ=Q} aᵢvᵢ) × Q = Σ ±aᵢRᵢ
+
+for some choice of signs, where Rᵢ is lifted from rᵢ. The left side costs
+one base multiplication per group and one multiplication per distinct key
+instead of two multiplications per signature. ECDSA fixes R only up to
+sign, so the 2^(k−1) sign choices are searched by flipping one sign at a
+time; this costs k − 1 bits of the 128-bit soundness margin. A group that
+fails, or whose R cannot be lifted, is verified signature by signature, so
+the result always agrees with verify_ecdsa_sig. Only P-256 keys take part
+in the combination; groups with keys on other curves are verified singly.
+*/
+func VerifyBatch(sigs []ECDSASignature, keys []*ecdsa.PublicKey, msgs [][]byte) bool {
+	return VerifyOptions{}.VerifyBatch(sigs, keys, msgs)
+}
+
+// Verifies as VerifyBatch does, under opts.
+func (opts VerifyOptions) VerifyBatch(sigs []ECDSASignature, keys []*ecdsa.PublicKey, msgs [][]byte) bool {
+	if len(sigs) != len(keys) || len(sigs) != len(msgs) {
+		return false
+	}
+	for i := 0; i < len(sigs); i += ecdsaBatchSize {
+		j := i + ecdsaBatchSize
+		if j > len(sigs) {
+			j = len(sigs)
+		}
+		if !opts.verify_group(sigs[i:j], keys[i:j], msgs[i:j]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Verifies one group of VerifyBatch, falling back to single verification.
+func (opts VerifyOptions) verify_group(sigs []ECDSASignature, keys []*ecdsa.PublicKey, msgs [][]byte) bool {
+	if opts.batch_equation(sigs, keys, msgs) {
+		return true
+	}
+	for i := range sigs {
+		if keys[i] == nil || !opts.Verify(keys[i], "", sigs[i].R, sigs[i].S, msgs[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Evaluates the randomized batch equation described at VerifyBatch.
+func (opts VerifyOptions) batch_equation(sigs []ECDSASignature, keys []*ecdsa.PublicKey, msgs [][]byte) bool {
+	curve := elliptic.P256()
+	n := curve.Params().N
+	one := big.NewInt(1)
+
+	g := new(big.Int)
+	q := make(map[string]*big.Int) // key coefficients by encoded key
+	qkeys := make(map[string]*ecdsa.PublicKey)
+	rx := make([]*big.Int, len(sigs)) // aᵢRᵢ
+	ry := make([]*big.Int, len(sigs))
+
+	// all sᵢ⁻¹ with one inversion; an sᵢ of 0 maps to 0 and is rejected below
+	ss := make([]*big.Int, len(sigs))
+	for i, sig := range sigs {
+		if sig.S == nil {
+			return false
+		}
+		ss[i] = sig.S
+	}
+	ws := edwards.BatchInvert(ss, n)
+	for i, sig := range sigs {
+		Q, r, s := keys[i], sig.R, sig.S
+		if Q == nil || Q.X == nil || Q.Y == nil || r == nil || s == nil ||
+			ecdsa_curve(Q.Curve) != curve || !curve.IsOnCurve(Q.X, Q.Y) {
+			return false
+		}
+		if r.Cmp(one) < 0 || r.Cmp(n) >= 0 || s.Cmp(one) < 0 || s.Cmp(n) >= 0 ||
+			(opts.RejectHighS && !is_low_s(curve, s)) {
+			return false
+		}
+		y, err := RecoverP256Y(r, 0)
+		if err != nil {
+			return false
+		}
+
+		a := one
+		if i > 0 {
+			a, err = rand.Int(rand.Reader, new(big.Int).Lsh(one, 128))
+			if err != nil || a.Sign() == 0 {
+				return false
+			}
+		}
+		e := ecdsa_digest(curve, "", msgs[i])
+		w := ws[i]
+		u := new(big.Int).Mul(new(big.Int).SetBytes(e), w)
+		g.Add(g, u.Mul(u, a)).Mod(g, n)
+
+		id := string(p256_to_compact(Q))
+		if q[id] == nil {
+			q[id], qkeys[id] = new(big.Int), Q
+		}
+		v := new(big.Int).Mul(r, w)
+		q[id].Add(q[id], v.Mul(v, a)).Mod(q[id], n)
+
+		rx[i], ry[i] = curve.ScalarMult(r, y, toFixedBytes(a, p256Width))
+	}
+
+	lx, ly := curve.ScalarBaseMult(toFixedBytes(g, p256Width))
+	for id, c := range q {
+		x, y := curve.ScalarMult(qkeys[id].X, qkeys[id].Y, toFixedBytes(c, p256Width))
+		lx, ly = curve.Add(lx, ly, x, y)
+	}
+
+	// Walk the signs of a₁R₁ … in Gray code order, keeping a₀R₀ positive;
+	// comparing x alone accepts both Σ and −Σ.
+	sx, sy := rx[0], ry[0]
+	for i := 1; i < len(sigs); i++ {
+		sx, sy = curve.Add(sx, sy, rx[i], ry[i])
+	}
+	sign := make([]bool, len(sigs)) // true once aᵢRᵢ is negated
+	for step := 1; ; step++ {
+		if sx.Cmp(lx) == 0 && (sx.Sign() != 0 || sy.Sign() != 0) {
+			return true
+		}
+		if step == 1<<(len(sigs)-1) {
+			return false
+		}
+		k := 1
+		for step>>(k-1)&1 == 0 {
+			k++
+		}
+		// S ← S ∓ 2aₖRₖ
+		dx, dy := curve.Double(rx[k], ry[k])
+		if !sign[k] {
+			dy = new(big.Int).Sub(curve.Params().P, dy)
+		}
+		sign[k] = !sign[k]
+		sx, sy = curve.Add(sx, sy, dx, dy)
+	}
+}
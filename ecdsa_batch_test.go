@@ -0,0 +1,59 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+// VerifyBatch agrees with verify_ecdsa_sig across groups and shared keys.
+func TestBatchECDSAVerification(t *testing.T) {
+
+	n := elliptic.P256().Params().N
+	var ds []*big.Int
+	var Qs []*ecdsa.PublicKey
+	for i := 0; i < 3; i++ {
+		d := new(big.Int).Mod(generateRandomBigInt(), n)
+		Q := &ecdsa.PublicKey{Curve: elliptic.P256()}
+		Q.X, Q.Y = elliptic.P256().ScalarBaseMult(toFixedBytes(d, p256Width))
+		ds, Qs = append(ds, d), append(Qs, Q)
+	}
+
+	// more than one group, keys reused across signatures
+	var sigs []ECDSASignature
+	var keys []*ecdsa.PublicKey
+	var msgs [][]byte
+	for i := 0; i < 2*ecdsaBatchSize+3; i++ {
+		msg := []byte(fmt.Sprintf("entry %d", i))
+		r, s := sign_message_ecdsa(elliptic.P256(), &msg, ds[i%3])
+		sigs = append(sigs, ECDSASignature{r, s})
+		keys, msgs = append(keys, Qs[i%3]), append(msgs, msg)
+	}
+	if !VerifyBatch(sigs, keys, msgs) {
+		t.Fatalf("batch of %d signatures does not verify", len(sigs))
+	}
+	if !VerifyBatch(nil, nil, nil) || !VerifyBatch(sigs[:1], keys[:1], msgs[:1]) {
+		t.Fatal("empty or single signature batch does not verify")
+	}
+
+	// one bad signature anywhere fails the batch
+	for _, i := range []int{0, ecdsaBatchSize + 2, len(sigs) - 1} {
+		bad := append([]ECDSASignature(nil), sigs...)
+		bad[i].S = new(big.Int).Add(bad[i].S, big.NewInt(1))
+		if VerifyBatch(bad, keys, msgs) {
+			t.Fatalf("batch verifies with signature %d changed", i)
+		}
+	}
+	swapped := append([]*ecdsa.PublicKey(nil), keys...)
+	swapped[1], swapped[2] = swapped[2], swapped[1]
+	if VerifyBatch(sigs, swapped, msgs) {
+		t.Fatal("batch verifies with two keys swapped")
+	}
+	if VerifyBatch(sigs, keys[1:], msgs) {
+		t.Fatal("batch verifies with one key too few")
+	}
+}
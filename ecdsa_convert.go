@@ -0,0 +1,39 @@
+package sig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"errors"
+	"math/big"
+)
+
+// Returned for public key coordinates that are not a point of the curve.
+var ErrInvalidPublicKey = errors.New("public key is not a point of the curve")
+
+/*
+Builds a crypto/ecdsa public key from the raw coordinates (x, y) on curve,
+for x509.MarshalPKIXPublicKey and the rest of the standard library. The
+point must pass ValidatePublicKey; the coordinates are copied.
+*/
+func ToECDSAPublicKey(curve elliptic.Curve, x, y *big.Int) (*ecdsa.PublicKey, error) {
+	if err := ValidatePublicKey(curve, x, y); err != nil {
+		return nil, err
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).Set(x), Y: new(big.Int).Set(y)}, nil
+}
+
+/*
+Returns copies of the raw coordinates of a crypto/ecdsa public key, for
+example one parsed by x509.ParsePKIXPublicKey, after checking it with
+ValidatePublicKey.
+*/
+func FromECDSAPublicKey(pub *ecdsa.PublicKey) (*big.Int, *big.Int, error) {
+	if pub == nil {
+		return nil, nil, ErrPublicKeyMissing
+	}
+	Q, err := ToECDSAPublicKey(pub.Curve, pub.X, pub.Y)
+	if err != nil {
+		return nil, nil, err
+	}
+	return Q.X, Q.Y, nil
+}
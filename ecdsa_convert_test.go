@@ -0,0 +1,92 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// Keys survive a round trip through crypto/ecdsa and the x509 encodings.
+func TestECDSAKeyConversions(t *testing.T) {
+
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384()} {
+		name := curve.Params().Name
+		d, err := rand.Int(rand.Reader, curve.Params().N)
+		if err != nil {
+			t.Fatal(err)
+		}
+		priv, err := ToECDSAPrivateKey(curve, d)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !priv.Curve.IsOnCurve(priv.X, priv.Y) {
+			t.Fatalf("%s: public key (%x, %x) is not on the curve", name, priv.X, priv.Y)
+		}
+
+		pkcs8, err := x509.MarshalPKCS8PrivateKey(priv)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		parsed, err := x509.ParsePKCS8PrivateKey(pkcs8)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		key, err := FromECDSAPrivateKey(parsed.(*ecdsa.PrivateKey))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if key.D.Cmp(d) != 0 || key.Curve != curve {
+			t.Fatalf("%s: key converts back as %x on %s", name, key.D, key.Curve.Params().Name)
+		}
+		pkix, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		pub, err := x509.ParsePKIXPublicKey(pkix)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		x, y, err := FromECDSAPublicKey(pub.(*ecdsa.PublicKey))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		Q, err := ToECDSAPublicKey(curve, x, y)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		msg := []byte("converted")
+		digest := ecdsa_digest(curve, "", msg)
+		r, s, err := key.Sign("", &msg)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !ecdsa.Verify(&priv.PublicKey, digest, r, s) || !verify_ecdsa_sig(Q, r, s, &msg) {
+			t.Fatalf("%s: signature by the converted key does not verify", name)
+		}
+	}
+
+	// out of range scalars and points off the curve are refused
+	n := elliptic.P256().Params().N
+	if _, err := ToECDSAPrivateKey(elliptic.P256(), n); err != ErrInvalidPrivateKey {
+		t.Fatalf("d = n: %v, want %v", err, ErrInvalidPrivateKey)
+	}
+	if _, err := ToECDSAPrivateKey(elliptic.P256(), big.NewInt(0)); err != ErrZeroScalar {
+		t.Fatalf("d = 0: %v, want %v", err, ErrZeroScalar)
+	}
+	if _, err := ToECDSAPublicKey(elliptic.P256(), big.NewInt(1), big.NewInt(1)); !errors.Is(err, ErrInvalidPublicKey) {
+		t.Fatalf("(1, 1): %v, want %v", err, ErrInvalidPublicKey)
+	}
+	if _, _, err := FromECDSAPublicKey(&ecdsa.PublicKey{Curve: elliptic.P256(), X: big.NewInt(0), Y: big.NewInt(0)}); !errors.Is(err, ErrInvalidPublicKey) {
+		t.Fatalf("(0, 0): %v, want %v", err, ErrInvalidPublicKey)
+	}
+	if _, err := FromECDSAPrivateKey(&ecdsa.PrivateKey{}); err != ErrInvalidPrivateKey {
+		t.Fatalf("empty key: %v, want %v", err, ErrInvalidPrivateKey)
+	}
+}
@@ -0,0 +1,268 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"container/list"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"io"
+	"math/big"
+	"sync"
+)
+
+// Returned when a signature would reuse the r, and thus the nonce k, of an earlier signature.
+var ErrDuplicateNonce = errors.New("duplicate ECDSA nonce")
+
+// Number of recent r values remembered per key by default.
+const defaultNonceCacheSize = 1024
+
+/*
+ECDSA signing key for secp256r1, or for P-384, P-521 or secp256k1 if Curve is set.
+Unless disabled, the key remembers the
+r values of its recent signatures and refuses to emit a signature that
+repeats one: two signatures sharing k under the same key reveal the key.
+*/
+type ECDSAPrivateKey struct {
+	D             *big.Int
+	Curve         elliptic.Curve // P-256 when nil
+	Rand          io.Reader      // source of nonces, crypto/rand when nil
+	Deterministic bool           // derive nonces per RFC 6979 instead of reading Rand
+	Hedged        bool           // derive nonces per RFC 6979 mixed with bytes read from Rand
+	LowS          bool           // emit n − s for s > n/2, as VerifyOptions.RejectHighS requires
+
+	mu     sync.Mutex
+	nonces *nonceCache
+	wiped  bool
+}
+
+// Wraps d with the nonce cache enabled.
+func NewECDSAPrivateKey(d *big.Int) *ECDSAPrivateKey {
+	return &ECDSAPrivateKey{D: d, nonces: new_nonce_cache(defaultNonceCacheSize)}
+}
+
+/*
+Converts a crypto/ecdsa private key, for example one parsed by
+x509.ParsePKCS8PrivateKey, into a key of this package on the same curve
+with the nonce cache enabled. D is copied.
+*/
+func FromECDSAPrivateKey(priv *ecdsa.PrivateKey) (*ECDSAPrivateKey, error) {
+	if priv == nil || priv.D == nil || priv.Curve == nil {
+		return nil, ErrInvalidPrivateKey
+	}
+	if err := check_ecdsa_scalar(priv.Curve, priv.D); err != nil {
+		return nil, err
+	}
+	key := NewECDSAPrivateKey(new(big.Int).Set(priv.D))
+	key.Curve = priv.Curve
+	return key, nil
+}
+
+/*
+Converts the raw scalar d on curve into a crypto/ecdsa private key with
+its public key filled in, for x509.MarshalPKCS8PrivateKey and the rest of
+the standard library. d must lie in [1, n−1]; it is copied.
+*/
+func ToECDSAPrivateKey(curve elliptic.Curve, d *big.Int) (*ecdsa.PrivateKey, error) {
+	if err := check_ecdsa_scalar(curve, d); err != nil {
+		return nil, err
+	}
+	key := &ECDSAPrivateKey{D: d, Curve: curve}
+	return &ecdsa.PrivateKey{PublicKey: *key.PublicKey(), D: new(big.Int).Set(d)}, nil
+}
+
+// Public key d × G of key.
+func (key *ECDSAPrivateKey) PublicKey() *ecdsa.PublicKey {
+	curve := ecdsa_curve(key.Curve)
+	pub := &ecdsa.PublicKey{Curve: curve}
+	pub.X, pub.Y = curve.ScalarBaseMult(toFixedBytes(new(big.Int).Mod(key.D, curve.Params().N), curve_width(curve)))
+	return pub
+}
+
+// Fingerprint of the public key d × G, see Fingerprint.
+func (key *ECDSAPrivateKey) Fingerprint() Fingerprint {
+	pub := key.PublicKey()
+	return fingerprint_of(uncompressed_point(pub.X, pub.Y, curve_width(pub.Curve)))
+}
+
+// Returned for a private scalar outside [1, n−1].
+var ErrInvalidPrivateKey = errors.New("private key out of range")
+
+// Checks 1 ≤ d ≤ n − 1 for the order n of curve.
+func check_ecdsa_scalar(curve elliptic.Curve, d *big.Int) error {
+	if d == nil || d.Sign() < 0 || d.Cmp(curve.Params().N) >= 0 {
+		return ErrInvalidPrivateKey
+	}
+	if d.Sign() == 0 {
+		return ErrZeroScalar
+	}
+	return nil
+}
+
+// Enables the nonce cache holding the last size r values, or disables it if size <= 0.
+func (key *ECDSAPrivateKey) SetNonceCache(size int) {
+	key.mu.Lock()
+	defer key.mu.Unlock()
+	if size <= 0 {
+		key.nonces = nil
+		return
+	}
+	key.nonces = new_nonce_cache(size)
+}
+
+/*
+Signs msg in domain and returns (r, s), or ErrDuplicateNonce if r was already
+emitted by this key while the nonce cache is enabled. A key that is
+0 mod n is refused with ErrZeroScalar, and a Rand that fails or keeps
+yielding unusable nonces gives its error or ErrNonceAttempts.
+
+Deterministic and hedged signatures do not consult the nonce cache: their
+nonce is a function of the key, the message and, if hedged, the bytes read
+from Rand, so r repeats only when the signature does. Hedged takes
+precedence over Deterministic.
+*/
+func (key *ECDSAPrivateKey) Sign(domain string, msg *[]byte) (*big.Int, *big.Int, error) {
+	return key.sign_digest(ecdsa_digest(key.Curve, domain, *msg))
+}
+
+/*
+Signs msg in domain as Sign does and encodes the signature with its
+recovery id as v || r || s, see encode_recoverable, so that verifiers can
+recover the public key with RecoverPublicKey. The recovery id is found by
+recovering each candidate key and keeping the one that matches this key.
+*/
+func (key *ECDSAPrivateKey) SignRecoverable(domain string, msg *[]byte) ([]byte, error) {
+	r, s, err := key.Sign(domain, msg)
+	if err != nil {
+		return nil, err
+	}
+	curve := ecdsa_curve(key.Curve)
+	pub := key.PublicKey()
+	e := ecdsa_digest(curve, domain, *msg)
+	for recid := byte(0); recid < 4; recid++ {
+		Q, err := recover_ecdsa_key(curve, recid, e, r, s)
+		if err == nil && Q.X.Cmp(pub.X) == 0 && Q.Y.Cmp(pub.Y) == 0 {
+			return encode_recoverable(curve, recid, r, s), nil
+		}
+	}
+	return nil, ErrRecoverableSignature
+}
+
+/*
+Signs the message read from in, in domain, without holding it in memory:
+the message is hashed in chunks of readerChunkSize bytes. Read errors are
+returned before anything is signed.
+*/
+func (key *ECDSAPrivateKey) SignReader(domain string, in io.Reader) (*big.Int, *big.Int, error) {
+	e, err := ecdsa_digest_reader(key.Curve, domain, in)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key.sign_digest(e)
+}
+
+/*
+Signs a message hashed by the caller, see VerifyDigest for what digest must
+contain. Returns ErrDigestLength unless digest has the length of the
+curve's hash, 32 bytes for P-256 and 64 for P-521. The key cannot tell
+what was hashed, so the caller is responsible for the hash.
+*/
+func (key *ECDSAPrivateKey) SignDigest(digest []byte) (*big.Int, *big.Int, error) {
+	if len(digest) != ecdsa_hash(key.Curve).Size() {
+		return nil, nil, ErrDigestLength
+	}
+	return key.sign_digest(digest)
+}
+
+// Signs the message hash e as described at Sign.
+func (key *ECDSAPrivateKey) sign_digest(e []byte) (*big.Int, *big.Int, error) {
+	key.mu.Lock()
+	wiped := key.wiped
+	key.mu.Unlock()
+	if wiped {
+		return nil, nil, ErrDestroyedSecret
+	}
+	curve := ecdsa_curve(key.Curve)
+	if new(big.Int).Mod(key.D, curve.Params().N).Sign() == 0 {
+		return nil, nil, ErrZeroScalar
+	}
+	rnd := key.Rand
+	if rnd == nil {
+		rnd = rand.Reader
+	}
+	if key.Hedged {
+		random := make([]byte, ecdsa_hash(curve).Size())
+		defer wipe_bytes(random)
+		if _, err := io.ReadFull(rnd, random); err != nil {
+			return nil, nil, err
+		}
+		r, s := sign_ecdsa_hedged_digest(curve, e, key.D, random)
+		return r, key.normalize_s(s), nil
+	}
+	if key.Deterministic {
+		r, s := sign_ecdsa_deterministic_digest(curve, e, key.D)
+		return r, key.normalize_s(s), nil
+	}
+	r, s, err := sign_ecdsa_digest_with_reader(curve, e, key.D, rnd)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key.mu.Lock()
+	defer key.mu.Unlock()
+	if key.nonces != nil && !key.nonces.add(r) {
+		return nil, nil, ErrDuplicateNonce
+	}
+	return r, key.normalize_s(s), nil
+}
+
+/*
+Overwrites D with zeros. Signing with the key afterwards fails with
+ErrDestroyedSecret. Wipe must not race with a signature in progress.
+*/
+func (key *ECDSAPrivateKey) Wipe() {
+	key.mu.Lock()
+	defer key.mu.Unlock()
+	if key.D != nil {
+		wipe_int(key.D)
+	}
+	key.wiped = true
+}
+
+// Replaces a high s by n − s if the key emits low-S signatures.
+func (key *ECDSAPrivateKey) normalize_s(s *big.Int) *big.Int {
+	curve := ecdsa_curve(key.Curve)
+	if key.LowS && !is_low_s(curve, s) {
+		return s.Sub(curve.Params().N, s)
+	}
+	return s
+}
+
+// Bounded LRU set of r values.
+type nonceCache struct {
+	size  int
+	order *list.List // front is most recently seen
+	seen  map[string]*list.Element
+}
+
+func new_nonce_cache(size int) *nonceCache {
+	return &nonceCache{size: size, order: list.New(), seen: make(map[string]*list.Element)}
+}
+
+// Records r and returns false if it was already present.
+func (c *nonceCache) add(r *big.Int) bool {
+	id := string(r.Bytes())
+	if el, ok := c.seen[id]; ok {
+		c.order.MoveToFront(el)
+		return false
+	}
+	c.seen[id] = c.order.PushFront(id)
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.seen, oldest.Value.(string))
+	}
+	return true
+}
@@ -0,0 +1,199 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"math/big"
+	"sync"
+	"testing"
+)
+
+/*
+Recovers the ECDSA private key from two signatures (r, s1) and (r, s2)
+over digests z1 and z2 that share the nonce k:
+k = (z1 - z2) / (s1 - s2) and d = (s1·k - z1) / r mod n.
+*/
+func recover_key_from_duplicate_nonce(r, s1, s2, z1, z2 *big.Int) *big.Int {
+	n := elliptic.P256().Params().N
+	k := new(big.Int).Sub(z1, z2)
+	k.Mul(k, new(big.Int).ModInverse(new(big.Int).Mod(new(big.Int).Sub(s1, s2), n), n))
+	k.Mod(k, n)
+	d := new(big.Int).Mul(s1, k)
+	d.Sub(d, z1)
+	d.Mul(d, new(big.Int).ModInverse(r, n))
+	return d.Mod(d, n)
+}
+
+// Reader that always yields the same bytes, as a broken RNG would.
+type stuckReader struct{}
+
+func (stuckReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0x5a
+	}
+	return len(p), nil
+}
+
+// A repeated nonce leaks the key, and the nonce cache refuses the second signature.
+func TestDuplicateNonceGuard(t *testing.T) {
+
+	d := new(big.Int).Mod(generateRandomBigInt(), elliptic.P256().Params().N)
+	msg1, msg2 := []byte("first message"), []byte("second message")
+	h1, h2 := sha256.Sum256(msg1), sha256.Sum256(msg2)
+	z1, z2 := new(big.Int).SetBytes(h1[:]), new(big.Int).SetBytes(h2[:])
+
+	key := NewECDSAPrivateKey(d)
+	key.Rand = stuckReader{}
+	key.SetNonceCache(0)
+	r1, s1, err := key.Sign("", &msg1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, s2, err := key.Sign("", &msg2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r1.Cmp(r2) != 0 {
+		t.Fatalf("stuck reader gives r = %v and %v", r1, r2)
+	}
+	if recovered := recover_key_from_duplicate_nonce(r1, s1, s2, z1, z2); recovered.Cmp(d) != 0 {
+		t.Fatalf("recovered key %v, want %v", recovered, d)
+	}
+
+	key.SetNonceCache(16)
+	if _, _, err := key.Sign("", &msg1); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := key.Sign("", &msg2); !errors.Is(err, ErrDuplicateNonce) {
+		t.Fatalf("repeated nonce: %v, want %v", err, ErrDuplicateNonce)
+	}
+
+	// a working reader is unaffected, including under concurrent use
+	key = NewECDSAPrivateKey(d)
+	var wg sync.WaitGroup
+	errs := make(chan error, 32)
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := key.Sign("", &msg1)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// Signatures over a caller's digest are interchangeable with Sign's.
+func TestPrehashedECDSA(t *testing.T) {
+
+	n := elliptic.P256().Params().N
+	key := NewECDSAPrivateKey(new(big.Int).Mod(generateRandomBigInt(), n))
+	Q := &ecdsa.PublicKey{Curve: elliptic.P256()}
+	Q.X, Q.Y = elliptic.P256().ScalarBaseMult(toFixedBytes(key.D, p256Width))
+	msg := []byte("content addressed blob")
+	digest := sha256.Sum256(msg)
+
+	r, s, err := key.SignDigest(digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verify_ecdsa_sig(Q, r, s, &msg) || !VerifyDigest(Q, digest[:], r, s) {
+		t.Fatal("SignDigest signature does not verify")
+	}
+	r2, s2, err := key.Sign("", &msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifyDigest(Q, digest[:], r2, s2) {
+		t.Fatal("Sign signature does not verify with VerifyDigest")
+	}
+
+	// domain digests are what ecdsa_digest computes
+	inDomain := ecdsa_digest(Q.Curve, "store", msg)
+	r, s, err = key.SignDigest(inDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verify_ecdsa_sig_domain(Q, "store", r, s, &msg) {
+		t.Fatal("signature over the domain digest does not verify in the domain")
+	}
+	if VerifyDigest(Q, digest[:], r, s) {
+		t.Fatal("signature over the domain digest verifies outside it")
+	}
+
+	if _, _, err := key.SignDigest(digest[:20]); err != ErrDigestLength {
+		t.Fatalf("20 byte digest: %v, want %v", err, ErrDigestLength)
+	}
+	if VerifyDigest(Q, append(digest[:], 0), r2, s2) {
+		t.Fatal("signature verifies a 33 byte digest")
+	}
+}
+
+// Hedged nonces differ per signature yet stay safe when the random source fails.
+func TestHedgedECDSA(t *testing.T) {
+
+	n := elliptic.P256().Params().N
+	key := NewECDSAPrivateKey(new(big.Int).Mod(generateRandomBigInt(), n))
+	key.Hedged = true
+	Q := &ecdsa.PublicKey{Curve: elliptic.P256()}
+	Q.X, Q.Y = elliptic.P256().ScalarBaseMult(toFixedBytes(key.D, p256Width))
+	msg := []byte("hedged")
+
+	r1, s1, err := key.Sign("", &msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, s2, err := key.Sign("", &msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rd, _ := sign_ecdsa_deterministic(elliptic.P256(), &msg, key.D, "")
+	if r1.Cmp(r2) == 0 || r1.Cmp(rd) == 0 {
+		t.Fatalf("hedged r = %x and %x, deterministic r = %x", r1, r2, rd)
+	}
+	if !verify_ecdsa_sig(Q, r1, s1, &msg) || !verify_ecdsa_sig(Q, r2, s2, &msg) {
+		t.Fatal("hedged signature does not verify")
+	}
+
+	// a stuck source repeats the whole signature, never just the nonce
+	key.Rand = stuckReader{}
+	r3, s3, err := key.Sign("", &msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r4, s4, err := key.Sign("", &msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r3.Cmp(r4) != 0 || s3.Cmp(s4) != 0 {
+		t.Fatalf("stuck source gives (%x, %x) and (%x, %x)", r3, s3, r4, s4)
+	}
+	if r3.Cmp(rd) == 0 || !verify_ecdsa_sig(Q, r3, s3, &msg) {
+		t.Fatalf("stuck source gives r = %x, deterministic r = %x", r3, rd)
+	}
+	other := []byte("hedged, other message")
+	r5, _, err := key.Sign("", &other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r5.Cmp(r3) == 0 {
+		t.Fatalf("stuck source reuses r = %x for another message", r5)
+	}
+
+	key.Rand = bytes.NewReader(nil)
+	if _, _, err := key.Sign("", &msg); err != io.EOF {
+		t.Fatalf("empty source: %v, want %v", err, io.EOF)
+	}
+}
@@ -0,0 +1,46 @@
+package sig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"io"
+	"math/big"
+)
+
+// Bytes read and hashed at a time by SignReader and VerifyReader.
+const readerChunkSize = 64 * 1024
+
+// ecdsa_digest of the message read from in, hashed one chunk at a time.
+func ecdsa_digest_reader(curve elliptic.Curve, domain string, in io.Reader) ([]byte, error) {
+	h := ecdsa_hasher(curve, domain)
+	buf := make([]byte, readerChunkSize)
+	for {
+		n, err := in.Read(buf)
+		h.Write(buf[:n])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return h.Sum(nil), nil
+}
+
+/*
+Verifies the ECDSA signature (r, s) made in domain over the message read
+from in, hashing it in chunks so that it never has to fit in memory.
+Returns false with the error if in fails before EOF.
+*/
+func VerifyReader(Q_a *ecdsa.PublicKey, domain string, r, s *big.Int, in io.Reader) (bool, error) {
+	return VerifyOptions{}.VerifyReader(Q_a, domain, r, s, in)
+}
+
+// Verifies as VerifyReader does, under opts.
+func (opts VerifyOptions) VerifyReader(Q_a *ecdsa.PublicKey, domain string, r, s *big.Int, in io.Reader) (bool, error) {
+	e, err := ecdsa_digest_reader(Q_a.Curve, domain, in)
+	if err != nil {
+		return false, err
+	}
+	return opts.verify_digest(Q_a, e, r, s), nil
+}
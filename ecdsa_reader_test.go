@@ -0,0 +1,60 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"errors"
+	"io"
+	"math/big"
+	"testing"
+	"testing/iotest"
+)
+
+// SignReader and VerifyReader agree with Sign over a stream of many chunks.
+func TestECDSAReaderSignatures(t *testing.T) {
+
+	n := elliptic.P256().Params().N
+	key := NewECDSAPrivateKey(new(big.Int).Mod(generateRandomBigInt(), n))
+	key.Deterministic = true
+	Q := &ecdsa.PublicKey{Curve: elliptic.P256()}
+	Q.X, Q.Y = elliptic.P256().ScalarBaseMult(toFixedBytes(key.D, p256Width))
+
+	size := int64(3*readerChunkSize + 17)
+	msg := make([]byte, size)
+	stuckReader{}.Read(msg)
+	for _, domain := range []string{"", "ingest"} {
+		in := &countingReader{r: io.LimitReader(stuckReader{}, size)}
+		r, s, err := key.SignReader(domain, in)
+		if err != nil {
+			t.Fatalf("domain %q: %v", domain, err)
+		}
+		if in.n != size {
+			t.Fatalf("domain %q: read %d bytes, want %d", domain, in.n, size)
+		}
+		r2, s2, err := key.Sign(domain, &msg)
+		if err != nil {
+			t.Fatalf("domain %q: %v", domain, err)
+		}
+		if r.Cmp(r2) != 0 || s.Cmp(s2) != 0 {
+			t.Fatalf("domain %q: SignReader gives (%x, %x), Sign gives (%x, %x)", domain, r, s, r2, s2)
+		}
+		if !verify_ecdsa_sig_domain(Q, domain, r, s, &msg) {
+			t.Fatalf("domain %q: signature does not verify", domain)
+		}
+		if ok, err := VerifyReader(Q, domain, r, s, io.LimitReader(stuckReader{}, size)); !ok || err != nil {
+			t.Fatalf("domain %q: VerifyReader = %v, %v", domain, ok, err)
+		}
+		if ok, _ := VerifyReader(Q, domain, r, s, io.LimitReader(stuckReader{}, size-1)); ok {
+			t.Fatalf("domain %q: signature verifies a truncated stream", domain)
+		}
+	}
+
+	// read errors surface instead of a signature over a truncated message
+	failing := io.MultiReader(bytes.NewReader(msg[:10]), iotest.ErrReader(errors.New("disk")))
+	if r, _, err := key.SignReader("", failing); r != nil || err == nil {
+		t.Fatalf("failing reader: r = %v, %v", r, err)
+	}
+}
@@ -0,0 +1,286 @@
+package sig
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+)
+
+/*
+Verifies a signature (r, s) against a public key Qₐ
+Remark: by https://www.secg.org/sec1-v2.pdf 4.1.6 (page 47)
+It is possible to recover Qₐ from (r, s)
+This can reduce signature and transmission size requirements.
+
+	returns true iff signature is validated against key
+*/
+func verify_ecdsa_sig(Q_a *ecdsa.PublicKey, r, s *big.Int, msg *[]byte) bool {
+	return verify_ecdsa_sig_domain(Q_a, "", r, s, msg)
+}
+
+/*
+Options of ECDSA verification. The zero value, which verify_ecdsa_sig,
+VerifyDigest, VerifyReader and VerifyBatch use, accepts every valid
+signature.
+*/
+type VerifyOptions struct {
+	// Rejects signatures whose s lies in the upper half of [1, n−1].
+	// (r, s) and (r, n − s) are both valid, so without this anyone can turn
+	// a signature into a second valid one, which breaks systems that
+	// identify transactions by their signature bytes.
+	RejectHighS bool
+}
+
+// True iff s ≤ n/2 for the order n of curve, the canonical choice among s and n − s.
+func is_low_s(curve elliptic.Curve, s *big.Int) bool {
+	half := new(big.Int).Rsh(curve.Params().N, 1)
+	return s.Cmp(half) <= 0
+}
+
+// The curve of an ECDSA key, P-256 when the key does not name one.
+func ecdsa_curve(curve elliptic.Curve) elliptic.Curve {
+	if curve == nil {
+		return elliptic.P256()
+	}
+	return curve
+}
+
+/*
+The integer z of the Lₙ leftmost bits of the hash e, Lₙ being the bit
+length of the order n of curve (FIPS 186-4 Sec 6.4). Of the standard
+pairings only P-521 truncates: its SHA-512 digest is 512 bits, its order
+521.
+*/
+func ecdsa_bits2int(curve elliptic.Curve, e []byte) *big.Int {
+	z := new(big.Int).SetBytes(e)
+	if excess := 8*len(e) - curve.Params().N.BitLen(); excess > 0 {
+		z.Rsh(z, uint(excess))
+	}
+	return z
+}
+
+/*
+The hash ECDSA uses on curve, the one of matching strength that FIPS 186-5
+and the ecdsa-sha2-* and ES* algorithms pair it with: SHA-384 for P-384,
+SHA-512 for P-521 and SHA-256 for P-256 and secp256k1.
+*/
+func ecdsa_hash(curve elliptic.Curve) crypto.Hash {
+	switch ecdsa_curve(curve) {
+	case elliptic.P384():
+		return crypto.SHA384
+	case elliptic.P521():
+		return crypto.SHA512
+	}
+	return crypto.SHA256
+}
+
+/*
+Hash of msg signed by ECDSA on curve in domain: ecdsa_hash(curve) of the
+length prefixed domain followed by msg, or of msg alone in the empty
+domain.
+*/
+func ecdsa_digest(curve elliptic.Curve, domain string, msg []byte) []byte {
+	h := ecdsa_hasher(curve, domain)
+	h.Write(msg)
+	return h.Sum(nil)
+}
+
+// State of the curve's hash primed with the length prefixed domain, if any, for ecdsa_digest.
+func ecdsa_hasher(curve elliptic.Curve, domain string) hash.Hash {
+	h := ecdsa_hash(curve).New()
+	if domain != "" {
+		h.Write(length_prefixed([]byte(domain)))
+	}
+	return h
+}
+
+// Verifies an ECDSA signature made in domain.
+func verify_ecdsa_sig_domain(Q_a *ecdsa.PublicKey, domain string, r, s *big.Int, msg *[]byte) bool {
+	return verify_ecdsa_digest(Q_a, ecdsa_digest(Q_a.Curve, domain, *msg), r, s)
+}
+
+// Returned for a prehashed message that is not a digest of the curve's hash.
+var ErrDigestLength = errors.New("digest length does not match the hash of the curve")
+
+/*
+Verifies the ECDSA signature (r, s) over a message hashed by the caller.
+digest must be the hash of the message that ecdsa_hash pairs with the
+curve of Q_a, SHA-256 for P-256, or in a domain the hash of the length
+prefixed domain followed by the message, as ecdsa_digest computes it. Any
+other length is rejected.
+*/
+func VerifyDigest(Q_a *ecdsa.PublicKey, digest []byte, r, s *big.Int) bool {
+	return VerifyOptions{}.VerifyDigest(Q_a, digest, r, s)
+}
+
+// Verifies as VerifyDigest does, under opts.
+func (opts VerifyOptions) VerifyDigest(Q_a *ecdsa.PublicKey, digest []byte, r, s *big.Int) bool {
+	if len(digest) != ecdsa_hash(Q_a.Curve).Size() {
+		return false
+	}
+	return opts.verify_digest(Q_a, digest, r, s)
+}
+
+// Verifies the ECDSA signature (r, s) made in domain over msg under opts.
+func (opts VerifyOptions) Verify(Q_a *ecdsa.PublicKey, domain string, r, s *big.Int, msg []byte) bool {
+	return opts.verify_digest(Q_a, ecdsa_digest(Q_a.Curve, domain, msg), r, s)
+}
+
+// Reasons given by ValidatePublicKey, all matching ErrInvalidPublicKey under errors.Is.
+var (
+	ErrPublicKeyMissing  = fmt.Errorf("%w: missing curve or coordinate", ErrInvalidPublicKey)
+	ErrPublicKeyInfinity = fmt.Errorf("%w: point at infinity", ErrInvalidPublicKey)
+	ErrPublicKeyRange    = fmt.Errorf("%w: coordinate outside [0, p−1]", ErrInvalidPublicKey)
+	ErrPublicKeyOffCurve = fmt.Errorf("%w: point does not satisfy the curve equation", ErrInvalidPublicKey)
+	ErrPublicKeyOrder    = fmt.Errorf("%w: n × Q is not the point at infinity", ErrInvalidPublicKey)
+)
+
+/*
+Full public key validation per SEC 1 Section 3.2.2.1, as done before every
+ECDSA verification:
+
+ 1. Check Qₐ != 𝒪, which crypto/elliptic represents as (0, 0)
+ 2. Check that x and y lie in [0, p−1] and Qₐ ∈ 𝔼
+ 3. Check n × Qₐ = 𝒪
+
+Returns nil for a valid key and otherwise one of the ErrPublicKey errors.
+Step 3 cannot fail on the prime order curves supported here, but is kept
+so that the routine stays correct for curves with a cofactor.
+*/
+func ValidatePublicKey(curve elliptic.Curve, X, Y *big.Int) error {
+	if curve == nil || X == nil || Y == nil {
+		return ErrPublicKeyMissing
+	}
+	if X.Sign() == 0 && Y.Sign() == 0 {
+		return ErrPublicKeyInfinity
+	}
+	p := curve.Params().P
+	if X.Sign() < 0 || X.Cmp(p) >= 0 || Y.Sign() < 0 || Y.Cmp(p) >= 0 {
+		return ErrPublicKeyRange
+	}
+	if !curve.IsOnCurve(X, Y) { // crypto/elliptic panics on multiplying points off the curve
+		return ErrPublicKeyOffCurve
+	}
+	if x, y := curve.ScalarMult(X, Y, curve.Params().N.Bytes()); x.Sign() != 0 || y.Sign() != 0 {
+		return ErrPublicKeyOrder
+	}
+	return nil
+}
+
+/*
+Curves that compute u₁ × G + u₂ × Q in one interleaved pass, faster than
+two multiplications and an addition. secp256k1 implements it; the
+standard library curves do not, and their separate multiplications use
+precomputed tables and assembly that a generic big.Int Shamir loop
+cannot beat.
+*/
+type combinedMultCurve interface {
+	CombinedMult(x, y *big.Int, baseScalar, scalar []byte) (*big.Int, *big.Int)
+}
+
+// u₁ × G + u₂ × Q, jointly if the curve supports it.
+func ecdsa_combined_mult(curve elliptic.Curve, Q *ecdsa.PublicKey, u1, u2 []byte) (*big.Int, *big.Int) {
+	if c, ok := curve.(combinedMultCurve); ok {
+		return c.CombinedMult(Q.X, Q.Y, u1, u2)
+	}
+	return ecdsa_separate_mult(curve, Q, u1, u2)
+}
+
+// u₁ × G + u₂ × Q as two multiplications and an addition.
+func ecdsa_separate_mult(curve elliptic.Curve, Q *ecdsa.PublicKey, u1, u2 []byte) (*big.Int, *big.Int) {
+	x1, y1 := curve.ScalarBaseMult(u1)
+	x2, y2 := curve.ScalarMult(Q.X, Q.Y, u2)
+	return curve.Add(x1, y1, x2, y2)
+}
+
+/*
+Verifies an ECDSA signature over the message hash e on the curve of Q_a,
+which may be P-256, P-384, P-521 or secp256k1. u₁ × G + u₂ × Q is computed
+by Strauss–Shamir on secp256k1 only, see combinedMultCurve; P-256 and the
+other NIST curves keep the standard library's two multiplications.
+*/
+func verify_ecdsa_digest(Q_a *ecdsa.PublicKey, e []byte, r, s *big.Int) bool {
+	return VerifyOptions{}.verify_digest(Q_a, e, r, s)
+}
+
+// verify_ecdsa_digest under opts.
+func (opts VerifyOptions) verify_digest(Q_a *ecdsa.PublicKey, e []byte, r, s *big.Int) bool {
+
+	//Define curve and n
+	curve := ecdsa_curve(Q_a.Curve)
+	n := curve.Params().N
+	width := curve_width(curve)
+
+	// Phase 1: Public Key verification, see ValidatePublicKey
+	// Phase 2: Signature verification
+	if ValidatePublicKey(curve, Q_a.X, Q_a.Y) == nil {
+		// 1. Check that r, s ∈ [1...n−1]
+		//    and, if required, that s is low
+		one := big.NewInt(1)
+		if r.Cmp(n) < 0 && r.Cmp(one) >= 0 &&
+			s.Cmp(n) < 0 && s.Cmp(one) >= 0 && (!opts.RejectHighS || is_low_s(curve, s)) {
+
+			// 2. e was calculated using same hash function as signature generation
+			// 3. Let Z be Lₙ leftmost bits of e, where Lₙ is bit length of
+			// group order n
+			z := ecdsa_bits2int(curve, e)
+			// 4.a. u₁ = zs⁻¹ mod n
+			s_inv := new(big.Int).ModInverse(s, n) // Compute s⁻¹ only once
+			zs_inv := new(big.Int).Mul(z, s_inv)
+			u1 := new(big.Int).Mod(zs_inv, n)
+
+			// 4.b. u₂ = rs⁻¹ mod n
+			u2 := new(big.Int).Mul(r, s_inv)
+			u2 = new(big.Int).Mod(u2, n)
+
+			// 5. Calculate curve point (x₁, y₁) = u₁ × G + u₂ × Qₐ
+			// if (x₁, y₁) = 𝒪 then signature is invalid because for curves in
+			// Weierstrass form, 𝒪 is conventionally represented
+			// by a point that doesn’t satisfy the curve equation.
+			res_x, _ := ecdsa_combined_mult(curve, Q_a, toFixedBytes(u1, width), toFixedBytes(u2, width))
+
+			// 6. Signature is valid iff r ≡ x₁ mod n
+			return new(big.Int).Mod(res_x, n).Cmp(r) == 0
+		} else {
+			// r and/or s not in valid range
+			return false
+		}
+	} else {
+		// public key invalid
+		return false
+	}
+}
+
+// Known answer vectors from RFC 6979 Appendix A.2.5 (P-256, SHA-256).
+var ecdsaKnownAnswers = []struct {
+	msg, d, qx, qy, r, s string
+}{
+	{
+		msg: "sample",
+		d:   "c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f6721",
+		qx:  "60fed4ba255a9d31c961eb74c6356d68c049b8923b61fa6ce669622e60f29fb6",
+		qy:  "7903fe1008b8bc99a41ae9e95628bc64f2f1b20c2d7e9f5177a3c294d4462299",
+		r:   "efd48b2aacb6a8fd1140dd9cd45e81d69d2c877b56aaf991c34d0ea84eaf3716",
+		s:   "f7cb1c942d657c41d436c7a1b6e29f65f3e900dbb9aff4064dc4ab2f843acda8",
+	},
+	{
+		msg: "test",
+		d:   "c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f6721",
+		qx:  "60fed4ba255a9d31c961eb74c6356d68c049b8923b61fa6ce669622e60f29fb6",
+		qy:  "7903fe1008b8bc99a41ae9e95628bc64f2f1b20c2d7e9f5177a3c294d4462299",
+		r:   "f1abb023518351cd71d881567b1ea663ed3efcf6c5132b354f28d3b0b7d38367",
+		s:   "019f4113742a2b14bd25926b49c649155f267e60d3814b4c0cc84250e46f0083",
+	},
+}
+
+// Parses a hexadecimal constant of the test vectors.
+func hexInt(s string) *big.Int {
+	v, _ := new(big.Int).SetString(s, 16)
+	return v
+}
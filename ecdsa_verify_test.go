@@ -0,0 +1,280 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"sig/secp256k1"
+)
+
+// High-S signatures are malleable unless verifiers insist on low S.
+func TestLowSNormalization(t *testing.T) {
+
+	n := elliptic.P256().Params().N
+	d := new(big.Int).Mod(generateRandomBigInt(), n)
+	Q := &ecdsa.PublicKey{Curve: elliptic.P256()}
+	Q.X, Q.Y = elliptic.P256().ScalarBaseMult(toFixedBytes(d, p256Width))
+	msg := []byte("low s")
+
+	// about half of all signatures have a high s
+	var r, high *big.Int
+	for high == nil {
+		r1, s1 := sign_message_ecdsa(elliptic.P256(), &msg, d)
+		if !is_low_s(elliptic.P256(), s1) {
+			r, high = r1, s1
+		}
+	}
+	low := new(big.Int).Sub(n, high)
+	if !is_low_s(elliptic.P256(), low) {
+		t.Fatalf("n - s = %x is not low", low)
+	}
+	if !verify_ecdsa_sig(Q, r, high, &msg) || !verify_ecdsa_sig(Q, r, low, &msg) {
+		t.Fatal("the default verifier refuses one of s and n - s")
+	}
+
+	strict := VerifyOptions{RejectHighS: true}
+	digest := ecdsa_digest(elliptic.P256(), "", msg)
+	if strict.Verify(Q, "", r, high, msg) || strict.VerifyDigest(Q, digest, r, high) ||
+		strict.VerifyBatch([]ECDSASignature{{r, high}}, []*ecdsa.PublicKey{Q}, [][]byte{msg}) {
+		t.Fatalf("strict verifier accepts high s = %x", high)
+	}
+	if !strict.Verify(Q, "", r, low, msg) || !strict.VerifyDigest(Q, digest, r, low) ||
+		!strict.VerifyBatch([]ECDSASignature{{r, low}}, []*ecdsa.PublicKey{Q}, [][]byte{msg}) {
+		t.Fatalf("strict verifier refuses low s = %x", low)
+	}
+	if ok, err := strict.VerifyReader(Q, "", r, high, bytes.NewReader(msg)); ok || err != nil {
+		t.Fatalf("strict VerifyReader with high s = %v, %v", ok, err)
+	}
+
+	key := NewECDSAPrivateKey(d)
+	key.LowS = true
+	for i := 0; i < 9; i++ {
+		key.Deterministic = i == 8
+		r, s, err := key.Sign("", &msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !is_low_s(elliptic.P256(), s) || !strict.Verify(Q, "", r, s, msg) {
+			t.Fatalf("low S key signed s = %x", s)
+		}
+	}
+}
+
+// ECDSA hashes each curve with its standard hash and interoperates with crypto/ecdsa both ways.
+func TestECDSACurves(t *testing.T) {
+
+	msg := []byte("compliance profile")
+	curves := []struct {
+		curve  elliptic.Curve
+		hash   crypto.Hash
+		digest []byte
+	}{
+		{elliptic.P256(), crypto.SHA256, func() []byte { d := sha256.Sum256(msg); return d[:] }()},
+		{elliptic.P384(), crypto.SHA384, func() []byte { d := sha512.Sum384(msg); return d[:] }()},
+		{elliptic.P521(), crypto.SHA512, func() []byte { d := sha512.Sum512(msg); return d[:] }()},
+		{secp256k1.Curve(), crypto.SHA256, func() []byte { d := sha256.Sum256(msg); return d[:] }()},
+	}
+	for _, c := range curves {
+		curve, digest := c.curve, c.digest
+		name := curve.Params().Name
+		priv, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		Q := &priv.PublicKey
+		if h := ecdsa_hash(curve); h != c.hash {
+			t.Fatalf("%s hashes with %v, want %v", name, h, c.hash)
+		}
+		if d := ecdsa_digest(curve, "", msg); !bytes.Equal(d, digest) {
+			t.Fatalf("%s digest %x, want %x", name, d, digest)
+		}
+
+		// our signatures verify in crypto/ecdsa, random, deterministic and prehashed alike
+		r, s := sign_message_ecdsa(curve, &msg, priv.D)
+		if !ecdsa.Verify(Q, digest, r, s) {
+			t.Fatalf("%s: random signature does not verify in crypto/ecdsa", name)
+		}
+		key := NewECDSAPrivateKey(priv.D)
+		key.Curve, key.Deterministic = curve, true
+		r2, s2, err := key.Sign("", &msg)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !ecdsa.Verify(Q, digest, r2, s2) {
+			t.Fatalf("%s: deterministic signature does not verify in crypto/ecdsa", name)
+		}
+		r3, s3, err := key.Sign("", &msg)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if r2.Cmp(r3) != 0 || s2.Cmp(s3) != 0 {
+			t.Fatalf("%s: deterministic signatures (%x, %x) and (%x, %x)", name, r2, s2, r3, s3)
+		}
+		r4, s4, err := key.SignDigest(digest)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !ecdsa.Verify(Q, digest, r4, s4) {
+			t.Fatalf("%s: prehashed signature does not verify in crypto/ecdsa", name)
+		}
+		der, err := key.Signer().Sign(nil, digest, c.hash)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !ecdsa.VerifyASN1(Q, digest, der) {
+			t.Fatalf("%s: crypto.Signer signature does not verify in crypto/ecdsa", name)
+		}
+		if _, err := key.Signer().Sign(nil, digest[:20], crypto.SHA1); err != ErrSignerHash {
+			t.Fatalf("%s with SHA-1: %v, want %v", name, err, ErrSignerHash)
+		}
+		tampered := append([]byte(nil), msg...)
+		tampered[0] ^= 1
+		if verify_ecdsa_sig(Q, r, s, &tampered) {
+			t.Fatalf("%s: signature verifies a tampered message", name)
+		}
+
+		// and those of crypto/ecdsa verify here
+		r, s, err = ecdsa.Sign(rand.Reader, priv, digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !verify_ecdsa_sig(Q, r, s, &msg) || !VerifyDigest(Q, digest, r, s) {
+			t.Fatalf("%s: crypto/ecdsa signature does not verify", name)
+		}
+		if VerifyDigest(Q, digest[:len(digest)-1], r, s) {
+			t.Fatalf("%s: signature verifies a truncated digest", name)
+		}
+
+		// a key on one curve does not verify under another
+		other := &ecdsa.PublicKey{Curve: elliptic.P256()}
+		if curve == other.Curve {
+			other.Curve = elliptic.P384()
+		}
+		other.X, other.Y = other.Curve.ScalarBaseMult(priv.D.Bytes())
+		if verify_ecdsa_sig(other, r, s, &msg) {
+			t.Fatalf("%s signature verifies on %s", name, other.Curve.Params().Name)
+		}
+	}
+
+	// P-521 keeps the leftmost 521 bits of longer inputs, as crypto/ecdsa does
+	priv, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	long := make([]byte, 80)
+	rand.Read(long)
+	r, s, err := ecdsa.Sign(rand.Reader, priv, long)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verify_ecdsa_digest(&priv.PublicKey, long, r, s) {
+		t.Fatal("P-521 signature over an 80 byte digest does not verify")
+	}
+	if n := ecdsa_bits2int(elliptic.P521(), long).BitLen(); n > 521 {
+		t.Fatalf("bits2int keeps %d bits", n)
+	}
+}
+
+// secp256k1 arithmetic and signatures match published values.
+func TestSecp256k1Signatures(t *testing.T) {
+
+	curve := secp256k1.Curve()
+	x2, y2 := curve.ScalarBaseMult([]byte{2})
+	if got := fmt.Sprintf("%x", x2); got != "c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5" {
+		t.Fatalf("2G has x = %s", got)
+	}
+	if dx, dy := curve.Double(curve.Params().Gx, curve.Params().Gy); dx.Cmp(x2) != 0 || dy.Cmp(y2) != 0 {
+		t.Fatalf("G + G = (%x, %x), want 2G = (%x, %x)", dx, dy, x2, y2)
+	}
+	x3, y3 := curve.Add(x2, y2, curve.Params().Gx, curve.Params().Gy)
+	if got := fmt.Sprintf("%x", y3); got != "388f7b0f632de8140fe337e62a37f3566500a99934c2231b6cb9fd7584b8e672" {
+		t.Fatalf("3G has y = %s", got)
+	}
+	if !curve.IsOnCurve(x3, y3) || curve.IsOnCurve(x2, y3) {
+		t.Fatal("IsOnCurve is wrong for 3G or (x(2G), y(3G))")
+	}
+	if nx, ny := curve.ScalarBaseMult(curve.Params().N.Bytes()); nx.Sign() != 0 || ny.Sign() != 0 {
+		t.Fatalf("nG = (%x, %x), want the point at infinity", nx, ny)
+	}
+
+	// the widely used RFC 6979 vector for key 1
+	key := NewECDSAPrivateKey(big.NewInt(1))
+	key.Curve, key.Deterministic, key.LowS = curve, true, true
+	msg := []byte("Satoshi Nakamoto")
+	r, s, err := key.Sign("", &msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantR := "934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d8"
+	wantS := "2442ce9d2b916064108014783e923ec36b49743e2ffa1c4496f01a512aafd9e5"
+	if fmt.Sprintf("%x", r) != wantR || fmt.Sprintf("%x", s) != wantS {
+		t.Fatalf("(r, s) = (%x, %x), want (%s, %s)", r, s, wantR, wantS)
+	}
+	Q := &ecdsa.PublicKey{Curve: curve, X: curve.Params().Gx, Y: curve.Params().Gy}
+	if !verify_ecdsa_sig(Q, r, s, &msg) {
+		t.Fatal("signature by key 1 does not verify")
+	}
+
+	d, err := rand.Int(rand.Reader, curve.Params().N)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Q.X, Q.Y = curve.ScalarBaseMult(toFixedBytes(d, p256Width))
+	r, s = sign_message_ecdsa(curve, &msg, d)
+	digest := sha256.Sum256(msg)
+	if !verify_ecdsa_sig(Q, r, s, &msg) || !ecdsa.Verify(Q, digest[:], r, s) {
+		t.Fatal("secp256k1 signature does not verify")
+	}
+	if verify_ecdsa_sig(&ecdsa.PublicKey{Curve: elliptic.P256(), X: Q.X, Y: Q.Y}, r, s, &msg) {
+		t.Fatal("secp256k1 signature verifies on P-256")
+	}
+}
+
+// The interleaved u₁G + u₂Q agrees with separate multiplications, edge cases included.
+func TestShamirCombinedMult(t *testing.T) {
+
+	curve := secp256k1.Curve()
+	n := curve.Params().N
+	d := new(big.Int).Mod(generateRandomBigInt(), n)
+	Q := &ecdsa.PublicKey{Curve: curve}
+	Q.X, Q.Y = curve.ScalarBaseMult(toFixedBytes(d, p256Width))
+	G := &ecdsa.PublicKey{Curve: curve, X: curve.Params().Gx, Y: curve.Params().Gy}
+
+	check := func(Q *ecdsa.PublicKey, u1, u2 []byte) {
+		t.Helper()
+		x1, y1 := ecdsa_combined_mult(curve, Q, u1, u2)
+		x2, y2 := ecdsa_separate_mult(curve, Q, u1, u2)
+		if x1.Cmp(x2) != 0 || y1.Cmp(y2) != 0 {
+			t.Fatalf("u1 = %x, u2 = %x: combined (%x, %x), separate (%x, %x)", u1, u2, x1, y1, x2, y2)
+		}
+	}
+	for i := 0; i < 8; i++ {
+		u1 := toFixedBytes(new(big.Int).Mod(generateRandomBigInt(), n), p256Width)
+		u2 := toFixedBytes(new(big.Int).Mod(generateRandomBigInt(), n), p256Width)
+		check(Q, u1, u2)
+	}
+	// zero scalars, unequal lengths, Q = G and a sum that is the point at infinity
+	check(Q, []byte{0}, []byte{5})
+	check(Q, []byte{7, 1}, toFixedBytes(d, p256Width))
+	check(G, []byte{3}, []byte{3})
+	negD := toFixedBytes(new(big.Int).Sub(n, d), p256Width)
+	if x, y := ecdsa_combined_mult(curve, Q, negD, []byte{1}); x.Sign() != 0 || y.Sign() != 0 {
+		t.Fatalf("−dG + Q = (%x, %x), want the point at infinity", x, y)
+	}
+
+	// signatures still verify through the joint path
+	msg := []byte("shamir")
+	if r, s := sign_message_ecdsa(curve, &msg, d); !verify_ecdsa_sig(Q, r, s, &msg) {
+		t.Fatal("signature does not verify")
+	}
+}
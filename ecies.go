@@ -0,0 +1,122 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"math/big"
+
+	"sig/kmac"
+)
+
+// Version byte of the cryptograms of EncryptE222.
+const eciesVersion = 0x01
+
+var ErrDecryption = errors.New("cryptogram failed to authenticate")
+
+/*
+Encrypts msg to the holder of the E222 public key V with the DHIES
+construction of ecies_seal under the label "PM", which keeps these
+cryptograms apart from the key stanzas of encrypt_to_recipients:
+
+	version (1 byte) || Z (56 bytes) || c (|msg| bytes) || t (32 bytes)
+
+The format is fixed by the version byte; DecryptE222 refuses any other.
+*/
+func EncryptE222(V *E222, msg []byte) ([]byte, error) {
+	sealed, err := ecies_seal(V, msg, "PM")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{eciesVersion}, sealed...), nil
+}
+
+/*
+Decrypts a cryptogram of EncryptE222 with the secret key x of the
+recipient. Cryptograms that are tampered with or meant for another key
+fail with ErrDecryption and release no plaintext.
+*/
+func DecryptE222(x *SecretScalar, cryptogram []byte) ([]byte, error) {
+	if len(cryptogram) < 1+2*e222Width+envelopeTagLength || cryptogram[0] != eciesVersion {
+		return nil, errors.New("unsupported cryptogram format")
+	}
+	s, err := x.Int()
+	if err != nil {
+		return nil, err
+	}
+	defer wipe_int(s)
+	if err := check_scalar(s); err != nil {
+		return nil, err
+	}
+	return ecies_open(s, cryptogram[1:], "PM")
+}
+
+/*
+Encrypts m for the holder of V, with the customization strings of KMAC
+all starting with the label S:
+
+	k ← random scalar, W ← k × 4V, Z ← k × G
+	(ke || ka) ← KMACXOF256(W.x, "", 512, S)
+	c ← KMACXOF256(ke, "", |m|, S ‖ "KE") ⊕ m
+	t ← KMACXOF256(ka, m, 256, S ‖ "KA")
+
+and returns Z || c || t.
+*/
+func ecies_seal(V *E222, m []byte, S string) ([]byte, error) {
+	if err := validate_public_key(V); err != nil {
+		return nil, err
+	}
+	k_bytes := make([]byte, 32)
+	if _, err := rand.Read(k_bytes); err != nil {
+		return nil, err
+	}
+	k := fromFixedBytes(k_bytes)
+	wipe_bytes(k_bytes)
+	defer wipe_int(k)
+
+	W := V.ClearCofactor().SecMul(k)
+	Z := e222Curve.BaseMul(k)
+	shared := toFixedBytes(W.X(), e222Width)
+	defer wipe_bytes(shared)
+	keys := derive_keys(shared, S)
+	defer keys.Wipe()
+	ke, ka := split_keys(keys.b)
+
+	c := xor_bytes(kmac.XOF256(ke, nil, 8*len(m), S+"KE"), m)
+	t := kmac.XOF256(ka, m, 8*envelopeTagLength, S+"KA")
+	out := append(e222_to_bytes(Z), c...)
+	return append(out, t...), nil
+}
+
+/*
+Opens Z || c || t sealed by ecies_seal under the label S with the secret
+scalar s, which the caller has checked. Fails with ErrDecryption if t does not
+authenticate the decrypted message.
+*/
+func ecies_open(s *big.Int, sealed []byte, S string) ([]byte, error) {
+	if len(sealed) < 2*e222Width+envelopeTagLength {
+		return nil, ErrDecryption
+	}
+	Z, err := e222_from_bytes(sealed[:2*e222Width])
+	if err != nil {
+		return nil, err
+	}
+	c := sealed[2*e222Width : len(sealed)-envelopeTagLength]
+	t := sealed[len(sealed)-envelopeTagLength:]
+
+	W := Z.ClearCofactor().SecMul(s)
+	shared := toFixedBytes(W.X(), e222Width)
+	defer wipe_bytes(shared)
+	keys := derive_keys(shared, S)
+	defer keys.Wipe()
+	ke, ka := split_keys(keys.b)
+
+	m := xor_bytes(kmac.XOF256(ke, nil, 8*len(c), S+"KE"), c)
+	if subtle.ConstantTimeCompare(t, kmac.XOF256(ka, m, 8*envelopeTagLength, S+"KA")) != 1 {
+		wipe_bytes(m)
+		return nil, ErrDecryption
+	}
+	return m, nil
+}
@@ -0,0 +1,82 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestEciesEncryption(t *testing.T) {
+
+	x_int := generateRandomBigInt()
+	x := NewSecretScalar(x_int)
+	V := e222Curve.BaseMul(x_int)
+	for _, msg := range [][]byte{nil, []byte("attack at dawn"), make([]byte, 1000)} {
+		ct, err := EncryptE222(V, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := 1 + 2*e222Width + len(msg) + envelopeTagLength; len(ct) != want || ct[0] != eciesVersion {
+			t.Fatalf("cryptogram of %d bytes, version %d: want %d bytes, version %d", len(ct), ct[0], want, eciesVersion)
+		}
+		pt, err := DecryptE222(x, ct)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(pt, msg) {
+			t.Fatalf("decrypted %x, want %x", pt, msg)
+		}
+	}
+
+	msg := []byte("attack at dawn")
+	ct, err := EncryptE222(V, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := EncryptE222(V, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(ct, again) {
+		t.Fatal("encryption is deterministic")
+	}
+
+	// any changed byte after the ephemeral key, or the wrong key, fails to authenticate
+	for _, i := range []int{1 + 2*e222Width, len(ct) - 1} {
+		tampered := append([]byte(nil), ct...)
+		tampered[i] ^= 1
+		if _, err := DecryptE222(x, tampered); err != ErrDecryption {
+			t.Fatalf("byte %d changed: %v, want %v", i, err, ErrDecryption)
+		}
+	}
+	if _, err := DecryptE222(NewSecretScalar(generateRandomBigInt()), ct); err != ErrDecryption {
+		t.Fatalf("wrong key: %v, want %v", err, ErrDecryption)
+	}
+
+	// key stanzas of multi-recipient envelopes do not open as cryptograms
+	stanza, err := ecies_wrap(V, make([]byte, contentKeyLength))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecryptE222(x, append([]byte{eciesVersion}, stanza...)); err != ErrDecryption {
+		t.Fatalf("key stanza: %v, want %v", err, ErrDecryption)
+	}
+
+	// other versions, truncation, invalid keys and destroyed secrets are refused
+	other := append([]byte{0x02}, ct[1:]...)
+	if _, err := DecryptE222(x, other); err == nil {
+		t.Fatal("other version decrypts")
+	}
+	if _, err := DecryptE222(x, ct[:1+2*e222Width+envelopeTagLength-1]); err == nil {
+		t.Fatal("truncated cryptogram decrypts")
+	}
+	if _, err := EncryptE222(e222Curve.IdPoint(), msg); !errors.Is(err, ErrIdentityPoint) {
+		t.Fatalf("identity key: %v, want %v", err, ErrIdentityPoint)
+	}
+	x.Destroy()
+	if _, err := DecryptE222(x, ct); err != ErrDestroyedSecret {
+		t.Fatalf("destroyed key: %v, want %v", err, ErrDestroyedSecret)
+	}
+}
@@ -0,0 +1,64 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"math/big"
+
+	"golang.org/x/crypto/sha3"
+)
+
+/*
+Expands a 57 byte Ed448 seed as in RFC 8032 Section 5.2.5: the first half
+of SHAKE256(seed, 114), pruned, is the secret scalar s, and the second half
+is the prefix that keys the nonce derivation.
+*/
+func ed448_expand(seed []byte) (*big.Int, []byte) {
+	h := make([]byte, Ed448SignatureSize)
+	sha3.ShakeSum256(h, seed)
+	defer wipe_bytes(h[:Ed448SeedSize])
+	h[0] &= 0xfc               // clear the cofactor bits
+	h[Ed448SeedSize-1] = 0     // clear the last byte
+	h[Ed448SeedSize-2] |= 0x80 // set the top bit of the second to last byte
+	return fromLittleEndian(h[:Ed448SeedSize]), h[Ed448SeedSize:]
+}
+
+// The encoded Ed448 public key A = s × G of seed, or ErrEd448SeedLength.
+func Ed448PublicKey(seed []byte) ([]byte, error) {
+	if len(seed) != Ed448SeedSize {
+		return nil, ErrEd448SeedLength
+	}
+	s, prefix := ed448_expand(seed)
+	defer wipe_int(s)
+	defer wipe_bytes(prefix)
+	return ed448_encode(ed448Curve.BaseMul(s)), nil
+}
+
+/*
+Signs msg with the Ed448 seed under the context ctx, empty for plain
+Ed448, as in RFC 8032 Section 5.2.6. The signature R ‖ S, with the nonce
+r = SHAKE256(dom4(0, ctx) ‖ prefix ‖ msg, 114) mod r, R = r × G and
+S = r + k·s mod r, verifies with VerifyEd448 and with other RFC 8032
+implementations.
+*/
+func SignEd448(seed, msg, ctx []byte) ([]byte, error) {
+	if len(seed) != Ed448SeedSize {
+		return nil, ErrEd448SeedLength
+	}
+	if len(ctx) > ed448ContextMax {
+		return nil, ErrEd448Context
+	}
+	s, prefix := ed448_expand(seed)
+	defer wipe_int(s)
+	defer wipe_bytes(prefix)
+	A := ed448_encode(ed448Curve.BaseMul(s))
+
+	r := ed448_hash(ctx, prefix, msg)
+	defer wipe_int(r)
+	R := ed448_encode(ed448Curve.BaseMul(r))
+	k := ed448_hash(ctx, R, A, msg)
+
+	S := new(big.Int).Mul(k, s)
+	S.Add(S, r).Mod(S, ed448Params.R)
+	return append(R, ed448Curve.EncodeScalar(S)...), nil
+}
@@ -0,0 +1,56 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+)
+
+func TestEd448Signatures(t *testing.T) {
+
+	for i, v := range ed448KnownAnswers {
+		seed, _ := hex.DecodeString(v.seed)
+		msg, _ := hex.DecodeString(v.msg)
+		ctx, _ := hex.DecodeString(v.ctx)
+		pub, err := Ed448PublicKey(seed)
+		if err != nil {
+			t.Fatalf("vector %d: %v", i, err)
+		}
+		if got := hex.EncodeToString(pub); got != v.pub {
+			t.Fatalf("vector %d: public key %s, want %s", i, got, v.pub)
+		}
+		sig, err := SignEd448(seed, msg, ctx)
+		if err != nil {
+			t.Fatalf("vector %d: %v", i, err)
+		}
+		if got := hex.EncodeToString(sig); got != v.sig {
+			t.Fatalf("vector %d: signature %s, want %s", i, got, v.sig)
+		}
+	}
+
+	seed := make([]byte, Ed448SeedSize)
+	rand.Read(seed)
+	pub, err := Ed448PublicKey(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("Goldilocks")
+	sig, err := SignEd448(seed, msg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifyEd448(pub, msg, sig, nil) {
+		t.Fatal("signature does not verify")
+	}
+	if h := ed448Curve.Params().Cofactor.Int64(); h != 4 {
+		t.Fatalf("cofactor %d, want 4", h)
+	}
+	if _, err := SignEd448(seed[1:], msg, nil); err != ErrEd448SeedLength {
+		t.Fatalf("56 byte seed: %v, want %v", err, ErrEd448SeedLength)
+	}
+	if _, err := SignEd448(seed, msg, make([]byte, 256)); err != ErrEd448Context {
+		t.Fatalf("256 byte context: %v, want %v", err, ErrEd448Context)
+	}
+}
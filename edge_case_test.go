@@ -0,0 +1,148 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+/*
+Tests of the identity and zero scalar policy documented next to
+ErrIdentityPoint: arithmetic accepts the identity, protocols reject it.
+*/
+
+// Every combination of Add and SecMul involving the identity or a zero scalar.
+func TestIdentityArithmetic(t *testing.T) {
+
+	O := e222Curve.IdPoint()
+	G := e222Curve.GenPoint()
+	r := e222Curve.Params().R
+	zero := big.NewInt(0)
+
+	if !O.IsIdentity() || G.IsIdentity() {
+		t.Fatal("IsIdentity does not tell the identity from the generator")
+	}
+	if err := O.Validate(); err != nil {
+		t.Fatalf("identity does not validate: %v", err)
+	}
+	for _, c := range []struct {
+		name      string
+		got, want *E222
+	}{
+		{"O + O", O.Add(O), O},
+		{"O + G", O.Add(G), G},
+		{"G + O", G.Add(O), G},
+		{"0·O", O.SecMul(zero), O},
+		{"0·G", G.SecMul(zero), O},
+		{"7·O", O.SecMul(big.NewInt(7)), O},
+		{"r·O", O.SecMul(r), O},
+		{"r·G", G.SecMul(r), O},
+		{"1·G", G.SecMul(big.NewInt(1)), G},
+		{"-O", O.Neg(), O},
+		{"G - G", G.Add(G.Neg()), O},
+		{"cofactor cleared O", O.ClearCofactor(), O},
+	} {
+		if !c.got.Equals(c.want) {
+			t.Fatalf("%s = (%v, %v), want (%v, %v)", c.name, c.got.X(), c.got.Y(), c.want.X(), c.want.Y())
+		}
+	}
+	if x := O.Neg().X(); x.Sign() != 0 {
+		t.Fatalf("-O has x = %v, want 0", x)
+	}
+}
+
+// Identity and small order public keys are refused by every protocol.
+func TestIdentityPublicKeysRejected(t *testing.T) {
+
+	O := e222Curve.IdPoint()
+	// (0, -1) has order 2
+	T := e222Curve.NewPointXY(*big.NewInt(0), *new(big.Int).Sub(e222Curve.Params().P, big.NewInt(1)))
+	if err := T.Validate(); err != nil {
+		t.Fatalf("(0, -1) does not validate: %v", err)
+	}
+	if !T.Add(T).IsIdentity() {
+		t.Fatal("(0, -1) does not have order 2")
+	}
+
+	for _, V := range []*E222{O, T} {
+		name := fmt.Sprintf("(%v, %v)", V.X(), V.Y())
+		if err := validate_public_key(V); !errors.Is(err, ErrIdentityPoint) {
+			t.Fatalf("%s: %v, want %v", name, err, ErrIdentityPoint)
+		}
+
+		// key import
+		if _, err := e222_from_bytes(e222_to_bytes(V)); !errors.Is(err, ErrIdentityPoint) {
+			t.Fatalf("%s imported: %v, want %v", name, err, ErrIdentityPoint)
+		}
+
+		// ECIES
+		if _, err := ecies_wrap(V, make([]byte, contentKeyLength)); !errors.Is(err, ErrIdentityPoint) {
+			t.Fatalf("encrypting to %s: %v, want %v", name, err, ErrIdentityPoint)
+		}
+		if _, err := encrypt_to_recipients([]*E222{V}, []byte("msg")); err == nil {
+			t.Fatalf("envelope for %s is made", name)
+		}
+
+		// verification: with an identity key any s = k, e pair would otherwise verify
+		msg := []byte("forged")
+		k := big.NewInt(12345)
+		e := e222_challenge(signatureVersion, "", e222Curve.GenPoint().SecMul(k).X(), &msg)
+		if verify_sig_e222(V, k, e, &msg) {
+			t.Fatalf("forged signature verifies under %s", name)
+		}
+	}
+
+	// key objects
+	key := &KeyObj{Owner: "nobody", PubKey: O}
+	if ok, err := VerifyKeyObjSelfSig(key); ok || !errors.Is(err, ErrIdentityPoint) {
+		t.Fatalf("key object with the identity: %v, %v, want %v", ok, err, ErrIdentityPoint)
+	}
+
+	// a zero challenge verifies independently of the key
+	msg := []byte("zero challenge")
+	G := e222Curve.GenPoint()
+	if verify_sig_e222(G, big.NewInt(5), big.NewInt(0), &msg) {
+		t.Fatal("E222 signature with a zero challenge verifies")
+	}
+
+	// secp256 Schnorr keys off the curve, including (0, 0)
+	curve := elliptic.P256()
+	bad := &ecdsa.PublicKey{Curve: curve, X: big.NewInt(0), Y: big.NewInt(0)}
+	if verify_sig_secp256(bad, big.NewInt(1), big.NewInt(1), &msg) {
+		t.Fatal("secp256 signature verifies under (0, 0)")
+	}
+	good := &ecdsa.PublicKey{Curve: curve, X: curve.Params().Gx, Y: curve.Params().Gy}
+	if verify_sig_secp256(good, big.NewInt(5), big.NewInt(0), &msg) {
+		t.Fatal("secp256 signature with a zero challenge verifies")
+	}
+}
+
+// Zero and order-multiple secret scalars are refused by every signer and decrypter.
+func TestZeroScalarsRejected(t *testing.T) {
+
+	msg := []byte("msg")
+	for _, v := range []*big.Int{big.NewInt(0), e222Curve.Params().R} {
+		if _, _, err := sign_with_key_e222(NewSecretScalar(v), "", &msg); !errors.Is(err, ErrZeroScalar) {
+			t.Fatalf("signing with %v: %v, want %v", v, err, ErrZeroScalar)
+		}
+		if _, err := sign_text_e222(NewSecretScalar(v), "", msg, true); !errors.Is(err, ErrZeroScalar) {
+			t.Fatalf("signing text with %v: %v, want %v", v, err, ErrZeroScalar)
+		}
+		if _, err := NewSigningContextFromScalar(NewSecretScalar(v)); !errors.Is(err, ErrZeroScalar) {
+			t.Fatalf("signing context for %v: %v, want %v", v, err, ErrZeroScalar)
+		}
+		if _, err := ecies_unwrap(v, make([]byte, stanzaLength)); !errors.Is(err, ErrZeroScalar) {
+			t.Fatalf("decrypting with %v: %v, want %v", v, err, ErrZeroScalar)
+		}
+	}
+	for _, d := range []*big.Int{big.NewInt(0), elliptic.P256().Params().N} {
+		if _, _, err := NewECDSAPrivateKey(d).Sign("", &msg); !errors.Is(err, ErrZeroScalar) {
+			t.Fatalf("ECDSA signing with %v: %v, want %v", d, err, ErrZeroScalar)
+		}
+	}
+}
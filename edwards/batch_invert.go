@@ -0,0 +1,41 @@
+package edwards
+
+import (
+	"math/big"
+)
+
+/*
+Returns the inverses mod p of all of xs with a single ModInverse, by
+Montgomery's trick: with prefix products cᵢ = x₀·…·xᵢ, one inversion of
+cₙ₋₁ yields every xᵢ⁻¹ = cᵢ₋₁·(x₀·…·xᵢ)⁻¹ at the cost of three
+multiplications per element. Elements that are 0 mod p are skipped and
+map to 0, as inv0 of RFC 9380. p must be prime, and xs is not modified.
+*/
+func BatchInvert(xs []*big.Int, p *big.Int) []*big.Int {
+	out := make([]*big.Int, len(xs))
+	prefix := make([]*big.Int, len(xs)) // product of the non-zero elements up to i
+	acc := big.NewInt(1)
+	for i, x := range xs {
+		out[i] = new(big.Int).Mod(x, p)
+		if out[i].Sign() != 0 {
+			acc = new(big.Int).Mul(acc, out[i])
+			acc.Mod(acc, p)
+		}
+		prefix[i] = acc
+	}
+	inv := new(big.Int).ModInverse(acc, p) // (x₀·…·xₙ₋₁)⁻¹
+	for i := len(xs) - 1; i >= 0; i-- {
+		if out[i].Sign() == 0 {
+			continue
+		}
+		prev := big.NewInt(1)
+		if i > 0 {
+			prev = prefix[i-1]
+		}
+		x := out[i]
+		out[i] = new(big.Int).Mul(inv, prev)
+		out[i].Mod(out[i], p)
+		inv.Mul(inv, x).Mod(inv, p)
+	}
+	return out
+}
@@ -0,0 +1,21 @@
+package edwards
+
+import (
+	"math/big"
+)
+
+/**
+ * E222 Elliptic Curve (Edward's Curve) of equation: (x²) + (y²) = 1 + d(x²)(y²)
+ * where d = 160102, over the field F(p) for p = 2²²²−117.
+ */
+var e222Curve *Curve
+
+func init() {
+	P := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 222), big.NewInt(117))
+	R, _ := new(big.Int).SetString("1684996666696914987166688442938726735569737456760058294185521417407", 10)
+	Gx, _ := new(big.Int).SetString("2705691079882681090389589001251962954446177367541711474502428610129", 10)
+	e222Curve = new_curve("E222", P, big.NewInt(1), big.NewInt(160102), R, big.NewInt(4), Gx, big.NewInt(28))
+}
+
+// The E222 curve.
+func E222() *Curve { return e222Curve }
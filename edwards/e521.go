@@ -0,0 +1,26 @@
+package edwards
+
+import (
+	"math/big"
+)
+
+/**
+ * E521 Elliptic Curve (Edward's Curve) of equation: (x²) + (y²) = 1 + d(x²)(y²)
+ * where d = −376014, over the Mersenne prime field F(p) for p = 2⁵²¹−1,
+ * with the generator of y = 12 and even x. Shares its arithmetic with E222,
+ * except that SecMul runs over the fixed-limb field of e521_field.go.
+ */
+var e521Curve *Curve
+
+func init() {
+	P := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 521), big.NewInt(1))
+	R, _ := new(big.Int).SetString("337554763258501705789107630418782636071904961214051226618635150085779108655765", 10)
+	R.Sub(new(big.Int).Lsh(big.NewInt(1), 519), R) // r = 2⁵¹⁹ − 3375…5765
+	Gx, _ := new(big.Int).SetString("752cb45c48648b189df90cb2296b2878a3bfd9f42fc6c818ec8bf3c9c0c6203913f6ecc5ccc72434b1ae949d568fc99c6059d0fb13364838aa302a940a2f19ba6c", 16)
+	e521Curve = new_curve("E521", P, big.NewInt(1), big.NewInt(-376014), R, big.NewInt(4), Gx, big.NewInt(12))
+	e521D = fe521_from_int(e521Curve.d)
+	e521Curve.ladder = e521_ladder
+}
+
+// The E521 curve.
+func E521() *Curve { return e521Curve }
@@ -0,0 +1,233 @@
+package edwards
+
+import (
+	"math/big"
+	"math/bits"
+)
+
+/*
+Elements of the E521 field F(p), p = 2⁵²¹ − 1, as nine little endian
+64-bit limbs, in place of big.Int, which allocates for every operation
+and whose running time depends on the values it holds. Since 2⁵²¹ ≡ 1,
+reduction folds the bits above 2⁵²¹ back onto the bottom: no division,
+no data dependent branches. Elements are kept below 2⁵²¹, so p itself
+may stand for 0 until canonical reduces it. Loops run over all
+limbs and choices are made with masks.
+*/
+type fe521 [9]uint64
+
+const fe521TopBits = 521 - 8*64 // bits used in the top limb
+
+// 2p = 2⁵²² − 2, added before subtracting so that differences stay non-negative.
+var fe521TwoP = fe521{^uint64(1), ^uint64(0), ^uint64(0), ^uint64(0), ^uint64(0), ^uint64(0), ^uint64(0), ^uint64(0), 1<<(fe521TopBits+1) - 1}
+
+// The element of v, which must lie in [0, p−1].
+func fe521_from_int(v *big.Int) fe521 {
+	var out fe521
+	b := toFixedBytes(v, 72)
+	for i := range out {
+		for j := 0; j < 8; j++ {
+			out[i] |= uint64(b[len(b)-1-8*i-j]) << uint(8*j)
+		}
+	}
+	return out
+}
+
+// The integer in [0, p−1] held by a.
+func (a *fe521) to_int() *big.Int {
+	c := a.canonical()
+	b := make([]byte, 72)
+	for i, w := range c {
+		for j := 0; j < 8; j++ {
+			b[len(b)-1-8*i-j] = byte(w >> uint(8*j))
+		}
+	}
+	return new(big.Int).SetBytes(b)
+}
+
+/*
+Folds the bits of the top limb above bit 521 onto the bottom limb. The
+top limb may hold up to 64 bits, as sums and differences leave it; two
+folds bring any such value below 2⁵²¹.
+*/
+func (a *fe521) fold() {
+	for round := 0; round < 2; round++ {
+		carry := a[8] >> fe521TopBits
+		a[8] &= 1<<fe521TopBits - 1
+		for i := range a {
+			a[i], carry = bits.Add64(a[i], carry, 0)
+		}
+	}
+}
+
+// a + b
+func fe521_add(a, b *fe521) fe521 {
+	var out fe521
+	var carry uint64
+	for i := range out {
+		out[i], carry = bits.Add64(a[i], b[i], carry)
+	}
+	out.fold()
+	return out
+}
+
+// a − b, computed as a + 2p − b to stay non-negative.
+func fe521_sub(a, b *fe521) fe521 {
+	var out fe521
+	var carry, borrow uint64
+	for i := range out {
+		out[i], carry = bits.Add64(a[i], fe521TwoP[i], carry)
+		out[i], borrow = bits.Sub64(out[i], b[i], borrow)
+	}
+	out.fold()
+	return out
+}
+
+/*
+a · b by schoolbook multiplication into 18 limbs, then reduced by adding
+the product's bits above 2⁵²¹ to its bits below, since 2⁵²¹ ≡ 1 (mod p).
+*/
+func fe521_mul(a, b *fe521) fe521 {
+	var t [18]uint64
+	for i := 0; i < 9; i++ {
+		var carry uint64
+		for j := 0; j < 9; j++ {
+			hi, lo := bits.Mul64(a[i], b[j])
+			lo, c1 := bits.Add64(lo, t[i+j], 0)
+			lo, c2 := bits.Add64(lo, carry, 0)
+			t[i+j], carry = lo, hi+c1+c2
+		}
+		t[i+9] = carry
+	}
+	var out fe521
+	var carry uint64
+	for i := range out {
+		high := t[8+i]>>fe521TopBits | t[9+i]<<(64-fe521TopBits)
+		low := t[i]
+		if i == 8 {
+			low &= 1<<fe521TopBits - 1
+		}
+		out[i], carry = bits.Add64(low, high, carry)
+	}
+	out.fold()
+	return out
+}
+
+// a^(p−2) = a⁻¹ by Fermat, 0 for a = 0. The exponent is public.
+func fe521_inv(a *fe521) fe521 {
+	// p − 2 = 2⁵²¹ − 3 is 519 ones, a zero and a one
+	out := *a
+	for i := 0; i < 518; i++ {
+		out = fe521_mul(&out, &out)
+		out = fe521_mul(&out, a)
+	}
+	out = fe521_mul(&out, &out)
+	out = fe521_mul(&out, &out)
+	return fe521_mul(&out, a)
+}
+
+// The representative of a in [0, p−1]: a − p if a + 1 reaches 2⁵²¹, else a.
+func (a *fe521) canonical() fe521 {
+	var t fe521
+	carry := uint64(1)
+	for i := range t {
+		t[i], carry = bits.Add64(a[i], 0, carry)
+	}
+	over := t[8] >> fe521TopBits // 1 iff a ≥ p
+	t[8] &= 1<<fe521TopBits - 1
+	mask := -over
+	var out fe521
+	for i := range out {
+		out[i] = a[i] ^ (mask & (a[i] ^ t[i]))
+	}
+	return out
+}
+
+// Swaps a and b if swap is 1, leaves them if it is 0.
+func fe521_cswap(a, b *fe521, swap uint64) {
+	mask := -(swap & 1)
+	for i := range a {
+		t := mask & (a[i] ^ b[i])
+		a[i] ^= t
+		b[i] ^= t
+	}
+}
+
+// An E521 point in extended coordinates over fe521, see extendedPoint.
+type e521Extended struct {
+	X, Y, Z, T fe521
+}
+
+var e521D fe521 // d of E521, set by E521's init
+
+// P + Q by add-2008-hwcd with a = 1.
+func e521_add(P, Q *e521Extended) e521Extended {
+	A := fe521_mul(&P.X, &Q.X)
+	B := fe521_mul(&P.Y, &Q.Y)
+	C := fe521_mul(&P.T, &Q.T)
+	C = fe521_mul(&C, &e521D)
+	D := fe521_mul(&P.Z, &Q.Z)
+	s1 := fe521_add(&P.X, &P.Y)
+	s2 := fe521_add(&Q.X, &Q.Y)
+	E := fe521_mul(&s1, &s2)
+	E = fe521_sub(&E, &A)
+	E = fe521_sub(&E, &B)
+	F := fe521_sub(&D, &C)
+	G := fe521_add(&D, &C)
+	H := fe521_sub(&B, &A)
+	return e521Extended{fe521_mul(&E, &F), fe521_mul(&G, &H), fe521_mul(&F, &G), fe521_mul(&E, &H)}
+}
+
+// 2P by dbl-2008-hwcd with a = 1.
+func e521_double(P *e521Extended) e521Extended {
+	A := fe521_mul(&P.X, &P.X)
+	B := fe521_mul(&P.Y, &P.Y)
+	C := fe521_mul(&P.Z, &P.Z)
+	C = fe521_add(&C, &C)
+	E := fe521_add(&P.X, &P.Y)
+	E = fe521_mul(&E, &E)
+	E = fe521_sub(&E, &A)
+	E = fe521_sub(&E, &B)
+	G := fe521_add(&A, &B)
+	F := fe521_sub(&G, &C)
+	H := fe521_sub(&A, &B)
+	return e521Extended{fe521_mul(&E, &F), fe521_mul(&G, &H), fe521_mul(&F, &G), fe521_mul(&E, &H)}
+}
+
+/*
+k × P on E521 by the Montgomery ladder of SecMul over fe521: the same
+fixed number of steps, one addition and one doubling each, operands chosen
+by masked swaps, and one inversion at the end. k must lie in [0, n−1].
+*/
+func e521_ladder(P *Point, k *big.Int) *Point {
+	c := P.curve
+	x := fe521_from_int(new(big.Int).Mod(&P.x, c.p))
+	y := fe521_from_int(new(big.Int).Mod(&P.y, c.p))
+	r0 := e521Extended{Y: fe521{1}, Z: fe521{1}}
+	r1 := e521Extended{X: x, Y: y, Z: fe521{1}, T: fe521_mul(&x, &y)}
+	swap := uint64(0)
+	for i := c.n.BitLen() - 1; i >= 0; i-- {
+		bit := uint64(k.Bit(i))
+		swap ^= bit
+		fe521_cswap(&r0.X, &r1.X, swap)
+		fe521_cswap(&r0.Y, &r1.Y, swap)
+		fe521_cswap(&r0.Z, &r1.Z, swap)
+		fe521_cswap(&r0.T, &r1.T, swap)
+		swap = bit
+		r1 = e521_add(&r0, &r1)
+		r0 = e521_double(&r0)
+	}
+	fe521_cswap(&r0.X, &r1.X, swap)
+	fe521_cswap(&r0.Y, &r1.Y, swap)
+	fe521_cswap(&r0.Z, &r1.Z, swap)
+	fe521_cswap(&r0.T, &r1.T, swap)
+
+	z := r0.Z.canonical()
+	if z == (fe521{}) {
+		return c.invalid_point()
+	}
+	z_inv := fe521_inv(&r0.Z)
+	ax := fe521_mul(&r0.X, &z_inv)
+	ay := fe521_mul(&r0.Y, &z_inv)
+	return c.NewPointXY(*ax.to_int(), *ay.to_int())
+}
@@ -0,0 +1,26 @@
+package edwards
+
+import (
+	"math/big"
+)
+
+/**
+ * Ed448-Goldilocks (RFC 7748, RFC 8032 Section 5.2), the Edwards curve
+ * x² + y² = 1 + d(x²)(y²) with d = −39081 over F(p) for the Goldilocks
+ * prime p = 2⁴⁴⁸ − 2²²⁴ − 1. Since p ≡ 3 (mod 4), x is recovered from y
+ * with the same square root as E222 and E521.
+ */
+var ed448Curve *Curve
+
+func init() {
+	P := new(big.Int).Lsh(big.NewInt(1), 448)
+	P.Sub(P, new(big.Int).Lsh(big.NewInt(1), 224)).Sub(P, big.NewInt(1))
+	R, _ := new(big.Int).SetString("13818066809895115352007386748515426880336692474882178609894547503885", 10)
+	R.Sub(new(big.Int).Lsh(big.NewInt(1), 446), R) // r = 2⁴⁴⁶ − 1381…3885
+	Gx, _ := new(big.Int).SetString("224580040295924300187604334099896036246789641632564134246125461686950415467406032909029192869357953282578032075146446173674602635247710", 10)
+	Gy, _ := new(big.Int).SetString("298819210078481492676017930443930673437544040154080242095928241372331506189835876003536878655418784733982303233503462500531545062832660", 10)
+	ed448Curve = new_curve("Ed448", P, big.NewInt(1), big.NewInt(-39081), R, big.NewInt(4), Gx, Gy)
+}
+
+// The Ed448-Goldilocks curve.
+func Ed448() *Curve { return ed448Curve }
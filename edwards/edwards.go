@@ -0,0 +1,583 @@
+/*
+Package edwards implements the arithmetic of Edwards curves: E222 and E521,
+on which the Schnorr signatures of package sig are defined, and the
+Ed448-Goldilocks and edwards25519 curves of RFC 8032. Points are immutable
+values of a Curve; they encode compressed with Bytes and as in RFC 8032
+with EncodeRFC8032, and hash to the curve with Curve.HashToPoint.
+*/
+package edwards
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+/*
+A twisted Edwards curve ax² + y² = 1 + dx²y² over the prime field F(p),
+together with a generator G of its subgroup of prime order r. The curve
+has n = h·r points for the cofactor h. E222, E521 and Ed448 have a = 1,
+edwards25519 has a = −1; all share the point arithmetic below, so a fix
+to one is a fix to all.
+
+The square roots of solve_for_y require p ≡ 3 (mod 4) or p ≡ 5 (mod 8)
+and ClearCofactor requires h to be a power of two; new_curve
+checks both.
+*/
+type Curve struct {
+	name     string
+	p        *big.Int // prime defining the field F(p)
+	a        *big.Int // curve constant, 1 for untwisted curves
+	d        *big.Int // curve constant, a non-square mod p
+	r        *big.Int // order of the prime subgroup generated by G
+	n        *big.Int // number of points on the curve, cofactor · r
+	cofactor *big.Int
+	gx, gy   *big.Int // generator point
+
+	baseTable [][1 << baseWindowBits]extendedPoint // multiples of G for BaseMul
+	ladder    func(P *Point, k *big.Int) *Point    // fixed-limb SecMul, if the field has one
+}
+
+// Domain parameters of an Edwards curve. Values returned by Params are
+// copies, so modifying them has no effect on the curve.
+type Parameters struct {
+	P        *big.Int // prime defining the field F(p)
+	A        *big.Int // curve constant a
+	D        *big.Int // curve constant d
+	R        *big.Int // order of the prime subgroup generated by G
+	N        *big.Int // number of points on Curve -> n := cofactor * (R)
+	Cofactor *big.Int
+	Gx       *big.Int // X coordinate of generator point
+	Gy       *big.Int // Y coordinate of generator point
+}
+
+var (
+	ErrCurveParameters = errors.New("inconsistent curve parameters")
+	ErrInvalidPoint    = errors.New("invalid Edwards curve point")
+	ErrSmallSubgroup   = errors.New("point is not in the prime order subgroup")
+)
+
+// Builds a curve from its constants, panicking if they fail SelfTest.
+func new_curve(name string, p, a, d, r, cofactor, gx, gy *big.Int) *Curve {
+	c := &Curve{
+		name:     name,
+		p:        p,
+		a:        a.Mod(a, p),
+		d:        d.Mod(d, p),
+		r:        r,
+		n:        new(big.Int).Mul(r, cofactor),
+		cofactor: cofactor,
+		gx:       gx,
+		gy:       gy,
+	}
+	if err := c.SelfTest(); err != nil {
+		panic(err)
+	}
+	c.build_base_table()
+	return c
+}
+
+/*
+Checks the hardcoded constants of the curve against each other, so that a
+transcription error fails loudly at startup instead of silently producing
+points off the curve or outside the subgroup: p and r are prime (a mistyped
+prime almost never is), p ≡ 3 (mod 4) or p ≡ 5 (mod 8) and the cofactor is
+a power of two as the arithmetic requires, n = h·r lies within the Hasse
+bound |n − (p + 1)| ≤ 2√p, a is a square and d a non-square so that Add is
+complete, and G is a point of the curve other than 𝒪 with r·G = 𝒪.
+*/
+func (c *Curve) SelfTest() error {
+	fail := func(msg string) error { return fmt.Errorf("%w: %s: %s", ErrCurveParameters, c.name, msg) }
+	if !c.p.ProbablyPrime(20) {
+		return fail("p is not prime")
+	}
+	if m := new(big.Int).And(c.p, big.NewInt(7)).Int64(); m != 3 && m != 7 && m != 5 {
+		return fail("p is neither 3 mod 4 nor 5 mod 8")
+	}
+	if !c.r.ProbablyPrime(20) {
+		return fail("r is not prime")
+	}
+	if c.cofactor.Sign() <= 0 || new(big.Int).And(c.cofactor, new(big.Int).Sub(c.cofactor, big.NewInt(1))).Sign() != 0 {
+		return fail("cofactor is not a power of two")
+	}
+	// (n − p − 1)² ≤ 4p
+	t := new(big.Int).Sub(c.n, new(big.Int).Add(c.p, big.NewInt(1)))
+	if t.Mul(t, t).Cmp(new(big.Int).Lsh(c.p, 2)) > 0 {
+		return fail("n = h·r is outside the Hasse bound")
+	}
+	if !c.is_square(c.a) || c.is_square(c.d) {
+		return fail("a is not a square or d is not a non-square")
+	}
+	G := c.GenPoint()
+	if !G.IsOnCurve() {
+		return fail("generator point does not satisfy the curve equation")
+	}
+	if G.IsIdentity() || !G.SecMul(c.r).IsIdentity() {
+		return fail("generator point does not have order r")
+	}
+	return nil
+}
+
+// Name of the curve, e.g. "E222".
+func (c *Curve) Name() string { return c.name }
+
+// The order r of the prime subgroup generated by G, as a copy.
+func (c *Curve) Order() *big.Int { return new(big.Int).Set(c.r) }
+
+// Returns a copy of the domain parameters.
+func (c *Curve) Params() Parameters {
+	return Parameters{
+		P:        new(big.Int).Set(c.p),
+		A:        new(big.Int).Set(c.a),
+		D:        new(big.Int).Set(c.d),
+		R:        new(big.Int).Set(c.r),
+		N:        new(big.Int).Set(c.n),
+		Cofactor: new(big.Int).Set(c.cofactor),
+		Gx:       new(big.Int).Set(c.gx),
+		Gy:       new(big.Int).Set(c.gy),
+	}
+}
+
+/*
+The point (x, y) of the curve, which is not checked to satisfy the curve
+equation. The coordinates are copied: a big.Int passed by value still
+shares its words with the caller's, who may go on to modify them. A
+coordinate outside [0, p−1] gives an invalid point, so that x and x + p
+are not two encodings of the same point.
+*/
+func (c *Curve) NewPointXY(x, y big.Int) *Point {
+	if x.Sign() < 0 || x.Cmp(c.p) >= 0 || y.Sign() < 0 || y.Cmp(c.p) >= 0 {
+		return c.invalid_point()
+	}
+	return &Point{x: *new(big.Int).Set(&x), y: *new(big.Int).Set(&y), curve: c}
+}
+
+/*
+Solves for the y whose least significant bit is parity (0 or 1). The two
+solutions y and p − y give the points P and T − P, where T = (0, −1) has
+order 2; they are not negatives of each other, since negation on Edwards
+curves flips x. The result is invalid if no such y exists.
+*/
+func (c *Curve) NewPointX(x big.Int, parity uint) *Point {
+	y := c.solve_for_y(&x, parity)
+	if y == nil {
+		return c.invalid_point()
+	}
+	return c.NewPointXY(x, *y)
+}
+
+// Generator point for the curve
+func (c *Curve) GenPoint() *Point {
+	return c.NewPointXY(*new(big.Int).Set(c.gx), *new(big.Int).Set(c.gy))
+}
+
+// The identity point (0, 1) of the curve, the neutral element of Add.
+func (c *Curve) IdPoint() *Point {
+	return c.NewPointXY(*big.NewInt(0), *big.NewInt(1))
+}
+
+/*
+Samples a uniformly random point of the prime order subgroup other than
+the identity: a random x and parity of y are drawn from rnd until the
+curve has such a point, which is then multiplied by the cofactor. Each
+point of the subgroup is the image of h such pairs, so the result is
+uniform. Nobody knows its discrete logarithm to G, which suits blinding
+factors and Pedersen style generators; use HashToPoint where others must
+be able to recompute the point.
+*/
+func (c *Curve) RandomPoint(rnd io.Reader) (*Point, error) {
+	twoP := new(big.Int).Lsh(c.p, 1)
+	for {
+		v, err := rand.Int(rnd, twoP) // x = v mod p, parity = v div p
+		if err != nil {
+			return nil, err
+		}
+		parity := uint(0)
+		if v.Cmp(c.p) >= 0 {
+			v.Sub(v, c.p)
+			parity = 1
+		}
+		P := c.NewPointX(*v, parity)
+		if P.invalid {
+			continue
+		}
+		if P = P.ClearCofactor(); !P.IsIdentity() {
+			return P, nil
+		}
+	}
+}
+
+// A point marking the result of arithmetic on invalid input.
+func (c *Curve) invalid_point() *Point {
+	p := c.IdPoint()
+	p.invalid = true
+	return p
+}
+
+// solves curve equation a𝑥² + 𝑦² = 1 + 𝑑𝑥²𝑦² for the y value of the given parity
+func (c *Curve) solve_for_y(x *big.Int, parity uint) *big.Int {
+	x_sq := new(big.Int).Mul(x, x)
+	// y² = (1 − ax²) / (1 − dx²)
+	num := new(big.Int).Sub(big.NewInt(1), new(big.Int).Mul(c.a, x_sq))
+	denom := new(big.Int).Sub(big.NewInt(1), new(big.Int).Mul(c.d, x_sq))
+	return c.sqrt_ratio(num, denom, parity)
+}
+
+// solves curve equation a𝑥² + 𝑦² = 1 + 𝑑𝑥²𝑦² for the x value of the given parity, as point decoding does
+func (c *Curve) solve_for_x(y *big.Int, parity uint) *big.Int {
+	y_sq := new(big.Int).Mul(y, y)
+	// x² = (y² − 1) / (dy² − a)
+	num := new(big.Int).Sub(y_sq, big.NewInt(1))
+	denom := new(big.Int).Sub(new(big.Int).Mul(c.d, y_sq), c.a)
+	return c.sqrt_ratio(num, denom, parity)
+}
+
+// A square root of num / denom mod p of the given parity, nil if there is none.
+func (c *Curve) sqrt_ratio(num, denom *big.Int, parity uint) *big.Int {
+	num = new(big.Int).Mod(num, c.p)
+	denom = new(big.Int).Mod(denom, c.p)
+	denom = denom.ModInverse(denom, c.p)
+	if denom == nil {
+		return nil
+	}
+	return c.sqrt(num.Mul(num, denom), parity)
+}
+
+/*
+ * Compute a square root of v mod p with a specified
+ * the least significant bit, if such a root exists.
+ * Provided by Dr. Paulo Barretto.
+ * @param v   the radicand.
+ * parity is desired least significant bit (0 or 1).
+ * return a square root r of v mod p with r mod 2 = parity
+ * if such a root exists, otherwise nil. The root 0 of v ≡ 0 is returned
+ * for either parity.
+ * For p ≡ 5 (mod 8) the candidate v^((p+3)/8) is corrected by a square
+ * root of −1 when its square is −v, as in RFC 8032 Section 5.1.3.
+ */
+func (c *Curve) sqrt(v *big.Int, parity uint) *big.Int {
+	P := c.p
+	v = new(big.Int).Mod(v, P)
+	if v.Sign() == 0 {
+		return big.NewInt(0)
+	}
+	var r *big.Int
+	if P.Bit(1) == 1 {
+		r = new(big.Int).Exp(v, new(big.Int).Add(new(big.Int).Rsh(P, 2), big.NewInt(1)), P)
+	} else {
+		r = new(big.Int).Exp(v, new(big.Int).Add(new(big.Int).Rsh(P, 3), big.NewInt(1)), P)
+		if neg := new(big.Int).Mul(r, r); neg.Add(neg, v).Mod(neg, P).Sign() == 0 {
+			i := new(big.Int).Exp(big.NewInt(2), new(big.Int).Rsh(P, 2), P) // √−1
+			r.Mul(r, i).Mod(r, P)
+		}
+	}
+	// v is a square iff r² ≡ v, whichever root is picked below
+	bi := new(big.Int).Sub(new(big.Int).Mul(r, r), v)
+	if bi.Mod(bi, P).Sign() != 0 {
+		return nil
+	}
+	if r.Bit(0) != parity {
+		r.Sub(P, r) // correct the parity
+	}
+	return r
+}
+
+/*
+A point of a Curve. Points carry their curve, and arithmetic
+between points of different curves gives an invalid point.
+
+Points are immutable: no method modifies its receiver or arguments,
+results are always fresh points, and coordinates go in and out as
+copies, so a point never shares a big.Int with its caller.
+*/
+type Point struct {
+	x     big.Int // X coordinate
+	y     big.Int // Y cooridinate
+	curve *Curve
+	// set when the point came out of arithmetic on invalid input,
+	// e.g. a non-invertible denominator in Add. Invalid points stay invalid.
+	invalid bool
+}
+
+// The curve of the point.
+func (e *Point) Curve() *Curve { return e.curve }
+
+// A copy of the x coordinate.
+func (e *Point) X() *big.Int { return new(big.Int).Set(&e.x) }
+
+// A copy of the y coordinate.
+func (e *Point) Y() *big.Int { return new(big.Int).Set(&e.y) }
+
+// order of the prime subgroup generated by G
+func (e *Point) getR() big.Int { return *new(big.Int).Set(e.curve.r) }
+
+// prime defining the field F(p)
+func (e *Point) getP() big.Int { return *new(big.Int).Set(e.curve.p) }
+
+/*
+Returns ErrInvalidPoint if p is nil, the result of invalid arithmetic,
+built from a coordinate outside [0, p−1] or does not satisfy the curve
+equation. Callers check externally supplied
+points with this before doing any arithmetic with them.
+*/
+func (p *Point) Validate() error {
+	if p == nil || !p.IsOnCurve() {
+		return ErrInvalidPoint
+	}
+	return nil
+}
+
+/*
+True iff p is a valid point of the subgroup of prime order r generated by
+G, i.e. r × p is the identity. Points outside it have a component of small
+order dividing the cofactor, which an attacker can use to learn a secret
+scalar mod the cofactor or to make two keys give the same results.
+*/
+func (p *Point) InPrimeSubgroup() bool {
+	return p.Validate() == nil && p.SecMul(p.curve.r).IsIdentity()
+}
+
+// True iff p has no small order component, the same check as InPrimeSubgroup.
+func (p *Point) IsTorsionFree() bool { return p.InPrimeSubgroup() }
+
+// True iff p is the identity (0, 1).
+func (p *Point) IsIdentity() bool {
+	return p.Equals(p.curve.IdPoint())
+}
+
+/*
+The negative −P of the point, defined as the following:
+if P = (X, Y), −P = (−X mod p, Y).
+The receiver is left unchanged.
+*/
+func (e *Point) Neg() *Point {
+	x := new(big.Int).Neg(&e.x)
+	return e.curve.NewPointXY(*x.Mod(x, e.curve.p), e.y)
+}
+
+// Checks two points for equality by comparing their coordinates.
+// Invalid points and points of different curves are not equal.
+func (A *Point) Equals(B *Point) bool {
+	return B != nil && !A.invalid && !B.invalid && A.curve == B.curve && A.x.Cmp(&B.x) == 0 && A.y.Cmp(&B.y) == 0
+}
+
+/*
+Adds two points and returns another point of the same curve.
+Point addition operation is defined as:
+
+	(x₁, y₁) + (x₂, y₂)  = (x₁y₂ + y₁x₂) / (1 + dx₁x₂y₁y₂), (y₁y₂ − ax₁x₂) / (1 − dx₁x₂y₁y₂)
+
+where "/" is defined to be multiplication by modular inverse.
+
+The formula is complete: it needs no special case for the identity, for
+P + P or for P + (−P). If a is a square and d is not, as for every curve
+in this package, the denominators never vanish for points on the curve
+(Bernstein and Lange, Faster addition and doubling on elliptic curves,
+Theorem 3.3): were dx₁x₂y₁y₂ = ±1, the curve equation would make d or
+d·a⁻¹ a square. For other input, a nil B, or points of different curves
+the result is an invalid point rather than a nil dereference or a panic.
+*/
+func (A *Point) Add(B *Point) *Point {
+
+	c := A.curve
+	if B == nil || A.invalid || B.invalid || B.curve != c {
+		return c.invalid_point()
+	}
+
+	x1, y1, x2, y2 := &A.x, &A.y, &B.x, &B.y // read only
+
+	xNum := new(big.Int).Add(new(big.Int).Mul(x1, y2), new(big.Int).Mul(y1, x2))
+	xNum.Mod(xNum, c.p)
+
+	mul := new(big.Int).Mul(c.d, x1) //x1 * x2 *  y1 * y2
+	mul = new(big.Int).Mul(mul, x2)
+	mul = new(big.Int).Mul(mul, y1)
+	mul = new(big.Int).Mul(mul, y2)
+
+	xDenom := new(big.Int).Add(big.NewInt(1), mul)
+	xDenom.Mod(xDenom, c.p)
+	xDenom = new(big.Int).ModInverse(xDenom, c.p)
+	if xDenom == nil {
+		return c.invalid_point()
+	}
+
+	newX := new(big.Int).Mul(xNum, xDenom)
+	newX.Mod(newX, c.p)
+
+	yNum := new(big.Int).Sub(new(big.Int).Mul(y1, y2), new(big.Int).Mul(c.a, new(big.Int).Mul(x1, x2)))
+	yNum.Mod(yNum, c.p)
+
+	yDenom := new(big.Int).Sub(big.NewInt(1), mul)
+	yDenom.Mod(yDenom, c.p)
+	yDenom = new(big.Int).ModInverse(yDenom, c.p)
+	if yDenom == nil {
+		return c.invalid_point()
+	}
+
+	newY := new(big.Int).Mul(yNum, yDenom)
+	newY.Mod(newY, c.p)
+
+	return c.NewPointXY(*newX, *newY)
+}
+
+/*
+Doubles the point with the dedicated doubling formula, which needs fewer
+multiplications than Add(A, A) since terms repeat:
+
+	2(x, y) = 2xy / (ax² + y²), (y² − ax²) / (2 − ax² − y²)
+
+using ax² + y² = 1 + dx²y² on the curve. The scalar multiplications double
+with the same formula in extended coordinates, see extended_double.
+*/
+func (A *Point) Double() *Point {
+	c := A.curve
+	if A.invalid {
+		return c.invalid_point()
+	}
+	p := c.p
+	ax2 := new(big.Int).Mul(&A.x, &A.x)
+	ax2.Mul(ax2, c.a).Mod(ax2, p)
+	y2 := new(big.Int).Mul(&A.y, &A.y)
+	y2.Mod(y2, p)
+
+	xDenom := new(big.Int).Add(ax2, y2)
+	xDenom = xDenom.ModInverse(xDenom.Mod(xDenom, p), p)
+	yDenom := new(big.Int).Sub(big.NewInt(2), ax2)
+	yDenom.Sub(yDenom, y2)
+	yDenom = yDenom.ModInverse(yDenom.Mod(yDenom, p), p)
+	if xDenom == nil || yDenom == nil {
+		return c.invalid_point()
+	}
+
+	newX := new(big.Int).Mul(&A.x, &A.y)
+	newX.Lsh(newX, 1).Mul(newX, xDenom).Mod(newX, p)
+	newY := new(big.Int).Sub(y2, ax2)
+	newY.Mul(newY, yDenom).Mod(newY, p)
+	return c.NewPointXY(*newX, *newY)
+}
+
+/*
+EC Multiplication algorithm using the Montgomery Ladder approach to mitigate
+power consumption side channel attacks. Mostly constructed around:
+
+(pg 4.)	https://eprint.iacr.org/2014/140.pdf
+
+S is a  scalar value to multiply by. S is a private key and should be kept secret.
+Returns the point which is result of multiplication. S is reduced mod n,
+which leaves the result unchanged since n × P is the identity for every
+point, and the ladder then always runs for the bit length of n, so the
+number of steps does not reveal the length of S. Each step adds and
+doubles whatever the bit of S, choosing the operands by a masked
+conditional swap instead of a branch. The ladder runs in extended
+coordinates and inverts only once, converting the result back to affine
+coordinates.
+
+Security Remark: big.Int arithmetic is not constant time, so this removes
+the branches and the length leak but does not make the whole
+multiplication constant time.
+*/
+func (P *Point) SecMul(S *big.Int) *Point {
+	c := P.curve
+	if P.invalid {
+		return c.invalid_point()
+	}
+	k := new(big.Int).Mod(S, c.n)
+	defer wipe_int(k)
+	if c.ladder != nil {
+		return c.ladder(P, k)
+	}
+	r0 := c.extended_identity()
+	r1 := c.to_extended(P)
+	swap := uint(0)
+	for i := c.n.BitLen() - 1; i >= 0; i-- {
+		bit := k.Bit(i)
+		swap ^= bit
+		c.extended_cswap(&r0, &r1, swap)
+		swap = bit
+		r1 = c.extended_add(r0, r1)
+		r0 = c.extended_double(r0)
+	}
+	c.extended_cswap(&r0, &r1, swap)
+	return c.from_extended(r0) // r0 = P * s
+}
+
+/*
+Multiplies the point by the cofactor, a power of two, by repeated
+doubling. The result lies in the prime order subgroup, so any small order
+component of an externally supplied point is discarded.
+*/
+func (p *Point) ClearCofactor() *Point {
+	c := p.curve
+	if p.invalid {
+		return c.invalid_point()
+	}
+	E := c.to_extended(p)
+	for i := uint(0); i < c.cofactor.TrailingZeroBits(); i++ {
+		E = c.extended_double(E)
+	}
+	return c.from_extended(E)
+}
+
+// Solves curve eq with p = (x, y)
+// a𝑥² + 𝑦² = 1 + 𝑑𝑥²𝑦² mod p
+func (p *Point) IsOnCurve() bool {
+	if p.invalid {
+		return false
+	}
+	P := p.curve.p
+	x_sq := new(big.Int).Exp(&p.x, big.NewInt(2), P)
+	y_sq := new(big.Int).Exp(&p.y, big.NewInt(2), P)
+	sum := new(big.Int).Add(new(big.Int).Mul(p.curve.a, x_sq), y_sq)
+	sum.Mod(sum, P)
+	prod := new(big.Int).Mul(x_sq, y_sq)
+	rhs := new(big.Int).Add(big.NewInt(1), new(big.Int).Mul(p.curve.d, prod))
+	rhs.Mod(rhs, P)
+	return sum.Cmp(rhs) == 0
+}
+
+/*
+Length in bytes of the encodings of points by Bytes and EncodeRFC8032 and
+of scalars by EncodeScalar: the bits of a coordinate and one more for the
+sign bit of the other, 28 for E222, 57 for Ed448 and 66 for E521.
+*/
+func (c *Curve) EncodedLen() int { return (c.p.BitLen() + 8) / 8 }
+
+/*
+Encodes a point as in RFC 8032 Sections 5.1.2 and 5.2.2: y as
+EncodedLen little endian bytes, with the least significant bit of x in
+the top bit of the last byte.
+*/
+func (c *Curve) encode_point(P *Point) []byte {
+	out := toLittleEndian(&P.y, c.EncodedLen())
+	out[len(out)-1] |= byte(P.x.Bit(0)) << 7
+	return out
+}
+
+/*
+Decodes a point as in RFC 8032 Sections 5.1.3 and 5.2.3, rejecting
+y ≥ p, which covers stray bits next to the sign bit, points off the
+curve and the encoding of x = 0 with the sign bit set.
+*/
+func (c *Curve) decode_point(b []byte) (*Point, error) {
+	if len(b) != c.EncodedLen() {
+		return nil, ErrInvalidPoint
+	}
+	sign := uint(b[len(b)-1] >> 7)
+	enc := append([]byte(nil), b...)
+	enc[len(enc)-1] &= 0x7f
+	y := fromLittleEndian(enc)
+	if y.Cmp(c.p) >= 0 {
+		return nil, ErrInvalidPoint
+	}
+	x := c.solve_for_x(y, sign)
+	if x == nil || (x.Sign() == 0 && sign == 1) {
+		return nil, ErrInvalidPoint
+	}
+	P := c.NewPointXY(*x, *y)
+	if !P.IsOnCurve() {
+		return nil, ErrInvalidPoint
+	}
+	return P, nil
+}
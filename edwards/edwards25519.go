@@ -0,0 +1,27 @@
+package edwards
+
+import (
+	"math/big"
+)
+
+/**
+ * edwards25519 (RFC 7748, RFC 8032 Section 5.1), the twisted Edwards curve
+ * −(x²) + (y²) = 1 + d(x²)(y²) with d = −121665/121666 over F(p) for
+ * p = 2²⁵⁵ − 19, birationally equivalent to Curve25519. Since p ≡ 5 (mod 8),
+ * x is recovered from y with the square root of RFC 8032 Section 5.1.3.
+ */
+var ed25519Curve *Curve
+
+func init() {
+	P := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+	D := new(big.Int).ModInverse(big.NewInt(121666), P)
+	D.Mul(D, big.NewInt(-121665))
+	R, _ := new(big.Int).SetString("27742317777372353535851937790883648493", 10)
+	R.Add(new(big.Int).Lsh(big.NewInt(1), 252), R) // r = 2²⁵² + 2774…8493
+	Gx, _ := new(big.Int).SetString("15112221349535400772501151409588531511454012693041857206046113283949847762202", 10)
+	Gy, _ := new(big.Int).SetString("46316835694926478169428394003475163141307993866256225615783033603165251855960", 10)
+	ed25519Curve = new_curve("edwards25519", P, big.NewInt(-1), D, R, big.NewInt(8), Gx, Gy)
+}
+
+// The edwards25519 curve.
+func Edwards25519() *Curve { return ed25519Curve }
@@ -0,0 +1,740 @@
+package edwards
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+	"testing/iotest"
+)
+
+// SecMul in extended coordinates agrees with double-and-add over the affine Add.
+func TestExtendedCoordinates(t *testing.T) {
+
+	for _, c := range []*Curve{e222Curve, e521Curve, ed448Curve, ed25519Curve} {
+		G := c.GenPoint()
+		k, _ := rand.Int(rand.Reader, c.Params().N)
+		want := c.IdPoint()
+		for i := k.BitLen() - 1; i >= 0; i-- {
+			want = want.Add(want)
+			if k.Bit(i) == 1 {
+				want = want.Add(G)
+			}
+		}
+		if !G.SecMul(k).Equals(want) {
+			t.Fatalf("%s: SecMul(%v) differs from double-and-add", c.Name(), k)
+		}
+		if !G.SecMul(big.NewInt(0)).IsIdentity() || !G.SecMul(big.NewInt(1)).Equals(G) {
+			t.Fatalf("%s: SecMul by 0 or 1 is wrong", c.Name())
+		}
+		quad := want.Add(want).Add(want.Add(want))
+		if !want.ClearCofactor().Equals(quad.SecMul(new(big.Int).Rsh(c.Params().Cofactor, 2))) {
+			t.Fatalf("%s: ClearCofactor differs from multiplication by the cofactor", c.Name())
+		}
+		if err := c.invalid_point().SecMul(k).Validate(); err != ErrInvalidPoint {
+			t.Fatalf("%s: SecMul of an invalid point: %v, want %v", c.Name(), err, ErrInvalidPoint)
+		}
+	}
+}
+
+// BaseMul over the precomputed tables agrees with the ladder, for any scalar.
+func TestFixedBaseTables(t *testing.T) {
+
+	for _, c := range []*Curve{e222Curve, e521Curve, ed448Curve, ed25519Curve} {
+		G := c.GenPoint()
+		r := c.Params().R
+		scalars := []*big.Int{big.NewInt(0), big.NewInt(1), big.NewInt(15), big.NewInt(16),
+			new(big.Int).Sub(r, big.NewInt(1)), r, new(big.Int).Neg(big.NewInt(3)), c.Params().N}
+		for i := 0; i < 4; i++ {
+			k, _ := rand.Int(rand.Reader, r)
+			scalars = append(scalars, k)
+		}
+		for _, k := range scalars {
+			want := G.SecMul(new(big.Int).Mod(k, r))
+			if !c.BaseMul(k).Equals(want) {
+				t.Fatalf("%s: BaseMul(%v) differs from the ladder", c.Name(), k)
+			}
+		}
+
+		// the masked scan returns exactly the entry of the digit
+		for _, row := range [][1 << baseWindowBits]extendedPoint{c.baseTable[0], c.baseTable[len(c.baseTable)-1]} {
+			for digit := range row {
+				E, want := c.extended_select(&row, digit), row[digit]
+				if E.X.Cmp(want.X) != 0 || E.Y.Cmp(want.Y) != 0 || E.Z.Cmp(want.Z) != 0 || E.T.Cmp(want.T) != 0 {
+					t.Fatalf("%s: digit %d selects (%v, %v, %v, %v), want (%v, %v, %v, %v)",
+						c.Name(), digit, E.X, E.Y, E.Z, E.T, want.X, want.Y, want.Z, want.T)
+				}
+			}
+		}
+	}
+}
+
+func TestPointCompression(t *testing.T) {
+
+	for _, c := range []*Curve{e222Curve, e521Curve} {
+		params := c.Params()
+		points := []*Point{c.IdPoint(), c.GenPoint(),
+			c.NewPointXY(*big.NewInt(0), *new(big.Int).Sub(params.P, big.NewInt(1)))}
+		for i := 0; i < 4; i++ {
+			k, _ := rand.Int(rand.Reader, params.N)
+			points = append(points, c.GenPoint().SecMul(k))
+		}
+		for _, P := range points {
+			b := P.Bytes()
+			if len(b) != c.EncodedLen() {
+				t.Fatalf("%s: %d byte encoding, want %d", c.Name(), len(b), c.EncodedLen())
+			}
+			Q, err := c.FromBytes(b)
+			if err != nil {
+				t.Fatalf("%s: %x: %v", c.Name(), b, err)
+			}
+			if !Q.Equals(P) || !bytes.Equal(Q.Bytes(), b) {
+				t.Fatalf("%s: %x does not round trip", c.Name(), b)
+			}
+		}
+
+		// x ≥ p, stray high bits, wrong lengths and x without a point are rejected
+		G := c.GenPoint().Bytes()
+		high := toFixedBytes(params.P, c.EncodedLen())
+		stray := append([]byte(nil), G...)
+		stray[0] |= 0x40
+		for _, b := range [][]byte{high, stray, G[1:], append(G, 0)} {
+			if _, err := c.FromBytes(b); err != ErrNonCanonicalPoint {
+				t.Fatalf("%s: %x: %v, want %v", c.Name(), b, err, ErrNonCanonicalPoint)
+			}
+		}
+		if !errors.Is(ErrNonCanonicalPoint, ErrInvalidPoint) {
+			t.Fatal("ErrNonCanonicalPoint is not an ErrInvalidPoint")
+		}
+		missing := 0
+		for x := int64(2); x < 40; x++ {
+			if c.NewPointX(*big.NewInt(x), 0).Validate() != nil {
+				if _, err := c.FromBytes(toFixedBytes(big.NewInt(x), c.EncodedLen())); err != ErrInvalidPoint {
+					t.Fatalf("%s: x = %d: %v, want %v", c.Name(), x, err, ErrInvalidPoint)
+				}
+				missing++
+			}
+		}
+		if missing == 0 {
+			t.Fatalf("%s: every x below 40 has a point", c.Name())
+		}
+		if b := c.invalid_point().Bytes(); b != nil {
+			t.Fatalf("%s: invalid point encodes as %x", c.Name(), b)
+		}
+	}
+
+	// E222 points compress to 28 bytes and E521 points to 66
+	if n := len(e222Curve.GenPoint().Bytes()); n != 28 {
+		t.Fatalf("E222 point of %d bytes, want 28", n)
+	}
+	if n := len(e521Curve.GenPoint().Bytes()); n != 66 {
+		t.Fatalf("E521 point of %d bytes, want 66", n)
+	}
+}
+
+// Points share no big.Int with their callers, and operations modify neither receiver nor arguments.
+func TestPointImmutability(t *testing.T) {
+
+	G := e222Curve.GenPoint()
+	gx, gy := G.X(), G.Y()
+	enc := G.Bytes()
+
+	// caller owned coordinates may be reused after building a point
+	x, y := new(big.Int).Set(gx), new(big.Int).Set(gy)
+	P := e222Curve.NewPointXY(*x, *y)
+	x.Add(x, big.NewInt(1))
+	y.SetInt64(0)
+	if !P.Equals(G) || !P.IsOnCurve() {
+		t.Fatal("point changes with the coordinates it was built from")
+	}
+
+	// coordinates handed out are copies
+	P.X().SetInt64(5)
+	P.Y().Add(P.Y(), big.NewInt(1))
+	if !P.Equals(G) {
+		t.Fatal("point changes with the coordinates it handed out")
+	}
+
+	// no operation changes its operands
+	k := big.NewInt(12345)
+	kCopy := new(big.Int).Set(k)
+	Q := G.SecMul(k)
+	opp := G.Neg()
+	sum := G.Add(Q)
+	G.ClearCofactor()
+	e222Curve.BaseMul(k)
+	if !bytes.Equal(G.Bytes(), enc) || G.X().Cmp(gx) != 0 || G.Y().Cmp(gy) != 0 {
+		t.Fatal("an operation changed its receiver")
+	}
+	if k.Cmp(kCopy) != 0 {
+		t.Fatalf("scalar changed from %v to %v", kCopy, k)
+	}
+	if opp.Equals(G) || !opp.Neg().Equals(G) {
+		t.Fatal("Neg is not an involution")
+	}
+	if !sum.Equals(e222Curve.BaseMul(big.NewInt(12346))) {
+		t.Fatal("G + 12345·G differs from 12346·G")
+	}
+
+	// results are fresh points even when equal to an input
+	id := e222Curve.IdPoint()
+	R := G.Add(id)
+	if R == G || !R.Equals(G) {
+		t.Fatal("G + O is not a fresh copy of G")
+	}
+	if e222Curve.GenPoint() == e222Curve.GenPoint() || e222Curve.GenPoint().X() == e222Curve.GenPoint().X() {
+		t.Fatal("GenPoint shares its point or coordinates")
+	}
+}
+
+// The branch free ladder agrees with double-and-add for scalars of any size and sign.
+func TestLadderConditionalSwap(t *testing.T) {
+
+	for _, c := range []*Curve{e222Curve, e521Curve} {
+		params := c.Params()
+		T := c.NewPointXY(*big.NewInt(0), *new(big.Int).Sub(params.P, big.NewInt(1))) // order 2
+		points := []*Point{c.GenPoint(), c.GenPoint().Add(T), T}
+		scalars := []*big.Int{big.NewInt(0), big.NewInt(1), big.NewInt(2), big.NewInt(-7),
+			new(big.Int).Sub(params.N, big.NewInt(1)), params.N, new(big.Int).Add(params.N, big.NewInt(5)),
+			new(big.Int).Lsh(big.NewInt(1), 600)}
+		for i, P := range points {
+			for _, k := range scalars {
+				m := new(big.Int).Mod(k, params.N)
+				want := c.IdPoint()
+				for i := m.BitLen() - 1; i >= 0; i-- {
+					want = want.Add(want)
+					if m.Bit(i) == 1 {
+						want = want.Add(P)
+					}
+				}
+				if !P.SecMul(k).Equals(want) {
+					t.Fatalf("%s: point %d times %v differs from double-and-add", c.Name(), i, k)
+				}
+			}
+		}
+
+		// the swap exchanges all four coordinates under swap = 1 only
+		A, B := c.to_extended(c.GenPoint()), c.extended_identity()
+		c.extended_cswap(&A, &B, 0)
+		if A.X.Cmp(params.Gx) != 0 || B.Y.Cmp(big.NewInt(1)) != 0 {
+			t.Fatalf("%s: swap = 0 exchanges the points", c.Name())
+		}
+		c.extended_cswap(&A, &B, 1)
+		if B.X.Cmp(params.Gx) != 0 || B.Y.Cmp(params.Gy) != 0 {
+			t.Fatalf("%s: swap = 1 leaves (%v, %v) in B", c.Name(), B.X, B.Y)
+		}
+		if A.X.Sign() != 0 || A.Y.Cmp(big.NewInt(1)) != 0 || A.Z.Cmp(big.NewInt(1)) != 0 || A.T.Sign() != 0 {
+			t.Fatalf("%s: swap = 1 leaves (%v, %v, %v, %v) in A", c.Name(), A.X, A.Y, A.Z, A.T)
+		}
+	}
+}
+
+func TestPrimeSubgroup(t *testing.T) {
+
+	for _, c := range []*Curve{e222Curve, e521Curve, ed448Curve, ed25519Curve} {
+		params := c.Params()
+		T := c.NewPointXY(*big.NewInt(0), *new(big.Int).Sub(params.P, big.NewInt(1))) // order 2
+		k, _ := rand.Int(rand.Reader, params.R)
+		kG := c.BaseMul(k)
+		if !c.GenPoint().InPrimeSubgroup() || !kG.IsTorsionFree() || !c.IdPoint().InPrimeSubgroup() {
+			t.Fatalf("%s: a point of the prime order subgroup is outside it", c.Name())
+		}
+		if T.InPrimeSubgroup() || kG.Add(T).IsTorsionFree() {
+			t.Fatalf("%s: a point with a torsion component is in the prime order subgroup", c.Name())
+		}
+		if c.invalid_point().InPrimeSubgroup() {
+			t.Fatalf("%s: an invalid point is in the prime order subgroup", c.Name())
+		}
+
+		// coordinates outside [0, p−1] are not another name for the point
+		G := c.GenPoint()
+		for _, xy := range [][2]*big.Int{{new(big.Int).Add(G.X(), params.P), G.Y()}, {G.X(), new(big.Int).Add(G.Y(), params.P)},
+			{new(big.Int).Sub(G.X(), params.P), G.Y()}} {
+			if err := c.NewPointXY(*xy[0], *xy[1]).Validate(); err != ErrInvalidPoint {
+				t.Fatalf("%s: (%v, %v): %v, want %v", c.Name(), xy[0], xy[1], err, ErrInvalidPoint)
+			}
+		}
+	}
+}
+
+func TestHashToCurve(t *testing.T) {
+
+	// expand_message_xof vectors from RFC 9380 Appendix K.6, expand_message_xmd with SHA-512 from K.3
+	xof := []byte("QUUX-V01-CS02-with-expander-SHAKE256")
+	xmd := []byte("QUUX-V01-CS02-with-expander-SHA512-256")
+	for _, v := range []struct {
+		expand   func(msg, dst []byte, n, bits int) ([]byte, error)
+		dst, msg []byte
+		want     string
+	}{
+		{expand_message_xof, xof, []byte(""), "2ffc05c48ed32b95d72e807f6eab9f7530dd1c2f013914c8fed38c5ccc15ad76"},
+		{expand_message_xof, xof, []byte("abc"), "b39e493867e2767216792abce1f2676c197c0692aed061560ead251821808e07"},
+		{expand_message_xmd, xmd, []byte(""), "6b9a7312411d92f921c6f68ca0b6380730a1a4d982c507211a90964c394179ba"},
+	} {
+		out, err := v.expand(v.msg, v.dst, 0x20, 128)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(out); got != v.want {
+			t.Fatalf("%s over %q: %s, want %s", v.dst, v.msg, got, v.want)
+		}
+	}
+
+	// the standard suites of edwards25519 and Ed448, RFC 9380 Appendix J.5 and J.7
+	for _, v := range []struct {
+		c            *Curve
+		dst, msg     string
+		u0, u1, x, y string
+	}{
+		{ed25519Curve, "QUUX-V01-CS02-with-edwards25519_XMD:SHA-512_ELL2_RO_", "",
+			"03fef4813c8cb5f98c6eef88fae174e6e7d5380de2b007799ac7ee712d203f3a",
+			"780bdddd137290c8f589dc687795aafae35f6b674668d92bf92ae793e6a60c75",
+			"3c3da6925a3c3c268448dcabb47ccde5439559d9599646a8260e47b1e4822fc6",
+			"09a6c8561a0b22bef63124c588ce4c62ea83a3c899763af26d795302e115dc21"},
+		{ed25519Curve, "QUUX-V01-CS02-with-edwards25519_XMD:SHA-512_ELL2_RO_", "abc", "", "",
+			"608040b42285cc0d72cbb3985c6b04c935370c7361f4b7fbdb1ae7f8c1a8ecad",
+			"1a8395b88338f22e435bbd301183e7f20a5f9de643f11882fb237f88268a5531"},
+		{ed448Curve, "QUUX-V01-CS02-with-edwards448_XOF:SHAKE256_ELL2_RO_", "", "", "",
+			"73036d4a88949c032f01507005c133884e2f0d81f9a950826245dda9e844fc78186c39daaa7147ead3e462cff60e9c6340b58134480b4d17",
+			"94c1d61b43728e5d784ef4fcb1f38e1075f3aef5e99866911de5a234f1aafdc26b554344742e6ba0420b71b298671bbeb2b7736618634610"},
+	} {
+		width := (v.c.Params().P.BitLen() + 7) / 8
+		P, err := v.c.HashToPoint([]byte(v.msg), []byte(v.dst))
+		if err != nil {
+			t.Fatal(err)
+		}
+		x, y := hex.EncodeToString(toFixedBytes(P.X(), width)), hex.EncodeToString(toFixedBytes(P.Y(), width))
+		if x != v.x || y != v.y {
+			t.Fatalf("%s over %q: (%s, %s), want (%s, %s)", v.c.Name(), v.msg, x, y, v.x, v.y)
+		}
+		if v.u0 != "" {
+			u, err := v.c.hash_to_field([]byte(v.msg), []byte(v.dst), 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			u0, u1 := hex.EncodeToString(toFixedBytes(u[0], width)), hex.EncodeToString(toFixedBytes(u[1], width))
+			if u0 != v.u0 || u1 != v.u1 {
+				t.Fatalf("%s over %q: u = (%s, %s), want (%s, %s)", v.c.Name(), v.msg, u0, u1, v.u0, v.u1)
+			}
+		}
+	}
+
+	for _, c := range []*Curve{e222Curve, e521Curve, ed448Curve, ed25519Curve} {
+		// every field element maps onto the curve, including the exceptional 0
+		u, _ := rand.Int(rand.Reader, c.Params().P)
+		for _, u := range []*big.Int{big.NewInt(0), big.NewInt(1), big.NewInt(2), big.NewInt(3), big.NewInt(4),
+			big.NewInt(5), big.NewInt(6), big.NewInt(7), big.NewInt(8), big.NewInt(9), big.NewInt(10),
+			big.NewInt(11), big.NewInt(12), big.NewInt(13), big.NewInt(14), big.NewInt(15), u} {
+			if !c.map_to_curve(u).IsOnCurve() {
+				t.Fatalf("%s: u = %v maps off the curve", c.Name(), u)
+			}
+		}
+
+		H1, err := c.HashToPoint([]byte("msg"), []byte("app-v1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		H2, err := c.HashToPoint([]byte("msg"), []byte("app-v1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		H3, err := c.HashToPoint([]byte("msg"), []byte("app-v2"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		H4, err := c.HashToPoint([]byte("msh"), []byte("app-v1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		H5, err := c.HashToPoint([]byte("msg"), bytes.Repeat([]byte("d"), 300))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !H1.Equals(H2) {
+			t.Fatalf("%s: HashToPoint is not deterministic", c.Name())
+		}
+		if H1.Equals(H3) || H1.Equals(H4) {
+			t.Fatalf("%s: HashToPoint ignores the message or the DST", c.Name())
+		}
+		if !H1.InPrimeSubgroup() || H1.IsIdentity() || !H5.InPrimeSubgroup() {
+			t.Fatalf("%s: HashToPoint leaves the prime order subgroup", c.Name())
+		}
+		if _, err := c.HashToPoint([]byte("msg"), nil); err != ErrHashToCurveDST {
+			t.Fatalf("%s empty DST: %v, want %v", c.Name(), err, ErrHashToCurveDST)
+		}
+	}
+}
+
+func TestPointDoubling(t *testing.T) {
+
+	for _, c := range []*Curve{e222Curve, e521Curve, ed448Curve, ed25519Curve} {
+		params := c.Params()
+		T := c.NewPointXY(*big.NewInt(0), *new(big.Int).Sub(params.P, big.NewInt(1)))
+		k, _ := rand.Int(rand.Reader, params.N)
+		points := []*Point{c.IdPoint(), c.GenPoint(), T, c.GenPoint().SecMul(k), c.GenPoint().Add(T)}
+		for i, P := range points {
+			if !P.Double().Equals(P.Add(P)) || !P.Double().IsOnCurve() {
+				t.Fatalf("%s: doubling point %d differs from P + P", c.Name(), i)
+			}
+		}
+		if !T.Double().IsIdentity() {
+			t.Fatalf("%s: doubling a point of order 2 does not give the identity", c.Name())
+		}
+		if err := c.invalid_point().Double().Validate(); err != ErrInvalidPoint {
+			t.Fatalf("%s: doubling an invalid point: %v, want %v", c.Name(), err, ErrInvalidPoint)
+		}
+	}
+}
+
+/*
+Small order points of curve c: r × Q for points Q off the prime order
+subgroup, collected until all cofactor many have been found.
+*/
+func small_order_points(c *Curve) []*Point {
+	params := c.Params()
+	torsion := []*Point{c.IdPoint()}
+	for x := int64(2); len(torsion) < int(params.Cofactor.Int64()) && x < 1000; x++ {
+		for parity := uint(0); parity < 2; parity++ {
+			Q := c.NewPointX(*big.NewInt(x), parity)
+			if Q.Validate() != nil {
+				continue
+			}
+			T := Q.SecMul(params.R)
+			for M := T; !M.IsIdentity(); M = M.Add(T) {
+				known := false
+				for _, U := range torsion {
+					known = known || U.Equals(M)
+				}
+				if !known {
+					torsion = append(torsion, M)
+				}
+			}
+		}
+	}
+	return torsion
+}
+
+// Add is complete: identities, opposites, doublings and every pair of small order points.
+func TestSmallOrderAddition(t *testing.T) {
+
+	for _, c := range []*Curve{e222Curve, e521Curve, ed448Curve, ed25519Curve} {
+		params := c.Params()
+		if !c.is_square(params.A) || c.is_square(params.D) {
+			t.Fatalf("%s: a is not a square or d is", c.Name())
+		}
+
+		torsion := small_order_points(c)
+		if len(torsion) != int(params.Cofactor.Int64()) {
+			t.Fatalf("%s: %d small order points, want %v", c.Name(), len(torsion), params.Cofactor)
+		}
+		in_torsion := func(P *Point) bool {
+			for _, T := range torsion {
+				if T.Equals(P) {
+					return true
+				}
+			}
+			return false
+		}
+		k, _ := rand.Int(rand.Reader, params.R)
+		P := c.BaseMul(k)
+		for i, T1 := range torsion {
+			if !T1.IsOnCurve() || !T1.ClearCofactor().IsIdentity() {
+				t.Fatalf("%s: small order point %d is off the curve or survives ClearCofactor", c.Name(), i)
+			}
+			if !T1.Add(T1.Neg()).IsIdentity() || !T1.Add(c.IdPoint()).Equals(T1) || !T1.Add(T1).Equals(T1.Double()) {
+				t.Fatalf("%s: small order point %d: opposite, identity or doubling is wrong", c.Name(), i)
+			}
+			for j, T2 := range torsion {
+				sum := T1.Add(T2)
+				if !sum.IsOnCurve() || !in_torsion(sum) {
+					t.Fatalf("%s: T%d + T%d is not a small order point", c.Name(), i, j)
+				}
+				if !sum.Equals(T2.Add(T1)) || !P.Add(T1).Add(T2).Equals(P.Add(sum)) {
+					t.Fatalf("%s: T%d + T%d does not commute or associate", c.Name(), i, j)
+				}
+			}
+		}
+
+		// the identity, P + P and P + (−P) need no special case
+		if !P.Add(c.IdPoint()).Equals(P) || !c.IdPoint().Add(P).Equals(P) {
+			t.Fatalf("%s: adding the identity changes P", c.Name())
+		}
+		if !P.Add(P).Equals(c.BaseMul(new(big.Int).Lsh(k, 1))) {
+			t.Fatalf("%s: P + P differs from 2kG", c.Name())
+		}
+		if !P.Add(P.Neg()).IsIdentity() {
+			t.Fatalf("%s: P + (-P) is not the identity", c.Name())
+		}
+
+		// nil, foreign and invalid operands give invalid points, never a panic
+		if err := P.Add(nil).Validate(); err != ErrInvalidPoint {
+			t.Fatalf("%s: P + nil: %v, want %v", c.Name(), err, ErrInvalidPoint)
+		}
+		if P.Equals(nil) {
+			t.Fatalf("%s: P equals nil", c.Name())
+		}
+		if err := P.Add(c.invalid_point()).Validate(); err != ErrInvalidPoint {
+			t.Fatalf("%s: P + invalid point: %v, want %v", c.Name(), err, ErrInvalidPoint)
+		}
+	}
+}
+
+// fe521 arithmetic agrees with big.Int mod p, and so does the E521 ladder over it with the generic one.
+func TestE521FieldLimbs(t *testing.T) {
+
+	c := e521Curve
+	p := c.Params().P
+	edge := []*big.Int{big.NewInt(0), big.NewInt(1), big.NewInt(2), new(big.Int).Sub(p, big.NewInt(1)),
+		new(big.Int).Lsh(big.NewInt(1), 520), new(big.Int).Lsh(big.NewInt(1), 512), new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 512), big.NewInt(1))}
+	values := append([]*big.Int{}, edge...)
+	for i := 0; i < 16; i++ {
+		v, _ := rand.Int(rand.Reader, p)
+		values = append(values, v)
+	}
+	mod := func(v *big.Int) *big.Int { return v.Mod(v, p) }
+	for _, a := range values {
+		fa := fe521_from_int(a)
+		if got := fa.to_int(); got.Cmp(a) != 0 {
+			t.Fatalf("%v converts back as %v", a, got)
+		}
+		if a.Sign() != 0 {
+			inv := fe521_inv(&fa)
+			if got, want := inv.to_int(), new(big.Int).ModInverse(a, p); got.Cmp(want) != 0 {
+				t.Fatalf("1/%v = %v, want %v", a, got, want)
+			}
+		}
+		for _, b := range values {
+			fb := fe521_from_int(b)
+			sum, diff, prod := fe521_add(&fa, &fb), fe521_sub(&fa, &fb), fe521_mul(&fa, &fb)
+			if got, want := sum.to_int(), mod(new(big.Int).Add(a, b)); got.Cmp(want) != 0 {
+				t.Fatalf("%v + %v = %v, want %v", a, b, got, want)
+			}
+			if got, want := diff.to_int(), mod(new(big.Int).Sub(a, b)); got.Cmp(want) != 0 {
+				t.Fatalf("%v - %v = %v, want %v", a, b, got, want)
+			}
+			if got, want := prod.to_int(), mod(new(big.Int).Mul(a, b)); got.Cmp(want) != 0 {
+				t.Fatalf("%v * %v = %v, want %v", a, b, got, want)
+			}
+		}
+	}
+	// p itself stands for 0 until canonical
+	zero := fe521{^uint64(0), ^uint64(0), ^uint64(0), ^uint64(0), ^uint64(0), ^uint64(0), ^uint64(0), ^uint64(0), 1<<fe521TopBits - 1}
+	if got := zero.to_int(); got.Sign() != 0 {
+		t.Fatalf("p converts as %v, want 0", got)
+	}
+
+	// the big.Int ladder, on a copy of the curve without the fixed-limb one
+	generic := *c
+	generic.ladder = nil
+	points := small_order_points(c)
+	for i := 0; i < 4; i++ {
+		k, _ := rand.Int(rand.Reader, c.Params().R)
+		points = append(points, c.BaseMul(k))
+	}
+	n := c.Params().N
+	big512, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 512))
+	for i, P := range points {
+		Q := generic.NewPointXY(*P.X(), *P.Y())
+		for _, k := range []*big.Int{big.NewInt(0), big.NewInt(1), big.NewInt(4), new(big.Int).Sub(n, big.NewInt(1)), big512} {
+			want := Q.SecMul(k)
+			got := P.SecMul(k)
+			if got.X().Cmp(want.X()) != 0 || got.Y().Cmp(want.Y()) != 0 {
+				t.Fatalf("point %d times %v: (%v, %v), want (%v, %v)", i, k, got.X(), got.Y(), want.X(), want.Y())
+			}
+		}
+	}
+}
+
+// BatchInvert agrees with ModInverse element by element, maps 0 to 0 and leaves its input alone.
+func TestBatchInversion(t *testing.T) {
+
+	if n := len(BatchInvert(nil, big.NewInt(7))); n != 0 {
+		t.Fatalf("inverting nothing gives %d values", n)
+	}
+	for _, p := range []*big.Int{e521Curve.Params().P, elliptic.P256().Params().N, big.NewInt(7)} {
+		xs := []*big.Int{big.NewInt(0), big.NewInt(1), new(big.Int).Sub(p, big.NewInt(1)), new(big.Int).Set(p), big.NewInt(-3)}
+		for i := 0; i < 8; i++ {
+			x, _ := rand.Int(rand.Reader, p)
+			xs = append(xs, x, big.NewInt(0))
+		}
+		before := make([]string, len(xs))
+		for i, x := range xs {
+			before[i] = x.String()
+		}
+		for i, inv := range BatchInvert(xs, p) {
+			want := new(big.Int).ModInverse(new(big.Int).Mod(xs[i], p), p)
+			if want == nil {
+				want = new(big.Int)
+			}
+			if inv.Cmp(want) != 0 {
+				t.Fatalf("1/%v mod %v = %v, want %v", xs[i], p, inv, want)
+			}
+			if xs[i].String() != before[i] {
+				t.Fatalf("input %d changed from %s to %v", i, before[i], xs[i])
+			}
+		}
+	}
+	// the fixed-base tables are normalized by it
+	for _, c := range []*Curve{e222Curve, e521Curve, ed448Curve, ed25519Curve} {
+		for i, row := range c.baseTable {
+			for j, E := range row {
+				if E.Z.Cmp(big.NewInt(1)) != 0 || !c.from_extended(E).IsOnCurve() {
+					t.Fatalf("%s: table entry [%d][%d] has Z = %v", c.Name(), i, j, E.Z)
+				}
+			}
+		}
+	}
+}
+
+// RandomPoint gives distinct points of the prime order subgroup, reproducibly for a fixed reader.
+func TestRandomPoints(t *testing.T) {
+
+	for _, c := range []*Curve{e222Curve, e521Curve, ed448Curve, ed25519Curve} {
+		var prev *Point
+		for i := 0; i < 4; i++ {
+			P, err := c.RandomPoint(rand.Reader)
+			if err != nil {
+				t.Fatalf("%s: %v", c.Name(), err)
+			}
+			if !P.IsOnCurve() || !P.InPrimeSubgroup() || P.IsIdentity() {
+				t.Fatalf("%s: random point outside the prime order subgroup, or the identity", c.Name())
+			}
+			if P.Equals(prev) {
+				t.Fatalf("%s: two random points are equal", c.Name())
+			}
+			prev = P
+		}
+		seed := make([]byte, 4096)
+		rand.Read(seed)
+		P1, err := c.RandomPoint(bytes.NewReader(seed))
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name(), err)
+		}
+		P2, err := c.RandomPoint(bytes.NewReader(seed))
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name(), err)
+		}
+		if !P1.Equals(P2) {
+			t.Fatalf("%s: the same reader gives different points", c.Name())
+		}
+
+		if _, err := c.RandomPoint(iotest.ErrReader(errors.New("no entropy"))); err == nil {
+			t.Fatalf("%s: failing reader gives a point", c.Name())
+		}
+	}
+}
+
+// SelfTest accepts every curve defined here and catches a mistyped constant in a copy of one.
+func TestCurveSelfTest(t *testing.T) {
+
+	for _, c := range []*Curve{e222Curve, e521Curve, ed448Curve, ed25519Curve} {
+		if err := c.SelfTest(); err != nil {
+			t.Fatalf("%s: %v", c.Name(), err)
+		}
+	}
+	c := *e222Curve
+	c.ladder = nil
+	corrupt := []func(b *Curve){
+		func(b *Curve) { b.gy = new(big.Int).Add(b.gy, big.NewInt(1)) },
+		func(b *Curve) { b.gx = new(big.Int).Xor(b.gx, big.NewInt(1<<20)) },
+		func(b *Curve) { b.p = new(big.Int).Add(b.p, big.NewInt(2)) },
+		func(b *Curve) { b.d = big.NewInt(4) },
+		func(b *Curve) {
+			b.r = new(big.Int).Add(b.r, big.NewInt(2))
+			for !b.r.ProbablyPrime(20) {
+				b.r.Add(b.r, big.NewInt(2))
+			}
+			b.n = new(big.Int).Mul(b.r, b.cofactor)
+		},
+		func(b *Curve) { b.cofactor = big.NewInt(3); b.n = new(big.Int).Mul(b.r, b.cofactor) },
+		func(b *Curve) { b.gx, b.gy = big.NewInt(0), big.NewInt(1) },
+	}
+	for i, f := range corrupt {
+		b := c
+		f(&b)
+		if err := b.SelfTest(); !errors.Is(err, ErrCurveParameters) {
+			t.Fatalf("corruption %d: %v, want %v", i, err, ErrCurveParameters)
+		}
+	}
+}
+
+// The RFC 8032 encodings round trip, match crypto/ed25519 keys byte for byte and reject non-canonical input.
+func TestRFC8032Encodings(t *testing.T) {
+
+	for _, c := range []*Curve{e222Curve, e521Curve, ed448Curve, ed25519Curve} {
+		params := c.Params()
+		for _, P := range append(small_order_points(c), c.GenPoint(), c.BaseMul(big.NewInt(12345))) {
+			enc := P.EncodeRFC8032()
+			if len(enc) != c.EncodedLen() {
+				t.Fatalf("%s: %d byte encoding, want %d", c.Name(), len(enc), c.EncodedLen())
+			}
+			Q, err := c.DecodeRFC8032(enc)
+			if err != nil {
+				t.Fatalf("%s: %x: %v", c.Name(), enc, err)
+			}
+			if !Q.Equals(P) {
+				t.Fatalf("%s: %x does not round trip", c.Name(), enc)
+			}
+			if !P.InPrimeSubgroup() {
+				if _, err := c.DecodeRFC8032(enc, RequirePrimeOrder); err != ErrSmallSubgroup {
+					t.Fatalf("%s: %x under RequirePrimeOrder: %v, want %v", c.Name(), enc, err, ErrSmallSubgroup)
+				}
+			}
+		}
+		if enc := c.invalid_point().EncodeRFC8032(); enc != nil {
+			t.Fatalf("%s: invalid point encodes as %x", c.Name(), enc)
+		}
+
+		// y = p, x = 0 with the sign bit set, wrong lengths
+		zero := c.IdPoint().EncodeRFC8032()
+		zero[len(zero)-1] |= 0x80
+		for _, b := range [][]byte{toLittleEndian(params.P, c.EncodedLen()), zero, zero[1:]} {
+			if _, err := c.DecodeRFC8032(b); err != ErrNonCanonicalPoint {
+				t.Fatalf("%s: %x: %v, want %v", c.Name(), b, err, ErrNonCanonicalPoint)
+			}
+		}
+
+		k, _ := rand.Int(rand.Reader, params.R)
+		if n := len(c.EncodeScalar(k)); n != c.EncodedLen() {
+			t.Fatalf("%s: %d byte scalar, want %d", c.Name(), n, c.EncodedLen())
+		}
+		s, err := c.DecodeScalar(c.EncodeScalar(k))
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name(), err)
+		}
+		if s.Cmp(k) != 0 {
+			t.Fatalf("%s: scalar %v decodes as %v", c.Name(), k, s)
+		}
+		minus, err := c.DecodeScalar(c.EncodeScalar(big.NewInt(-1)))
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name(), err)
+		}
+		if want := new(big.Int).Sub(params.R, big.NewInt(1)); minus.Cmp(want) != 0 {
+			t.Fatalf("%s: -1 decodes as %v, want %v", c.Name(), minus, want)
+		}
+		for _, b := range [][]byte{toLittleEndian(params.R, c.EncodedLen()), c.EncodeScalar(k)[1:]} {
+			if _, err := c.DecodeScalar(b); err != ErrNonCanonicalScalar {
+				t.Fatalf("%s: scalar %x: %v, want %v", c.Name(), b, err, ErrNonCanonicalScalar)
+			}
+		}
+	}
+
+	// public keys of crypto/ed25519 decode and encode unchanged
+	for i := 0; i < 4; i++ {
+		pub, _, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		P, err := ed25519Curve.DecodeRFC8032(pub)
+		if err != nil {
+			t.Fatalf("%x: %v", pub, err)
+		}
+		if got := P.EncodeRFC8032(); !bytes.Equal(got, pub) {
+			t.Fatalf("%x encodes back as %x", pub, got)
+		}
+	}
+}
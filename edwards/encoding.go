@@ -0,0 +1,146 @@
+package edwards
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var (
+	ErrNonCanonicalPoint  = fmt.Errorf("%w: non-canonical encoding", ErrInvalidPoint)
+	ErrNonCanonicalScalar = errors.New("non-canonical scalar encoding")
+)
+
+// Optional checks applied by the point decoders on top of validity.
+type DecodeOption int
+
+const (
+	// Reject points with a small order component with ErrSmallSubgroup.
+	RequirePrimeOrder DecodeOption = iota + 1
+)
+
+/*
+Applies the checks of opts to a valid point, as FromBytes and
+DecodeRFC8032 do, for points decoded from other encodings.
+*/
+func (P *Point) Check(opts ...DecodeOption) error {
+	for _, opt := range opts {
+		if opt == RequirePrimeOrder && !P.InPrimeSubgroup() {
+			return ErrSmallSubgroup
+		}
+	}
+	return nil
+}
+
+/*
+Compressed encoding of the point: x as EncodedLen big endian bytes, 28
+for E222 and 66 for E521, with the least significant bit of y in the most
+significant bit of the first byte, which x never reaches. FromBytes
+recovers y from x and that bit. Returns nil for an invalid point.
+*/
+func (P *Point) Bytes() []byte {
+	if P.invalid {
+		return nil
+	}
+	out := toFixedBytes(&P.x, P.curve.EncodedLen())
+	out[0] |= byte(P.y.Bit(0)) << 7
+	return out
+}
+
+/*
+Decodes a point encoded by Bytes. Decoding is strict, so that every point
+has exactly one accepted encoding: the length must be exact, x must lie in
+[0, p−1], which also rules out stray bits next to the parity bit, and the
+parity bit must be clear when y = 0. These fail with ErrNonCanonicalPoint;
+an x with no point on the curve fails with ErrInvalidPoint. The identity
+and small order points are accepted unless RequirePrimeOrder is given.
+*/
+func (c *Curve) FromBytes(b []byte, opts ...DecodeOption) (*Point, error) {
+	if len(b) != c.EncodedLen() {
+		return nil, ErrNonCanonicalPoint
+	}
+	parity := uint(b[0] >> 7)
+	enc := append([]byte(nil), b...)
+	enc[0] &= 0x7f
+	x := new(big.Int).SetBytes(enc)
+	if x.Cmp(c.p) >= 0 {
+		return nil, ErrNonCanonicalPoint
+	}
+	y := c.solve_for_y(x, parity)
+	if y == nil {
+		return nil, ErrInvalidPoint
+	}
+	if y.Sign() == 0 && parity == 1 {
+		return nil, ErrNonCanonicalPoint
+	}
+	P := c.NewPointXY(*x, *y)
+	if !P.IsOnCurve() {
+		return nil, ErrInvalidPoint
+	}
+	if err := P.Check(opts...); err != nil {
+		return nil, err
+	}
+	return P, nil
+}
+
+/*
+Encoding of the point as in RFC 8032 Sections 5.1.2 and 5.2.2: y as
+EncodedLen little endian bytes with the least significant bit of x in the
+top bit of the last byte, 32 bytes for edwards25519 and 57 for Ed448, as
+other Ed25519 and Ed448 implementations read and write public keys. E222
+and E521 get the same layout, 28 and 66 bytes, though no standard defines
+it for them. Returns nil for an invalid point.
+*/
+func (P *Point) EncodeRFC8032() []byte {
+	if P.invalid {
+		return nil
+	}
+	return P.curve.encode_point(P)
+}
+
+/*
+Decodes a point encoded by EncodeRFC8032 as in RFC 8032 Sections 5.1.3 and
+5.2.3. Decoding is strict: y ≥ p and x = 0 with the sign bit set fail with
+ErrNonCanonicalPoint, a y with no point on the curve with ErrInvalidPoint.
+*/
+func (c *Curve) DecodeRFC8032(b []byte, opts ...DecodeOption) (*Point, error) {
+	if len(b) != c.EncodedLen() {
+		return nil, ErrNonCanonicalPoint
+	}
+	enc := append([]byte(nil), b...)
+	enc[len(enc)-1] &= 0x7f
+	if fromLittleEndian(enc).Cmp(c.p) >= 0 {
+		return nil, ErrNonCanonicalPoint
+	}
+	P, err := c.decode_point(b)
+	if err != nil {
+		if c.solve_for_x(fromLittleEndian(enc), 0) != nil {
+			return nil, ErrNonCanonicalPoint // x = 0 with the sign bit set
+		}
+		return nil, ErrInvalidPoint
+	}
+	if err := P.Check(opts...); err != nil {
+		return nil, err
+	}
+	return P, nil
+}
+
+// s mod r as EncodedLen little endian bytes, the encoding of S in RFC 8032 signatures.
+func (c *Curve) EncodeScalar(s *big.Int) []byte {
+	k := new(big.Int).Mod(s, c.r)
+	defer wipe_int(k)
+	return toLittleEndian(k, c.EncodedLen())
+}
+
+// Decodes a scalar encoded by EncodeScalar, failing with ErrNonCanonicalScalar
+// unless it has the exact length and lies in [0, r−1], as RFC 8032 requires of S.
+func (c *Curve) DecodeScalar(b []byte) (*big.Int, error) {
+	if len(b) != c.EncodedLen() {
+		return nil, ErrNonCanonicalScalar
+	}
+	s := fromLittleEndian(b)
+	if s.Cmp(c.r) >= 0 {
+		return nil, ErrNonCanonicalScalar
+	}
+	return s, nil
+}
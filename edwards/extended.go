@@ -0,0 +1,292 @@
+package edwards
+
+import (
+	"crypto/subtle"
+	"math/big"
+)
+
+/*
+A point in extended coordinates (X : Y : Z : T) with x = X/Z, y = Y/Z and
+xy = T/Z (Hisil, Wong, Carter and Dawson, https://eprint.iacr.org/2008/522).
+The formulas below need no inversion, so scalar multiplication works on
+these and converts back to affine coordinates once at the end, where the
+affine Add would invert twice per step. They are complete on curves with
+a square and d non-square, as for every curve defined in this package.
+*/
+type extendedPoint struct {
+	X, Y, Z, T *big.Int
+}
+
+// Coordinates are reduced mod p, as extended_cswap requires.
+func (c *Curve) to_extended(P *Point) extendedPoint {
+	x := new(big.Int).Mod(&P.x, c.p)
+	y := new(big.Int).Mod(&P.y, c.p)
+	T := new(big.Int).Mul(x, y)
+	return extendedPoint{x, y, big.NewInt(1), T.Mod(T, c.p)}
+}
+
+// The affine point of E, invalid if Z is not invertible, which only input off the curve can cause.
+func (c *Curve) from_extended(E extendedPoint) *Point {
+	z_inv := new(big.Int).ModInverse(E.Z, c.p)
+	if z_inv == nil {
+		return c.invalid_point()
+	}
+	x := new(big.Int).Mul(E.X, z_inv)
+	y := new(big.Int).Mul(E.Y, z_inv)
+	return c.NewPointXY(*x.Mod(x, c.p), *y.Mod(y, c.p))
+}
+
+/*
+Rescales the points to Z = 1, as from_extended would one by one, with a
+single inversion shared through BatchInvert. Points whose Z is not
+invertible are left as they are.
+*/
+func (c *Curve) normalize_extended(points []*extendedPoint) {
+	zs := make([]*big.Int, len(points))
+	for i, E := range points {
+		zs[i] = E.Z
+	}
+	mod := func(v *big.Int) *big.Int { return v.Mod(v, c.p) }
+	for i, z_inv := range BatchInvert(zs, c.p) {
+		if z_inv.Sign() == 0 {
+			continue
+		}
+		E := points[i]
+		E.X = mod(new(big.Int).Mul(E.X, z_inv))
+		E.Y = mod(new(big.Int).Mul(E.Y, z_inv))
+		E.T = mod(new(big.Int).Mul(E.T, z_inv))
+		E.Z = big.NewInt(1)
+	}
+}
+
+// The identity (0 : 1 : 1 : 0).
+func (c *Curve) extended_identity() extendedPoint {
+	return extendedPoint{big.NewInt(0), big.NewInt(1), big.NewInt(1), big.NewInt(0)}
+}
+
+/*
+Swaps P and Q if swap is 1 and leaves them if it is 0, touching the same
+bytes either way: each coordinate pair is written out at the fixed width
+of p and exchanged under a mask. Coordinates must lie in [0, p−1].
+*/
+func (c *Curve) extended_cswap(P, Q *extendedPoint, swap uint) {
+	width := (c.p.BitLen() + 7) / 8
+	a := make([]byte, width)
+	b := make([]byte, width)
+	defer wipe_bytes(a)
+	defer wipe_bytes(b)
+	mask := -byte(swap & 1)
+	for _, pair := range [4][2]*big.Int{{P.X, Q.X}, {P.Y, Q.Y}, {P.Z, Q.Z}, {P.T, Q.T}} {
+		pair[0].FillBytes(a)
+		pair[1].FillBytes(b)
+		for i := range a {
+			t := mask & (a[i] ^ b[i])
+			a[i] ^= t
+			b[i] ^= t
+		}
+		pair[0].SetBytes(a)
+		pair[1].SetBytes(b)
+	}
+}
+
+// P + Q by the unified addition formulas add-2008-hwcd.
+func (c *Curve) extended_add(P, Q extendedPoint) extendedPoint {
+	p := c.p
+	mod := func(v *big.Int) *big.Int { return v.Mod(v, p) }
+	A := mod(new(big.Int).Mul(P.X, Q.X))
+	B := mod(new(big.Int).Mul(P.Y, Q.Y))
+	C := mod(new(big.Int).Mul(c.d, mod(new(big.Int).Mul(P.T, Q.T))))
+	D := mod(new(big.Int).Mul(P.Z, Q.Z))
+	E := new(big.Int).Mul(new(big.Int).Add(P.X, P.Y), new(big.Int).Add(Q.X, Q.Y))
+	E = mod(E.Sub(E, A).Sub(E, B))
+	F := mod(new(big.Int).Sub(D, C))
+	G := mod(new(big.Int).Add(D, C))
+	H := mod(new(big.Int).Sub(B, new(big.Int).Mul(c.a, A)))
+	return extendedPoint{
+		X: mod(new(big.Int).Mul(E, F)),
+		Y: mod(new(big.Int).Mul(G, H)),
+		Z: mod(new(big.Int).Mul(F, G)),
+		T: mod(new(big.Int).Mul(E, H)),
+	}
+}
+
+// 2P by the doubling formulas dbl-2008-hwcd, which do not use T.
+func (c *Curve) extended_double(P extendedPoint) extendedPoint {
+	p := c.p
+	mod := func(v *big.Int) *big.Int { return v.Mod(v, p) }
+	A := mod(new(big.Int).Mul(P.X, P.X))
+	B := mod(new(big.Int).Mul(P.Y, P.Y))
+	C := mod(new(big.Int).Lsh(new(big.Int).Mul(P.Z, P.Z), 1))
+	D := mod(new(big.Int).Mul(c.a, A))
+	E := new(big.Int).Add(P.X, P.Y)
+	E = mod(E.Mul(E, E).Sub(E, A).Sub(E, B))
+	G := mod(new(big.Int).Add(D, B))
+	F := mod(new(big.Int).Sub(G, C))
+	H := mod(new(big.Int).Sub(D, B))
+	return extendedPoint{
+		X: mod(new(big.Int).Mul(E, F)),
+		Y: mod(new(big.Int).Mul(G, H)),
+		Z: mod(new(big.Int).Mul(F, G)),
+		T: mod(new(big.Int).Mul(E, H)),
+	}
+}
+
+// Width in bits of the windows of the fixed-base tables.
+const baseWindowBits = 4
+
+/*
+Precomputes j·16ⁱ × G for every window position i of a scalar mod r and
+every digit j in [0, 15], so that BaseMul needs one addition per 4-bit
+window and no doublings. The entries are then normalized to Z = 1 with one
+inversion for the whole table.
+*/
+func (c *Curve) build_base_table() {
+	windows := (c.r.BitLen() + baseWindowBits - 1) / baseWindowBits
+	c.baseTable = make([][1 << baseWindowBits]extendedPoint, windows)
+	B := c.to_extended(c.GenPoint()) // 16ⁱ × G
+	for i := range c.baseTable {
+		c.baseTable[i][0] = c.extended_identity()
+		c.baseTable[i][1] = B
+		for j := 2; j < 1<<baseWindowBits; j++ {
+			c.baseTable[i][j] = c.extended_add(c.baseTable[i][j-1], B)
+		}
+		B = c.extended_add(c.baseTable[i][(1<<baseWindowBits)-1], B)
+	}
+	entries := make([]*extendedPoint, 0, len(c.baseTable)<<baseWindowBits)
+	for i := range c.baseTable {
+		for j := range c.baseTable[i] {
+			entries = append(entries, &c.baseTable[i][j])
+		}
+	}
+	c.normalize_extended(entries)
+}
+
+/*
+s × G by the fixed-base windowed method over the table built with the
+curve: s is reduced mod r and split into 4-bit digits dᵢ, and the result
+is Σ dᵢ·16ⁱ × G, one table addition per digit, with a single inversion at
+the end. Zero digits add the identity, so the number of additions does
+not depend on s.
+
+Security Remark: the table entry is not indexed by the secret digit but
+picked by extended_select, which reads all 16 entries of the window, so
+the memory access pattern does not depend on s. The arithmetic uses
+big.Int, which is not constant time.
+*/
+func (c *Curve) BaseMul(s *big.Int) *Point {
+	k := new(big.Int).Mod(s, c.r)
+	defer wipe_int(k)
+	return c.from_extended(c.base_mul_extended(k))
+}
+
+// k × G in extended coordinates over the fixed-base tables, for k in [0, r−1].
+func (c *Curve) base_mul_extended(k *big.Int) extendedPoint {
+	R := c.extended_identity()
+	for i := range c.baseTable {
+		digit := 0
+		for b := 0; b < baseWindowBits; b++ {
+			digit |= int(k.Bit(i*baseWindowBits+b)) << b
+		}
+		R = c.extended_add(R, c.extended_select(&c.baseTable[i], digit))
+	}
+	return R
+}
+
+/*
+A copy of table[digit], selected without indexing by digit: every entry is
+written out at the fixed width of p and merged into the result under a
+mask that is all ones for the wanted entry only, so the same bytes are
+read whatever digit is. Coordinates must lie in [0, p−1].
+*/
+func (c *Curve) extended_select(table *[1 << baseWindowBits]extendedPoint, digit int) extendedPoint {
+	width := (c.p.BitLen() + 7) / 8
+	var out [4][]byte
+	for i := range out {
+		out[i] = make([]byte, width)
+		defer wipe_bytes(out[i])
+	}
+	entry := make([]byte, width)
+	defer wipe_bytes(entry)
+	for j := range table {
+		mask := byte(subtle.ConstantTimeEq(int32(j), int32(digit))) * 0xff
+		for i, v := range [4]*big.Int{table[j].X, table[j].Y, table[j].Z, table[j].T} {
+			v.FillBytes(entry)
+			for b := range entry {
+				out[i][b] |= mask & entry[b]
+			}
+		}
+	}
+	return extendedPoint{
+		X: new(big.Int).SetBytes(out[0]),
+		Y: new(big.Int).SetBytes(out[1]),
+		Z: new(big.Int).SetBytes(out[2]),
+		T: new(big.Int).SetBytes(out[3]),
+	}
+}
+
+// The multiples j × P for j in [0, 15], for window_mul.
+func (c *Curve) window_table(P extendedPoint) *[1 << baseWindowBits]extendedPoint {
+	var table [1 << baseWindowBits]extendedPoint
+	table[0] = c.extended_identity()
+	for j := 1; j < len(table); j++ {
+		table[j] = c.extended_add(table[j-1], P)
+	}
+	return &table
+}
+
+/*
+k × P for k ≥ 0 by the fixed 4-bit window method over the table of
+window_table: four doublings and one addition per window, from the top.
+Zero windows are skipped, so the running time depends on k, which must
+therefore be public, as in signature verification.
+*/
+func (c *Curve) window_mul(table *[1 << baseWindowBits]extendedPoint, k *big.Int) extendedPoint {
+	R := c.extended_identity()
+	windows := (k.BitLen() + baseWindowBits - 1) / baseWindowBits
+	for i := windows - 1; i >= 0; i-- {
+		for b := 0; b < baseWindowBits; b++ {
+			R = c.extended_double(R)
+		}
+		digit := 0
+		for b := 0; b < baseWindowBits; b++ {
+			digit |= int(k.Bit(i*baseWindowBits+b)) << b
+		}
+		if digit != 0 {
+			R = c.extended_add(R, table[digit])
+		}
+	}
+	return R
+}
+
+/*
+Computes sᵢ × G + eᵢ × Pᵢ for every i, as verifiers of Schnorr signatures
+recompute the commitments of a batch: sᵢ × G comes from the fixed-base
+tables, eᵢ × Pᵢ from a window table built once per distinct Pᵢ, all in
+extended coordinates, and the results are brought back to affine form
+with one inversion for the whole batch through BatchInvert. sᵢ is
+reduced mod r and eᵢ must not be negative. A result whose Z is not
+invertible, only possible off the curve, is an invalid point. The running
+time depends on every input, which must therefore be public.
+*/
+func (c *Curve) BatchMulAdd(s, e []*big.Int, P []*Point) []*Point {
+	tables := make(map[string]*[1 << baseWindowBits]extendedPoint) // by encoded point
+	results := make([]*extendedPoint, len(s))
+	for i := range s {
+		id := string(P[i].Bytes())
+		if tables[id] == nil {
+			tables[id] = c.window_table(c.to_extended(P[i]))
+		}
+		R := c.extended_add(c.base_mul_extended(new(big.Int).Mod(s[i], c.r)), c.window_mul(tables[id], e[i]))
+		results[i] = &R
+	}
+	c.normalize_extended(results)
+	out := make([]*Point, len(results))
+	for i, R := range results {
+		if R.Z.Cmp(big.NewInt(1)) != 0 {
+			out[i] = c.invalid_point()
+			continue
+		}
+		out[i] = c.NewPointXY(*R.X, *R.Y)
+	}
+	return out
+}
@@ -0,0 +1,47 @@
+package edwards
+
+import (
+	"math/big"
+)
+
+// Encodes v as exactly size big endian bytes, keeping leading zeros that v.Bytes() would drop.
+func toFixedBytes(v *big.Int, size int) []byte {
+	out := make([]byte, size)
+	v.FillBytes(out)
+	return out
+}
+
+// Encodes v as exactly size little endian bytes, as RFC 8032 encodes
+// scalars and coordinates.
+func toLittleEndian(v *big.Int, size int) []byte {
+	out := toFixedBytes(v, size)
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+// Decodes a little endian byte string of any width.
+func fromLittleEndian(b []byte) *big.Int {
+	be := make([]byte, len(b))
+	for i := range b {
+		be[len(b)-1-i] = b[i]
+	}
+	return new(big.Int).SetBytes(be)
+}
+
+// Zeroes the words backing v and sets it to 0, for secret scalars.
+func wipe_int(v *big.Int) {
+	words := v.Bits()
+	for i := range words {
+		words[i] = 0
+	}
+	v.SetInt64(0)
+}
+
+// Zeroes b.
+func wipe_bytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,302 @@
+package edwards
+
+import (
+	"crypto/sha512"
+	"errors"
+	"math/big"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Returned by HashToPoint for an empty domain separation tag.
+var ErrHashToCurveDST = errors.New("hash to curve: domain separation tag must not be empty")
+
+/*
+Hashes msg to a point of the prime order subgroup as hash_to_curve of
+RFC 9380 Section 3, the random oracle variant: two field elements from
+hash_to_field are mapped to the curve with Elligator 2 (Section 6.7.1),
+added, and the cofactor is cleared. dst separates applications and must
+not be empty. The suites are
+
+	edwards25519  edwards25519_XMD:SHA-512_ELL2_RO_ of RFC 9380 Section 8.5
+	Ed448         edwards448_XOF:SHAKE256_ELL2_RO_ of RFC 9380 Section 8.6
+	E222, E521    this package's own, see below
+
+and give the points of RFC 9380 Appendix J on the standard curves. E222
+and E521 have no suite in the RFC; for them the package defines one in
+the same pattern, E222_XOF:SHAKE256_ELL2_RO_ and E521_XOF:SHAKE256_ELL2_RO_,
+with expand_message_xof and Elligator 2 on the Montgomery form of the
+curve, carried over with the rational map of Appendix D.1. Other
+implementations agree with it only if they implement it as defined here.
+
+Nobody knows the discrete logarithm of the result to G, which is what
+VRFs, OPRFs and Pedersen commitments need from it.
+*/
+func (c *Curve) HashToPoint(msg, dst []byte) (*Point, error) {
+	u, err := c.hash_to_field(msg, dst, 2)
+	if err != nil {
+		return nil, err
+	}
+	Q0 := c.map_to_curve(u[0])
+	Q1 := c.map_to_curve(u[1])
+	return Q0.Add(Q1).ClearCofactor(), nil
+}
+
+// Security level k in bits of RFC 9380 Section 5, half the bit length of r.
+func (c *Curve) SecurityBits() int { return (c.r.BitLen() + 1) / 2 }
+
+/*
+hash_to_field of RFC 9380 Section 5.2 with m = 1: count elements of F(p),
+each reduced from L = ⌈(⌈log₂ p⌉ + k) / 8⌉ uniform bytes, so that their
+bias is at most 2⁻ᵏ. The bytes come from expand_message_xmd with SHA-512
+on edwards25519, as its suite requires, and from expand_message_xof with
+SHAKE256 elsewhere.
+*/
+func (c *Curve) hash_to_field(msg, dst []byte, count int) ([]*big.Int, error) {
+	L := (c.p.BitLen() + c.SecurityBits() + 7) / 8
+	expand := expand_message_xof
+	if c == ed25519Curve {
+		expand = expand_message_xmd
+	}
+	uniform, err := expand(msg, dst, count*L, c.SecurityBits())
+	if err != nil {
+		return nil, err
+	}
+	u := make([]*big.Int, count)
+	for i := range u {
+		u[i] = new(big.Int).SetBytes(uniform[i*L : (i+1)*L])
+		u[i].Mod(u[i], c.p)
+	}
+	return u, nil
+}
+
+/*
+expand_message_xof of RFC 9380 Section 5.3.2 with SHAKE256:
+
+	SHAKE256(msg ‖ I2OSP(n, 2) ‖ DST ‖ I2OSP(len(DST), 1), n)
+
+A DST longer than 255 bytes is first replaced by
+SHAKE256("H2C-OVERSIZE-DST-" ‖ DST, ⌈2k / 8⌉) as in Section 5.3.3.
+*/
+func expand_message_xof(msg, dst []byte, n, k int) ([]byte, error) {
+	if len(dst) == 0 {
+		return nil, ErrHashToCurveDST
+	}
+	if n > 65535 {
+		return nil, errors.New("hash to curve: requested output too long")
+	}
+	if len(dst) > 255 {
+		h := sha3.NewShake256()
+		h.Write([]byte("H2C-OVERSIZE-DST-"))
+		h.Write(dst)
+		dst = make([]byte, (2*k+7)/8)
+		h.Read(dst)
+	}
+	h := sha3.NewShake256()
+	h.Write(msg)
+	h.Write([]byte{byte(n >> 8), byte(n)})
+	h.Write(dst)
+	h.Write([]byte{byte(len(dst))})
+	out := make([]byte, n)
+	h.Read(out)
+	return out, nil
+}
+
+/*
+expand_message_xmd of RFC 9380 Section 5.3.1 with SHA-512, whose block
+is 128 bytes:
+
+	b₀ = SHA-512(Z_pad ‖ msg ‖ I2OSP(n, 2) ‖ 0 ‖ DST′)
+	b₁ = SHA-512(b₀ ‖ 1 ‖ DST′), bᵢ = SHA-512((b₀ ⊕ bᵢ₋₁) ‖ i ‖ DST′)
+
+with DST′ = DST ‖ I2OSP(len(DST), 1). A DST longer than 255 bytes is
+first replaced by SHA-512("H2C-OVERSIZE-DST-" ‖ DST) as in Section 5.3.3.
+*/
+func expand_message_xmd(msg, dst []byte, n, k int) ([]byte, error) {
+	if len(dst) == 0 {
+		return nil, ErrHashToCurveDST
+	}
+	ell := (n + sha512.Size - 1) / sha512.Size
+	if ell > 255 || n > 65535 {
+		return nil, errors.New("hash to curve: requested output too long")
+	}
+	if len(dst) > 255 {
+		h := sha512.Sum512(append([]byte("H2C-OVERSIZE-DST-"), dst...))
+		dst = h[:]
+	}
+	dstPrime := append(append([]byte(nil), dst...), byte(len(dst)))
+	h := sha512.New()
+	h.Write(make([]byte, sha512.BlockSize))
+	h.Write(msg)
+	h.Write([]byte{byte(n >> 8), byte(n), 0})
+	h.Write(dstPrime)
+	b0 := h.Sum(nil)
+	out := make([]byte, 0, ell*sha512.Size)
+	b := make([]byte, sha512.Size)
+	for i := 1; i <= ell; i++ {
+		for j := range b {
+			b[j] ^= b0[j]
+		}
+		h.Reset()
+		h.Write(b)
+		h.Write([]byte{byte(i)})
+		h.Write(dstPrime)
+		b = h.Sum(nil)
+		out = append(out, b...)
+	}
+	return out[:n], nil
+}
+
+// True iff v is 0 or a square mod p, by Euler's criterion.
+func (c *Curve) is_square(v *big.Int) bool {
+	e := new(big.Int).Rsh(c.p, 1) // (p − 1) / 2
+	return new(big.Int).Exp(v, e, c.p).Cmp(big.NewInt(1)) <= 0
+}
+
+// The non-square Z of Elligator 2 chosen as by find_z_ell2 of RFC 9380 Appendix H.3: 1, −1, 2, −2, … .
+func (c *Curve) elligator_z() *big.Int {
+	for ctr := int64(1); ; ctr++ {
+		for _, z := range []*big.Int{big.NewInt(ctr), new(big.Int).Sub(c.p, big.NewInt(ctr))} {
+			if !c.is_square(z) {
+				return z
+			}
+		}
+	}
+}
+
+/*
+Maps the field element u to the curve: with Elligator 2 to a Montgomery
+curve and from there to the Edwards curve. edwards25519 and Ed448 use
+the maps their suites fix, from curve25519 and curve448 of RFC 7748;
+other curves their own Montgomery form, see map_to_curve_generic.
+*/
+func (c *Curve) map_to_curve(u *big.Int) *Point {
+	switch c {
+	case ed25519Curve:
+		return c.map_to_edwards25519(u)
+	case ed448Curve:
+		return c.map_to_edwards448(u)
+	}
+	return c.map_to_curve_generic(u)
+}
+
+/*
+Elligator 2 of RFC 9380 Section 6.7.1: the point (s, t) of the Montgomery
+curve K·t² = s³ + J·s² + s that u maps to, with Z from elligator_z. It
+needs J² − 4 to be a non-square times K², which holds for every curve here.
+*/
+func (c *Curve) elligator2(u, J, K *big.Int) (*big.Int, *big.Int) {
+	p := c.p
+	mod := func(v *big.Int) *big.Int { return v.Mod(v, p) }
+	JK := mod(new(big.Int).Mul(J, c.inv0(K))) // J/K
+	KK := c.inv0(mod(new(big.Int).Mul(K, K))) // 1/K²
+	g := func(x *big.Int) *big.Int {          // x³ + (J/K)x² + x/K²
+		x2 := mod(new(big.Int).Mul(x, x))
+		gx := new(big.Int).Mul(x2, new(big.Int).Add(x, JK))
+		return mod(gx.Add(gx, new(big.Int).Mul(x, KK)))
+	}
+
+	// 1. - 2. x1 = −(J/K) / (1 + Z·u²), or −(J/K) where that is 0
+	zu2 := mod(new(big.Int).Mul(c.elligator_z(), new(big.Int).Mul(u, u)))
+	x1 := mod(new(big.Int).Mul(new(big.Int).Neg(JK), c.inv0(zu2.Add(zu2, big.NewInt(1)))))
+	if x1.Sign() == 0 {
+		x1 = mod(new(big.Int).Neg(JK))
+	}
+	// 3. - 7. take x1 if g(x1) is square, else x2 = −x1 − J/K
+	var x, y *big.Int
+	if gx1 := g(x1); c.is_square(gx1) {
+		x, y = x1, c.sqrt(gx1, 1)
+	} else {
+		x2 := mod(new(big.Int).Sub(new(big.Int).Neg(x1), JK))
+		x, y = x2, c.sqrt(g(x2), 0)
+	}
+	// 8. - 9. (s, t) = (x·K, y·K)
+	return mod(new(big.Int).Mul(x, K)), mod(new(big.Int).Mul(y, K))
+}
+
+// 1/v mod p, or 0 for v = 0 as inv0 of RFC 9380.
+func (c *Curve) inv0(v *big.Int) *big.Int {
+	if w := new(big.Int).ModInverse(v, c.p); w != nil {
+		return w
+	}
+	return new(big.Int)
+}
+
+/*
+The map of this package's suites: Elligator 2 to the Montgomery form
+with J = 2(a + d)/(a − d) and K = 4/(a − d), then the rational map of
+Appendix D.1 to (s/t, (s − 1)/(s + 1)), or the identity where that is
+undefined.
+*/
+func (c *Curve) map_to_curve_generic(u *big.Int) *Point {
+	p := c.p
+	mod := func(v *big.Int) *big.Int { return v.Mod(v, p) }
+	amd := c.inv0(mod(new(big.Int).Sub(c.a, c.d))) // 1/(a − d)
+	J := mod(new(big.Int).Mul(new(big.Int).Lsh(new(big.Int).Add(c.a, c.d), 1), amd))
+	K := mod(new(big.Int).Lsh(amd, 2))
+	s, t := c.elligator2(u, J, K)
+
+	s1 := mod(new(big.Int).Add(s, big.NewInt(1)))
+	if t.Sign() == 0 || s1.Sign() == 0 {
+		return c.IdPoint()
+	}
+	v := mod(new(big.Int).Mul(s, c.inv0(t)))
+	w := mod(new(big.Int).Mul(new(big.Int).Sub(s, big.NewInt(1)), c.inv0(s1)))
+	return c.NewPointXY(*v, *w)
+}
+
+/*
+The map of the edwards25519 suite of RFC 9380: Elligator 2 to
+curve25519, J = 486662 and K = 1, then the rational map of RFC 7748 to
+(√−486664·s/t, (s − 1)/(s + 1)) with the even root, or the identity where
+that is undefined.
+*/
+func (c *Curve) map_to_edwards25519(u *big.Int) *Point {
+	p := c.p
+	mod := func(v *big.Int) *big.Int { return v.Mod(v, p) }
+	s, t := c.elligator2(u, big.NewInt(486662), big.NewInt(1))
+
+	s1 := mod(new(big.Int).Add(s, big.NewInt(1)))
+	if t.Sign() == 0 || s1.Sign() == 0 {
+		return c.IdPoint()
+	}
+	c1 := c.sqrt(big.NewInt(-486664), 0)
+	v := mod(new(big.Int).Mul(new(big.Int).Mul(c1, s), c.inv0(t)))
+	w := mod(new(big.Int).Mul(new(big.Int).Sub(s, big.NewInt(1)), c.inv0(s1)))
+	return c.NewPointXY(*v, *w)
+}
+
+/*
+The map of the edwards448 suite of RFC 9380: Elligator 2 to curve448,
+J = 156326 and K = 1, then the 4-isogeny of RFC 7748 to
+
+	x = 4t(s² − 1) / (s⁴ − 2s² + 4t² + 1)
+	y = −(s⁵ − 2s³ − 4st² + s) / (s⁵ − 2s²t² − 2s³ − 2t² + s)
+
+or the identity where a denominator is 0.
+*/
+func (c *Curve) map_to_edwards448(u *big.Int) *Point {
+	p := c.p
+	mod := func(v *big.Int) *big.Int { return v.Mod(v, p) }
+	s, t := c.elligator2(u, big.NewInt(156326), big.NewInt(1))
+
+	s2 := mod(new(big.Int).Mul(s, s))
+	s3 := mod(new(big.Int).Mul(s2, s))
+	s4 := mod(new(big.Int).Mul(s2, s2))
+	s5 := mod(new(big.Int).Mul(s4, s))
+	t2 := mod(new(big.Int).Mul(t, t))
+	xn := new(big.Int).Mul(big.NewInt(4), t)
+	xn.Mul(xn, new(big.Int).Sub(s2, big.NewInt(1)))
+	xd := new(big.Int).Sub(s4, new(big.Int).Lsh(s2, 1))
+	xd.Add(xd, new(big.Int).Lsh(t2, 2)).Add(xd, big.NewInt(1))
+	yn := new(big.Int).Sub(s5, new(big.Int).Lsh(s3, 1))
+	yn.Sub(yn, new(big.Int).Lsh(mod(new(big.Int).Mul(s, t2)), 2)).Add(yn, s).Neg(yn)
+	yd := new(big.Int).Sub(s5, new(big.Int).Lsh(mod(new(big.Int).Mul(s2, t2)), 1))
+	yd.Sub(yd, new(big.Int).Lsh(s3, 1)).Sub(yd, new(big.Int).Lsh(t2, 1)).Add(yd, s)
+	if mod(xd).Sign() == 0 || mod(yd).Sign() == 0 {
+		return c.IdPoint()
+	}
+	x := mod(xn.Mul(xn, c.inv0(xd)))
+	y := mod(yn.Mul(yn, c.inv0(yd)))
+	return c.NewPointXY(*x, *y)
+}
@@ -0,0 +1,73 @@
+package sig
+
+import (
+	"crypto/sha512"
+	"errors"
+	"math/big"
+
+	"sig/edwards"
+)
+
+// Lengths of RFC 8032 Ed25519 keys and signatures.
+const (
+	Ed25519PublicKeySize = 32
+	Ed25519SeedSize      = 32
+	Ed25519SignatureSize = 64
+)
+
+var (
+	ErrEd25519Encoding   = errors.New("ed25519: invalid point encoding")
+	ErrEd25519SeedLength = errors.New("ed25519: seed must be 32 bytes")
+	ErrEd25519Domain     = errors.New("ed25519: signing domains are not supported, use Ed448")
+)
+
+// Encodes an edwards25519 point as in RFC 8032 Section 5.1.2, in 32 bytes.
+func ed25519_encode(P *edwards.Point) []byte { return P.EncodeRFC8032() }
+
+// Decodes an edwards25519 point as in RFC 8032 Section 5.1.3, or fails with ErrEd25519Encoding.
+func ed25519_decode(b []byte) (*edwards.Point, error) {
+	P, err := ed25519Curve.DecodeRFC8032(b)
+	if err != nil {
+		return nil, ErrEd25519Encoding
+	}
+	return P, nil
+}
+
+// SHA-512(parts…) as an integer mod r, for the nonce and challenge of RFC 8032 Section 5.1.
+func ed25519_hash(parts ...[]byte) *big.Int {
+	h := sha512.New()
+	for _, p := range parts {
+		h.Write(p)
+	}
+	v := fromLittleEndian(h.Sum(nil))
+	return v.Mod(v, ed25519Params.R)
+}
+
+/*
+Verifies the RFC 8032 Ed25519 signature sig = R ‖ S of msg under the
+encoded public key pub. Accepts iff S < r and [8][S]G = [8]R + [8][k]A
+for the challenge k = SHA-512(R ‖ A ‖ msg) mod r, which agrees with
+crypto/ed25519 on all signatures it produces.
+*/
+func VerifyEd25519(pub, msg, sig []byte) bool {
+	if len(sig) != Ed25519SignatureSize {
+		return false
+	}
+	A, err := ed25519_decode(pub)
+	if err != nil {
+		return false
+	}
+	R, err := ed25519_decode(sig[:Ed25519PublicKeySize])
+	if err != nil {
+		return false
+	}
+	S, err := ed25519Curve.DecodeScalar(sig[Ed25519PublicKeySize:])
+	if err != nil {
+		return false
+	}
+	k := ed25519_hash(sig[:Ed25519PublicKeySize], pub, msg)
+
+	lhs := ed25519Curve.BaseMul(S).ClearCofactor()
+	rhs := R.Add(A.SecMul(k)).ClearCofactor()
+	return lhs.Equals(rhs)
+}
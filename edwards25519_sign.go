@@ -0,0 +1,60 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto/sha512"
+	"math/big"
+)
+
+/*
+Expands a 32 byte Ed25519 seed as in RFC 8032 Section 5.1.5: the first
+half of SHA-512(seed), pruned, is the secret scalar s, and the second half
+is the prefix that keys the nonce derivation.
+*/
+func ed25519_expand(seed []byte) (*big.Int, []byte) {
+	h := sha512.Sum512(seed)
+	defer wipe_bytes(h[:Ed25519SeedSize])
+	h[0] &= 0xf8  // clear the cofactor bits
+	h[31] &= 0x7f // clear the top bit
+	h[31] |= 0x40 // set the second highest bit
+	prefix := append([]byte(nil), h[Ed25519SeedSize:]...)
+	wipe_bytes(h[Ed25519SeedSize:])
+	return fromLittleEndian(h[:Ed25519SeedSize]), prefix
+}
+
+// The encoded Ed25519 public key A = s × G of seed, or ErrEd25519SeedLength.
+func Ed25519PublicKey(seed []byte) ([]byte, error) {
+	if len(seed) != Ed25519SeedSize {
+		return nil, ErrEd25519SeedLength
+	}
+	s, prefix := ed25519_expand(seed)
+	defer wipe_int(s)
+	defer wipe_bytes(prefix)
+	return ed25519_encode(ed25519Curve.BaseMul(s)), nil
+}
+
+/*
+Signs msg with the Ed25519 seed as in RFC 8032 Section 5.1.6. The
+signature R ‖ S, with the nonce r = SHA-512(prefix ‖ msg) mod r,
+R = r × G and S = r + k·s mod r, equals the one crypto/ed25519 produces
+for the same seed.
+*/
+func SignEd25519(seed, msg []byte) ([]byte, error) {
+	if len(seed) != Ed25519SeedSize {
+		return nil, ErrEd25519SeedLength
+	}
+	s, prefix := ed25519_expand(seed)
+	defer wipe_int(s)
+	defer wipe_bytes(prefix)
+	A := ed25519_encode(ed25519Curve.BaseMul(s))
+
+	r := ed25519_hash(prefix, msg)
+	defer wipe_int(r)
+	R := ed25519_encode(ed25519Curve.BaseMul(r))
+	k := ed25519_hash(R, A, msg)
+
+	S := new(big.Int).Mul(k, s)
+	S.Add(S, r).Mod(S, ed25519Params.R)
+	return append(R, ed25519Curve.EncodeScalar(S)...), nil
+}
@@ -0,0 +1,68 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+func TestEd25519Signatures(t *testing.T) {
+
+	for i, v := range ed25519KnownAnswers {
+		seed, _ := hex.DecodeString(v.seed)
+		msg, _ := hex.DecodeString(v.msg)
+		pub, err := Ed25519PublicKey(seed)
+		if err != nil {
+			t.Fatalf("vector %d: %v", i, err)
+		}
+		if got := hex.EncodeToString(pub); got != v.pub {
+			t.Fatalf("vector %d: public key %s, want %s", i, got, v.pub)
+		}
+		sig, err := SignEd25519(seed, msg)
+		if err != nil {
+			t.Fatalf("vector %d: %v", i, err)
+		}
+		if got := hex.EncodeToString(sig); got != v.sig {
+			t.Fatalf("vector %d: signature %s, want %s", i, got, v.sig)
+		}
+	}
+
+	// interoperates with crypto/ed25519 in both directions
+	for i := 0; i < 4; i++ {
+		seed := make([]byte, Ed25519SeedSize)
+		rand.Read(seed)
+		msg := make([]byte, i*17)
+		rand.Read(msg)
+		std := ed25519.NewKeyFromSeed(seed)
+		pub, err := Ed25519PublicKey(seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := std.Public().(ed25519.PublicKey); !bytes.Equal(pub, want) {
+			t.Fatalf("seed %x: public key %x, crypto/ed25519 gives %x", seed, pub, want)
+		}
+		sig, err := SignEd25519(seed, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stdSig := ed25519.Sign(std, msg)
+		if !bytes.Equal(sig, stdSig) {
+			t.Fatalf("seed %x: signature %x, crypto/ed25519 gives %x", seed, sig, stdSig)
+		}
+		if !ed25519.Verify(pub, msg, sig) || !VerifyEd25519(pub, msg, stdSig) {
+			t.Fatalf("seed %x: signature does not verify", seed)
+		}
+	}
+	if _, err := SignEd25519(make([]byte, 31), nil); err != ErrEd25519SeedLength {
+		t.Fatalf("31 byte seed: %v, want %v", err, ErrEd25519SeedLength)
+	}
+	params := ed25519Curve.Params()
+	if a := new(big.Int).Sub(params.P, big.NewInt(1)); params.A.Cmp(a) != 0 {
+		t.Fatalf("a = %v, want −1", params.A)
+	}
+}
@@ -0,0 +1,242 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"sig/kmac"
+)
+
+const (
+	streamVersion        = 0x01
+	streamModePassphrase = 0x01     // file key wrapped by EncryptWithPassphrase
+	streamModeE222       = 0x02     // file key wrapped by EncryptE222
+	streamChunkSize      = 64 << 10 // bytes of plaintext per chunk
+	streamMaxChunkSize   = 16 << 20 // largest chunk size accepted on decryption
+	streamTagLength      = 32       // bytes
+	streamFileKeyLength  = 32       // bytes
+)
+
+var (
+	ErrStreamTruncated = errors.New("stream: encrypted stream ends before its final chunk")
+	ErrStreamKeyMode   = errors.New("stream: file key is not wrapped for this kind of key")
+)
+
+/*
+Streams encrypted in fixed size chunks, so that files of any size are
+encrypted and decrypted in memory bounded by one chunk. A random file key
+K is wrapped for the recipient, by passphrase or to an E222 public key,
+and stored in the header:
+
+	version (1 byte) || mode (1 byte) || chunk size (4 bytes) ||
+	len(wrapped K) (2 bytes) || wrapped K
+
+followed by the chunks. Every chunk but the last holds exactly chunk
+size bytes of plaintext; the last holds the rest, possibly nothing. With
+(ke || ka) ← KMACXOF256(K, header, 512, "F"), chunk i of plaintext m_i is
+
+	n_i ← i (8 bytes, big endian) || final (1 byte, 1 on the last chunk)
+	c_i ← KMACXOF256(ke, n_i, |m_i|, "FKE") ⊕ m_i
+	t_i ← KMACXOF256(ka, n_i || c_i, 256, "FKA")
+
+and written as c_i || t_i. Keys bound to the header keep it from being
+altered, the index keeps chunks from being reordered or dropped, and the
+final flag lets decryption tell a complete stream from a truncated one.
+
+Decryption writes each chunk as soon as its tag checks, before the end of
+the stream is reached: output of a call that fails must be discarded.
+*/
+type streamHeader struct {
+	mode      byte
+	chunkSize int
+	wrapped   []byte
+}
+
+func (h *streamHeader) bytes() []byte {
+	out := []byte{streamVersion, h.mode}
+	out = binary.BigEndian.AppendUint32(out, uint32(h.chunkSize))
+	out = binary.BigEndian.AppendUint16(out, uint16(len(h.wrapped)))
+	return append(out, h.wrapped...)
+}
+
+func read_stream_header(r io.Reader) (*streamHeader, []byte, error) {
+	fixed := make([]byte, 8)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return nil, nil, errors.New("stream: header truncated")
+	}
+	if fixed[0] != streamVersion {
+		return nil, nil, errors.New("stream: unsupported format")
+	}
+	h := &streamHeader{mode: fixed[1], chunkSize: int(binary.BigEndian.Uint32(fixed[2:6]))}
+	if h.chunkSize == 0 || h.chunkSize > streamMaxChunkSize {
+		return nil, nil, errors.New("stream: unsupported chunk size")
+	}
+	h.wrapped = make([]byte, binary.BigEndian.Uint16(fixed[6:8]))
+	if _, err := io.ReadFull(r, h.wrapped); err != nil {
+		return nil, nil, errors.New("stream: header truncated")
+	}
+	return h, append(fixed, h.wrapped...), nil
+}
+
+// Encrypts src to dst under pw, see streamHeader.
+func EncryptStreamWithPassphrase(dst io.Writer, src io.Reader, pw []byte) error {
+	return encrypt_stream(dst, src, streamChunkSize, streamModePassphrase, func(K []byte) ([]byte, error) {
+		return EncryptWithPassphrase(pw, K)
+	})
+}
+
+// Decrypts a stream of EncryptStreamWithPassphrase from src to dst.
+func DecryptStreamWithPassphrase(dst io.Writer, src io.Reader, pw []byte) error {
+	return decrypt_stream(dst, src, streamModePassphrase, func(wrapped []byte) ([]byte, error) {
+		return DecryptWithPassphrase(pw, wrapped)
+	})
+}
+
+// Encrypts src to dst for the holder of the E222 public key V, see streamHeader.
+func EncryptStreamE222(dst io.Writer, src io.Reader, V *E222) error {
+	return encrypt_stream(dst, src, streamChunkSize, streamModeE222, func(K []byte) ([]byte, error) {
+		return EncryptE222(V, K)
+	})
+}
+
+// Decrypts a stream of EncryptStreamE222 from src to dst with the secret key x.
+func DecryptStreamE222(dst io.Writer, src io.Reader, x *SecretScalar) error {
+	return decrypt_stream(dst, src, streamModeE222, func(wrapped []byte) ([]byte, error) {
+		return DecryptE222(x, wrapped)
+	})
+}
+
+// ke || ka ← KMACXOF256(K, header, 512, "F") as SecretBytes for the caller to wipe.
+func stream_keys(K, header []byte) *SecretBytes {
+	return NewSecretBytes(kmac.XOF256(K, header, 512, "F"))
+}
+
+// n_i of chunk i.
+func stream_nonce(i uint64, final bool) []byte {
+	n := binary.BigEndian.AppendUint64(nil, i)
+	if final {
+		return append(n, 1)
+	}
+	return append(n, 0)
+}
+
+func stream_tag(ka, nonce, c []byte) []byte {
+	return kmac.XOF256(ka, append(append([]byte{}, nonce...), c...), 8*streamTagLength, "FKA")
+}
+
+/*
+Encrypts src to dst in chunks of chunkSize bytes under a fresh file key,
+which wrap wraps for the header under mode. A chunk is known to be the
+last when src has nothing after it, found by peeking one byte ahead.
+*/
+func encrypt_stream(dst io.Writer, src io.Reader, chunkSize int, mode byte, wrap func(K []byte) ([]byte, error)) error {
+	K := make([]byte, streamFileKeyLength)
+	if _, err := rand.Read(K); err != nil {
+		return err
+	}
+	defer wipe_bytes(K)
+	wrapped, err := wrap(K)
+	if err != nil {
+		return err
+	}
+	header := (&streamHeader{mode: mode, chunkSize: chunkSize, wrapped: wrapped}).bytes()
+	if _, err := dst.Write(header); err != nil {
+		return err
+	}
+	keys := stream_keys(K, header)
+	defer keys.Wipe()
+	ke, ka := split_keys(keys.b)
+
+	in := bufio.NewReader(src)
+	buf := make([]byte, chunkSize)
+	defer wipe_bytes(buf)
+	for i := uint64(0); ; i++ {
+		n, err := io.ReadFull(in, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		final := n < chunkSize
+		if !final {
+			if _, err := in.Peek(1); err == io.EOF {
+				final = true
+			} else if err != nil {
+				return err
+			}
+		}
+		nonce := stream_nonce(i, final)
+		c := xor_bytes(kmac.XOF256(ke, nonce, 8*n, "FKE"), buf[:n])
+		if _, err := dst.Write(append(c, stream_tag(ka, nonce, c)...)); err != nil {
+			return err
+		}
+		if final {
+			return nil
+		}
+	}
+}
+
+/*
+Decrypts a stream of encrypt_stream from src to dst, unwrapping the file
+key with unwrap, which must accept keys wrapped under mode. A chunk that
+fails its tag fails with ErrDecryption, a stream that ends without its
+final chunk with ErrStreamTruncated.
+*/
+func decrypt_stream(dst io.Writer, src io.Reader, mode byte, unwrap func(wrapped []byte) ([]byte, error)) error {
+	in := bufio.NewReader(src)
+	h, header, err := read_stream_header(in)
+	if err != nil {
+		return err
+	}
+	if h.mode != mode {
+		return ErrStreamKeyMode
+	}
+	K, err := unwrap(h.wrapped)
+	if err != nil {
+		return err
+	}
+	defer wipe_bytes(K)
+	keys := stream_keys(K, header)
+	defer keys.Wipe()
+	ke, ka := split_keys(keys.b)
+
+	buf := make([]byte, h.chunkSize+streamTagLength)
+	for i := uint64(0); ; i++ {
+		n, err := io.ReadFull(in, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		if n < streamTagLength {
+			return ErrStreamTruncated
+		}
+		final := n < len(buf)
+		if !final {
+			if _, err := in.Peek(1); err == io.EOF {
+				final = true
+			} else if err != nil {
+				return err
+			}
+		}
+		c, t := buf[:n-streamTagLength], buf[n-streamTagLength:n]
+		nonce := stream_nonce(i, final)
+		if subtle.ConstantTimeCompare(t, stream_tag(ka, nonce, c)) != 1 {
+			if final && subtle.ConstantTimeCompare(t, stream_tag(ka, stream_nonce(i, false), c)) == 1 {
+				return ErrStreamTruncated
+			}
+			return ErrDecryption
+		}
+		m := xor_bytes(kmac.XOF256(ke, nonce, 8*len(c), "FKE"), c)
+		_, err = dst.Write(m)
+		wipe_bytes(m)
+		if err != nil {
+			return err
+		}
+		if final {
+			return nil
+		}
+	}
+}
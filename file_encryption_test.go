@@ -0,0 +1,112 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func TestStreamingFileEncryption(t *testing.T) {
+
+	pw := []byte("correct horse battery staple")
+	x_int := generateRandomBigInt()
+	x := NewSecretScalar(x_int)
+	V := e222Curve.BaseMul(x_int)
+
+	// sizes around the chunk boundary, under both kinds of key wrapping
+	const chunk = 1000
+	for _, size := range []int{0, 1, chunk - 1, chunk, chunk + 1, 3 * chunk, 3*chunk + 17} {
+		data := make([]byte, size)
+		rand.Read(data)
+		var ct bytes.Buffer
+		err := encrypt_stream(&ct, bytes.NewReader(data), chunk, streamModePassphrase, func(K []byte) ([]byte, error) {
+			return EncryptWithPassphrase(pw, K)
+		})
+		if err != nil {
+			t.Fatalf("%d bytes: %v", size, err)
+		}
+		var pt bytes.Buffer
+		if err := DecryptStreamWithPassphrase(&pt, bytes.NewReader(ct.Bytes()), pw); err != nil {
+			t.Fatalf("%d bytes: %v", size, err)
+		}
+		if !bytes.Equal(pt.Bytes(), data) {
+			t.Fatalf("%d bytes: decrypted %d bytes that differ", size, pt.Len())
+		}
+	}
+	data := make([]byte, 2*streamChunkSize+123)
+	rand.Read(data)
+	var ct bytes.Buffer
+	if err := EncryptStreamE222(&ct, iotest.HalfReader(bytes.NewReader(data)), V); err != nil {
+		t.Fatal(err)
+	}
+	var pt bytes.Buffer
+	if err := DecryptStreamE222(&pt, iotest.HalfReader(bytes.NewReader(ct.Bytes())), x); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pt.Bytes(), data) {
+		t.Fatalf("decrypted %d bytes that differ", pt.Len())
+	}
+
+	// streams for one kind of key do not open with the other, nor with the wrong key
+	if err := DecryptStreamWithPassphrase(io.Discard, bytes.NewReader(ct.Bytes()), pw); err != ErrStreamKeyMode {
+		t.Fatalf("passphrase on a key stream: %v, want %v", err, ErrStreamKeyMode)
+	}
+	if err := DecryptStreamE222(io.Discard, bytes.NewReader(ct.Bytes()), NewSecretScalar(generateRandomBigInt())); err != ErrDecryption {
+		t.Fatalf("wrong key: %v, want %v", err, ErrDecryption)
+	}
+
+	// truncation at a chunk boundary, inside a chunk, and tampering are all caught
+	var small bytes.Buffer
+	data = make([]byte, 3*chunk+17)
+	rand.Read(data)
+	err := encrypt_stream(&small, bytes.NewReader(data), chunk, streamModeE222, func(K []byte) ([]byte, error) { return EncryptE222(V, K) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := small.Bytes()
+	header := len(enc) - 4*streamTagLength - len(data)
+	truncations := []struct {
+		name string
+		n    int
+		want error
+	}{
+		{"at a chunk boundary", header + 2*(chunk+streamTagLength), ErrStreamTruncated},
+		{"after the header", header, ErrStreamTruncated},
+		{"inside the final chunk", len(enc) - 5, ErrDecryption},
+	}
+	for _, tr := range truncations {
+		if err := DecryptStreamE222(io.Discard, bytes.NewReader(enc[:tr.n]), x); err != tr.want {
+			t.Fatalf("truncated %s: %v, want %v", tr.name, err, tr.want)
+		}
+	}
+	for _, i := range []int{header + 5, header + chunk + streamTagLength + 1, len(enc) - 1} {
+		tampered := append([]byte(nil), enc...)
+		tampered[i] ^= 1
+		if err := DecryptStreamE222(io.Discard, bytes.NewReader(tampered), x); err != ErrDecryption {
+			t.Fatalf("byte %d changed: %v, want %v", i, err, ErrDecryption)
+		}
+	}
+
+	// swapping two chunks breaks their indexes, data after the final chunk is refused
+	swapped := append([]byte(nil), enc[:header]...)
+	swapped = append(swapped, enc[header+chunk+streamTagLength:header+2*(chunk+streamTagLength)]...)
+	swapped = append(swapped, enc[header:header+chunk+streamTagLength]...)
+	swapped = append(swapped, enc[header+2*(chunk+streamTagLength):]...)
+	if err := DecryptStreamE222(io.Discard, bytes.NewReader(swapped), x); err != ErrDecryption {
+		t.Fatalf("swapped chunks: %v, want %v", err, ErrDecryption)
+	}
+	if err := DecryptStreamE222(io.Discard, bytes.NewReader(append(append([]byte(nil), enc...), 0)), x); err == nil {
+		t.Fatal("trailing data decrypts")
+	}
+
+	// the chunk size is bound into the keys
+	altered := append([]byte(nil), enc...)
+	altered[5] ^= 1
+	if err := DecryptStreamE222(io.Discard, bytes.NewReader(altered), x); err == nil {
+		t.Fatal("altered chunk size decrypts")
+	}
+}
@@ -0,0 +1,54 @@
+package sig
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+)
+
+// Returned by FindSigner when no key of the table made the signature.
+var ErrNoSigner = errors.New("no key in table verifies this signature")
+
+/*
+Finds which of keys produced the encoded Schnorr signature over msg in domain by
+verifying against the public key of every key concurrently on a bounded pool of
+workers. Key tables hold E222 keys, so signatures on other curves match none.
+
+	return: index of the matching key, the lowest one if several keys match,
+	or ErrNoSigner if no key verifies the signature
+*/
+func FindSigner(keys []*KeyObj, domain string, encoded []byte, msg *[]byte) (int, error) {
+	if _, err := DecodeSignature(encoded); err != nil {
+		return -1, err
+	}
+
+	matches := make([]bool, len(keys))
+	indices := make(chan int)
+	var wg sync.WaitGroup
+
+	workers := runtime.NumCPU()
+	if workers > len(keys) {
+		workers = len(keys)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				matches[i] = keys[i] != nil && VerifySignature(keys[i].PubKey, domain, encoded, msg)
+			}
+		}()
+	}
+	for i := range keys {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	for i, ok := range matches {
+		if ok {
+			return i, nil
+		}
+	}
+	return -1, ErrNoSigner
+}
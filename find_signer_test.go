@@ -0,0 +1,45 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"testing"
+)
+
+func TestFindSignerTables(t *testing.T) {
+
+	msg := []byte("short message")
+	signer, s, e := sign_message_e222(&msg)
+	sig := EncodeSignature(&SchnorrSignature{Curve: curveE222, S: s, E: e})
+
+	table := func(size, at int) []*KeyObj {
+		keys := make([]*KeyObj, size)
+		for i := range keys {
+			keys[i] = &KeyObj{PubKey: e222Curve.GenPoint().SecMul(generateRandomBigInt())}
+		}
+		if at >= 0 {
+			keys[at] = &KeyObj{PubKey: signer}
+		}
+		return keys
+	}
+
+	dup := table(10, 7)
+	dup[3] = &KeyObj{PubKey: signer}
+	for _, tt := range []struct {
+		name  string
+		keys  []*KeyObj
+		index int
+		err   error
+	}{
+		{"empty table", table(0, -1), 0, ErrNoSigner},
+		{"single key", table(1, 0), 0, nil},
+		{"100 keys", table(100, 63), 63, nil},
+		{"no signer", table(100, -1), 0, ErrNoSigner},
+		{"duplicate signer", dup, 3, nil},
+	} {
+		i, err := FindSigner(tt.keys, "", sig, &msg)
+		if err != tt.err || (err == nil && i != tt.index) {
+			t.Fatalf("%s: FindSigner = %d, %v, want %d, %v", tt.name, i, err, tt.index, tt.err)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package sig
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+
+	"sig/edwards"
+)
+
+// Number of BIP39 words in Fingerprint.Words, 66 bits of the fingerprint.
+const fingerprintWords = 6
+
+/*
+SHA3-256 over the encoding a public key has in signatures of this
+package:
+
+	E222                   x ‖ y, as key files and KeyObj.Id
+	Ed25519, Ed448         the RFC 8032 encoding
+	E521                   the compressed encoding of Bytes
+	ECDSA                  04 ‖ x ‖ y of SEC 1
+
+so that the first 16 bytes are the key Id and the signer fingerprint of
+detached signatures. Two people compare fingerprints over a channel they
+trust, reading either the hex or the words.
+*/
+type Fingerprint [32]byte
+
+// The 64 hex digits of the fingerprint.
+func (f Fingerprint) String() string { return hex.EncodeToString(f[:]) }
+
+// The hex of the first 16 bytes, equal to KeyObj.Id for E222 keys.
+func (f Fingerprint) Short() string { return hex.EncodeToString(f[:16]) }
+
+/*
+The first 66 bits of the fingerprint as six words of the BIP39 English
+wordlist, 11 bits each, e.g. "legal winner thank year wave sausage": easier
+to read out over the phone than hex, and still long enough that forging a
+key with the same words takes 2⁶⁶ tries.
+*/
+func (f Fingerprint) Words() string {
+	words := make([]string, fingerprintWords)
+	for i := range words {
+		idx := 0
+		for j := i * 11; j < (i+1)*11; j++ {
+			idx = idx<<1 | int(f[j/8]>>(7-j%8)&1)
+		}
+		words[i] = bip39English[idx]
+	}
+	return strings.Join(words, " ")
+}
+
+// The fingerprint of the encoded public key pub.
+func fingerprint_of(pub []byte) Fingerprint { return sha3.Sum256(pub) }
+
+// The fingerprint of the point as a public key, see Fingerprint for the encoding.
+func point_fingerprint(P *edwards.Point) Fingerprint {
+	switch P.Curve() {
+	case e222Curve:
+		return fingerprint_of(e222_to_bytes(P))
+	case ed25519Curve, ed448Curve:
+		return fingerprint_of(P.EncodeRFC8032())
+	}
+	return fingerprint_of(P.Bytes())
+}
+
+// The fingerprint of the key's public key; its prefix is the key Id.
+func (key *KeyObj) Fingerprint() Fingerprint { return point_fingerprint(key.PubKey) }
+
+/*
+The fingerprint of pub, an *ecdsa.PublicKey or *edwards.Point. Points
+that are no valid public key fail with edwards.ErrInvalidPoint or the error of
+validatePublicKey, other types with ErrUnsupportedAlgorithm.
+*/
+func PublicKeyFingerprint(pub interface{}) (Fingerprint, error) {
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		if key == nil || key.Curve == nil || key.X == nil || key.Y == nil || !key.Curve.IsOnCurve(key.X, key.Y) {
+			return Fingerprint{}, edwards.ErrInvalidPoint
+		}
+		return fingerprint_of(uncompressed_point(key.X, key.Y, curve_width(key.Curve))), nil
+	case *edwards.Point:
+		if key == nil {
+			return Fingerprint{}, edwards.ErrInvalidPoint
+		}
+		if err := validate_public_key(key); err != nil {
+			return Fingerprint{}, err
+		}
+		return point_fingerprint(key), nil
+	}
+	return Fingerprint{}, ErrUnsupportedAlgorithm
+}
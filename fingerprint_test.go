@@ -0,0 +1,119 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sig/edwards"
+)
+
+// Fingerprints extend the key Id and the signer fingerprint of detached signatures, and read as words.
+func TestKeyFingerprints(t *testing.T) {
+
+	key, err := GenerateKeyObj("alice", []byte("fingerprint test passphrase"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := key.Fingerprint()
+	g, err := PublicKeyFingerprint(key.PubKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g != f {
+		t.Fatalf("public key fingerprint %v, key object's %v", g, f)
+	}
+	if f.Short() != key.Id || !strings.HasPrefix(f.String(), key.Id) || len(f.String()) != 64 {
+		t.Fatalf("fingerprint %v, short %v, for key Id %v", f, f.Short(), key.Id)
+	}
+	words := strings.Fields(f.Words())
+	if len(words) != 6 {
+		t.Fatalf("fingerprint reads as %d words, want 6", len(words))
+	}
+	for _, w := range words {
+		if _, ok := bip39Index[w]; !ok {
+			t.Fatalf("%q is not a BIP39 word", w)
+		}
+	}
+	other, err := GenerateKeyObj("bob", []byte("another fingerprint passphrase"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Fingerprint().Words() == f.Words() {
+		t.Fatal("two keys read as the same words")
+	}
+
+	// the six words are the top 66 bits, 11 per word
+	var top Fingerprint
+	top[0], top[1] = 0xff, 0xe0
+	if got, want := top.Words(), "zoo abandon abandon abandon abandon abandon"; got != want {
+		t.Fatalf("words %q, want %q", got, want)
+	}
+
+	// ECDSA and Edwards keys hash the encoding their signatures carry
+	ecKey := NewECDSAPrivateKey(big.NewInt(7))
+	pub := ecKey.PublicKey()
+	ecF, err := PublicKeyFingerprint(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ecF != ecKey.Fingerprint() {
+		t.Fatalf("ECDSA fingerprint %v, private key's %v", ecF, ecKey.Fingerprint())
+	}
+	if want := key_fingerprint(elliptic.Marshal(elliptic.P256(), pub.X, pub.Y)); ecF.Short() != want {
+		t.Fatalf("ECDSA fingerprint %v, signer fingerprint %v", ecF.Short(), want)
+	}
+	seed := bytes.Repeat([]byte{3}, Ed25519SeedSize)
+	edPub, err := Ed25519PublicKey(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	A, err := ed25519_decode(edPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edF, err := PublicKeyFingerprint(A)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := key_fingerprint(edPub); edF.Short() != want {
+		t.Fatalf("Ed25519 fingerprint %v, signer fingerprint %v", edF.Short(), want)
+	}
+	if _, err := PublicKeyFingerprint(e222Curve.IdPoint()); err == nil {
+		t.Fatal("identity point has a fingerprint")
+	}
+	if _, err := PublicKeyFingerprint("key"); err != ErrUnsupportedAlgorithm {
+		t.Fatalf("string key: %v, want %v", err, ErrUnsupportedAlgorithm)
+	}
+	if _, err := PublicKeyFingerprint((*ecdsa.PublicKey)(nil)); err != edwards.ErrInvalidPoint {
+		t.Fatalf("nil key: %v, want %v", err, edwards.ErrInvalidPoint)
+	}
+
+	// detached signatures report the signer's words once the fingerprint checks out
+	doc := filepath.Join(t.TempDir(), "doc.txt")
+	if err := os.WriteFile(doc, []byte("fingerprinted"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := sign_detached_ecdsa(ecKey, "", doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigPath := DetachedSigPath(doc)
+	if err := WriteDetachedSignature(sigPath, c); err != nil {
+		t.Fatal(err)
+	}
+	res, err := VerifyDetachedSignature(doc, sigPath, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Valid || res.Words != ecF.Words() {
+		t.Fatalf("valid %v, words %q, want %q", res.Valid, res.Words, ecF.Words())
+	}
+}
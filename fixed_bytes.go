@@ -0,0 +1,52 @@
+package sig
+
+import (
+	"crypto/elliptic"
+	"math/big"
+)
+
+// Canonical big endian widths of encoded coordinates and scalars.
+const (
+	e222Width = 28 // E222 coordinates and scalars mod n
+	p256Width = 32 // P-256 coordinates and scalars
+)
+
+/*
+Encodes v as exactly size big endian bytes, keeping leading zeros that
+v.Bytes() would drop. Panics if v does not fit, which indicates a value
+that was never reduced into range.
+*/
+func toFixedBytes(v *big.Int, size int) []byte {
+	out := make([]byte, size)
+	v.FillBytes(out)
+	return out
+}
+
+// Width of the scalars and coordinates of a NIST curve: 32, 48 and 66 bytes for P-256, P-384 and P-521.
+func curve_width(curve elliptic.Curve) int {
+	return (curve.Params().BitSize + 7) / 8
+}
+
+// Decodes a big endian byte string of any width.
+func fromFixedBytes(b []byte) *big.Int {
+	return new(big.Int).SetBytes(b)
+}
+
+// Encodes v as exactly size little endian bytes, as RFC 8032 encodes
+// scalars and coordinates.
+func toLittleEndian(v *big.Int, size int) []byte {
+	out := toFixedBytes(v, size)
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+// Decodes a little endian byte string of any width.
+func fromLittleEndian(b []byte) *big.Int {
+	be := make([]byte, len(b))
+	for i := range b {
+		be[len(b)-1-i] = b[i]
+	}
+	return new(big.Int).SetBytes(be)
+}
@@ -2,6 +2,8 @@ module sig
 
 go 1.19
 
-require golang.org/x/crypto v0.5.0
-
-require golang.org/x/sys v0.4.0 // indirect
+require (
+	golang.org/x/crypto v0.5.0
+	golang.org/x/sys v0.4.0
+	golang.org/x/text v0.6.0
+)
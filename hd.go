@@ -0,0 +1,266 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"sig/edwards"
+	"sig/kmac"
+)
+
+// Indices from HardenedIndex on derive hardened children, written i' or iH in paths.
+const HardenedIndex = 1 << 31
+
+var (
+	ErrHDPath     = errors.New("hd: malformed derivation path")
+	ErrHDHardened = errors.New("hd: Edwards keys only derive hardened children")
+	ErrHDDepth    = errors.New("hd: derivation deeper than 255 levels")
+)
+
+/*
+A node of a key tree of BIP32 as generalized by SLIP-0010: a 32 byte
+secret and a 32 byte chain code, from which the children at indices
+0 … 2³²−1 are derived. The secret is the ECDSA scalar on P-256 and
+secp256k1, the Ed25519 seed of SignEd25519 on Ed25519 and the seed of
+E222Key on E222; the Edwards curves only have hardened children.
+Hardened children,
+
+	I ← HMAC-SHA512(c, 0x00 ‖ k ‖ ser32(i))
+
+depend on the parent secret k; normal ones,
+
+	I ← HMAC-SHA512(c, compressed(k × G) ‖ ser32(i))
+
+on the parent public key alone. The child secret is IL + k mod n on the
+ECDSA curves and IL on the Edwards curves, its chain code IR.
+*/
+type HDKey struct {
+	ChainCode []byte
+	Depth     uint8
+	Index     uint32 // of the key among its parent's children, 0 for the master key
+
+	curve   elliptic.Curve // nil for the Edwards curves
+	edwards *edwards.Curve // ed25519Curve or e222Curve when curve is nil
+	secret  *SecretBytes
+}
+
+/*
+Derives the master key of seed on curve, elliptic.P256(), secp256k1.Curve(),
+ed25519Curve or e222Curve, as SLIP-0010 does:
+
+	I ← HMAC-SHA512(name, seed)
+
+with name "Nist256p1 seed", "Bitcoin seed", "ed25519 seed" or, as
+SLIP-0010 names no E222 curve, "e222 seed". The seed, 16 to 64 bytes, is
+typically the BIP39 seed of MnemonicToSeed; the master key on secp256k1
+is the BIP32 one. Other curves fail with ErrUnsupportedAlgorithm.
+*/
+func NewHDMasterKey(curve interface{}, seed []byte) (*HDKey, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, errors.New("hd: seed must be 16 to 64 bytes")
+	}
+	var name string
+	var ec elliptic.Curve
+	var ed *edwards.Curve
+	switch c := curve.(type) {
+	case elliptic.Curve:
+		switch c.Params().Name {
+		case "P-256":
+			name, ec = "Nist256p1 seed", c
+		case "secp256k1":
+			name, ec = "Bitcoin seed", c
+		default:
+			return nil, ErrUnsupportedAlgorithm
+		}
+	case *edwards.Curve:
+		switch c {
+		case ed25519Curve:
+			name, ed = "ed25519 seed", c
+		case e222Curve:
+			name, ed = "e222 seed", c
+		default:
+			return nil, ErrUnsupportedAlgorithm
+		}
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	I := hd_hmac([]byte(name), seed)
+	// a scalar of 0 or beyond n is replaced by hashing I again
+	for ec != nil && !hd_valid_scalar(ec, I[:32]) {
+		next := hd_hmac([]byte(name), I)
+		wipe_bytes(I)
+		I = next
+	}
+	return &HDKey{ChainCode: I[32:], curve: ec, edwards: ed, secret: NewSecretBytes(I[:32])}, nil
+}
+
+/*
+Derives the child at index, hardened from HardenedIndex on. Should IL
+be n or more, or the child scalar 0, on an ECDSA curve, I is recomputed
+from 0x01 ‖ IR ‖ ser32(i) as SLIP-0010 prescribes, where BIP32 would
+skip the index; the chance of that is below 2⁻¹²⁷.
+*/
+func (k *HDKey) Child(index uint32) (*HDKey, error) {
+	parent, err := k.secret.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	if k.Depth == 255 {
+		return nil, ErrHDDepth
+	}
+	if k.curve == nil && index < HardenedIndex {
+		return nil, ErrHDHardened
+	}
+	var data []byte
+	if index >= HardenedIndex {
+		data = append([]byte{0}, parent...)
+	} else {
+		x, y := k.curve.ScalarBaseMult(parent)
+		data = elliptic.MarshalCompressed(k.curve, x, y)
+	}
+	data = binary.BigEndian.AppendUint32(data, index)
+	I := hd_hmac(k.ChainCode, data)
+	wipe_bytes(data)
+	child := &HDKey{ChainCode: I[32:], Depth: k.Depth + 1, Index: index, curve: k.curve, edwards: k.edwards}
+	if k.curve == nil {
+		child.secret = NewSecretBytes(I[:32])
+		return child, nil
+	}
+
+	n := k.curve.Params().N
+	kpar := new(big.Int).SetBytes(parent)
+	defer wipe_int(kpar)
+	for {
+		IL := new(big.Int).SetBytes(I[:32])
+		ki := IL.Add(IL, kpar)
+		ki.Mod(ki, n)
+		if hd_valid_scalar(k.curve, I[:32]) && ki.Sign() != 0 {
+			child.ChainCode = I[32:]
+			child.secret = NewSecretBytes(toFixedBytes(ki, 32))
+			wipe_bytes(I[:32])
+			wipe_int(ki)
+			return child, nil
+		}
+		wipe_int(ki)
+		retry := binary.BigEndian.AppendUint32(append([]byte{1}, I[32:]...), index)
+		wipe_bytes(I)
+		I = hd_hmac(k.ChainCode, retry)
+	}
+}
+
+/*
+Derives the key at path below k, as in "m/44'/0'/0'/0/7": "m" stands for
+a copy of k, and each component is an index below 2³¹, hardened when
+followed by ', h or H.
+*/
+func (k *HDKey) Derive(path string) (*HDKey, error) {
+	parts := strings.Split(path, "/")
+	if parts[0] != "m" {
+		return nil, ErrHDPath
+	}
+	b, err := k.secret.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	key := &HDKey{ChainCode: append([]byte(nil), k.ChainCode...), Depth: k.Depth, Index: k.Index,
+		curve: k.curve, edwards: k.edwards, secret: NewSecretBytes(append([]byte(nil), b...))}
+	for _, part := range parts[1:] {
+		var offset uint32
+		if trimmed := strings.TrimRight(part, "'hH"); len(trimmed) == len(part)-1 {
+			part, offset = trimmed, HardenedIndex
+		}
+		i, err := strconv.ParseUint(part, 10, 31)
+		if err != nil {
+			key.Destroy()
+			return nil, ErrHDPath
+		}
+		child, err := key.Child(uint32(i) + offset)
+		key.Destroy()
+		if err != nil {
+			return nil, err
+		}
+		key = child
+	}
+	return key, nil
+}
+
+// The signing key of an ECDSA node with the nonce cache enabled; Edwards nodes fail with ErrUnsupportedAlgorithm.
+func (k *HDKey) ECDSAPrivateKey() (*ECDSAPrivateKey, error) {
+	if k.curve == nil {
+		return nil, ErrUnsupportedAlgorithm
+	}
+	b, err := k.secret.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	key := NewECDSAPrivateKey(new(big.Int).SetBytes(b))
+	key.Curve = k.curve
+	return key, nil
+}
+
+// A copy of the Ed25519 seed of an Ed25519 node for the caller to wipe; other nodes fail with ErrUnsupportedAlgorithm.
+func (k *HDKey) Ed25519Seed() ([]byte, error) {
+	if k.edwards != ed25519Curve {
+		return nil, ErrUnsupportedAlgorithm
+	}
+	b, err := k.secret.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), b...), nil
+}
+
+/*
+The E222 key pair of an E222 node, the secret scalar expanded from the
+node secret as passphrases are,
+
+	s ← KMACXOF256(secret, "", 448, "HD") mod r
+	V ← s × G
+
+so that s is uniform mod r. Other nodes fail with ErrUnsupportedAlgorithm.
+*/
+func (k *HDKey) E222Key() (*SecretScalar, *E222, error) {
+	if k.edwards != e222Curve {
+		return nil, nil, ErrUnsupportedAlgorithm
+	}
+	b, err := k.secret.Bytes()
+	if err != nil {
+		return nil, nil, err
+	}
+	derived := NewSecretBytes(kmac.XOF256(b, nil, 448, "HD"))
+	defer derived.Wipe()
+	s := fromFixedBytes(derived.b)
+	defer wipe_int(s)
+	s.Mod(s, e222Params.R)
+	if err := check_scalar(s); err != nil {
+		return nil, nil, err
+	}
+	return NewSecretScalar(s), e222Curve.BaseMul(s), nil
+}
+
+// Wipes the secret; the chain code alone does not reveal it.
+func (k *HDKey) Destroy() {
+	k.secret.Wipe()
+}
+
+func hd_hmac(key, data []byte) []byte {
+	mac := hmac.New(sha512.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// True iff b parses to a scalar in [1, n−1].
+func hd_valid_scalar(curve elliptic.Curve, b []byte) bool {
+	s := new(big.Int).SetBytes(b)
+	defer wipe_int(s)
+	return s.Sign() != 0 && s.Cmp(curve.Params().N) < 0
+}
@@ -0,0 +1,194 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"sig/secp256k1"
+)
+
+// HD derivation matches the vectors of BIP32 and SLIP-0010, and derived keys sign.
+func TestHDKeyDerivation(t *testing.T) {
+
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	for _, v := range []struct {
+		curve            interface{}
+		path, key, chain string
+	}{
+		{secp256k1.Curve(), "m/0'/1", "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368",
+			"2a7857631386ba23dacac34180dd1983734e444fdbf774041578e9b6adb37c19"},
+		{secp256k1.Curve(), "m/0H/1/2h", "cbce0d719ecf7431d88e6a89fa1483e02e35092af60c042b1df2ff59fa424dca",
+			"04466b9cc8e161e966409ca52986c584f07e9dc81f735db683c3ff6ec7b1503f"},
+		{elliptic.P256(), "m", "612091aaa12e22dd2abef664f8a01a82cae99ad7441b7ef8110424915c268bc2",
+			"beeb672fe4621673f722f38529c07392fecaa61015c80c34f29ce8b41b3cb6ea"},
+		{elliptic.P256(), "m/0'", "6939694369114c67917a182c59ddb8cafc3004e63ca5d3b84403ba8613debc0c",
+			"3460cea53e6a6bb5fb391eeef3237ffd8724bf0a40e94943c98b83825342ee11"},
+		{ed25519Curve, "m/0'", "68e0fe46dfb67e368c75379acec591dad19df3cde26e63b93a8e704f1dade7a3",
+			"8b59aa11380b624e81507a27fedda59fea6d0b779a778918a2fd3590e16e9c69"},
+		{ed25519Curve, "m/0'/1'", "b1d0bad404bf35da785a64ca1ac54b2617211d2777696fbffaf208f746ae84f2",
+			"a320425f77d1b5c2505a6b1b27382b37368ee640e3557c315416801243552f14"},
+	} {
+		master, err := NewHDMasterKey(v.curve, seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		k, err := master.Derive(v.path)
+		if err != nil {
+			t.Fatalf("%s: %v", v.path, err)
+		}
+		if got := hex.EncodeToString(k.secret.b); got != v.key {
+			t.Fatalf("%s: key %s, want %s", v.path, got, v.key)
+		}
+		if got := hex.EncodeToString(k.ChainCode); got != v.chain {
+			t.Fatalf("%s: chain code %s, want %s", v.path, got, v.chain)
+		}
+		if want := strings.Count(v.path, "/"); int(k.Depth) != want {
+			t.Fatalf("%s: depth %d, want %d", v.path, k.Depth, want)
+		}
+		k.Destroy()
+		if _, err := k.Child(0); err != ErrDestroyedSecret {
+			t.Fatalf("%s destroyed: %v, want %v", v.path, err, ErrDestroyedSecret)
+		}
+		if master.secret.b[0] == 0 {
+			t.Fatalf("%s: destroying a child wipes its parent", v.path)
+		}
+	}
+
+	// keys derived from a mnemonic sign and verify
+	bip39, err := MnemonicToSeed([]byte(strings.Repeat("abandon ", 11)+"about"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bip39.Wipe()
+	master, err := NewHDMasterKey(elliptic.P256(), bip39.b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	child, err := master.Derive("m/44'/0'/0'/0/7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if child.Index != 7 {
+		t.Fatalf("child index %d, want 7", child.Index)
+	}
+	key, err := child.ECDSAPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte("hd"))
+	r, s, err := key.SignDigest(digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifyDigest(key.PublicKey(), digest[:], r, s) {
+		t.Fatal("derived ECDSA key's signature does not verify")
+	}
+	edMaster, err := NewHDMasterKey(ed25519Curve, bip39.b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edChild, err := edMaster.Child(HardenedIndex + 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edSeed, err := edChild.Ed25519Seed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := Ed25519PublicKey(edSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := SignEd25519(edSeed, []byte("hd"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifyEd25519(pub, []byte("hd"), sig) {
+		t.Fatal("derived Ed25519 key's signature does not verify")
+	}
+
+	e222Master, err := NewHDMasterKey(e222Curve, bip39.b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e222Child, err := e222Master.Derive("m/44'/7'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, V, err := e222Child.E222Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := e222Master.Derive("m/44'/7'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, V2, err := again.E222Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sibling, err := e222Master.Derive("m/44'/8'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, V3, err := sibling.E222Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !V.Equals(V2) {
+		t.Fatal("E222 derivation is not deterministic")
+	}
+	if V.Equals(V3) {
+		t.Fatal("sibling E222 keys are equal")
+	}
+	msg := []byte("hd")
+	se, e, err := sign_with_key_e222(x, "", &msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verify_sig_e222(V, se, e, &msg) {
+		t.Fatal("derived E222 key's signature does not verify")
+	}
+	if _, err := e222Master.Child(3); err != ErrHDHardened {
+		t.Fatalf("normal E222 child: %v, want %v", err, ErrHDHardened)
+	}
+	if _, err := e222Child.Ed25519Seed(); err != ErrUnsupportedAlgorithm {
+		t.Fatalf("Ed25519 seed of an E222 key: %v, want %v", err, ErrUnsupportedAlgorithm)
+	}
+	if _, _, err := edChild.E222Key(); err != ErrUnsupportedAlgorithm {
+		t.Fatalf("E222 key of an Ed25519 key: %v, want %v", err, ErrUnsupportedAlgorithm)
+	}
+
+	// Ed25519 has no normal children; malformed paths and other curves fail
+	if _, err := edMaster.Child(3); err != ErrHDHardened {
+		t.Fatalf("normal Ed25519 child: %v, want %v", err, ErrHDHardened)
+	}
+	if _, err := edMaster.Derive("m/0"); err != ErrHDHardened {
+		t.Fatalf("normal Ed25519 path: %v, want %v", err, ErrHDHardened)
+	}
+	if _, err := edChild.ECDSAPrivateKey(); err != ErrUnsupportedAlgorithm {
+		t.Fatalf("ECDSA key of an Ed25519 key: %v, want %v", err, ErrUnsupportedAlgorithm)
+	}
+	if _, err := child.Ed25519Seed(); err != ErrUnsupportedAlgorithm {
+		t.Fatalf("Ed25519 seed of an ECDSA key: %v, want %v", err, ErrUnsupportedAlgorithm)
+	}
+	for _, path := range []string{"", "0/1", "m/", "m/1''", "m/-1", "m/+1", "m/2147483648", "m/x"} {
+		if _, err := master.Derive(path); err != ErrHDPath {
+			t.Fatalf("path %q: %v, want %v", path, err, ErrHDPath)
+		}
+	}
+	if _, err := NewHDMasterKey(elliptic.P384(), seed); err != ErrUnsupportedAlgorithm {
+		t.Fatalf("P-384: %v, want %v", err, ErrUnsupportedAlgorithm)
+	}
+	if _, err := NewHDMasterKey(ed448Curve, seed); err != ErrUnsupportedAlgorithm {
+		t.Fatalf("Ed448: %v, want %v", err, ErrUnsupportedAlgorithm)
+	}
+	if _, err := NewHDMasterKey(elliptic.P256(), seed[:15]); err == nil {
+		t.Fatal("15 byte seed makes a master key")
+	}
+}
@@ -0,0 +1,99 @@
+package sig
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Message catalogs shipped with the program, one JSON object of key → format string per locale.
+//
+//go:embed locales/*.json
+var localeFS embed.FS
+
+// Locale whose catalog defines every message and backs up all others.
+const fallbackLocale = "en"
+
+type Catalog map[string]string
+
+var (
+	fallbackCatalog = must_load_catalog(fallbackLocale)
+	activeCatalog   = select_catalog(os.Getenv("LANG"))
+)
+
+// Parses a catalog file, which must be a non-empty JSON object of strings.
+func parse_catalog(data []byte) (Catalog, error) {
+	var c Catalog
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("malformed message catalog: %w", err)
+	}
+	if len(c) == 0 {
+		return nil, errors.New("empty message catalog")
+	}
+	return c, nil
+}
+
+// Loads the shipped catalog for locale, e.g. "de".
+func load_catalog(locale string) (Catalog, error) {
+	data, err := localeFS.ReadFile("locales/" + locale + ".json")
+	if err != nil {
+		return nil, err
+	}
+	return parse_catalog(data)
+}
+
+func must_load_catalog(locale string) Catalog {
+	c, err := load_catalog(locale)
+	if err != nil {
+		panic("i18n: " + err.Error())
+	}
+	return c
+}
+
+// Locales with a shipped catalog.
+func shipped_locales() []string {
+	entries, _ := localeFS.ReadDir("locales")
+	var locales []string
+	for _, e := range entries {
+		locales = append(locales, strings.TrimSuffix(e.Name(), ".json"))
+	}
+	return locales
+}
+
+// Language part of a LANG value such as "de_DE.UTF-8".
+func locale_from_lang(lang string) string {
+	lang = strings.SplitN(lang, ".", 2)[0]
+	lang = strings.SplitN(lang, "@", 2)[0]
+	return strings.ToLower(strings.SplitN(lang, "_", 2)[0])
+}
+
+// Catalog for the locale named by lang, or the fallback catalog if none is shipped.
+func select_catalog(lang string) Catalog {
+	c, err := load_catalog(locale_from_lang(lang))
+	if err != nil {
+		return fallbackCatalog
+	}
+	return c
+}
+
+/*
+Looks up key in c, then in the fallback catalog, and formats the message
+with args. An unknown key is returned as is so that it stays visible.
+*/
+func (c Catalog) T(key string, args ...interface{}) string {
+	format, ok := c[key]
+	if !ok {
+		if format, ok = fallbackCatalog[key]; !ok {
+			return key
+		}
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Translates key in the locale selected by LANG.
+func T(key string, args ...interface{}) string {
+	return activeCatalog.T(key, args...)
+}
@@ -0,0 +1,75 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"testing"
+)
+
+// LANG selects a shipped catalog; missing locales and keys fall back to English.
+func TestCatalogFallback(t *testing.T) {
+
+	for lang, want := range map[string]string{"de_DE.UTF-8": "de", "C": "c"} {
+		if locale := locale_from_lang(lang); locale != want {
+			t.Fatalf("locale_from_lang(%q) = %q, want %q", lang, locale, want)
+		}
+	}
+	for _, tt := range []struct {
+		catalog Catalog
+		key     string
+		arg     interface{}
+		want    string
+	}{
+		{select_catalog("de_DE.UTF-8"), "verify.result", true, "Verifiziert: true"},
+		{select_catalog("xx_YY"), "verify.result", true, "Verified: true"},
+		{select_catalog(""), "verify.result", false, "Verified: false"},
+		{Catalog{"verify.result": "OK? %v"}, "verify.result", true, "OK? true"},
+		{Catalog{"verify.result": "OK? %v"}, "verify.signed_by", "abc", "Signed by: abc"},
+	} {
+		if got := tt.catalog.T(tt.key, tt.arg); got != tt.want {
+			t.Fatalf("T(%q, %v) = %q, want %q", tt.key, tt.arg, got, tt.want)
+		}
+	}
+	if got := (Catalog{"verify.result": "OK? %v"}).T("no.such.key"); got != "no.such.key" {
+		t.Fatalf("unknown key gives %q", got)
+	}
+}
+
+func TestMalformedCatalogs(t *testing.T) {
+
+	for _, data := range []string{"", "{", "[]", `{"a": 1}`, `{"a": ["b"]}`, "{}", "null"} {
+		if _, err := parse_catalog([]byte(data)); err == nil {
+			t.Fatalf("catalog %q parses", data)
+		}
+	}
+	c, err := parse_catalog([]byte(`{"a": "b"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c["a"] != "b" {
+		t.Fatalf("catalog maps a to %q, want b", c["a"])
+	}
+}
+
+// Every shipped catalog defines every message of the fallback catalog, and nothing else.
+func TestCatalogsComplete(t *testing.T) {
+
+	locales := shipped_locales()
+	if len(locales) < 2 {
+		t.Fatalf("shipped locales %v", locales)
+	}
+	for _, locale := range locales {
+		c, err := load_catalog(locale)
+		if err != nil {
+			t.Fatalf("%s: %v", locale, err)
+		}
+		for key := range fallbackCatalog {
+			if _, ok := c[key]; !ok {
+				t.Errorf("catalog %s lacks %s", locale, key)
+			}
+		}
+		if len(c) != len(fallbackCatalog) {
+			t.Errorf("catalog %s has %d messages, want %d", locale, len(c), len(fallbackCatalog))
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package sig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+
+	"sig/edwards"
+	"sig/secp256k1"
+)
+
+var (
+	ErrJWK        = errors.New("jwk: malformed or unsupported key")
+	ErrJWKPrivate = errors.New("jwk: key holds a private part, read it with PrivateKeyFromJWK")
+)
+
+// The ECDSA curve of an EC "crv": RFC 7518 Section 6.2.1.1 and, for secp256k1, RFC 8812 Section 3.1.
+func jwk_ec_curve(crv string) elliptic.Curve {
+	switch crv {
+	case "P-256":
+		return elliptic.P256()
+	case "P-384":
+		return elliptic.P384()
+	case "P-521":
+		return elliptic.P521()
+	case "secp256k1":
+		return secp256k1.Curve()
+	}
+	return nil
+}
+
+/*
+The Edwards curve of an OKP "crv". Ed25519 and Ed448 are those of RFC
+8037 Section 2; E222 and E521 are this package's own, which other JOSE
+tooling does not know.
+*/
+func jwk_okp_curve(crv string) *edwards.Curve {
+	for _, c := range []*edwards.Curve{ed25519Curve, ed448Curve, e222Curve, e521Curve} {
+		if jwk_okp_name(c) == crv {
+			return c
+		}
+	}
+	return nil
+}
+
+func jwk_okp_name(c *edwards.Curve) string {
+	switch c {
+	case ed25519Curve:
+		return "Ed25519"
+	case ed448Curve, e222Curve, e521Curve:
+		return c.Name()
+	}
+	return ""
+}
+
+/*
+Encodes a public key as a JWK, one of
+
+	*ecdsa.PublicKey {"kty":"EC","crv":…,"x":…,"y":…} with x and y big
+	                 endian in the width of the curve, RFC 7518 Section 6.2
+	*edwards.Point    {"kty":"OKP","crv":…,"x":…} with x the encoding of
+	                 EncodeRFC8032, RFC 8037 Section 2
+
+on the curves of jwk_ec_curve and jwk_okp_curve, all base64url without
+padding. Private keys are encoded with ECDSAPrivateKey.ToJWK.
+*/
+func ToJWK(pub interface{}) ([]byte, error) {
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		jwk, err := ec_jwk(key)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(jwk)
+	case *edwards.Point:
+		if key == nil || jwk_okp_name(key.Curve()) == "" {
+			return nil, ErrUnsupportedAlgorithm
+		}
+		if err := validate_public_key(key); err != nil {
+			return nil, err
+		}
+		return json.Marshal(jsonWebKey{
+			Kty: "OKP",
+			Crv: jwk_okp_name(key.Curve()),
+			X:   base64.RawURLEncoding.EncodeToString(key.EncodeRFC8032()),
+		})
+	}
+	return nil, ErrUnsupportedAlgorithm
+}
+
+/*
+Decodes a public JWK of ToJWK into an *ecdsa.PublicKey or an
+*edwards.Point. Points off their curve fail with edwards.ErrInvalidPoint, Edwards
+keys of small order with ErrIdentityPoint, JWKs holding a private key with
+ErrJWKPrivate, and anything else malformed or unsupported with ErrJWK.
+*/
+func FromJWK(b []byte) (interface{}, error) {
+	var jwk jsonWebKey
+	if err := json.Unmarshal(b, &jwk); err != nil {
+		return nil, ErrJWK
+	}
+	if jwk.D != "" {
+		return nil, ErrJWKPrivate
+	}
+	switch jwk.Kty {
+	case "EC":
+		return ec_jwk_public(&jwk)
+	case "OKP":
+		c := jwk_okp_curve(jwk.Crv)
+		if c == nil || jwk.Y != "" {
+			return nil, ErrJWK
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, ErrJWK
+		}
+		P, err := c.DecodeRFC8032(x)
+		if err != nil {
+			return nil, err
+		}
+		if err := validate_public_key(P); err != nil {
+			return nil, err
+		}
+		return P, nil
+	}
+	return nil, ErrJWK
+}
+
+// The JWK of an ECDSA public key, without d.
+func ec_jwk(pub *ecdsa.PublicKey) (*jsonWebKey, error) {
+	if pub == nil || pub.Curve == nil || pub.X == nil || pub.Y == nil {
+		return nil, edwards.ErrInvalidPoint
+	}
+	name := pub.Curve.Params().Name
+	if jwk_ec_curve(name) != pub.Curve {
+		return nil, ErrUnsupportedAlgorithm
+	}
+	if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+		return nil, edwards.ErrInvalidPoint
+	}
+	width := curve_width(pub.Curve)
+	return &jsonWebKey{
+		Kty: "EC",
+		Crv: name,
+		X:   base64.RawURLEncoding.EncodeToString(toFixedBytes(pub.X, width)),
+		Y:   base64.RawURLEncoding.EncodeToString(toFixedBytes(pub.Y, width)),
+	}, nil
+}
+
+// The public key of an EC JWK, whose coordinates must have the full width of the curve.
+func ec_jwk_public(jwk *jsonWebKey) (*ecdsa.PublicKey, error) {
+	curve := jwk_ec_curve(jwk.Crv)
+	if curve == nil {
+		return nil, ErrJWK
+	}
+	x, errX := jwk_coordinate(jwk.X, curve_width(curve))
+	y, errY := jwk_coordinate(jwk.Y, curve_width(curve))
+	if errX != nil || errY != nil {
+		return nil, ErrJWK
+	}
+	if !curve.IsOnCurve(x, y) {
+		return nil, edwards.ErrInvalidPoint
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// Decodes a base64url big endian integer of exactly width bytes.
+func jwk_coordinate(s string, width int) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) != width {
+		return nil, ErrJWK
+	}
+	return fromFixedBytes(b), nil
+}
@@ -0,0 +1,61 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+/*
+Encodes the key as a private JWK, the public JWK of ToJWK with "d", the
+scalar big endian in the byte length of the order n as RFC 7518 Section
+6.2.2.1 requires. The result holds the key in the clear.
+*/
+func (key *ECDSAPrivateKey) ToJWK() ([]byte, error) {
+	key.mu.Lock()
+	wiped := key.wiped
+	key.mu.Unlock()
+	if wiped {
+		return nil, ErrDestroyedSecret
+	}
+	curve := ecdsa_curve(key.Curve)
+	if err := check_ecdsa_scalar(curve, key.D); err != nil {
+		return nil, err
+	}
+	jwk, err := ec_jwk(key.PublicKey())
+	if err != nil {
+		return nil, err
+	}
+	d := toFixedBytes(key.D, (curve.Params().N.BitLen()+7)/8)
+	defer wipe_bytes(d)
+	jwk.D = base64.RawURLEncoding.EncodeToString(d)
+	return json.Marshal(jwk)
+}
+
+/*
+Decodes a private EC JWK of ToJWK into a key with the nonce cache
+enabled. d must lie in [1, n−1] and x and y must be its public key, or it
+fails with ErrJWK.
+*/
+func PrivateKeyFromJWK(b []byte) (*ECDSAPrivateKey, error) {
+	var jwk jsonWebKey
+	if err := json.Unmarshal(b, &jwk); err != nil || jwk.Kty != "EC" || jwk.D == "" {
+		return nil, ErrJWK
+	}
+	pub, err := ec_jwk_public(&jwk)
+	if err != nil {
+		return nil, err
+	}
+	d, err := jwk_coordinate(jwk.D, (pub.Curve.Params().N.BitLen()+7)/8)
+	if err != nil || check_ecdsa_scalar(pub.Curve, d) != nil {
+		return nil, ErrJWK
+	}
+	key := NewECDSAPrivateKey(d)
+	key.Curve = pub.Curve
+	if derived := key.PublicKey(); derived.X.Cmp(pub.X) != 0 || derived.Y.Cmp(pub.Y) != 0 {
+		key.Wipe()
+		return nil, ErrJWK
+	}
+	return key, nil
+}
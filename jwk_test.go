@@ -0,0 +1,161 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"sig/edwards"
+	"sig/secp256k1"
+)
+
+// JWKs round trip for ECDSA and Edwards keys and match RFC 8037 Appendix A.2.
+func TestJWKImportExport(t *testing.T) {
+
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521(), secp256k1.Curve()} {
+		name := curve.Params().Name
+		d, _ := rand.Int(rand.Reader, new(big.Int).Sub(curve.Params().N, big.NewInt(1)))
+		d.Add(d, big.NewInt(1))
+		key := NewECDSAPrivateKey(d)
+		key.Curve = curve
+		pubJWK, err := ToJWK(key.PublicKey())
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if bytes.Contains(pubJWK, []byte(`"d"`)) {
+			t.Fatalf("%s: public JWK %s holds the private key", name, pubJWK)
+		}
+		privJWK, err := key.ToJWK()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		pub, err := FromJWK(pubJWK)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if Q := pub.(*ecdsa.PublicKey); Q.X.Cmp(key.PublicKey().X) != 0 || Q.Curve != curve {
+			t.Fatalf("%s: public JWK does not round trip", name)
+		}
+		priv, err := PrivateKeyFromJWK(privJWK)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if priv.D.Cmp(d) != 0 || priv.Curve != curve {
+			t.Fatalf("%s: private JWK does not round trip", name)
+		}
+		if _, err := FromJWK(privJWK); err != ErrJWKPrivate {
+			t.Fatalf("%s private JWK as a public key: %v, want %v", name, err, ErrJWKPrivate)
+		}
+		if _, err := PrivateKeyFromJWK(pubJWK); err != ErrJWK {
+			t.Fatalf("%s public JWK as a private key: %v, want %v", name, err, ErrJWK)
+		}
+	}
+	// P-256 keys agree with the JWS key format
+	key := NewECDSAPrivateKey(big.NewInt(7))
+	jwk, err := ToJWK(key.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy, err := MarshalJWK(key.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(jwk, legacy) {
+		t.Fatalf("JWK %s, JWS key format %s", jwk, legacy)
+	}
+
+	// RFC 8037 Appendix A.2, the Ed25519 key of RFC 8032 Section 7.1 test 1
+	seed, _ := hex.DecodeString("9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60")
+	rfcJWK := `{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`
+	raw, err := Ed25519PublicKey(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	P, err := FromJWK([]byte(rfcJWK))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := P.(*edwards.Point).EncodeRFC8032(); !bytes.Equal(got, raw) {
+		t.Fatalf("RFC 8037 key decodes as %x, want %x", got, raw)
+	}
+	out, err := ToJWK(P.(*edwards.Point))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != rfcJWK {
+		t.Fatalf("JWK %s, want %s", out, rfcJWK)
+	}
+
+	for _, c := range []*edwards.Curve{e222Curve, e521Curve, ed448Curve} {
+		a, _ := rand.Int(rand.Reader, c.Params().R)
+		Y := c.BaseMul(a)
+		jwk, err := ToJWK(Y)
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name(), err)
+		}
+		if !bytes.Contains(jwk, []byte(`"kty":"OKP"`)) {
+			t.Fatalf("%s: JWK %s is not an OKP key", c.Name(), jwk)
+		}
+		back, err := FromJWK(jwk)
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name(), err)
+		}
+		if !back.(*edwards.Point).Equals(Y) {
+			t.Fatalf("%s: JWK does not round trip", c.Name())
+		}
+		identity := `{"kty":"OKP","crv":"` + jwk_okp_name(c) + `","x":"` +
+			base64.RawURLEncoding.EncodeToString(c.IdPoint().EncodeRFC8032()) + `"}`
+		if _, err := FromJWK([]byte(identity)); err != ErrIdentityPoint {
+			t.Fatalf("%s identity key: %v, want %v", c.Name(), err, ErrIdentityPoint)
+		}
+	}
+
+	// unknown curves and types, short coordinates, mismatched private keys and points off the curve
+	for _, bad := range []string{
+		`{"kty":"OKP","crv":"X25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`,
+		`{"kty":"RSA","n":"AQAB","e":"AQAB"}`,
+		`{"kty":"EC","crv":"P-256","x":"AQ","y":"AQ"}`,
+		`not json`,
+	} {
+		if _, err := FromJWK([]byte(bad)); err != ErrJWK {
+			t.Fatalf("%s: %v, want %v", bad, err, ErrJWK)
+		}
+	}
+	priv, err := NewECDSAPrivateKey(big.NewInt(8)).ToJWK()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields, other map[string]string
+	if err := json.Unmarshal(priv, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(jwk, &other); err != nil {
+		t.Fatal(err)
+	}
+	fields["x"], fields["y"] = other["x"], other["y"]
+	mismatched, _ := json.Marshal(fields)
+	if _, err := PrivateKeyFromJWK(mismatched); err != ErrJWK {
+		t.Fatalf("mismatched private key: %v, want %v", err, ErrJWK)
+	}
+	fields["y"] = fields["x"]
+	offCurve, _ := json.Marshal(fields)
+	delete(fields, "d")
+	offCurvePub, _ := json.Marshal(fields)
+	if _, err := FromJWK(offCurvePub); err != edwards.ErrInvalidPoint {
+		t.Fatalf("public key off the curve: %v, want %v", err, edwards.ErrInvalidPoint)
+	}
+	if _, err := PrivateKeyFromJWK(offCurve); err != edwards.ErrInvalidPoint {
+		t.Fatalf("private key off the curve: %v, want %v", err, edwards.ErrInvalidPoint)
+	}
+	if _, err := ToJWK(NewSecretScalar(big.NewInt(3))); err != ErrUnsupportedAlgorithm {
+		t.Fatalf("E222 private key: %v, want %v", err, ErrUnsupportedAlgorithm)
+	}
+}
@@ -0,0 +1,107 @@
+package sig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+var (
+	ErrUnsupportedJWSAlgorithm = errors.New("unsupported JWS algorithm, only ES256 is accepted")
+	ErrInvalidJWS              = errors.New("malformed JWS token")
+	ErrJWSSignature            = errors.New("JWS signature verification failed")
+)
+
+/*
+JSON Web Key representation of an EC key (RFC 7517, RFC 7518 Section 6.2)
+or of an OKP key, which has no y (RFC 8037 Section 2). d is set for
+private keys only.
+*/
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y,omitempty"`
+	D   string `json:"d,omitempty"`
+}
+
+// Encodes a P-256 verification key as {"kty":"EC","crv":"P-256","x":...,"y":...}.
+func MarshalJWK(pub *ecdsa.PublicKey) ([]byte, error) {
+	if pub == nil || pub.Curve != elliptic.P256() || !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+		return nil, errors.New("JWK export requires a valid P-256 public key")
+	}
+	return json.Marshal(jsonWebKey{
+		Kty: "EC",
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(toFixedBytes(pub.X, p256Width)),
+		Y:   base64.RawURLEncoding.EncodeToString(toFixedBytes(pub.Y, p256Width)),
+	})
+}
+
+// Decodes a P-256 JWK, rejecting other key types, curves, and off-curve points.
+func UnmarshalJWK(b []byte) (*ecdsa.PublicKey, error) {
+	var jwk jsonWebKey
+	if err := json.Unmarshal(b, &jwk); err != nil {
+		return nil, err
+	}
+	if jwk.Kty != "EC" || jwk.Crv != "P-256" {
+		return nil, errors.New("JWK is not an EC P-256 key")
+	}
+	x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil || len(x) != p256Width {
+		return nil, errors.New("malformed JWK x coordinate")
+	}
+	y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+	if err != nil || len(y) != p256Width {
+		return nil, errors.New("malformed JWK y coordinate")
+	}
+	pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: fromFixedBytes(x), Y: fromFixedBytes(y)}
+	if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+		return nil, errors.New("JWK point is not on P-256")
+	}
+	return pub, nil
+}
+
+/*
+Verifies a compact serialized JWS (RFC 7515) signed with ES256 and returns
+its decoded payload. The signature is the raw r || s over the ASCII string
+header.payload; every algorithm other than ES256, including "none", is
+rejected with ErrUnsupportedJWSAlgorithm.
+*/
+func VerifyJWS(token string, pub *ecdsa.PublicKey) ([]byte, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidJWS
+	}
+	header_json, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidJWS
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(header_json, &header); err != nil {
+		return nil, ErrInvalidJWS
+	}
+	if header.Alg != "ES256" {
+		return nil, ErrUnsupportedJWSAlgorithm
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidJWS
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || len(sig) != 2*p256Width {
+		return nil, ErrInvalidJWS
+	}
+
+	signing_input := []byte(parts[0] + "." + parts[1])
+	r, s := fromFixedBytes(sig[:p256Width]), fromFixedBytes(sig[p256Width:])
+	if !verify_ecdsa_sig(pub, r, s, &signing_input) {
+		return nil, ErrJWSSignature
+	}
+	return payload, nil
+}
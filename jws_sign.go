@@ -0,0 +1,19 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto/elliptic"
+	"encoding/base64"
+	"math/big"
+)
+
+// Produces a compact serialized ES256 JWS over payload with private key d_a.
+func SignJWS(payload []byte, d_a *big.Int) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"ES256"}`))
+	signing_input := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	msg := []byte(signing_input)
+	r, s := sign_message_ecdsa(elliptic.P256(), &msg, d_a)
+	sig := append(toFixedBytes(r, p256Width), toFixedBytes(s, p256Width)...)
+	return signing_input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
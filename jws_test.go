@@ -0,0 +1,36 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"strings"
+	"testing"
+)
+
+func TestJWSRoundTrip(t *testing.T) {
+
+	d_a := generateRandomBigInt()
+	d_a.Mod(d_a, elliptic.P256().Params().N)
+	pub := ecdsa.PublicKey{Curve: elliptic.P256()}
+	pub.X, pub.Y = elliptic.P256().ScalarBaseMult(d_a.Bytes())
+
+	token := SignJWS([]byte(`{"sub":"alice"}`), d_a)
+	payload, err := VerifyJWS(token, &pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(payload) != `{"sub":"alice"}` {
+		t.Fatalf("payload %s", payload)
+	}
+
+	sigStart := strings.LastIndex(token, ".") + 1
+	flipped := map[byte]string{'A': "B"}[token[sigStart]]
+	if flipped == "" {
+		flipped = "A"
+	}
+	if _, err := VerifyJWS(token[:sigStart]+flipped+token[sigStart+1:], &pub); err != ErrJWSSignature {
+		t.Fatalf("tampered token: %v, want %v", err, ErrJWSSignature)
+	}
+}
@@ -0,0 +1,74 @@
+package sig
+
+import (
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/sha3"
+)
+
+/*
+An E222 public key together with its owner, creation date and optional
+expiry date. The fields are bound by a self-signature made with the key's
+own private scalar, so tampering with any of them can be detected using
+only the public key. A revoked key carries its revocation certificate,
+see KeyRevocation.
+*/
+type KeyObj struct {
+	Id          string // derived from PubKey by key_id
+	Owner       string
+	DateCreated time.Time
+	Expires     time.Time // zero for keys that do not expire
+	PubKey      *E222
+	Signature   []byte         // encoded Schnorr signature over canonical_fields
+	Revocation  *KeyRevocation // nil unless the key has been revoked
+	Untrusted   bool           // set on import when the self-signature fails
+}
+
+// Key identifier: hex of the first 16 bytes of SHA3-256 over the public key.
+func key_id(V *E222) string {
+	return hex.EncodeToString(key_id_bytes(V))
+}
+
+// The 16 bytes of SHA3-256 over the public key that key_id formats.
+func key_id_bytes(V *E222) []byte {
+	d := sha3.Sum256(e222_to_bytes(V))
+	return d[:16]
+}
+
+/*
+Canonical encoding of the signed key fields:
+
+	len(owner) (2 bytes) || owner || date (RFC 3339, UTC) (2 byte length prefixed) || public key
+
+followed, for keys that expire, by the expiry date (RFC 3339, UTC, 2 byte
+length prefixed). Keys without one encode as they did before expiry
+dates existed, so their self-signatures still hold.
+*/
+func (key *KeyObj) canonical_fields() []byte {
+	out := length_prefixed([]byte(key.Owner))
+	out = append(out, length_prefixed([]byte(key.DateCreated.UTC().Format(time.RFC3339)))...)
+	out = append(out, e222_to_bytes(key.PubKey)...)
+	if !key.Expires.IsZero() {
+		out = append(out, length_prefixed([]byte(key.Expires.UTC().Format(time.RFC3339)))...)
+	}
+	return out
+}
+
+// 2 byte big endian length followed by b.
+func length_prefixed(b []byte) []byte {
+	return append([]byte{byte(len(b) >> 8), byte(len(b))}, b...)
+}
+
+// Checks the key's self-signature using only its public key.
+func VerifyKeyObjSelfSig(key *KeyObj) (bool, error) {
+	if err := validate_public_key(key.PubKey); err != nil {
+		return false, err
+	}
+	if len(key.Owner) > 0xffff {
+		return false, errors.New("key owner too long")
+	}
+	fields := key.canonical_fields()
+	return VerifySignature(key.PubKey, "", key.Signature, &fields), nil
+}
@@ -0,0 +1,162 @@
+package sig
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+const keyArmorType = "E222 PUBLIC KEY"
+
+// Algorithm of exported key objects, recorded in their Algorithm header.
+const keyAlgorithm = algSchnorrE222
+
+/*
+Exports keys as a sequence of armored blocks, sorted by owner, creation
+date and Id so that the same set of keys always exports to the same bytes.
+Expiry dates and revocation certificates go in the Expires, Revoked,
+Revocation-Reason and Revocation headers of the keys that have them.
+*/
+func ExportKeys(keys []*KeyObj) ([]byte, error) {
+	sorted := append([]*KeyObj{}, keys...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Owner != b.Owner {
+			return a.Owner < b.Owner
+		}
+		if !a.DateCreated.Equal(b.DateCreated) {
+			return a.DateCreated.Before(b.DateCreated)
+		}
+		return a.Id < b.Id
+	})
+
+	var out bytes.Buffer
+	for _, key := range sorted {
+		if strings.ContainsAny(key.Owner, "\r\n") {
+			return nil, errors.New("key owner contains a line break")
+		}
+		if key.Revocation != nil && strings.ContainsAny(key.Revocation.Reason, "\r\n") {
+			return nil, errors.New("revocation reason contains a line break")
+		}
+		block := pem.Block{
+			Type: keyArmorType,
+			Headers: map[string]string{
+				"Algorithm": keyAlgorithm.String(),
+				"Id":        key.Id,
+				"Owner":     key.Owner,
+				"Created":   key.DateCreated.UTC().Format(time.RFC3339),
+				"Signature": hex.EncodeToString(key.Signature),
+			},
+			Bytes: e222_to_bytes(key.PubKey),
+		}
+		if !key.Expires.IsZero() {
+			block.Headers["Expires"] = key.Expires.UTC().Format(time.RFC3339)
+		}
+		if rev := key.Revocation; rev != nil {
+			block.Headers["Revoked"] = rev.Date.UTC().Format(time.RFC3339)
+			block.Headers["Revocation-Reason"] = rev.Reason
+			block.Headers["Revocation"] = hex.EncodeToString(rev.Signature)
+		}
+		if err := pem.Encode(&out, &block); err != nil {
+			return nil, err
+		}
+	}
+	return out.Bytes(), nil
+}
+
+/*
+Imports exported keys into table, returning the new table. Ids are
+recomputed from the public keys, keys already present are skipped so
+importing the same export twice is idempotent, and keys whose
+self-signature fails are added with Untrusted set. A revocation that
+holds is taken over even for a key already present, so that importing a
+revoked key's export revokes it; one that fails is dropped. Keys of any algorithm
+but keyAlgorithm fail with ErrUnsupportedAlgorithm; keys without an
+Algorithm header predate it and are taken to be keyAlgorithm.
+*/
+func ImportKeys(table []*KeyObj, data []byte) ([]*KeyObj, error) {
+	known := make(map[string]*KeyObj)
+	for _, key := range table {
+		known[key.Id] = key
+	}
+	for {
+		block, rest := pem.Decode(data)
+		if block == nil {
+			break
+		}
+		data = rest
+		if block.Type != keyArmorType {
+			continue
+		}
+		if name, ok := block.Headers["Algorithm"]; ok {
+			if id, err := parse_algorithm(name); err != nil || id != keyAlgorithm {
+				return table, ErrUnsupportedAlgorithm
+			}
+		}
+		V, err := e222_from_bytes(block.Bytes)
+		if err != nil {
+			return table, err
+		}
+		created, err := time.Parse(time.RFC3339, block.Headers["Created"])
+		if err != nil {
+			return table, errors.New("malformed key creation date")
+		}
+		signature, err := hex.DecodeString(block.Headers["Signature"])
+		if err != nil {
+			return table, errors.New("malformed key signature")
+		}
+		key := &KeyObj{
+			Id:          key_id(V),
+			Owner:       block.Headers["Owner"],
+			DateCreated: created,
+			PubKey:      V,
+			Signature:   signature,
+		}
+		if id, ok := block.Headers["Id"]; ok && id != key.Id {
+			return table, errors.New("key Id does not match its public key")
+		}
+		if expires, ok := block.Headers["Expires"]; ok {
+			if key.Expires, err = time.Parse(time.RFC3339, expires); err != nil {
+				return table, errors.New("malformed key expiry date")
+			}
+		}
+		if key.Revocation, err = parse_revocation_headers(block.Headers); err != nil {
+			return table, err
+		}
+		if ok, _ := VerifyKeyRevocation(key); !ok {
+			key.Revocation = nil
+		}
+		if existing := known[key.Id]; existing != nil {
+			if key.Revocation != nil && existing.Revocation == nil {
+				existing.Revocation = key.Revocation
+			}
+			continue
+		}
+		ok, _ := VerifyKeyObjSelfSig(key)
+		key.Untrusted = !ok
+		known[key.Id] = key
+		table = append(table, key)
+	}
+	return table, nil
+}
+
+// The revocation certificate of the Revoked, Revocation-Reason and Revocation headers, nil if there are none.
+func parse_revocation_headers(headers map[string]string) (*KeyRevocation, error) {
+	date, ok := headers["Revoked"]
+	if !ok {
+		return nil, nil
+	}
+	rev := &KeyRevocation{Reason: headers["Revocation-Reason"]}
+	var err error
+	if rev.Date, err = time.Parse(time.RFC3339, date); err != nil {
+		return nil, errors.New("malformed key revocation date")
+	}
+	if rev.Signature, err = hex.DecodeString(headers["Revocation"]); err != nil {
+		return nil, errors.New("malformed key revocation")
+	}
+	return rev, nil
+}
@@ -0,0 +1,62 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"testing"
+)
+
+// export → wipe → import → export is byte identical, and importing twice is idempotent.
+func TestKeyExportReproducible(t *testing.T) {
+
+	var table []*KeyObj
+	for _, owner := range []string{"carol", "alice", "bob", "alice"} {
+		key, err := GenerateKeyObj(owner, []byte(owner+generateRandomBigInt().String()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		table = append(table, key)
+	}
+	export1, err := ExportKeys(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table, err = ImportKeys(nil, export1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err = ImportKeys(table, export1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table) != 4 || table[0].Owner != "alice" {
+		t.Fatalf("%d keys after importing twice, first owned by %s", len(table), table[0].Owner)
+	}
+	export2, err := ExportKeys(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(export1, export2) {
+		t.Fatalf("export changed:\n%s\nthen\n%s", export1, export2)
+	}
+	for _, key := range table {
+		if key.Untrusted || key.Id != key_id(key.PubKey) {
+			t.Fatalf("%s: untrusted %v, id %s, want %s", key.Owner, key.Untrusted, key.Id, key_id(key.PubKey))
+		}
+	}
+
+	// a tampered owner is flagged on import
+	tampered := bytes.Replace(export1, []byte("Owner: bob"), []byte("Owner: eve"), 1)
+	table, _ = ImportKeys(nil, tampered)
+	var untrusted []string
+	for _, key := range table {
+		if key.Untrusted {
+			untrusted = append(untrusted, key.Owner)
+		}
+	}
+	if len(untrusted) != 1 || untrusted[0] != "eve" {
+		t.Fatalf("untrusted keys %v, want [eve]", untrusted)
+	}
+}
@@ -0,0 +1,118 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"errors"
+	"time"
+)
+
+// Returned when a secret scalar does not derive the public key of the key it should sign for.
+var ErrKeyMismatch = errors.New("secret key does not match the key's public key")
+
+/*
+Generates a key pair from the passphrase pw and returns its self-signed
+KeyObj. Weak passphrases, see GenerateKeyPair, are rejected with
+ErrWeakPassphrase, those shorter than DefaultMinPassphraseLength among
+them. The private scalar is never stored and is destroyed before
+returning.
+*/
+func GenerateKeyObj(owner string, pw []byte) (*KeyObj, error) {
+	secret, V, err := GenerateKeyPair(pw, DefaultMinPassphraseLength)
+	if err != nil {
+		return nil, err
+	}
+	defer secret.Destroy()
+
+	key := &KeyObj{
+		Id:          key_id(V),
+		Owner:       owner,
+		DateCreated: time.Now().UTC().Truncate(time.Second),
+		PubKey:      V,
+	}
+	if err := key.self_sign(secret); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Signs the key's canonical fields with secret, replacing its self-signature.
+func (key *KeyObj) self_sign(secret *SecretScalar) error {
+	fields := key.canonical_fields()
+	s, e, err := sign_with_key_e222(secret, "", &fields)
+	if err != nil {
+		return err
+	}
+	key.Signature = EncodeSignature(&SchnorrSignature{Curve: curveE222, S: s, E: e})
+	return nil
+}
+
+// Fails with ErrKeyMismatch unless secret derives the key's public key.
+func check_key_secret(key *KeyObj, secret *SecretScalar) error {
+	s, err := secret.Int()
+	if err != nil {
+		return err
+	}
+	defer wipe_int(s)
+	if key.PubKey == nil || !e222Curve.BaseMul(s).Equals(key.PubKey) {
+		return ErrKeyMismatch
+	}
+	return nil
+}
+
+/*
+Sets the key's expiry date, or removes it when expires is zero, and signs
+the key afresh with secret, which must derive its public key. The date
+is kept to the second, as key files store it, and must follow the
+creation date.
+*/
+func SetKeyExpiry(key *KeyObj, secret *SecretScalar, expires time.Time) error {
+	if err := check_key_secret(key, secret); err != nil {
+		return err
+	}
+	expires = expires.UTC().Truncate(time.Second)
+	if !expires.IsZero() && !expires.After(key.DateCreated) {
+		return errors.New("key expiry precedes its creation")
+	}
+	old := key.Expires
+	key.Expires = expires
+	if err := key.self_sign(secret); err != nil {
+		key.Expires = old
+		return err
+	}
+	return nil
+}
+
+/*
+Revokes the key as of now for reason, e.g. "key compromised" or
+"superseded", attaching a revocation certificate signed with secret,
+which must derive its public key. Exporting the key afterwards publishes
+the revocation; importing that export into a table that already holds
+the key revokes it there too.
+*/
+func RevokeKey(key *KeyObj, secret *SecretScalar, reason string) error {
+	if err := check_key_secret(key, secret); err != nil {
+		return err
+	}
+	if len(reason) > 0xffff {
+		return errors.New("revocation reason too long")
+	}
+	rev := &KeyRevocation{Date: time.Now().UTC().Truncate(time.Second), Reason: reason}
+	sig, err := sign_text_e222(secret, keyRevocationDomain, rev.canonical_fields(key.PubKey), false)
+	if err != nil {
+		return err
+	}
+	rev.Signature = sig
+	key.Revocation = rev
+	return nil
+}
+
+// Checks that pw derives the key's public key and that its self-signature holds.
+func VerifyKeyObjSignature(key *KeyObj, pw []byte) (bool, error) {
+	secret, V := e222_keypair_from_passphrase(pw)
+	secret.Destroy()
+	if key.PubKey == nil || !V.Equals(key.PubKey) {
+		return false, nil
+	}
+	return VerifyKeyObjSelfSig(key)
+}
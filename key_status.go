@@ -0,0 +1,146 @@
+package sig
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+var (
+	ErrKeyRevoked = errors.New("signing key has been revoked")
+	ErrKeyExpired = errors.New("signing key had expired when the signature was made")
+)
+
+// Signing domain of revocation certificates, keeping them apart from self-signatures.
+const keyRevocationDomain = "key-revocation"
+
+// Status of a key at a point in time, see KeyObj.Status.
+type KeyStatus int
+
+const (
+	KeyValid KeyStatus = iota
+	KeyExpired
+	KeyRevoked
+)
+
+func (s KeyStatus) String() string {
+	switch s {
+	case KeyValid:
+		return "valid"
+	case KeyExpired:
+		return "expired"
+	case KeyRevoked:
+		return "revoked"
+	}
+	return "unknown"
+}
+
+/*
+A revocation certificate: the key's own signature, in the domain
+keyRevocationDomain, over
+
+	date (RFC 3339, UTC) (2 byte length prefixed) || reason (2 byte length prefixed) || public key
+
+Anyone holding the key's public key can check it, so a revocation can be
+published alongside the key and travels with exported keys. Only the
+holder of the private key can make one.
+*/
+type KeyRevocation struct {
+	Date      time.Time
+	Reason    string
+	Signature []byte
+}
+
+func (rev *KeyRevocation) canonical_fields(V *E222) []byte {
+	out := length_prefixed([]byte(rev.Date.UTC().Format(time.RFC3339)))
+	out = append(out, length_prefixed([]byte(rev.Reason))...)
+	return append(out, e222_to_bytes(V)...)
+}
+
+// Checks the key's revocation certificate; false if it has none or it does not hold.
+func VerifyKeyRevocation(key *KeyObj) (bool, error) {
+	if key.Revocation == nil {
+		return false, nil
+	}
+	if err := validate_public_key(key.PubKey); err != nil {
+		return false, err
+	}
+	if len(key.Revocation.Reason) > 0xffff {
+		return false, errors.New("revocation reason too long")
+	}
+	fields := key.Revocation.canonical_fields(key.PubKey)
+	return VerifySignature(key.PubKey, keyRevocationDomain, key.Revocation.Signature, &fields), nil
+}
+
+/*
+The status of the key at time at: KeyRevoked if it carries a revocation
+certificate that holds, whatever the revocation date, KeyExpired after
+its expiry date, and KeyValid otherwise.
+*/
+func (key *KeyObj) Status(at time.Time) KeyStatus {
+	if ok, _ := VerifyKeyRevocation(key); ok {
+		return KeyRevoked
+	}
+	if !key.Expires.IsZero() && at.After(key.Expires) {
+		return KeyExpired
+	}
+	return KeyValid
+}
+
+/*
+Checks the key that made a signature at created, zero if the signature
+does not record it, when verified at now. A revoked key fails every
+signature with ErrKeyRevoked: a revocation may mean the key leaked, and
+whoever holds it can backdate signatures. A signature made after the key
+expired, or of unknown time once it has, fails with ErrKeyExpired; one
+made before the key expired still holds, with a warning.
+*/
+func check_signer_key(key *KeyObj, created, now time.Time) ([]string, error) {
+	if key.Status(now) == KeyRevoked {
+		return nil, fmt.Errorf("%w on %s: %s", ErrKeyRevoked, FormatCreated(key.Revocation.Date), key.Revocation.Reason)
+	}
+	if key.Expires.IsZero() {
+		return nil, nil
+	}
+	signed := created
+	if signed.IsZero() {
+		signed = now
+	}
+	if signed.After(key.Expires) {
+		return nil, fmt.Errorf("%w on %s", ErrKeyExpired, FormatCreated(key.Expires))
+	}
+	if now.After(key.Expires) {
+		return []string{T("verify.key_expired", FormatCreated(key.Expires))}, nil
+	}
+	return nil, nil
+}
+
+/*
+Looks up the signer of c among the exported keys in the file at path and
+checks it with check_signer_key. A signer missing from the file only
+draws a warning, since the file says nothing about its status, but one
+whose entry fails its self-signature fails: its expiry date may have been
+stripped.
+*/
+func CheckSignerInKeyFile(path string, c *SignatureContainer, now time.Time) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := ImportKeys(nil, data)
+	if err != nil {
+		return nil, err
+	}
+	id := key_fingerprint(c.PublicKey)
+	for _, key := range keys {
+		if key.Id != id {
+			continue
+		}
+		if key.Untrusted {
+			return nil, errors.New("the signer's entry in the key file fails its self-signature")
+		}
+		return check_signer_key(key, c.Created, now)
+	}
+	return []string{T("verify.key_unknown", id)}, nil
+}
@@ -0,0 +1,205 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Expiry dates are self-signed, revocations travel with exported keys, and verify --keys enforces both.
+func TestKeyExpiryAndRevocation(t *testing.T) {
+
+	pw := []byte("expiring key passphrase")
+	key, err := GenerateKeyObj("carol", pw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, _ := e222_keypair_from_passphrase(pw)
+	defer secret.Destroy()
+	other, _ := e222_keypair_from_passphrase([]byte("someone else entirely"))
+	defer other.Destroy()
+	now := time.Now()
+	expires := key.DateCreated.Add(time.Hour)
+
+	if s := key.Status(now); s != KeyValid {
+		t.Fatalf("new key status %v, want %v", s, KeyValid)
+	}
+	if err := SetKeyExpiry(key, other, expires); err != ErrKeyMismatch {
+		t.Fatalf("expiry set by another key: %v, want %v", err, ErrKeyMismatch)
+	}
+	if err := SetKeyExpiry(key, secret, key.DateCreated.Add(-time.Hour)); err == nil {
+		t.Fatal("key expires before it was created")
+	}
+	if err := SetKeyExpiry(key, secret, expires); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := VerifyKeyObjSelfSig(key); !ok {
+		t.Fatalf("self-signature does not verify: %v", err)
+	}
+	stripped := *key
+	stripped.Expires = time.Time{}
+	if ok, _ := VerifyKeyObjSelfSig(&stripped); ok {
+		t.Fatal("self-signature verifies without the expiry")
+	}
+	if !key.Expires.Equal(expires) {
+		t.Fatalf("key expires %v, want %v", key.Expires, expires)
+	}
+	if s := key.Status(now); s != KeyValid {
+		t.Fatalf("status before expiry %v, want %v", s, KeyValid)
+	}
+	if s := key.Status(expires.Add(time.Second)); s != KeyExpired {
+		t.Fatalf("status after expiry %v, want %v", s, KeyExpired)
+	}
+
+	// signatures made before expiry warn once it has passed, later ones fail
+	warnings, err := check_signer_key(key, now, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 0 {
+		t.Fatalf("warnings before expiry: %v", warnings)
+	}
+	late, err := check_signer_key(key, now, expires.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(late) != 1 {
+		t.Fatalf("warnings for a signature made before expiry: %v, want one", late)
+	}
+	if _, err := check_signer_key(key, expires.Add(time.Second), expires.Add(time.Minute)); !errors.Is(err, ErrKeyExpired) {
+		t.Fatalf("signature made after expiry: %v, want %v", err, ErrKeyExpired)
+	}
+	if _, err := check_signer_key(key, time.Time{}, expires.Add(time.Minute)); !errors.Is(err, ErrKeyExpired) {
+		t.Fatalf("undated signature after expiry: %v, want %v", err, ErrKeyExpired)
+	}
+
+	// exports carry the expiry; a revocation in a later export revokes the imported key
+	exported, err := ExportKeys([]*KeyObj{key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err := ImportKeys(nil, exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table) != 1 || table[0].Untrusted || !table[0].Expires.Equal(expires) || table[0].Revocation != nil {
+		t.Fatalf("imported %+v", table)
+	}
+	if err := RevokeKey(key, other, "lost"); err != ErrKeyMismatch {
+		t.Fatalf("revocation by another key: %v, want %v", err, ErrKeyMismatch)
+	}
+	if err := RevokeKey(key, secret, "key compromised"); err != nil {
+		t.Fatal(err)
+	}
+	if revoked, err := VerifyKeyRevocation(key); !revoked {
+		t.Fatalf("revocation does not verify: %v", err)
+	}
+	if s := key.Status(now); s != KeyRevoked {
+		t.Fatalf("status after revocation %v, want %v", s, KeyRevoked)
+	}
+	if _, err := check_signer_key(key, now, now); !errors.Is(err, ErrKeyRevoked) {
+		t.Fatalf("signature by a revoked key: %v, want %v", err, ErrKeyRevoked)
+	}
+	exported, err = ExportKeys([]*KeyObj{key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err = ImportKeys(table, exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table) != 1 || table[0].Status(now) != KeyRevoked || table[0].Revocation.Reason != "key compromised" {
+		t.Fatalf("revocation is not imported: %+v", table)
+	}
+
+	// a revocation whose reason was changed, or signed by another key, is dropped
+	forged := bytes.Replace(exported, []byte("key compromised"), []byte("superseded"), 1)
+	fresh, err := ImportKeys(nil, forged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fresh) != 1 || fresh[0].Revocation != nil || fresh[0].Status(now) != KeyValid {
+		t.Fatalf("forged revocation is imported: %+v", fresh)
+	}
+	stranger, err := GenerateKeyObj("dave", []byte("a stranger's passphrase"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	strangerSecret, _ := e222_keypair_from_passphrase([]byte("a stranger's passphrase"))
+	if err := RevokeKey(stranger, strangerSecret, "key compromised"); err != nil {
+		t.Fatal(err)
+	}
+	strangerSecret.Destroy()
+	mixed, err := ExportKeys([]*KeyObj{{Id: key.Id, Owner: key.Owner, DateCreated: key.DateCreated, Expires: key.Expires,
+		PubKey: key.PubKey, Signature: key.Signature, Revocation: stranger.Revocation}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh, err = ImportKeys(nil, mixed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fresh) != 1 || fresh[0].Revocation != nil {
+		t.Fatalf("another key's revocation is imported: %+v", fresh)
+	}
+
+	// keystores keep both
+	dir := t.TempDir()
+	saved := keystoreArgon2
+	keystoreArgon2 = keystoreKDF{time: 1, memory: 64, threads: 1}
+	defer func() { keystoreArgon2 = saved }()
+	ks, err := OpenKeystore(filepath.Join(dir, "keys.ks"), pw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ks.Destroy()
+	if err := ks.Add(key, secret); err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.Save(pw); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := OpenKeystore(filepath.Join(dir, "keys.ks"), pw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer loaded.Destroy()
+	if len(loaded.Keys()) != 1 {
+		t.Fatalf("keystore loads %d keys, want 1", len(loaded.Keys()))
+	}
+	if k := loaded.Keys()[0]; k.Untrusted || !k.Expires.Equal(expires) || k.Status(now) != KeyRevoked {
+		t.Fatalf("keystore loads %+v", k)
+	}
+
+	// a key file passes the signer of a valid key and fails it once it revokes the key
+	doc := filepath.Join(dir, "doc.txt")
+	keyFile := filepath.Join(dir, "keys.asc")
+	if err := os.WriteFile(doc, []byte("a signed document"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := sign_detached_e222(secret, "", doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unrevoked, err := ExportKeys(fresh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, unrevoked, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CheckSignerInKeyFile(keyFile, c, now); err != nil {
+		t.Fatalf("valid key: %v", err)
+	}
+	if err := os.WriteFile(keyFile, exported, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CheckSignerInKeyFile(keyFile, c, now); !errors.Is(err, ErrKeyRevoked) {
+		t.Fatalf("revoked key: %v, want %v", err, ErrKeyRevoked)
+	}
+}
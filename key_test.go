@@ -0,0 +1,42 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"testing"
+	"time"
+)
+
+// Legitimate keys verify; tampering with any single field does not.
+func TestKeyObjSelfSignature(t *testing.T) {
+
+	pw := []byte("correct horse")
+	key, err := GenerateKeyObj("alice", pw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := VerifyKeyObjSelfSig(key); !ok || err != nil {
+		t.Fatalf("VerifyKeyObjSelfSig = %v, %v", ok, err)
+	}
+	if ok, err := VerifyKeyObjSignature(key, pw); !ok {
+		t.Fatalf("VerifyKeyObjSignature = %v, %v", ok, err)
+	}
+	if ok, _ := VerifyKeyObjSignature(key, []byte("battery staple")); ok {
+		t.Fatal("key signature verifies with the wrong passphrase")
+	}
+
+	tampered := map[string]func(k *KeyObj){
+		"owner":     func(k *KeyObj) { k.Owner = "mallory" },
+		"date":      func(k *KeyObj) { k.DateCreated = k.DateCreated.Add(time.Second) },
+		"key":       func(k *KeyObj) { k.PubKey = k.PubKey.Add(e222Curve.GenPoint()) },
+		"signature": func(k *KeyObj) { k.Signature[len(k.Signature)-1] ^= 1 },
+	}
+	for field, tamper := range tampered {
+		k := *key
+		k.Signature = append([]byte{}, key.Signature...)
+		tamper(&k)
+		if ok, _ := VerifyKeyObjSelfSig(&k); ok {
+			t.Fatalf("self signature verifies with a tampered %s", field)
+		}
+	}
+}
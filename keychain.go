@@ -0,0 +1,202 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Service name under which keychain entries are filed, the account being the key Id.
+const keychainService = "sig E222 keys"
+
+var ErrKeychainUnavailable = errors.New("keychain: no platform keychain available")
+
+/*
+Storage for E222 key pairs, implemented by the encrypted file of
+Keystore and by KeychainStore, which leaves the secrets to the platform
+keychain. Secret returns a copy for the caller to destroy, and Add fails
+with ErrKeystoreKey unless the secret derives the key's public key.
+*/
+type KeyStore interface {
+	Keys() []*KeyObj
+	Add(key *KeyObj, secret *SecretScalar) error
+	Secret(id string) (*SecretScalar, error)
+	Remove(id string) bool
+}
+
+var (
+	_ KeyStore = (*Keystore)(nil)
+	_ KeyStore = (*KeychainStore)(nil)
+)
+
+/*
+A secret store of the operating system: the macOS Keychain, the Secret
+Service of Linux desktops (GNOME Keyring, KWallet), or DPAPI on Windows.
+Entries are keyed by key Id; get fails with ErrKeystoreMissing for Ids
+it does not hold.
+*/
+type keychainBackend interface {
+	set(id string, secret []byte) error
+	get(id string) ([]byte, error)
+	delete(id string) error
+}
+
+/*
+A KeyStore whose secret scalars live in the platform keychain, never on
+disk in this process's files: the keychain encrypts them under the user's
+login and may ask before releasing them. The public side, each KeyObj with
+its self-signature, expiry and revocation, is kept in an index file of
+exported keys, which holds nothing secret. Add and Remove take effect
+immediately; there is nothing to save.
+*/
+type KeychainStore struct {
+	index   string
+	keys    []*KeyObj
+	backend keychainBackend
+}
+
+/*
+Opens the keychain store whose key table is the index file at path, empty
+if the file does not exist yet. Fails with ErrKeychainUnavailable where
+the platform has no keychain, or where its command line tool, security on
+macOS or secret-tool on Linux, is missing.
+*/
+func OpenKeychainStore(path string) (*KeychainStore, error) {
+	backend, err := platform_keychain()
+	if err != nil {
+		return nil, err
+	}
+	return open_keychain_store(path, backend)
+}
+
+func open_keychain_store(path string, backend keychainBackend) (*KeychainStore, error) {
+	ks := &KeychainStore{index: path, backend: backend}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ks, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if ks.keys, err = ImportKeys(nil, data); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// The key table, in the order of the index file.
+func (ks *KeychainStore) Keys() []*KeyObj { return append([]*KeyObj(nil), ks.keys...) }
+
+/*
+Stores secret, which must derive key.PubKey, in the keychain and adds key
+to the index. A key already present is left as it is.
+*/
+func (ks *KeychainStore) Add(key *KeyObj, secret *SecretScalar) error {
+	if key == nil || check_key_secret(key, secret) != nil {
+		return ErrKeystoreKey
+	}
+	if ks.index_of(key.Id) >= 0 {
+		return nil
+	}
+	s, err := secret.Int()
+	if err != nil {
+		return err
+	}
+	scalar := toFixedBytes(s, e222Width)
+	wipe_int(s)
+	defer wipe_bytes(scalar)
+	if err := ks.backend.set(key.Id, scalar); err != nil {
+		return err
+	}
+	ks.keys = append(ks.keys, key)
+	if err := ks.write_index(); err != nil {
+		ks.keys = ks.keys[:len(ks.keys)-1]
+		ks.backend.delete(key.Id)
+		return err
+	}
+	return nil
+}
+
+/*
+Reads the secret scalar of the key with Id id from the keychain, a copy
+for the caller to destroy. A keychain entry that does not derive the
+key's public key fails with ErrKeystoreKey.
+*/
+func (ks *KeychainStore) Secret(id string) (*SecretScalar, error) {
+	i := ks.index_of(id)
+	if i < 0 {
+		return nil, ErrKeystoreMissing
+	}
+	b, err := ks.backend.get(id)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe_bytes(b)
+	s := fromFixedBytes(b)
+	defer wipe_int(s)
+	secret := NewSecretScalar(s)
+	if len(b) != e222Width || check_key_secret(ks.keys[i], secret) != nil {
+		secret.Destroy()
+		return nil, ErrKeystoreKey
+	}
+	return secret, nil
+}
+
+/*
+Deletes the key with Id id from the keychain and the index; false if
+there is none or the keychain refuses. Should rewriting the index fail,
+the key is listed again on the next open, and Secret reports
+ErrKeystoreMissing for it.
+*/
+func (ks *KeychainStore) Remove(id string) bool {
+	i := ks.index_of(id)
+	if i < 0 {
+		return false
+	}
+	if err := ks.backend.delete(id); err != nil && !errors.Is(err, ErrKeystoreMissing) {
+		return false
+	}
+	ks.keys = append(ks.keys[:i], ks.keys[i+1:]...)
+	ks.write_index()
+	return true
+}
+
+func (ks *KeychainStore) index_of(id string) int {
+	for i, key := range ks.keys {
+		if key.Id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (ks *KeychainStore) write_index() error {
+	data, err := ExportKeys(ks.keys)
+	if err != nil {
+		return err
+	}
+	return write_file_atomic(ks.index, data)
+}
+
+/*
+Runs a keychain command line tool with stdin as its input and returns
+its output with surrounding white space trimmed. Secrets go through stdin
+and stdout only, never the command line, which other users can list.
+*/
+func keychain_command(stdin []byte, name string, args ...string) ([]byte, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		wipe_bytes(out)
+		return nil, fmt.Errorf("keychain: %s %s: %w: %s", name, args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return bytes.TrimSpace(out), nil
+}
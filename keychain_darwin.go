@@ -0,0 +1,52 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"encoding/hex"
+	"errors"
+	"os/exec"
+)
+
+/*
+The macOS Keychain through the security tool, as generic passwords of
+service keychainService and the key Id as account. Secrets are stored hex
+encoded; add-generic-password runs in the interactive mode of security,
+reading its command from stdin, so that the secret never appears on a
+command line.
+*/
+type macKeychain struct{ tool string }
+
+func platform_keychain() (keychainBackend, error) {
+	tool, err := exec.LookPath("security")
+	if err != nil {
+		return nil, ErrKeychainUnavailable
+	}
+	return macKeychain{tool}, nil
+}
+
+func (k macKeychain) set(id string, secret []byte) error {
+	command := []byte(`add-generic-password -U -s "` + keychainService + `" -a "` + id + `" -w "` +
+		hex.EncodeToString(secret) + "\"\n")
+	defer wipe_bytes(command)
+	_, err := keychain_command(command, k.tool, "-i")
+	return err
+}
+
+func (k macKeychain) get(id string) ([]byte, error) {
+	out, err := keychain_command(nil, k.tool, "find-generic-password", "-s", keychainService, "-a", id, "-w")
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return nil, ErrKeystoreMissing
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer wipe_bytes(out)
+	return hex.DecodeString(string(out))
+}
+
+func (k macKeychain) delete(id string) error {
+	_, err := keychain_command(nil, k.tool, "delete-generic-password", "-s", keychainService, "-a", id)
+	return err
+}
@@ -0,0 +1,50 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"encoding/hex"
+	"errors"
+	"os/exec"
+)
+
+/*
+The Secret Service of freedesktop.org, as GNOME Keyring and KWallet
+provide it, through secret-tool of libsecret. Secrets are stored hex
+encoded under the attributes service and account.
+*/
+type secretServiceKeychain struct{ tool string }
+
+func platform_keychain() (keychainBackend, error) {
+	tool, err := exec.LookPath("secret-tool")
+	if err != nil {
+		return nil, ErrKeychainUnavailable
+	}
+	return secretServiceKeychain{tool}, nil
+}
+
+func (k secretServiceKeychain) set(id string, secret []byte) error {
+	enc := []byte(hex.EncodeToString(secret))
+	defer wipe_bytes(enc)
+	_, err := keychain_command(enc, k.tool, "store", "--label", keychainService+" "+id,
+		"service", keychainService, "account", id)
+	return err
+}
+
+func (k secretServiceKeychain) get(id string) ([]byte, error) {
+	out, err := keychain_command(nil, k.tool, "lookup", "service", keychainService, "account", id)
+	var exit *exec.ExitError
+	if errors.As(err, &exit) || (err == nil && len(out) == 0) {
+		return nil, ErrKeystoreMissing
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer wipe_bytes(out)
+	return hex.DecodeString(string(out))
+}
+
+func (k secretServiceKeychain) delete(id string) error {
+	_, err := keychain_command(nil, k.tool, "clear", "service", keychainService, "account", id)
+	return err
+}
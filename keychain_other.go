@@ -0,0 +1,7 @@
+//go:build !verifyonly && !darwin && !linux && !windows
+
+package sig
+
+func platform_keychain() (keychainBackend, error) {
+	return nil, ErrKeychainUnavailable
+}
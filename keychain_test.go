@@ -0,0 +1,149 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// A keychainBackend in memory, standing in for the platform keychain.
+type memoryKeychain map[string][]byte
+
+func (m memoryKeychain) set(id string, secret []byte) error {
+	m[id] = append([]byte(nil), secret...)
+	return nil
+}
+
+func (m memoryKeychain) get(id string) ([]byte, error) {
+	if b, ok := m[id]; ok {
+		return append([]byte(nil), b...), nil
+	}
+	return nil, ErrKeystoreMissing
+}
+
+func (m memoryKeychain) delete(id string) error {
+	if _, ok := m[id]; !ok {
+		return ErrKeystoreMissing
+	}
+	delete(m, id)
+	return nil
+}
+
+// The keystore file and the keychain store behave alike as KeyStores; the keychain index holds no secrets.
+func TestKeychainStoreBackend(t *testing.T) {
+
+	dir := t.TempDir()
+	keychain := memoryKeychain{}
+	index := filepath.Join(dir, "keys.asc")
+	chain, err := open_keychain_store(index, keychain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := OpenKeystore(filepath.Join(dir, "keys.ks"), []byte("master password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Destroy()
+
+	pws := [][]byte{[]byte("keychain passphrase one"), []byte("keychain passphrase two")}
+	wrong, _ := e222_keypair_from_passphrase([]byte("not the right one"))
+	defer wrong.Destroy()
+	for _, store := range []KeyStore{chain, file} {
+		for i, pw := range pws {
+			key, err := GenerateKeyObj(fmt.Sprint("owner ", i), pw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			secret, _ := e222_keypair_from_passphrase(pw)
+			if err := store.Add(key, secret); err != nil {
+				t.Fatalf("%T: %v", store, err)
+			}
+			if err := store.Add(key, secret); err != nil {
+				t.Fatalf("%T, adding again: %v", store, err)
+			}
+			if err := store.Add(key, wrong); err != ErrKeystoreKey {
+				t.Fatalf("%T, mismatched secret: %v, want %v", store, err, ErrKeystoreKey)
+			}
+			secret.Destroy()
+		}
+		keys := store.Keys()
+		if len(keys) != 2 {
+			t.Fatalf("%T holds %d keys, want 2", store, len(keys))
+		}
+		for _, key := range keys {
+			s, err := store.Secret(key.Id)
+			if err != nil {
+				t.Fatalf("%T: %v", store, err)
+			}
+			if err := check_key_secret(key, s); err != nil {
+				t.Fatalf("%T: %v", store, err)
+			}
+			s.Destroy()
+		}
+		if _, err := store.Secret("no such key"); err != ErrKeystoreMissing {
+			t.Fatalf("%T, missing key: %v, want %v", store, err, ErrKeystoreMissing)
+		}
+	}
+
+	// the index lists the keys without their secrets and reopens with them
+	data, err := os.ReadFile(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, secret := range keychain {
+		if !bytes.Contains(data, []byte(id)) {
+			t.Fatalf("index does not list %s", id)
+		}
+		if bytes.Contains(data, secret) || bytes.Contains(data, []byte(hex.EncodeToString(secret))) {
+			t.Fatalf("index holds the secret of %s", id)
+		}
+	}
+	reopened, err := open_keychain_store(index, keychain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(reopened.Keys()); n != 2 {
+		t.Fatalf("reopened store holds %d keys, want 2", n)
+	}
+	first := chain.Keys()[0].Id
+	s, err := reopened.Secret(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := check_key_secret(chain.Keys()[0], s); err != nil {
+		t.Fatal(err)
+	}
+	s.Destroy()
+
+	// a keychain entry for another key is refused; removal clears keychain and index
+	keychain[first] = bytes.Repeat([]byte{1}, e222Width)
+	if _, err := reopened.Secret(first); err != ErrKeystoreKey {
+		t.Fatalf("entry for another key: %v, want %v", err, ErrKeystoreKey)
+	}
+	if !reopened.Remove(first) {
+		t.Fatal("key is not removed")
+	}
+	if reopened.Remove(first) {
+		t.Fatal("key is removed twice")
+	}
+	if _, ok := keychain[first]; ok {
+		t.Fatal("removed key stays in the keychain")
+	}
+	after, err := open_keychain_store(index, keychain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := after.Keys(); len(keys) != 1 || keys[0].Id == first {
+		t.Fatalf("index lists %d keys after the removal", len(keys))
+	}
+
+	// without the platform tool there is no keychain to open
+	if _, err := OpenKeychainStore(index); err != nil && err != ErrKeychainUnavailable {
+		t.Fatalf("platform keychain: %v, want nil or %v", err, ErrKeychainUnavailable)
+	}
+}
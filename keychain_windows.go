@@ -0,0 +1,87 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+/*
+DPAPI: every secret is encrypted with CryptProtectData under the user's
+Windows login and the blob kept in a file named after the key Id in the
+sig\keychain folder of the user's configuration directory. Only the same
+user on the same machine can decrypt it.
+*/
+type dpapiKeychain struct{ dir string }
+
+func platform_keychain() (keychainBackend, error) {
+	config, err := os.UserConfigDir()
+	if err != nil {
+		return nil, ErrKeychainUnavailable
+	}
+	return dpapiKeychain{filepath.Join(config, "sig", "keychain")}, nil
+}
+
+func (k dpapiKeychain) path(id string) string { return filepath.Join(k.dir, id+".dpapi") }
+
+func (k dpapiKeychain) set(id string, secret []byte) error {
+	blob, err := dpapi(true, secret)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(k.dir, 0o700); err != nil {
+		return err
+	}
+	return write_file_atomic(k.path(id), blob)
+}
+
+func (k dpapiKeychain) get(id string) ([]byte, error) {
+	blob, err := os.ReadFile(k.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrKeystoreMissing
+	}
+	if err != nil {
+		return nil, err
+	}
+	return dpapi(false, blob)
+}
+
+func (k dpapiKeychain) delete(id string) error {
+	err := os.Remove(k.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrKeystoreMissing
+	}
+	return err
+}
+
+/*
+Runs CryptProtectData, or CryptUnprotectData unless protect is set, over
+data without UI, copies the result out of the buffer DPAPI allocated, and
+wipes and frees that.
+*/
+func dpapi(protect bool, data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, errors.New("keychain: empty DPAPI input")
+	}
+	in := windows.DataBlob{Size: uint32(len(data)), Data: &data[0]}
+	var out windows.DataBlob
+	var err error
+	if protect {
+		err = windows.CryptProtectData(&in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out)
+	} else {
+		err = windows.CryptUnprotectData(&in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(out.Data)))
+	buf := unsafe.Slice(out.Data, out.Size)
+	result := append([]byte(nil), buf...)
+	wipe_bytes(buf)
+	return result, nil
+}
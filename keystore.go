@@ -0,0 +1,422 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"sig/kmac"
+)
+
+const (
+	keystoreMagic      = "SIGKS"
+	keystoreVersion    = 0x02
+	keystoreSaltLength = 16      // bytes
+	keystoreTagLength  = 64      // bytes
+	keystoreMaxMemory  = 1 << 20 // KiB of Argon2id memory accepted on load
+	keystoreMaxTime    = 16      // Argon2id passes accepted on load
+)
+
+var (
+	ErrKeystoreFormat  = errors.New("keystore: not a keystore file, or an unsupported version")
+	ErrKeystoreKey     = errors.New("keystore: secret key does not match the key's public key")
+	ErrKeystoreMissing = errors.New("keystore: no key with this Id")
+)
+
+// Argon2id cost of new keystore files, the second recommendation of RFC 9106 Section 4.
+var keystoreArgon2 = keystoreKDF{time: 3, memory: 64 << 10, threads: 4}
+
+// Argon2id parameters as stored in a keystore header, memory in KiB.
+type keystoreKDF struct {
+	time, memory uint32
+	threads      uint8
+}
+
+/*
+A file of E222 key pairs, their KeyObj and secret scalar, encrypted as a
+whole under a master password:
+
+	K ← Argon2id(NFC(pw), salt, time, memory, threads, 256 bits)
+	(ke || ka) ← KMACXOF256(K, header, 512, "KS")
+	c ← KMACXOF256(ke, "", |m|, "KSKE") ⊕ m
+	t ← KMACXOF256(ka, header || c, 512, "KSKA")
+
+stored as header || c || t, with the header
+
+	"SIGKS" || version (1 byte) || time (4 bytes) || memory (4 bytes) ||
+	threads (1 byte) || salt (16 bytes)
+
+so that the cost can be raised without breaking older files. Every save
+draws a fresh salt and so fresh keys. The plaintext m is the key count
+(2 bytes) followed per key by its owner, creation date (RFC 3339, UTC),
+self-signature, expiry date (RFC 3339, UTC, empty if none) and
+revocation certificate (empty if none), each 2 byte length prefixed, and
+its secret scalar in e222Width bytes; public keys and Ids are derived
+again on load. Version 1 files, which lack the expiry date and
+revocation, still load, and are saved as version 2.
+*/
+type Keystore struct {
+	path    string
+	keys    []*KeyObj
+	secrets []*SecretScalar
+}
+
+/*
+Loads the keystore at path with the master password pw, or starts an
+empty one if no file exists there yet, so that it can be called on every
+startup. A wrong password and any change to the file fail alike with
+ErrDecryption.
+*/
+func OpenKeystore(path string, pw []byte) (*Keystore, error) {
+	ks := &Keystore{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ks, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	m, version, err := keystore_open(data, pw)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe_bytes(m)
+	if err := ks.parse(m, version); err != nil {
+		ks.Destroy()
+		return nil, err
+	}
+	return ks, nil
+}
+
+// The key table, in the order keys were added.
+func (ks *Keystore) Keys() []*KeyObj { return append([]*KeyObj(nil), ks.keys...) }
+
+/*
+Adds key with its secret scalar, which must derive key.PubKey, or fails
+with ErrKeystoreKey. The keystore keeps its own copy of secret. A key
+already present is left as it is.
+*/
+func (ks *Keystore) Add(key *KeyObj, secret *SecretScalar) error {
+	s, err := secret.Int()
+	if err != nil {
+		return err
+	}
+	defer wipe_int(s)
+	if key == nil || key.PubKey == nil || !e222Curve.BaseMul(s).Equals(key.PubKey) {
+		return ErrKeystoreKey
+	}
+	if ks.index(key_id(key.PubKey)) >= 0 {
+		return nil
+	}
+	ks.keys = append(ks.keys, key)
+	ks.secrets = append(ks.secrets, NewSecretScalar(s))
+	return nil
+}
+
+// The secret scalar of the key with Id id, a copy for the caller to destroy.
+func (ks *Keystore) Secret(id string) (*SecretScalar, error) {
+	i := ks.index(id)
+	if i < 0 {
+		return nil, ErrKeystoreMissing
+	}
+	s, err := ks.secrets[i].Int()
+	if err != nil {
+		return nil, err
+	}
+	defer wipe_int(s)
+	return NewSecretScalar(s), nil
+}
+
+// Removes the key with Id id and destroys its secret; false if there is none.
+func (ks *Keystore) Remove(id string) bool {
+	i := ks.index(id)
+	if i < 0 {
+		return false
+	}
+	ks.secrets[i].Destroy()
+	ks.keys = append(ks.keys[:i], ks.keys[i+1:]...)
+	ks.secrets = append(ks.secrets[:i], ks.secrets[i+1:]...)
+	return true
+}
+
+func (ks *Keystore) index(id string) int {
+	for i, key := range ks.keys {
+		if key.Id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+/*
+Encrypts the keystore under pw and writes it to its path atomically: to
+a temporary file in the same directory, synced and then renamed over the
+old file, so that a crash leaves either the old keystore or the new one,
+never a torn one. The file is readable by its owner only. Weak passwords
+are rejected with ErrWeakPassphrase.
+*/
+func (ks *Keystore) Save(pw []byte) error {
+	if err := check_passphrase(pw, DefaultMinPassphraseLength); err != nil {
+		return err
+	}
+	m, err := ks.bytes()
+	if err != nil {
+		return err
+	}
+	defer wipe_bytes(m)
+	data, err := keystore_seal(m, pw, keystoreArgon2)
+	if err != nil {
+		return err
+	}
+	return write_file_atomic(ks.path, data)
+}
+
+/*
+Writes data to path through a temporary file in the same directory,
+readable by its owner only, synced and then renamed over path.
+*/
+func write_file_atomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".keystore-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Destroys every secret scalar; the keystore is empty afterwards.
+func (ks *Keystore) Destroy() {
+	for _, s := range ks.secrets {
+		s.Destroy()
+	}
+	ks.keys, ks.secrets = nil, nil
+}
+
+// The plaintext m of the file format, for the caller to wipe.
+func (ks *Keystore) bytes() ([]byte, error) {
+	if len(ks.keys) > 0xffff {
+		return nil, errors.New("keystore: too many keys")
+	}
+	m := binary.BigEndian.AppendUint16(nil, uint16(len(ks.keys)))
+	for i, key := range ks.keys {
+		revocation := encode_revocation(key.Revocation)
+		if len(key.Owner) > 0xffff || len(key.Signature) > 0xffff || len(revocation) > 0xffff {
+			wipe_bytes(m)
+			return nil, errors.New("keystore: key fields too long")
+		}
+		s, err := ks.secrets[i].Int()
+		if err != nil {
+			wipe_bytes(m)
+			return nil, err
+		}
+		m = append(m, length_prefixed([]byte(key.Owner))...)
+		m = append(m, length_prefixed([]byte(key.DateCreated.UTC().Format(time.RFC3339)))...)
+		m = append(m, length_prefixed(key.Signature)...)
+		var expires []byte
+		if !key.Expires.IsZero() {
+			expires = []byte(key.Expires.UTC().Format(time.RFC3339))
+		}
+		m = append(m, length_prefixed(expires)...)
+		m = append(m, length_prefixed(revocation)...)
+		scalar := toFixedBytes(s, e222Width)
+		m = append(m, scalar...)
+		wipe_bytes(scalar)
+		wipe_int(s)
+	}
+	return m, nil
+}
+
+/*
+Reads the plaintext m of a file of version into the key table; keys
+whose self-signature fails get Untrusted set, and revocations that fail
+are dropped.
+*/
+func (ks *Keystore) parse(m []byte, version byte) error {
+	malformed := errors.New("keystore: malformed key table")
+	if len(m) < 2 {
+		return malformed
+	}
+	count := int(binary.BigEndian.Uint16(m))
+	rest := m[2:]
+	field := func() []byte {
+		if len(rest) < 2 || len(rest) < 2+int(binary.BigEndian.Uint16(rest)) {
+			return nil
+		}
+		n := int(binary.BigEndian.Uint16(rest))
+		f := rest[2 : 2+n]
+		rest = rest[2+n:]
+		return f
+	}
+	for i := 0; i < count; i++ {
+		owner, date, sig := field(), field(), field()
+		expires, revocation := []byte{}, []byte{}
+		if version >= 2 {
+			expires, revocation = field(), field()
+		}
+		if owner == nil || date == nil || sig == nil || expires == nil || revocation == nil || len(rest) < e222Width {
+			return malformed
+		}
+		created, err := time.Parse(time.RFC3339, string(date))
+		if err != nil {
+			return malformed
+		}
+		var expiry time.Time
+		if len(expires) > 0 {
+			if expiry, err = time.Parse(time.RFC3339, string(expires)); err != nil {
+				return malformed
+			}
+		}
+		rev, err := parse_revocation(revocation)
+		if err != nil {
+			return malformed
+		}
+		s := fromFixedBytes(rest[:e222Width])
+		rest = rest[e222Width:]
+		if check_scalar(s) != nil {
+			wipe_int(s)
+			return malformed
+		}
+		V := e222Curve.BaseMul(s)
+		key := &KeyObj{
+			Id:          key_id(V),
+			Owner:       string(owner),
+			DateCreated: created,
+			Expires:     expiry,
+			PubKey:      V,
+			Signature:   append([]byte(nil), sig...),
+			Revocation:  rev,
+		}
+		ok, _ := VerifyKeyObjSelfSig(key)
+		key.Untrusted = !ok
+		if revoked, _ := VerifyKeyRevocation(key); !revoked {
+			key.Revocation = nil
+		}
+		ks.keys = append(ks.keys, key)
+		ks.secrets = append(ks.secrets, NewSecretScalar(s))
+		wipe_int(s)
+	}
+	if len(rest) != 0 {
+		return malformed
+	}
+	return nil
+}
+
+// A revocation certificate as stored in a keystore: its date and reason, 2 byte length prefixed, and signature.
+func encode_revocation(rev *KeyRevocation) []byte {
+	if rev == nil {
+		return nil
+	}
+	out := length_prefixed([]byte(rev.Date.UTC().Format(time.RFC3339)))
+	out = append(out, length_prefixed([]byte(rev.Reason))...)
+	return append(out, rev.Signature...)
+}
+
+// Decodes encode_revocation, nil for an empty encoding.
+func parse_revocation(b []byte) (*KeyRevocation, error) {
+	if len(b) == 0 {
+		return nil, nil
+	}
+	malformed := errors.New("keystore: malformed revocation")
+	var fields [2][]byte
+	for i := range fields {
+		if len(b) < 2 || len(b) < 2+int(binary.BigEndian.Uint16(b)) {
+			return nil, malformed
+		}
+		n := int(binary.BigEndian.Uint16(b))
+		fields[i], b = b[2:2+n], b[2+n:]
+	}
+	date, err := time.Parse(time.RFC3339, string(fields[0]))
+	if err != nil {
+		return nil, malformed
+	}
+	return &KeyRevocation{Date: date, Reason: string(fields[1]), Signature: append([]byte(nil), b...)}, nil
+}
+
+func (p keystoreKDF) header(salt []byte) []byte {
+	h := append([]byte(keystoreMagic), keystoreVersion)
+	h = binary.BigEndian.AppendUint32(h, p.time)
+	h = binary.BigEndian.AppendUint32(h, p.memory)
+	h = append(h, p.threads)
+	return append(h, salt...)
+}
+
+// ke || ka of the file format as SecretBytes for the caller to wipe.
+func keystore_keys(pw, salt, header []byte, p keystoreKDF) *SecretBytes {
+	K := argon2.IDKey(normalize_passphrase(pw), salt, p.time, p.memory, p.threads, 32)
+	defer wipe_bytes(K)
+	return NewSecretBytes(kmac.XOF256(K, header, 512, "KS"))
+}
+
+func keystore_seal(m, pw []byte, p keystoreKDF) ([]byte, error) {
+	salt := make([]byte, keystoreSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	header := p.header(salt)
+	keys := keystore_keys(pw, salt, header, p)
+	defer keys.Wipe()
+	ke, ka := split_keys(keys.b)
+	out := append(header, xor_bytes(kmac.XOF256(ke, nil, 8*len(m), "KSKE"), m)...)
+	return append(out, kmac.XOF256(ka, out, 8*keystoreTagLength, "KSKA")...), nil
+}
+
+/*
+Checks and decrypts a keystore file, returning the plaintext and the
+file's version. The header is checked first, and
+costs beyond keystoreMaxMemory or keystoreMaxTime are refused, so that a
+crafted file cannot make loading exhaust memory or run for hours.
+*/
+func keystore_open(data, pw []byte) ([]byte, byte, error) {
+	headerLength := len(keystoreMagic) + 1 + 4 + 4 + 1 + keystoreSaltLength
+	if len(data) < headerLength+keystoreTagLength || !bytes.HasPrefix(data, []byte(keystoreMagic)) {
+		return nil, 0, ErrKeystoreFormat
+	}
+	version := data[len(keystoreMagic)]
+	if version != 0x01 && version != keystoreVersion {
+		return nil, 0, ErrKeystoreFormat
+	}
+	fixed := data[len(keystoreMagic)+1:]
+	p := keystoreKDF{
+		time:    binary.BigEndian.Uint32(fixed[0:4]),
+		memory:  binary.BigEndian.Uint32(fixed[4:8]),
+		threads: fixed[8],
+	}
+	if p.time == 0 || p.threads == 0 || p.memory < 8*uint32(p.threads) ||
+		p.memory > keystoreMaxMemory || p.time > keystoreMaxTime {
+		return nil, 0, ErrKeystoreFormat
+	}
+	header := data[:headerLength]
+	c := data[headerLength : len(data)-keystoreTagLength]
+	t := data[len(data)-keystoreTagLength:]
+
+	keys := keystore_keys(pw, header[headerLength-keystoreSaltLength:], header, p)
+	defer keys.Wipe()
+	ke, ka := split_keys(keys.b)
+	if subtle.ConstantTimeCompare(t, kmac.XOF256(ka, data[:len(data)-keystoreTagLength], 8*keystoreTagLength, "KSKA")) != 1 {
+		return nil, 0, ErrDecryption
+	}
+	return xor_bytes(kmac.XOF256(ke, nil, 8*len(c), "KSKE"), c), version, nil
+}
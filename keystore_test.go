@@ -0,0 +1,173 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Keystores save atomically under a master password and load their keys and secrets back.
+func TestKeystoreRoundTrip(t *testing.T) {
+
+	dir := t.TempDir()
+	saved := keystoreArgon2
+	keystoreArgon2 = keystoreKDF{time: 1, memory: 64, threads: 1}
+	defer func() { keystoreArgon2 = saved }()
+	path := filepath.Join(dir, "keys.ks")
+	master := []byte("correct horse battery")
+
+	// a missing file opens as an empty keystore
+	ks, err := OpenKeystore(path, master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(ks.Keys()); n != 0 {
+		t.Fatalf("missing file opens with %d keys", n)
+	}
+	pws := [][]byte{[]byte("first passphrase"), []byte("second passphrase")}
+	for i, pw := range pws {
+		key, err := GenerateKeyObj(fmt.Sprint("owner ", i), pw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		secret, _ := e222_keypair_from_passphrase(pw)
+		if err := ks.Add(key, secret); err != nil {
+			t.Fatal(err)
+		}
+		// adding a key again replaces it
+		if err := ks.Add(key, secret); err != nil {
+			t.Fatal(err)
+		}
+		secret.Destroy()
+	}
+	wrong, _ := e222_keypair_from_passphrase([]byte("another passphrase"))
+	if err := ks.Add(ks.Keys()[0], wrong); err != ErrKeystoreKey {
+		t.Fatalf("mismatched secret: %v, want %v", err, ErrKeystoreKey)
+	}
+	if n := len(ks.Keys()); n != 2 {
+		t.Fatalf("keystore holds %d keys, want 2", n)
+	}
+	if err := ks.Save([]byte("short")); err == nil {
+		t.Fatal("keystore saves under a weak master password")
+	}
+	if err := ks.Save(master); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Fatalf("keystore saved with mode %o, want 600", perm)
+	}
+
+	loaded, err := OpenKeystore(path, master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(loaded.Keys()); n != 2 {
+		t.Fatalf("keystore loads %d keys, want 2", n)
+	}
+	for i, key := range ks.Keys() {
+		got := loaded.Keys()[i]
+		if got.Id != key.Id || got.Owner != key.Owner || !got.DateCreated.Equal(key.DateCreated) || got.Untrusted {
+			t.Fatalf("key %d loads as %+v, want %+v", i, got, key)
+		}
+		s, err := loaded.Secret(key.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := s.Int()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, _ := e222_keypair_from_passphrase(pws[i])
+		e, err := expected.Int()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.Cmp(e) != 0 {
+			t.Fatalf("key %d: secret %v, want %v", i, v, e)
+		}
+	}
+
+	// saves draw fresh salts, removals persist, and no temporary files are left behind
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed := ks.Keys()[0].Id
+	if !loaded.Remove(removed) {
+		t.Fatal("key is not removed")
+	}
+	if loaded.Remove(removed) {
+		t.Fatal("key is removed twice")
+	}
+	if err := loaded.Save(master); err != nil {
+		t.Fatal(err)
+	}
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(before[:30], after[:30]) {
+		t.Fatal("save reuses the salt")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("%d files after saving, want 1", len(entries))
+	}
+	reloaded, err := OpenKeystore(path, master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(reloaded.Keys()); n != 1 {
+		t.Fatalf("keystore reloads %d keys, want 1", n)
+	}
+	if _, err := reloaded.Secret(removed); err != ErrKeystoreMissing {
+		t.Fatalf("removed key: %v, want %v", err, ErrKeystoreMissing)
+	}
+
+	// wrong passwords, tampering, other versions and excessive costs are refused
+	if _, err := OpenKeystore(path, []byte("wrong horse battery")); err != ErrDecryption {
+		t.Fatalf("wrong password: %v, want %v", err, ErrDecryption)
+	}
+	tampered := append([]byte(nil), after...)
+	tampered[len(tampered)-keystoreTagLength-1] ^= 1
+	version := append([]byte(nil), after...)
+	version[len(keystoreMagic)] = 0x03
+	costly := append([]byte(nil), after...)
+	binary.BigEndian.PutUint32(costly[len(keystoreMagic)+5:], keystoreMaxMemory+1)
+	slow := append([]byte(nil), after...)
+	binary.BigEndian.PutUint32(slow[len(keystoreMagic)+1:], keystoreMaxTime+1)
+	refused := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"tampered", tampered, ErrDecryption},
+		{"other version", version, ErrKeystoreFormat},
+		{"excessive memory", costly, ErrKeystoreFormat},
+		{"excessive time", slow, ErrKeystoreFormat},
+	}
+	for _, r := range refused {
+		if err := os.WriteFile(path, r.data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := OpenKeystore(path, master); err != r.want {
+			t.Fatalf("%s: %v, want %v", r.name, err, r.want)
+		}
+	}
+	ks.Destroy()
+	if n := len(ks.Keys()); n != 0 {
+		t.Fatalf("destroyed keystore holds %d keys", n)
+	}
+}
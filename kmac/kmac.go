@@ -0,0 +1,64 @@
+/*
+Package kmac implements KMACXOF256 and the encoding functions it is built
+from, as specified in NIST SP 800-185.
+*/
+package kmac
+
+import (
+	"golang.org/x/crypto/sha3"
+)
+
+/*
+XOF256 is KMACXOF256 as specified in NIST SP 800-185 Section 4.3.1:
+
+	KMACXOF256(K, X, L, S) = cSHAKE256(bytepad(encode_string(K), 136) || X || right_encode(0), L, "KMAC", S)
+
+	K: key
+	X: input data
+	L: output length in bits, a multiple of 8
+	S: customization string
+*/
+func XOF256(K, X []byte, L int, S string) []byte {
+	h := sha3.NewCShake256([]byte("KMAC"), []byte(S))
+	h.Write(Bytepad(EncodeString(K), 136))
+	h.Write(X)
+	h.Write(RightEncode(0))
+	out := make([]byte, L/8)
+	h.Read(out)
+	return out
+}
+
+// LeftEncode is left_encode from NIST SP 800-185 Section 2.3.1.
+func LeftEncode(x uint64) []byte {
+	b := big_endian_trimmed(x)
+	return append([]byte{byte(len(b))}, b...)
+}
+
+// RightEncode is right_encode from NIST SP 800-185 Section 2.3.1.
+func RightEncode(x uint64) []byte {
+	b := big_endian_trimmed(x)
+	return append(b, byte(len(b)))
+}
+
+// minimal big endian encoding of x, at least one byte long
+func big_endian_trimmed(x uint64) []byte {
+	b := []byte{byte(x)}
+	for x >>= 8; x > 0; x >>= 8 {
+		b = append([]byte{byte(x)}, b...)
+	}
+	return b
+}
+
+// EncodeString is encode_string from NIST SP 800-185 Section 2.3.2.
+func EncodeString(s []byte) []byte {
+	return append(LeftEncode(uint64(len(s))*8), s...)
+}
+
+// Bytepad is bytepad from NIST SP 800-185 Section 2.3.3.
+func Bytepad(x []byte, w int) []byte {
+	out := append(LeftEncode(uint64(w)), x...)
+	for len(out)%w != 0 {
+		out = append(out, 0)
+	}
+	return out
+}
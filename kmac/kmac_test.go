@@ -0,0 +1,21 @@
+package kmac
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// NIST SP 800-185 KMACXOF256 sample #4
+func TestXOF256KnownAnswer(t *testing.T) {
+
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(0x40 + i)
+	}
+	expected := "1755133f1534752aad0748f2c706fb5c784512cab835cd15676b16c0c6647fa9" +
+		"6faa7af634a0bf8ff6df39374fa00fad9a39e322a7c92065a64eb1fb0801eb2b"
+	out := XOF256(key, []byte{0, 1, 2, 3}, 512, "My Tagged Application")
+	if got := hex.EncodeToString(out); got != expected {
+		t.Fatalf("XOF256 = %s, want %s", got, expected)
+	}
+}
@@ -0,0 +1,69 @@
+package sig
+
+import (
+	"context"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Input is hashed in chunks of this size so that cancellation is noticed mid-hash.
+const liveDigestChunk = 64 * 1024
+
+// SHA3-256 digest and size statistics of one snapshot of text.
+type LiveDigest struct {
+	Digest []byte
+	Bytes  int
+	Chars  int
+}
+
+/*
+Hashes snapshots of text received on in, waiting until no new snapshot
+has arrived for delay before hashing the latest one, and passes each
+result to out. If in is buffered, a snapshot arriving while a hash is in
+progress abandons that hash. Returns when ctx is cancelled or in is closed; nothing is
+passed to out after cancellation.
+*/
+func run_digest_worker(ctx context.Context, in <-chan []byte, delay time.Duration, out func(LiveDigest)) {
+	var pending []byte
+	var timer <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case snapshot, ok := <-in:
+			if !ok {
+				return
+			}
+			pending = snapshot
+			timer = time.After(delay)
+		case <-timer:
+			timer = nil
+			if digest, ok := live_digest(ctx, in, pending); ok {
+				out(digest)
+			} else if ctx.Err() != nil {
+				return
+			}
+		}
+	}
+}
+
+// Hashes text, giving up if ctx is cancelled or a newer snapshot is waiting on in.
+func live_digest(ctx context.Context, in <-chan []byte, text []byte) (LiveDigest, bool) {
+	h := sha3.New256()
+	for i := 0; i < len(text); i += liveDigestChunk {
+		if ctx.Err() != nil || len(in) > 0 {
+			return LiveDigest{}, false
+		}
+		end := i + liveDigestChunk
+		if end > len(text) {
+			end = len(text)
+		}
+		h.Write(text[i:end])
+	}
+	if ctx.Err() != nil {
+		return LiveDigest{}, false
+	}
+	return LiveDigest{Digest: h.Sum(nil), Bytes: len(text), Chars: utf8.RuneCount(text)}, true
+}
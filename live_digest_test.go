@@ -0,0 +1,63 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Rapid successive updates produce a single digest of the latest snapshot.
+func TestLiveDigestDebounce(t *testing.T) {
+
+	in := make(chan []byte, 1)
+	results := make(chan LiveDigest, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go run_digest_worker(ctx, in, 50*time.Millisecond, func(d LiveDigest) { results <- d })
+
+	for _, s := range []string{"h", "he", "hel", "hell", "héllo"} {
+		in <- []byte(s)
+	}
+	first := <-results
+	expected := sha3.Sum256([]byte("héllo"))
+	if !bytes.Equal(first.Digest, expected[:]) {
+		t.Fatalf("digest %x, want %x", first.Digest, expected)
+	}
+	if first.Bytes != 6 || first.Chars != 5 {
+		t.Fatalf("%d bytes and %d characters, want 6 and 5", first.Bytes, first.Chars)
+	}
+	select {
+	case d := <-results:
+		t.Fatalf("second digest %x delivered", d.Digest)
+	case <-time.After(150 * time.Millisecond):
+	}
+}
+
+// Switching the worker off mid-hash delivers nothing.
+func TestLiveDigestCancel(t *testing.T) {
+
+	in := make(chan []byte, 1)
+	results := make(chan LiveDigest, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan bool)
+	go func() {
+		run_digest_worker(ctx, in, time.Millisecond, func(d LiveDigest) { results <- d })
+		done <- true
+	}()
+
+	in <- make([]byte, 256*1024*1024)
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	<-done
+
+	select {
+	case d := <-results:
+		t.Fatalf("digest %x delivered after cancelling", d.Digest)
+	default:
+	}
+}
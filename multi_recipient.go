@@ -0,0 +1,220 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"math/big"
+
+	"sig/kmac"
+)
+
+const (
+	messageArmorType  = "ENCRYPTED MESSAGE"
+	envelopeVersion   = 0x02
+	contentKeyLength  = 32 // bytes
+	envelopeTagLength = 32 // bytes
+	stanzaLength      = 2*e222Width + contentKeyLength + envelopeTagLength
+	recipientIDLength = 16 // bytes, see key_id_bytes
+)
+
+// Version of envelopes whose stanzas carry no recipient key IDs, still decrypted.
+const envelopeVersionAnonymous = 0x01
+
+var ErrNotRecipient = errors.New("no recipient stanza could be opened with this passphrase")
+
+/*
+Wraps key for the holder of public key V using the ECIES construction:
+
+	k ← random scalar, W ← k × 4V, Z ← k × G
+	(ke || ka) ← KMACXOF256(W.x, "", 512, "P")
+	c ← KMACXOF256(ke, "", |key|, "PKE") ⊕ key
+	t ← KMACXOF256(ka, key, 256, "PKA")
+
+and returns the stanza Z || c || t.
+*/
+func ecies_wrap(V *E222, key []byte) ([]byte, error) {
+	return ecies_seal(V, key, "P")
+}
+
+// Opens a stanza produced by ecies_wrap with secret scalar s.
+func ecies_unwrap(s *big.Int, stanza []byte) ([]byte, error) {
+	if err := check_scalar(s); err != nil {
+		return nil, err
+	}
+	if len(stanza) != stanzaLength {
+		return nil, errors.New("malformed recipient stanza")
+	}
+	key, err := ecies_open(s, stanza, "P")
+	if err == ErrDecryption {
+		return nil, errors.New("recipient stanza failed to authenticate")
+	}
+	return key, err
+}
+
+/*
+Encrypts msg so that the holder of any of pubs can decrypt it. A random
+content key encrypts the payload with the KMAC stream cipher:
+
+	(ke || ka) ← KMACXOF256(key, "", 512, "S")
+	c ← KMACXOF256(ke, "", |m|, "SKE") ⊕ m
+	t ← KMACXOF256(ka, m, 256, "SKA")
+
+and the content key is wrapped to each recipient with ecies_wrap, the
+stanza prefixed with the recipient's key ID, the raw 16 bytes of key_id,
+so that recipients find their stanza without trying the others. The
+result is armored as:
+
+	version (1 byte) || recipient count (2 bytes) ||
+	(key ID || stanza) per recipient || t || c
+*/
+func encrypt_to_recipients(pubs []*E222, msg []byte) ([]byte, error) {
+	if len(pubs) == 0 || len(pubs) > 0xffff {
+		return nil, errors.New("invalid number of recipients")
+	}
+	key := make([]byte, contentKeyLength)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	defer wipe_bytes(key)
+
+	out := []byte{envelopeVersion, byte(len(pubs) >> 8), byte(len(pubs))}
+	for _, V := range pubs {
+		stanza, err := ecies_wrap(V, key)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, key_id_bytes(V)...)
+		out = append(out, stanza...)
+	}
+
+	keys := derive_keys(key, "S")
+	defer keys.Wipe()
+	ke, ka := split_keys(keys.b)
+	out = append(out, kmac.XOF256(ka, msg, 8*envelopeTagLength, "SKA")...)
+	out = append(out, xor_bytes(kmac.XOF256(ke, nil, 8*len(msg), "SKE"), msg)...)
+
+	return pem.EncodeToMemory(&pem.Block{Type: messageArmorType, Bytes: out}), nil
+}
+
+// A parsed envelope of encrypt_to_recipients.
+type envelope struct {
+	ids     [][]byte // key ID of each stanza, nil in anonymous envelopes
+	stanzas [][]byte
+	t, c    []byte
+}
+
+// Parses an armored envelope of either version.
+func parse_envelope(armored []byte) (*envelope, error) {
+	block, _ := pem.Decode(armored)
+	if block == nil || block.Type != messageArmorType {
+		return nil, errors.New("no armored message found")
+	}
+	b := block.Bytes
+	if len(b) < 3 || (b[0] != envelopeVersion && b[0] != envelopeVersionAnonymous) {
+		return nil, errors.New("unsupported message format")
+	}
+	idLength := 0
+	if b[0] == envelopeVersion {
+		idLength = recipientIDLength
+	}
+	count := int(b[1])<<8 | int(b[2])
+	body := 3 + count*(idLength+stanzaLength)
+	if len(b) < body+envelopeTagLength {
+		return nil, errors.New("message truncated")
+	}
+	env := &envelope{t: b[body : body+envelopeTagLength], c: b[body+envelopeTagLength:]}
+	for i := 0; i < count; i++ {
+		entry := b[3+i*(idLength+stanzaLength) : 3+(i+1)*(idLength+stanzaLength)]
+		if idLength > 0 {
+			env.ids = append(env.ids, entry[:idLength])
+		}
+		env.stanzas = append(env.stanzas, entry[idLength:])
+	}
+	return env, nil
+}
+
+// Key IDs, as key_id formats them, of the recipients of an armored envelope; none for anonymous envelopes.
+func envelope_recipients(armored []byte) ([]string, error) {
+	env, err := parse_envelope(armored)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, id := range env.ids {
+		ids = append(ids, hex.EncodeToString(id))
+	}
+	return ids, nil
+}
+
+// Decrypts an armored envelope with the key pair derived from pw, see decrypt_envelope.
+func decrypt_as_recipient(pw []byte, armored []byte) ([]byte, error) {
+	secret, _ := e222_keypair_from_passphrase(pw)
+	defer secret.Destroy()
+	return decrypt_envelope(secret, armored)
+}
+
+/*
+Decrypts an armored envelope produced by encrypt_to_recipients with the
+secret key x. Only the stanzas carrying the key ID of x × G are tried, or
+every stanza of an anonymous envelope; stanzas that fail to authenticate,
+including tampered ones, are skipped. Fails with ErrNotRecipient if none
+opens.
+*/
+func decrypt_envelope(x *SecretScalar, armored []byte) ([]byte, error) {
+	env, err := parse_envelope(armored)
+	if err != nil {
+		return nil, err
+	}
+	s, err := x.Int()
+	if err != nil {
+		return nil, err
+	}
+	defer wipe_int(s)
+	id := key_id_bytes(e222Curve.BaseMul(s))
+
+	var key []byte
+	for i := 0; i < len(env.stanzas) && key == nil; i++ {
+		if env.ids != nil && !bytes.Equal(env.ids[i], id) {
+			continue
+		}
+		key, _ = ecies_unwrap(s, env.stanzas[i])
+	}
+	if key == nil {
+		return nil, ErrNotRecipient
+	}
+	defer wipe_bytes(key)
+
+	keys := derive_keys(key, "S")
+	defer keys.Wipe()
+	ke, ka := split_keys(keys.b)
+	msg := xor_bytes(kmac.XOF256(ke, nil, 8*len(env.c), "SKE"), env.c)
+	if subtle.ConstantTimeCompare(env.t, kmac.XOF256(ka, msg, 8*envelopeTagLength, "SKA")) != 1 {
+		return nil, errors.New("message failed to authenticate")
+	}
+	return msg, nil
+}
+
+// Derives ke || ka ← KMACXOF256(K, "", 512, S) as SecretBytes for the caller to wipe.
+func derive_keys(K []byte, S string) *SecretBytes {
+	return NewSecretBytes(kmac.XOF256(K, nil, 512, S))
+}
+
+// Splits a KMAC output into two equal halves (ke, ka).
+func split_keys(b []byte) ([]byte, []byte) {
+	return b[:len(b)/2], b[len(b)/2:]
+}
+
+// XORs a and b, which must be of equal length, into a new slice.
+func xor_bytes(a, b []byte) []byte {
+	out := make([]byte, len(a))
+	for i := range a {
+		out[i] = a[i] ^ b[i]
+	}
+	return out
+}
@@ -0,0 +1,142 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/pem"
+	"testing"
+
+	"sig/kmac"
+)
+
+func TestMultiRecipientEncryption(t *testing.T) {
+
+	msg := []byte("meet at the usual place")
+	passwords := [][]byte{[]byte("alice pw"), []byte("bob pw"), []byte("carol pw")}
+	var pubs []*E222
+	for _, pw := range passwords {
+		_, V := e222_keypair_from_passphrase(pw)
+		pubs = append(pubs, V)
+	}
+	armored, err := encrypt_to_recipients(pubs, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pw := range passwords {
+		out, err := decrypt_as_recipient(pw, armored)
+		if err != nil {
+			t.Fatalf("%s: %v", pw, err)
+		}
+		if !bytes.Equal(out, msg) {
+			t.Fatalf("%s decrypts %q, want %q", pw, out, msg)
+		}
+	}
+	if _, err := decrypt_as_recipient([]byte("mallory pw"), armored); err != ErrNotRecipient {
+		t.Fatalf("non-recipient: %v, want %v", err, ErrNotRecipient)
+	}
+
+	// tamper with alice's stanza: alice fails, bob and carol are unaffected
+	block, _ := pem.Decode(armored)
+	block.Bytes[3+2*e222Width] ^= 1
+	tampered := pem.EncodeToMemory(block)
+	if _, err := decrypt_as_recipient(passwords[0], tampered); err != ErrNotRecipient {
+		t.Fatalf("tampered stanza: %v, want %v", err, ErrNotRecipient)
+	}
+	for _, pw := range passwords[1:] {
+		out, err := decrypt_as_recipient(pw, tampered)
+		if err != nil {
+			t.Fatalf("%s after tampering with another stanza: %v", pw, err)
+		}
+		if !bytes.Equal(out, msg) {
+			t.Fatalf("%s decrypts %q after tampering with another stanza", pw, out)
+		}
+	}
+}
+
+func TestEnvelopeRecipientIDs(t *testing.T) {
+
+	msg := []byte("for the three of us")
+	var xs []*SecretScalar
+	var pubs []*E222
+	for i := 0; i < 3; i++ {
+		x_int := generateRandomBigInt()
+		xs = append(xs, NewSecretScalar(x_int))
+		pubs = append(pubs, e222Curve.BaseMul(x_int))
+	}
+	armored, err := encrypt_to_recipients(pubs, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids, err := envelope_recipients(armored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 3 {
+		t.Fatalf("%d recipient IDs, want 3", len(ids))
+	}
+	for i, x := range xs {
+		if ids[i] != key_id(pubs[i]) {
+			t.Fatalf("recipient %d: ID %v, want %v", i, ids[i], key_id(pubs[i]))
+		}
+		out, err := decrypt_envelope(x, armored)
+		if err != nil {
+			t.Fatalf("recipient %d: %v", i, err)
+		}
+		if !bytes.Equal(out, msg) {
+			t.Fatalf("recipient %d: decrypted %q, want %q", i, out, msg)
+		}
+	}
+	if _, err := decrypt_envelope(NewSecretScalar(generateRandomBigInt()), armored); err != ErrNotRecipient {
+		t.Fatalf("outsider: %v, want %v", err, ErrNotRecipient)
+	}
+
+	// a stanza is only tried by the key its ID names
+	block, _ := pem.Decode(armored)
+	block.Bytes[3] ^= 1
+	if _, err := decrypt_envelope(xs[0], pem.EncodeToMemory(block)); err != ErrNotRecipient {
+		t.Fatalf("changed ID: %v, want %v", err, ErrNotRecipient)
+	}
+
+	// anonymous version 1 envelopes still decrypt by trying every stanza
+	key := make([]byte, contentKeyLength)
+	rand.Read(key)
+	v1 := []byte{envelopeVersionAnonymous, 0, 2}
+	for _, V := range pubs[:2] {
+		stanza, err := ecies_wrap(V, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v1 = append(v1, stanza...)
+	}
+	keys := derive_keys(key, "S")
+	ke, ka := split_keys(keys.b)
+	v1 = append(v1, kmac.XOF256(ka, msg, 8*envelopeTagLength, "SKA")...)
+	v1 = append(v1, xor_bytes(kmac.XOF256(ke, nil, 8*len(msg), "SKE"), msg)...)
+	anonymous := pem.EncodeToMemory(&pem.Block{Type: messageArmorType, Bytes: v1})
+	out, err := decrypt_envelope(xs[1], anonymous)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, msg) {
+		t.Fatalf("anonymous envelope: decrypted %q, want %q", out, msg)
+	}
+	if _, err := decrypt_envelope(xs[2], anonymous); err != ErrNotRecipient {
+		t.Fatalf("anonymous envelope, outsider: %v, want %v", err, ErrNotRecipient)
+	}
+	ids, err = envelope_recipients(anonymous)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 0 {
+		t.Fatalf("anonymous envelope lists %d recipients", len(ids))
+	}
+
+	// truncated envelopes are refused
+	block, _ = pem.Decode(armored)
+	block.Bytes = block.Bytes[:3+3*(recipientIDLength+stanzaLength)]
+	if _, err := envelope_recipients(pem.EncodeToMemory(block)); err == nil {
+		t.Fatal("truncated envelope lists its recipients")
+	}
+}
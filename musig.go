@@ -0,0 +1,224 @@
+package sig
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"sig/edwards"
+	"sig/kmac"
+)
+
+var (
+	ErrMuSigKeys          = errors.New("musig: no keys or an invalid key to aggregate")
+	ErrMuSigNonce         = errors.New("musig: invalid public nonce")
+	ErrMuSigNonceEncoding = errors.New("musig: malformed public nonce encoding")
+	ErrMuSigNotSigner     = errors.New("musig: key is not one of the session's signers")
+	ErrMuSigNonceReused   = errors.New("musig: secret nonce already used")
+)
+
+/*
+Two round multi-signatures over E222 Schnorr signatures, after MuSig2
+(Nick, Ruffing and Seurin, 2021). n signers with keys y_1, …, y_n agree on
+the aggregate key
+
+	Y = a_1 × y_1 + … + a_n × y_n,  a_i = H_agg(L, y_i)
+
+where L is the list of keys, so that no signer can choose a key that
+cancels the others. In the first round each signer sends a public nonce
+(R_1, R_2) = (k_1 × G, k_2 × G), which may happen before the message is
+known; in the second each sends the partial signature
+
+	s_i = k_1 + b·k_2 − e·a_i·4x_i mod r
+
+for the aggregate nonce R = ΣR_1 + b × ΣR_2, b = H_non(Y, ΣR_1, ΣR_2,
+domain, msg), and the challenge e of sign_with_nonce_e222 over R. The sum
+of the partial signatures is an ordinary signature (s, e) under Y, which
+verify_sig_e222_version accepts without knowing that several keys made it.
+*/
+
+// A signer's first round message, or the sum of all of them.
+type MuSigPubNonce struct {
+	R1, R2 *edwards.Point
+}
+
+/*
+KMACXOF256 with customization string S and an empty key over items, each
+prefixed with its 4 byte big endian length as in dlog_challenge, reduced
+mod r from twice the bits of r.
+*/
+func musig_hash(S string, items ...[]byte) *big.Int {
+	var input []byte
+	for _, item := range items {
+		input = binary.BigEndian.AppendUint32(input, uint32(len(item)))
+		input = append(input, item...)
+	}
+	h := kmac.XOF256(nil, input, 2*e222Params.R.BitLen(), S)
+	return new(big.Int).Mod(fromFixedBytes(h), e222Params.R)
+}
+
+/*
+The coefficients a_i = H_agg(L, y_i) of keys, with H_agg keyed by the
+list L of all keys in the compressed encoding of Bytes, in the given
+order. Every signer must list the keys in the same order.
+*/
+func musig_coefficients(keys []*E222) []*big.Int {
+	var L []byte
+	for _, y := range keys {
+		L = append(L, y.Bytes()...)
+	}
+	coef := make([]*big.Int, len(keys))
+	for i, y := range keys {
+		coef[i] = musig_hash("MUSIG KEYAGG", L, y.Bytes())
+	}
+	return coef
+}
+
+/*
+Aggregates keys into the public key Y under which the signatures of
+their holders verify. Fails with ErrMuSigKeys if keys is empty, a key is
+not a valid public key, or Y is, which a set of keys chosen to cancel
+could otherwise produce.
+*/
+func MuSigAggregateKeys(keys []*E222) (*E222, error) {
+	if len(keys) == 0 {
+		return nil, ErrMuSigKeys
+	}
+	for _, y := range keys {
+		if y == nil || y.Curve() != e222Curve || validate_public_key(y) != nil {
+			return nil, ErrMuSigKeys
+		}
+	}
+	coef := musig_coefficients(keys)
+	Y := e222Curve.IdPoint()
+	for i, y := range keys {
+		Y = Y.Add(y.SecMul(coef[i]))
+	}
+	if validate_public_key(Y) != nil {
+		return nil, ErrMuSigKeys
+	}
+	return Y, nil
+}
+
+/*
+Sums the public nonces of all signers into the aggregate nonce, which a
+coordinator may do and send back in place of the list. Fails with
+ErrMuSigNonce unless every nonce point lies in the prime order subgroup.
+*/
+func MuSigAggregateNonces(nonces []*MuSigPubNonce) (*MuSigPubNonce, error) {
+	if len(nonces) == 0 {
+		return nil, ErrMuSigNonce
+	}
+	agg := &MuSigPubNonce{R1: e222Curve.IdPoint(), R2: e222Curve.IdPoint()}
+	for _, nonce := range nonces {
+		if !nonce.valid() {
+			return nil, ErrMuSigNonce
+		}
+		agg.R1 = agg.R1.Add(nonce.R1)
+		agg.R2 = agg.R2.Add(nonce.R2)
+	}
+	return agg, nil
+}
+
+// True iff both points of nonce are E222 points of the prime order subgroup.
+func (nonce *MuSigPubNonce) valid() bool {
+	return nonce != nil && nonce.R1 != nil && nonce.R2 != nil &&
+		nonce.R1.Curve() == e222Curve && nonce.R2.Curve() == e222Curve &&
+		nonce.R1.InPrimeSubgroup() && nonce.R2.InPrimeSubgroup()
+}
+
+// R_1 and R_2 in the compressed encoding of Bytes, 56 bytes in all.
+func (nonce *MuSigPubNonce) Bytes() []byte {
+	return append(nonce.R1.Bytes(), nonce.R2.Bytes()...)
+}
+
+// Decodes a public nonce encoded by Bytes, or fails with ErrMuSigNonceEncoding.
+func ParseMuSigPubNonce(b []byte) (*MuSigPubNonce, error) {
+	w := e222Curve.EncodedLen()
+	if len(b) != 2*w {
+		return nil, ErrMuSigNonceEncoding
+	}
+	R1, err1 := e222Curve.FromBytes(b[:w], edwards.RequirePrimeOrder)
+	R2, err2 := e222Curve.FromBytes(b[w:], edwards.RequirePrimeOrder)
+	if err1 != nil || err2 != nil {
+		return nil, ErrMuSigNonceEncoding
+	}
+	return &MuSigPubNonce{R1: R1, R2: R2}, nil
+}
+
+/*
+The state shared by the signers of one message: the keys, the aggregate
+key and nonce, and the challenge they imply. Every signer builds the same
+session from the same inputs, and the coordinator uses it to check and
+sum the partial signatures.
+*/
+type MuSigSession struct {
+	Keys      []*E222
+	Key       *E222 // the aggregate key Y
+	Nonce     *MuSigPubNonce
+	coef      []*big.Int
+	b         *big.Int
+	challenge *big.Int
+}
+
+/*
+Starts the signing of msg in domain by the holders of keys, given the
+aggregate of their public nonces.
+*/
+func NewMuSigSession(domain string, keys []*E222, nonce *MuSigPubNonce, msg []byte) (*MuSigSession, error) {
+	Y, err := MuSigAggregateKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+	if nonce == nil || nonce.R1 == nil || nonce.R2 == nil || nonce.R1.Validate() != nil || nonce.R2.Validate() != nil {
+		return nil, ErrMuSigNonce
+	}
+	b := musig_hash("MUSIG NONCE", Y.Bytes(), nonce.R1.Bytes(), nonce.R2.Bytes(), []byte(domain), msg)
+	R := nonce.R1.Add(nonce.R2.SecMul(b))
+	return &MuSigSession{
+		Keys:      keys,
+		Key:       Y,
+		Nonce:     nonce,
+		coef:      musig_coefficients(keys),
+		b:         b,
+		challenge: e222_challenge(signatureVersion, domain, R.X(), &msg),
+	}, nil
+}
+
+// Index of the first occurrence of y among the session's keys, or -1.
+func (session *MuSigSession) signer_index(y *E222) int {
+	for i, key := range session.Keys {
+		if key.Equals(y) {
+			return i
+		}
+	}
+	return -1
+}
+
+/*
+Checks the partial signature s of the holder of key, who sent nonce in
+the first round: s × G + e·a_i × 4y_i = R_1 + b × R_2. A coordinator uses
+it to name the signer whose contribution spoils the aggregate.
+*/
+func (session *MuSigSession) VerifyPartial(key *E222, nonce *MuSigPubNonce, s *big.Int) bool {
+	i := session.signer_index(key)
+	if i < 0 || !nonce.valid() || s == nil || s.Sign() < 0 || s.Cmp(e222Params.R) >= 0 {
+		return false
+	}
+	ea := new(big.Int).Mul(session.challenge, session.coef[i])
+	lhs := e222Curve.BaseMul(s).Add(key.ClearCofactor().SecMul(ea.Mod(ea, e222Params.R)))
+	return lhs.Equals(nonce.R1.Add(nonce.R2.SecMul(session.b)))
+}
+
+/*
+Sums the partial signatures of all signers into the signature (s, e)
+under the aggregate key, to be encoded and verified as any other E222
+Schnorr signature made in the session's domain.
+*/
+func (session *MuSigSession) Aggregate(partials []*big.Int) (*big.Int, *big.Int) {
+	s := new(big.Int)
+	for _, s_i := range partials {
+		s.Add(s, s_i)
+	}
+	return s.Mod(s, e222Params.R), new(big.Int).Set(session.challenge)
+}
@@ -0,0 +1,77 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto/rand"
+	"math/big"
+)
+
+/*
+The secret half of a MuSig2 nonce, the scalars k_1 and k_2 behind a
+MuSigPubNonce. It signs once: Sign wipes it, and signing twice with the
+same nonce and two challenges would reveal the key.
+*/
+type MuSigSecNonce struct {
+	k1, k2 *big.Int
+}
+
+/*
+Draws a fresh nonce pair from crypto/rand, k_1 and k_2 in [1, r−1], for
+the first round. The public half goes to the other signers; the secret
+half stays with the signer until Sign.
+*/
+func MuSigNonceGen() (*MuSigSecNonce, *MuSigPubNonce, error) {
+	bound := new(big.Int).Sub(e222Params.R, big.NewInt(1))
+	k1, err := rand.Int(rand.Reader, bound)
+	if err != nil {
+		return nil, nil, err
+	}
+	k2, err := rand.Int(rand.Reader, bound)
+	if err != nil {
+		wipe_int(k1)
+		return nil, nil, err
+	}
+	k1.Add(k1, big.NewInt(1))
+	k2.Add(k2, big.NewInt(1))
+	pub := &MuSigPubNonce{R1: e222Curve.BaseMul(k1), R2: e222Curve.BaseMul(k2)}
+	return &MuSigSecNonce{k1: k1, k2: k2}, pub, nil
+}
+
+/*
+Makes the partial signature s_i = k_1 + b·k_2 − e·a_i·4x_i mod r of the
+holder of x in the second round, and wipes sec. Fails with
+ErrMuSigNotSigner if x × G is not among the session's keys and with
+ErrMuSigNonceReused if sec has signed before.
+*/
+func (session *MuSigSession) Sign(x *SecretScalar, sec *MuSigSecNonce) (*big.Int, error) {
+	if sec == nil || sec.k1 == nil {
+		return nil, ErrMuSigNonceReused
+	}
+	x_int, err := x.Int()
+	if err != nil {
+		return nil, err
+	}
+	defer wipe_int(x_int)
+	if err := check_scalar(x_int); err != nil {
+		return nil, err
+	}
+	i := session.signer_index(e222Curve.BaseMul(x_int))
+	if i < 0 {
+		return nil, ErrMuSigNotSigner
+	}
+	k1, k2 := sec.k1, sec.k2
+	sec.k1, sec.k2 = nil, nil
+	defer wipe_int(k1)
+	defer wipe_int(k2)
+
+	x4 := cofactor_scalar(x_int)
+	defer wipe_int(x4)
+	xe := new(big.Int).Mul(x4, session.coef[i])
+	defer wipe_int(xe)
+	xe.Mul(xe, session.challenge)
+	s := new(big.Int).Mul(k2, session.b)
+	s.Add(s, k1)
+	s.Sub(s, xe)
+	return s.Mod(s, e222Params.R), nil
+}
@@ -0,0 +1,136 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestMuSigSignatures(t *testing.T) {
+
+	msg := []byte("co-signed release notes")
+	var xs []*SecretScalar
+	var keys []*E222
+	for i := 0; i < 3; i++ {
+		x_int := generateRandomBigInt()
+		xs = append(xs, NewSecretScalar(x_int))
+		keys = append(keys, e222Curve.BaseMul(x_int))
+	}
+
+	// round one: nonces are exchanged in their encoding and summed
+	var secs []*MuSigSecNonce
+	var pubs []*MuSigPubNonce
+	for range keys {
+		sec, pub, err := MuSigNonceGen()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := ParseMuSigPubNonce(pub.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !parsed.R1.Equals(pub.R1) || !parsed.R2.Equals(pub.R2) {
+			t.Fatal("public nonce does not survive its encoding")
+		}
+		secs = append(secs, sec)
+		pubs = append(pubs, parsed)
+	}
+	agg, err := MuSigAggregateNonces(pubs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := NewMuSigSession("app", keys, agg, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// round two: partial signatures, checked one by one and summed
+	var partials []*big.Int
+	for i, x := range xs {
+		s_i, err := session.Sign(x, secs[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !session.VerifyPartial(keys[i], pubs[i], s_i) {
+			t.Fatalf("partial signature %d does not verify", i)
+		}
+		partials = append(partials, s_i)
+	}
+	s, e := session.Aggregate(partials)
+	Y, err := MuSigAggregateKeys(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Y.Equals(session.Key) {
+		t.Fatal("session key differs from the aggregate key")
+	}
+	if !verify_sig_e222_version(signatureVersion, "app", Y, s, e, &msg) {
+		t.Fatal("aggregate signature does not verify")
+	}
+	if verify_sig_e222_version(signatureVersion, "", Y, s, e, &msg) {
+		t.Fatal("aggregate signature verifies without its domain")
+	}
+	encoded := EncodeSignature(&SchnorrSignature{Curve: curveE222, S: s, E: e})
+	if !VerifySignature(Y, "app", encoded, &msg) {
+		t.Fatal("encoded aggregate signature does not verify")
+	}
+
+	// no single key, nor the plain sum of the keys, verifies the result
+	sum := keys[0].Add(keys[1]).Add(keys[2])
+	if verify_sig_e222_version(signatureVersion, "app", keys[0], s, e, &msg) {
+		t.Fatal("aggregate signature verifies under a single key")
+	}
+	if verify_sig_e222_version(signatureVersion, "app", sum, s, e, &msg) {
+		t.Fatal("aggregate signature verifies under the plain sum of the keys")
+	}
+
+	// a spoiled partial signature is pinned on its signer
+	bad := new(big.Int).Add(partials[1], big.NewInt(1))
+	if session.VerifyPartial(keys[1], pubs[1], bad) {
+		t.Fatal("spoiled partial signature verifies")
+	}
+	if session.VerifyPartial(keys[0], pubs[1], partials[1]) {
+		t.Fatal("partial signature verifies under another signer's key")
+	}
+	s, e = session.Aggregate([]*big.Int{partials[0], bad, partials[2]})
+	if verify_sig_e222_version(signatureVersion, "app", Y, s, e, &msg) {
+		t.Fatal("aggregate of a spoiled partial signature verifies")
+	}
+
+	// secret nonces sign once, and only for the session's keys
+	if _, err := session.Sign(xs[0], secs[0]); err != ErrMuSigNonceReused {
+		t.Fatalf("reused nonce: %v, want %v", err, ErrMuSigNonceReused)
+	}
+	sec, _, err := MuSigNonceGen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := session.Sign(NewSecretScalar(generateRandomBigInt()), sec); err != ErrMuSigNotSigner {
+		t.Fatalf("outside signer: %v, want %v", err, ErrMuSigNotSigner)
+	}
+
+	// the order of the keys is part of the aggregate
+	reordered, err := MuSigAggregateKeys([]*E222{keys[1], keys[0], keys[2]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reordered.Equals(Y) {
+		t.Fatal("reordered keys aggregate to the same key")
+	}
+
+	// invalid keys and nonces are refused
+	if _, err := MuSigAggregateKeys(nil); err != ErrMuSigKeys {
+		t.Fatalf("no keys: %v, want %v", err, ErrMuSigKeys)
+	}
+	if _, err := MuSigAggregateKeys([]*E222{keys[0], e222Curve.IdPoint()}); err != ErrMuSigKeys {
+		t.Fatalf("identity key: %v, want %v", err, ErrMuSigKeys)
+	}
+	small := &MuSigPubNonce{R1: pubs[0].R1, R2: pubs[0].R2.Add(e222Curve.NewPointXY(*big.NewInt(0), *new(big.Int).Sub(e222Curve.Params().P, big.NewInt(1))))}
+	if _, err := MuSigAggregateNonces([]*MuSigPubNonce{pubs[1], small}); err != ErrMuSigNonce {
+		t.Fatalf("small order nonce: %v, want %v", err, ErrMuSigNonce)
+	}
+	if _, err := ParseMuSigPubNonce(pubs[0].Bytes()[1:]); err != ErrMuSigNonceEncoding {
+		t.Fatalf("short nonce: %v, want %v", err, ErrMuSigNonceEncoding)
+	}
+}
@@ -0,0 +1,114 @@
+package sig
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Number of entries kept by an OperationLog before the oldest are dropped.
+const operationLogSize = 500
+
+/*
+Receives updates from model functions so that a front end can display
+them. Progress reports how far a long running operation has got and
+Record is called once per completed operation.
+*/
+type UIReporter interface {
+	Progress(done, total int64)
+	Record(entry LogEntry)
+}
+
+// A UIReporter that discards everything, for callers with no front end.
+type discardReporter struct{}
+
+func (discardReporter) Progress(done, total int64) {}
+func (discardReporter) Record(entry LogEntry)      {}
+
+// An additional named value attached to a log entry. Secret values such
+// as passphrases or private scalars are redacted when formatted.
+type LogField struct {
+	Name   string
+	Value  string
+	Secret bool
+}
+
+// One completed operation.
+type LogEntry struct {
+	Time        time.Time
+	Operation   string // e.g. "hash", "sign", "verify"
+	Fingerprint string // key used, if any
+	Subject     string // file name or byte count
+	Result      string
+	Fields      []LogField
+}
+
+// Formats e as a single line, replacing secret field values.
+func format_log_entry(e LogEntry) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s", e.Time.Format("2006-01-02 15:04:05"), e.Operation)
+	if e.Fingerprint != "" {
+		fmt.Fprintf(&sb, " key=%s", e.Fingerprint)
+	}
+	if e.Subject != "" {
+		fmt.Fprintf(&sb, " subject=%s", e.Subject)
+	}
+	for _, f := range e.Fields {
+		value := f.Value
+		if f.Secret {
+			value = "[redacted]"
+		}
+		fmt.Fprintf(&sb, " %s=%s", f.Name, value)
+	}
+	fmt.Fprintf(&sb, ": %s", e.Result)
+	return sb.String()
+}
+
+/*
+Bounded, concurrency safe history of operations. Implements UIReporter,
+ignoring progress updates.
+*/
+type OperationLog struct {
+	mu      sync.Mutex
+	entries []LogEntry
+}
+
+func (l *OperationLog) Progress(done, total int64) {}
+
+// Appends entry, timestamping it if needed and dropping the oldest
+// entry once operationLogSize is reached.
+func (l *OperationLog) Record(entry LogEntry) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.entries) == operationLogSize {
+		l.entries = append(l.entries[:0], l.entries[1:]...)
+	}
+	l.entries = append(l.entries, entry)
+}
+
+// Returns a copy of the entries, oldest first.
+func (l *OperationLog) Entries() []LogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]LogEntry{}, l.entries...)
+}
+
+func (l *OperationLog) Clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = nil
+}
+
+// Formats the whole log, one entry per line, for copying.
+func (l *OperationLog) String() string {
+	var sb strings.Builder
+	for _, e := range l.Entries() {
+		sb.WriteString(format_log_entry(e))
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
@@ -0,0 +1,47 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestOperationLogRedaction(t *testing.T) {
+
+	line := format_log_entry(LogEntry{
+		Operation: "sign",
+		Subject:   "12 bytes",
+		Result:    "ok",
+		Fields: []LogField{
+			{Name: "passphrase", Value: "hunter2", Secret: true},
+			{Name: "curve", Value: "E222"},
+		},
+	})
+	if strings.Contains(line, "hunter2") || !strings.Contains(line, "passphrase=[redacted]") ||
+		!strings.Contains(line, "curve=E222") {
+		t.Fatalf("log line %q", line)
+	}
+}
+
+func TestOperationLogBounded(t *testing.T) {
+
+	var log OperationLog
+	var reporter UIReporter = &log
+	for i := 0; i < operationLogSize+10; i++ {
+		reporter.Record(LogEntry{Operation: "hash", Result: fmt.Sprint(i)})
+	}
+	entries := log.Entries()
+	if len(entries) != operationLogSize {
+		t.Fatalf("%d entries, want %d", len(entries), operationLogSize)
+	}
+	first, last := entries[0].Result, entries[len(entries)-1].Result
+	if first != "10" || last != fmt.Sprint(operationLogSize+9) {
+		t.Fatalf("entries run from %s to %s, want 10 to %d", first, last, operationLogSize+9)
+	}
+	log.Clear()
+	if n, s := len(log.Entries()), log.String(); n != 0 || s != "" {
+		t.Fatalf("cleared log has %d entries: %q", n, s)
+	}
+}
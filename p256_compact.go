@@ -0,0 +1,76 @@
+package sig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"errors"
+	"math/big"
+)
+
+// Length of a compact P-256 public key: parity byte || x.
+const p256CompactLength = 1 + p256Width
+
+var ErrNoP256Point = errors.New("no P-256 point with this x coordinate")
+
+/*
+Recovers the y coordinate of the P-256 point with the given x and parity
+of y (0 even, 1 odd) by solving y² = x³ − 3x + b mod p. Since p ≡ 3 mod 4
+the square root is a^((p+1)/4). Returns ErrNoP256Point if x³ − 3x + b is
+not a square, i.e. no point has this x.
+*/
+func RecoverP256Y(x *big.Int, yParity byte) (*big.Int, error) {
+	params := elliptic.P256().Params()
+	p := params.P
+	if yParity > 1 {
+		return nil, errors.New("y parity must be 0 or 1")
+	}
+	if x.Sign() < 0 || x.Cmp(p) >= 0 {
+		return nil, ErrNoP256Point
+	}
+
+	a := new(big.Int).Exp(x, big.NewInt(3), p)
+	a.Sub(a, new(big.Int).Mul(big.NewInt(3), x))
+	a.Add(a, params.B)
+	a.Mod(a, p)
+
+	exp := new(big.Int).Rsh(new(big.Int).Add(p, big.NewInt(1)), 2)
+	y := new(big.Int).Exp(a, exp, p)
+	if new(big.Int).Exp(y, big.NewInt(2), p).Cmp(a) != 0 {
+		return nil, ErrNoP256Point
+	}
+	if y.Bit(0) != uint(yParity) {
+		y.Sub(p, y)
+	}
+	return y, nil
+}
+
+// Encodes a P-256 public key in 33 bytes as its y parity (0x02 even, 0x03 odd) || x.
+func p256_to_compact(Q *ecdsa.PublicKey) []byte {
+	return append([]byte{0x02 | byte(Q.Y.Bit(0))}, toFixedBytes(Q.X, p256Width)...)
+}
+
+// Decodes a key encoded by p256_to_compact, recovering y.
+func p256_from_compact(b []byte) (*ecdsa.PublicKey, error) {
+	if len(b) != p256CompactLength || (b[0] != 0x02 && b[0] != 0x03) {
+		return nil, errors.New("malformed compact P-256 public key")
+	}
+	x := fromFixedBytes(b[1:])
+	y, err := RecoverP256Y(x, b[0]&1)
+	if err != nil {
+		return nil, err
+	}
+	return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+}
+
+/*
+Verifies the ECDSA signature (r, s) over msg under the public key given
+by its x coordinate and y parity. An error means no such key exists.
+*/
+func VerifyWithXOnlyKey(x *big.Int, parity byte, r, s *big.Int, msg []byte) (bool, error) {
+	y, err := RecoverP256Y(x, parity)
+	if err != nil {
+		return false, err
+	}
+	Q_a := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+	return verify_ecdsa_sig(Q_a, r, s, &msg), nil
+}
@@ -0,0 +1,90 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// Compact P-256 keys recover y for both parities and reject x values with no point.
+func TestP256YRecovery(t *testing.T) {
+
+	curve := elliptic.P256()
+	parities := map[uint]bool{}
+	for i := 0; i < 16; i++ {
+		priv, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		Q := &priv.PublicKey
+		parity := byte(Q.Y.Bit(0))
+		parities[Q.Y.Bit(0)] = true
+
+		y, err := RecoverP256Y(Q.X, parity)
+		if err != nil {
+			t.Fatalf("x = %v: %v", Q.X, err)
+		}
+		if y.Cmp(Q.Y) != 0 {
+			t.Fatalf("x = %v recovers y = %v, want %v", Q.X, y, Q.Y)
+		}
+		y_neg, err := RecoverP256Y(Q.X, parity^1)
+		if err != nil {
+			t.Fatalf("x = %v, other parity: %v", Q.X, err)
+		}
+		if sum := new(big.Int).Add(y, y_neg); sum.Cmp(curve.Params().P) != 0 {
+			t.Fatalf("the two solutions for x = %v sum to %v, want p", Q.X, sum)
+		}
+
+		compact := p256_to_compact(Q)
+		if len(compact) != p256CompactLength {
+			t.Fatalf("compact key is %d bytes, want %d", len(compact), p256CompactLength)
+		}
+		decoded, err := p256_from_compact(compact)
+		if err != nil {
+			t.Fatalf("%x: %v", compact, err)
+		}
+		if decoded.X.Cmp(Q.X) != 0 || decoded.Y.Cmp(Q.Y) != 0 {
+			t.Fatalf("%x decodes to (%v, %v), want (%v, %v)", compact, decoded.X, decoded.Y, Q.X, Q.Y)
+		}
+
+		msg := []byte("x-only key")
+		r, s := sign_message_ecdsa(elliptic.P256(), &msg, priv.D)
+		if ok, err := VerifyWithXOnlyKey(Q.X, parity, r, s, msg); !ok || err != nil {
+			t.Fatalf("VerifyWithXOnlyKey = %v, %v", ok, err)
+		}
+		if ok, _ := VerifyWithXOnlyKey(Q.X, parity^1, r, s, msg); ok {
+			t.Fatal("signature verifies with the wrong parity")
+		}
+	}
+	if !parities[0] || !parities[1] {
+		t.Fatalf("16 keys all had the same parity %v", parities)
+	}
+
+	// about half of all x have no point; find some
+	missing := 0
+	for x := int64(0); missing < 3; x++ {
+		if _, err := RecoverP256Y(big.NewInt(x), 0); err != nil {
+			if !errors.Is(err, ErrNoP256Point) {
+				t.Fatalf("x = %d: %v, want %v", x, err, ErrNoP256Point)
+			}
+			if _, err := VerifyWithXOnlyKey(big.NewInt(x), 0, big.NewInt(1), big.NewInt(1), nil); !errors.Is(err, ErrNoP256Point) {
+				t.Fatalf("verifying with x = %d: %v, want %v", x, err, ErrNoP256Point)
+			}
+			missing++
+		}
+	}
+	if _, err := RecoverP256Y(curve.Params().P, 0); !errors.Is(err, ErrNoP256Point) {
+		t.Fatalf("x = p: %v, want %v", err, ErrNoP256Point)
+	}
+	if _, err := RecoverP256Y(curve.Params().Gx, 2); err == nil {
+		t.Fatal("parity 2 gives no error")
+	}
+	if _, err := p256_from_compact(append([]byte{0x04}, make([]byte, p256Width)...)); err == nil {
+		t.Fatal("compact key with prefix 04 decodes")
+	}
+}
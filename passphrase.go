@@ -0,0 +1,78 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+	"sig/kmac"
+)
+
+// Returned, wrapped with the reason, for passphrases rejected as too weak.
+var ErrWeakPassphrase = errors.New("weak passphrase")
+
+// Minimum number of characters in a passphrase where the caller gives none.
+const DefaultMinPassphraseLength = 10
+
+/*
+Normalizes a passphrase to Unicode NFC before it is used as key material,
+so that the same visual passphrase always derives the same key regardless
+of how the input method composed it.
+*/
+func normalize_passphrase(pw []byte) []byte {
+	return norm.NFC.Bytes(pw)
+}
+
+// Rejects empty, all whitespace, and passphrases of fewer than minLength characters.
+func check_passphrase(pw []byte, minLength int) error {
+	normalized := normalize_passphrase(pw)
+	if !utf8.Valid(normalized) {
+		return fmt.Errorf("%w: not valid UTF-8", ErrWeakPassphrase)
+	}
+	if len(normalized) == 0 {
+		return fmt.Errorf("%w: passphrase is empty", ErrWeakPassphrase)
+	}
+	if strings.TrimSpace(string(normalized)) == "" {
+		return fmt.Errorf("%w: passphrase is only whitespace", ErrWeakPassphrase)
+	}
+	if n := utf8.RuneCount(normalized); n < minLength {
+		return fmt.Errorf("%w: passphrase has %d characters, at least %d are required",
+			ErrWeakPassphrase, n, minLength)
+	}
+	return nil
+}
+
+/*
+Derives an E222 key pair from pw like e222_keypair_from_passphrase, but
+first rejects with ErrWeakPassphrase passphrases that are empty, all
+whitespace or shorter than minLength characters, counted after NFC
+normalization. DefaultMinPassphraseLength is the minimum used elsewhere.
+*/
+func GenerateKeyPair(pw []byte, minLength int) (*SecretScalar, *E222, error) {
+	if err := check_passphrase(pw, minLength); err != nil {
+		return nil, nil, err
+	}
+	s, V := e222_keypair_from_passphrase(pw)
+	return s, V, nil
+}
+
+/*
+Derives an E222 key pair from a passphrase:
+
+	s ← KMACXOF256(NFC(pw), "", 448, "SK") mod r
+	V ← s × G
+*/
+func e222_keypair_from_passphrase(pw []byte) (*SecretScalar, *E222) {
+	derived := NewSecretBytes(kmac.XOF256(normalize_passphrase(pw), nil, 448, "SK"))
+	defer derived.Wipe()
+	s := fromFixedBytes(derived.b)
+	s.Mod(s, e222Params.R)
+	V := e222Curve.BaseMul(s)
+	secret := NewSecretScalar(s)
+	wipe_int(s)
+	return secret, V
+}
@@ -0,0 +1,59 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestPassphraseStrictMode(t *testing.T) {
+
+	weak := [][]byte{nil, []byte("short"), []byte("          \t\n"), []byte("ñññññññññ")}
+	for _, pw := range weak {
+		if _, _, err := GenerateKeyPair(pw, DefaultMinPassphraseLength); !errors.Is(err, ErrWeakPassphrase) {
+			t.Fatalf("%q: %v, want %v", pw, err, ErrWeakPassphrase)
+		}
+	}
+	for _, tt := range []struct {
+		pw      string
+		min     int
+		refused bool
+	}{
+		{"short", 5, false},
+		{"long enough?", 16, true},
+		{"", 0, true},
+		{"ññññññññññ", DefaultMinPassphraseLength, false},
+	} {
+		_, _, err := GenerateKeyPair([]byte(tt.pw), tt.min)
+		if errors.Is(err, ErrWeakPassphrase) != tt.refused || (!tt.refused && err != nil) {
+			t.Fatalf("%q with minimum %d: %v", tt.pw, tt.min, err)
+		}
+	}
+	if _, err := GenerateKeyObj("alice", []byte("short")); !errors.Is(err, ErrWeakPassphrase) {
+		t.Fatalf("GenerateKeyObj: %v, want %v", err, ErrWeakPassphrase)
+	}
+}
+
+// Composed and decomposed forms of the same passphrase derive the same key.
+func TestPassphraseNormalization(t *testing.T) {
+
+	composed := []byte("caf\u00e9 cr\u00e8me br\u00fbl\u00e9e")
+	decomposed := []byte("cafe\u0301 cre\u0300me bru\u0302le\u0301e")
+	if bytes.Equal(composed, decomposed) {
+		t.Fatal("composed and decomposed forms are the same bytes")
+	}
+	if n := normalize_passphrase(decomposed); !bytes.Equal(n, composed) {
+		t.Fatalf("normalize_passphrase(%q) = %q, want %q", decomposed, n, composed)
+	}
+	_, V1 := e222_keypair_from_passphrase(composed)
+	_, V2 := e222_keypair_from_passphrase(decomposed)
+	_, V3 := e222_keypair_from_passphrase([]byte("cafe creme brulee"))
+	if !V1.Equals(V2) {
+		t.Fatal("composed and decomposed passphrases derive different keys")
+	}
+	if V1.Equals(V3) {
+		t.Fatal("accents are dropped from the passphrase")
+	}
+}
@@ -0,0 +1,78 @@
+package sig
+
+import (
+	"errors"
+	"math/big"
+
+	"sig/edwards"
+)
+
+var ErrPedersenEncoding = errors.New("pedersen: malformed commitment encoding")
+
+/*
+A Pedersen commitment C = v × G + b × H to the value v with the blinding
+factor b, both mod r, on an Edwards curve of this package. C reveals
+nothing about v while b stays secret, and no one can open it to another
+value without knowing the discrete logarithm of H to G, which is why H is
+hashed to the curve rather than chosen, see PedersenGenerator. Sums of
+commitments commit to the sums of their values and blinding factors, see
+Add, so that totals can be audited without revealing the parts.
+*/
+type PedersenCommitment struct {
+	C *edwards.Point
+}
+
+/*
+The second generator H of curve c, hashed to the prime order subgroup
+from the curve name with HashToPoint under "PEDERSEN H". Anyone can
+derive it again and check that nothing was hidden in its choice.
+*/
+func PedersenGenerator(c *edwards.Curve) *edwards.Point {
+	H, _ := c.HashToPoint([]byte(c.Name()), []byte("PEDERSEN H"))
+	return H
+}
+
+// Commits to value with blinding on curve c, both reduced mod r, see PedersenCommitment.
+func PedersenCommit(c *edwards.Curve, value, blinding *big.Int) *PedersenCommitment {
+	r := c.Order()
+	v := new(big.Int).Mod(value, r)
+	b := new(big.Int).Mod(blinding, r)
+	defer wipe_int(b)
+	return &PedersenCommitment{C: c.BaseMul(v).Add(PedersenGenerator(c).SecMul(b))}
+}
+
+// True iff value and blinding, reduced mod r, open the commitment.
+func (com *PedersenCommitment) Open(value, blinding *big.Int) bool {
+	if com == nil || com.C == nil || value == nil || blinding == nil || com.C.Validate() != nil {
+		return false
+	}
+	return PedersenCommit(com.C.Curve(), value, blinding).C.Equals(com.C)
+}
+
+/*
+The commitment to the sum of the values with the sum of the blinding
+factors of com and other, which must be on the same curve. Opening it
+checks the total without opening the parts. Returns nil otherwise.
+*/
+func (com *PedersenCommitment) Add(other *PedersenCommitment) *PedersenCommitment {
+	if com == nil || other == nil || com.C == nil || other.C == nil || com.C.Curve() != other.C.Curve() {
+		return nil
+	}
+	return &PedersenCommitment{C: com.C.Add(other.C)}
+}
+
+// C in the compressed encoding of Bytes.
+func (com *PedersenCommitment) Bytes() []byte { return com.C.Bytes() }
+
+/*
+Decodes a commitment on curve c encoded by Bytes, or fails with
+ErrPedersenEncoding. Commitments outside the prime order subgroup are
+refused, as no value and blinding factor open them.
+*/
+func ParsePedersenCommitment(c *edwards.Curve, b []byte) (*PedersenCommitment, error) {
+	C, err := c.FromBytes(b, edwards.RequirePrimeOrder)
+	if err != nil {
+		return nil, ErrPedersenEncoding
+	}
+	return &PedersenCommitment{C: C}, nil
+}
@@ -0,0 +1,69 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"sig/edwards"
+)
+
+// Pedersen commitments open only to their value and blinding factor and add homomorphically.
+func TestPedersenCommitments(t *testing.T) {
+
+	for _, c := range []*edwards.Curve{e222Curve, e521Curve, ed25519Curve, ed448Curve} {
+		r := c.Params().R
+		H := PedersenGenerator(c)
+		if !H.InPrimeSubgroup() || H.IsIdentity() || H.Equals(c.GenPoint()) {
+			t.Fatalf("%s: H is the identity, the base point or outside the prime order subgroup", c.Name())
+		}
+		if !H.Equals(PedersenGenerator(c)) {
+			t.Fatalf("%s: generator is not fixed", c.Name())
+		}
+
+		v1, v2 := big.NewInt(1000), big.NewInt(234)
+		b1, _ := rand.Int(rand.Reader, r)
+		b2, _ := rand.Int(rand.Reader, r)
+		C1, C2 := PedersenCommit(c, v1, b1), PedersenCommit(c, v2, b2)
+		if !C1.Open(v1, b1) {
+			t.Fatalf("%s: commitment does not open", c.Name())
+		}
+		if C1.Open(big.NewInt(1001), b1) || C1.Open(v1, new(big.Int).Add(b1, big.NewInt(1))) || C1.Open(v2, b2) {
+			t.Fatalf("%s: commitment opens to another value or blinding factor", c.Name())
+		}
+
+		// hiding: the same value gives different commitments under different blinding factors
+		if PedersenCommit(c, v1, b2).C.Equals(C1.C) {
+			t.Fatalf("%s: commitment does not depend on the blinding factor", c.Name())
+		}
+		if !PedersenCommit(c, new(big.Int).Add(v1, r), b1).C.Equals(C1.C) {
+			t.Fatalf("%s: values are not taken modulo the order", c.Name())
+		}
+
+		sum := C1.Add(C2)
+		if !sum.Open(big.NewInt(1234), new(big.Int).Add(b1, b2)) || sum.Open(big.NewInt(1234), b1) {
+			t.Fatalf("%s: sum of commitments does not open to the sums", c.Name())
+		}
+
+		decoded, err := ParsePedersenCommitment(c, sum.Bytes())
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name(), err)
+		}
+		if !decoded.Open(big.NewInt(1234), new(big.Int).Add(b1, b2)) {
+			t.Fatalf("%s: decoded commitment does not open", c.Name())
+		}
+		T := small_order_points(c)[1]
+		if _, err := ParsePedersenCommitment(c, C1.C.Add(T).Bytes()); err != ErrPedersenEncoding {
+			t.Fatalf("%s small order component: %v, want %v", c.Name(), err, ErrPedersenEncoding)
+		}
+		if _, err := ParsePedersenCommitment(c, C1.Bytes()[1:]); err != ErrPedersenEncoding {
+			t.Fatalf("%s short commitment: %v, want %v", c.Name(), err, ErrPedersenEncoding)
+		}
+	}
+	// commitments on different curves do not add
+	if sum := PedersenCommit(e222Curve, big.NewInt(1), big.NewInt(2)).Add(PedersenCommit(e521Curve, big.NewInt(1), big.NewInt(2))); sum != nil {
+		t.Fatalf("commitments on different curves add to %v", sum)
+	}
+}
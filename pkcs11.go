@@ -0,0 +1,198 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/asn1"
+	"errors"
+	"io"
+	"math/big"
+	"sync"
+)
+
+var (
+	ErrPKCS11Unavailable = errors.New("pkcs11: PKCS#11 modules need cgo on a Unix system")
+	ErrPKCS11Token       = errors.New("pkcs11: no token with that label")
+	ErrPKCS11PIN         = errors.New("pkcs11: PIN incorrect or locked")
+	ErrPKCS11Signature   = errors.New("pkcs11: token returned an invalid signature")
+)
+
+// Object classes of PKCS#11, CKO_PUBLIC_KEY and CKO_PRIVATE_KEY.
+const (
+	pkcs11PublicKey  = 2
+	pkcs11PrivateKey = 3
+)
+
+// An EC key object on a token, with the attributes a token chose to reveal.
+type pkcs11Object struct {
+	handle uint
+	id     []byte // CKA_ID, shared by the halves of a key pair
+	label  string
+	params []byte // CKA_EC_PARAMS, the DER curve OID
+	point  []byte // CKA_EC_POINT, public keys only
+}
+
+/*
+A logged in session on a token of a PKCS#11 module: objects lists the EC
+keys of a class, and sign signs a digest with CKM_ECDSA, returning r ‖ s.
+Calls are serialized by PKCS11Token, since sessions are not for
+concurrent use.
+*/
+type pkcs11Module interface {
+	objects(class uint) ([]pkcs11Object, error)
+	sign(handle uint, digest []byte) ([]byte, error)
+	close() error
+}
+
+/*
+A hardware token, such as a YubiKey or a smart card, or SoftHSM, reached
+through its PKCS#11 module. Its P-256 keys sign through PKCS11Key without
+the private key ever leaving the token; their public keys verify as any
+other ECDSA key does. The token holds ECDSA keys only, so unlike a
+KeyStore it cannot keep E222 keys.
+*/
+type PKCS11Token struct {
+	mu     sync.Mutex
+	module pkcs11Module
+	keys   []*PKCS11Key
+}
+
+/*
+A P-256 key pair resident on a token, implementing crypto.Signer like
+ECDSAPrivateKey.Signer: digests are SHA-256 and signatures ASN.1 DER.
+*/
+type PKCS11Key struct {
+	ID     []byte
+	Label  string
+	LowS   bool // emit n − s for s > n/2, as VerifyOptions.RejectHighS requires
+	token  *PKCS11Token
+	handle uint
+	pub    *ecdsa.PublicKey
+}
+
+/*
+Loads the PKCS#11 module at module, e.g. /usr/lib/softhsm/libsofthsm2.so
+or the opensc-pkcs11.so of OpenSC, and logs in to the token labelled
+token with pin. Fails with ErrPKCS11Unavailable in builds without cgo and
+on Windows, with ErrPKCS11Token if no slot holds the token and with
+ErrPKCS11PIN for a wrong PIN. The caller wipes pin.
+*/
+func OpenPKCS11Token(module, token string, pin []byte) (*PKCS11Token, error) {
+	m, err := open_pkcs11_module(module, token, pin)
+	if err != nil {
+		return nil, err
+	}
+	t, err := open_pkcs11_token(m)
+	if err != nil {
+		m.close()
+		return nil, err
+	}
+	return t, nil
+}
+
+/*
+Lists the P-256 private keys of the session, each with the public key of
+the same CKA_ID for its point. Keys of other curves and private keys
+without a public half are skipped.
+*/
+func open_pkcs11_token(m pkcs11Module) (*PKCS11Token, error) {
+	private, err := m.objects(pkcs11PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	public, err := m.objects(pkcs11PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	t := &PKCS11Token{module: m}
+	for _, priv := range private {
+		for _, pub := range public {
+			if string(pub.id) != string(priv.id) {
+				continue
+			}
+			if key := pkcs11_public_key(pub); key != nil {
+				t.keys = append(t.keys, &PKCS11Key{ID: priv.id, Label: priv.label, token: t, handle: priv.handle, pub: key})
+			}
+			break
+		}
+	}
+	return t, nil
+}
+
+/*
+The P-256 public key of a public key object, nil for other curves and
+malformed points. CKA_EC_POINT holds the point as a DER OCTET STRING,
+though some modules give it bare.
+*/
+func pkcs11_public_key(obj pkcs11Object) *ecdsa.PublicKey {
+	if ecdsa_curve_from_oid(asn1.RawValue{FullBytes: obj.params}) != elliptic.P256() {
+		return nil
+	}
+	point := obj.point
+	var wrapped []byte
+	if rest, err := asn1.Unmarshal(point, &wrapped); err == nil && len(rest) == 0 {
+		point = wrapped
+	}
+	x, y, err := parse_uncompressed_point(point, 32)
+	if err != nil || !elliptic.P256().IsOnCurve(x, y) {
+		return nil
+	}
+	return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+}
+
+// The token's P-256 keys, in the order the module lists them.
+func (t *PKCS11Token) Keys() []*PKCS11Key { return append([]*PKCS11Key(nil), t.keys...) }
+
+// Logs out and unloads the module; the token's keys no longer sign.
+func (t *PKCS11Token) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.module == nil {
+		return nil
+	}
+	err := t.module.close()
+	t.module = nil
+	return err
+}
+
+func (key *PKCS11Key) Public() crypto.PublicKey { return key.pub }
+
+// The fingerprint of the key's public key, see Fingerprint.
+func (key *PKCS11Key) Fingerprint() Fingerprint {
+	return fingerprint_of(uncompressed_point(key.pub.X, key.pub.Y, 32))
+}
+
+/*
+Has the token sign the SHA-256 digest. The signature is checked against
+the public key before it is returned, so a faulty token or module fails
+with ErrPKCS11Signature rather than handing out a bad signature.
+*/
+func (key *PKCS11Key) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if opts.HashFunc() != crypto.SHA256 || len(digest) != 32 {
+		return nil, ErrSignerHash
+	}
+	key.token.mu.Lock()
+	if key.token.module == nil {
+		key.token.mu.Unlock()
+		return nil, errors.New("pkcs11: token closed")
+	}
+	raw, err := key.token.module.sign(key.handle, digest)
+	key.token.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) != 64 {
+		return nil, ErrPKCS11Signature
+	}
+	r, s := new(big.Int).SetBytes(raw[:32]), new(big.Int).SetBytes(raw[32:])
+	if key.LowS && !is_low_s(elliptic.P256(), s) {
+		s.Sub(elliptic.P256().Params().N, s)
+	}
+	if !verify_ecdsa_digest(key.pub, digest, r, s) {
+		return nil, ErrPKCS11Signature
+	}
+	return marshal_ecdsa_der(r, s)
+}
@@ -0,0 +1,364 @@
+//go:build !verifyonly && cgo && unix
+
+package sig
+
+/*
+#cgo linux LDFLAGS: -ldl
+
+#include <dlfcn.h>
+#include <stdlib.h>
+#include <string.h>
+
+// The subset of the PKCS#11 v2.40 types this file uses, with the
+// structure layout of Unix platforms.
+typedef unsigned long CK_ULONG;
+typedef CK_ULONG CK_RV;
+typedef CK_ULONG CK_SLOT_ID;
+typedef CK_ULONG CK_SESSION_HANDLE;
+typedef CK_ULONG CK_OBJECT_HANDLE;
+typedef unsigned char CK_BYTE;
+
+typedef struct { CK_BYTE major, minor; } CK_VERSION;
+
+typedef struct {
+	CK_ULONG type;
+	void *pValue;
+	CK_ULONG ulValueLen;
+} CK_ATTRIBUTE;
+
+typedef struct {
+	CK_ULONG mechanism;
+	void *pParameter;
+	CK_ULONG ulParameterLen;
+} CK_MECHANISM;
+
+typedef struct {
+	void *CreateMutex, *DestroyMutex, *LockMutex, *UnlockMutex;
+	CK_ULONG flags;
+	void *pReserved;
+} CK_C_INITIALIZE_ARGS;
+
+typedef struct {
+	CK_BYTE label[32], manufacturerID[32], model[16], serialNumber[16];
+	CK_ULONG flags;
+	CK_ULONG ulMaxSessionCount, ulSessionCount, ulMaxRwSessionCount, ulRwSessionCount;
+	CK_ULONG ulMaxPinLen, ulMinPinLen;
+	CK_ULONG ulTotalPublicMemory, ulFreePublicMemory, ulTotalPrivateMemory, ulFreePrivateMemory;
+	CK_VERSION hardwareVersion, firmwareVersion;
+	CK_BYTE utcTime[16];
+} CK_TOKEN_INFO;
+
+// CK_FUNCTION_LIST up to C_Sign, in the order of the standard.
+typedef struct {
+	CK_VERSION version;
+	CK_RV (*C_Initialize)(void *);
+	CK_RV (*C_Finalize)(void *);
+	void *C_GetInfo, *C_GetFunctionList;
+	CK_RV (*C_GetSlotList)(CK_BYTE, CK_SLOT_ID *, CK_ULONG *);
+	void *C_GetSlotInfo;
+	CK_RV (*C_GetTokenInfo)(CK_SLOT_ID, CK_TOKEN_INFO *);
+	void *C_GetMechanismList, *C_GetMechanismInfo, *C_InitToken, *C_InitPIN, *C_SetPIN;
+	CK_RV (*C_OpenSession)(CK_SLOT_ID, CK_ULONG, void *, void *, CK_SESSION_HANDLE *);
+	CK_RV (*C_CloseSession)(CK_SESSION_HANDLE);
+	void *C_CloseAllSessions, *C_GetSessionInfo, *C_GetOperationState, *C_SetOperationState;
+	CK_RV (*C_Login)(CK_SESSION_HANDLE, CK_ULONG, CK_BYTE *, CK_ULONG);
+	CK_RV (*C_Logout)(CK_SESSION_HANDLE);
+	void *C_CreateObject, *C_CopyObject, *C_DestroyObject, *C_GetObjectSize;
+	CK_RV (*C_GetAttributeValue)(CK_SESSION_HANDLE, CK_OBJECT_HANDLE, CK_ATTRIBUTE *, CK_ULONG);
+	void *C_SetAttributeValue;
+	CK_RV (*C_FindObjectsInit)(CK_SESSION_HANDLE, CK_ATTRIBUTE *, CK_ULONG);
+	CK_RV (*C_FindObjects)(CK_SESSION_HANDLE, CK_OBJECT_HANDLE *, CK_ULONG, CK_ULONG *);
+	CK_RV (*C_FindObjectsFinal)(CK_SESSION_HANDLE);
+	void *C_EncryptInit, *C_Encrypt, *C_EncryptUpdate, *C_EncryptFinal;
+	void *C_DecryptInit, *C_Decrypt, *C_DecryptUpdate, *C_DecryptFinal;
+	void *C_DigestInit, *C_Digest, *C_DigestUpdate, *C_DigestKey, *C_DigestFinal;
+	CK_RV (*C_SignInit)(CK_SESSION_HANDLE, CK_MECHANISM *, CK_OBJECT_HANDLE);
+	CK_RV (*C_Sign)(CK_SESSION_HANDLE, CK_BYTE *, CK_ULONG, CK_BYTE *, CK_ULONG *);
+} CK_FUNCTION_LIST;
+
+#define CKR_ATTRIBUTE_SENSITIVE 0x11UL
+#define CKR_ATTRIBUTE_TYPE_INVALID 0x12UL
+#define CKR_PIN_INCORRECT 0xa0UL
+#define CKR_PIN_LOCKED 0xa4UL
+#define CKR_USER_ALREADY_LOGGED_IN 0x100UL
+#define CKR_CRYPTOKI_ALREADY_INITIALIZED 0x191UL
+#define CKF_OS_LOCKING_OK 0x2UL
+#define CKF_SERIAL_SESSION 0x4UL
+#define CKU_USER 1UL
+#define CKA_CLASS 0x0UL
+#define CKA_KEY_TYPE 0x100UL
+#define CKK_EC 0x3UL
+#define CKM_ECDSA 0x1041UL
+
+static CK_FUNCTION_LIST *p11_load(const char *path, void **lib) {
+	CK_RV (*get)(CK_FUNCTION_LIST **);
+	CK_FUNCTION_LIST *f = NULL;
+	*lib = dlopen(path, RTLD_NOW | RTLD_LOCAL);
+	if (*lib == NULL) {
+		return NULL;
+	}
+	get = (CK_RV (*)(CK_FUNCTION_LIST **))dlsym(*lib, "C_GetFunctionList");
+	if (get == NULL || get(&f) != 0 || f == NULL) {
+		dlclose(*lib);
+		*lib = NULL;
+		return NULL;
+	}
+	return f;
+}
+
+static CK_RV p11_initialize(CK_FUNCTION_LIST *f) {
+	CK_C_INITIALIZE_ARGS args;
+	memset(&args, 0, sizeof args);
+	args.flags = CKF_OS_LOCKING_OK;
+	return f->C_Initialize(&args);
+}
+
+static CK_RV p11_finalize(CK_FUNCTION_LIST *f) { return f->C_Finalize(NULL); }
+
+static CK_RV p11_slots(CK_FUNCTION_LIST *f, CK_SLOT_ID *slots, CK_ULONG *count) {
+	return f->C_GetSlotList(1, slots, count);
+}
+
+static CK_RV p11_token_label(CK_FUNCTION_LIST *f, CK_SLOT_ID slot, CK_BYTE *label) {
+	CK_TOKEN_INFO info;
+	CK_RV rv = f->C_GetTokenInfo(slot, &info);
+	if (rv == 0) {
+		memcpy(label, info.label, sizeof info.label);
+	}
+	return rv;
+}
+
+static CK_RV p11_open(CK_FUNCTION_LIST *f, CK_SLOT_ID slot, CK_BYTE *pin, CK_ULONG pin_len, CK_SESSION_HANDLE *s) {
+	CK_RV rv = f->C_OpenSession(slot, CKF_SERIAL_SESSION, NULL, NULL, s);
+	if (rv != 0) {
+		return rv;
+	}
+	rv = f->C_Login(*s, CKU_USER, pin, pin_len);
+	if (rv != 0 && rv != CKR_USER_ALREADY_LOGGED_IN) {
+		f->C_CloseSession(*s);
+		return rv;
+	}
+	return 0;
+}
+
+static void p11_close(CK_FUNCTION_LIST *f, CK_SESSION_HANDLE s) {
+	f->C_Logout(s);
+	f->C_CloseSession(s);
+}
+
+static CK_RV p11_find(CK_FUNCTION_LIST *f, CK_SESSION_HANDLE s, CK_ULONG class,
+	CK_OBJECT_HANDLE *objs, CK_ULONG max, CK_ULONG *count) {
+	CK_ULONG key_type = CKK_EC;
+	CK_ATTRIBUTE template[2] = {
+		{CKA_CLASS, &class, sizeof class},
+		{CKA_KEY_TYPE, &key_type, sizeof key_type},
+	};
+	CK_RV rv = f->C_FindObjectsInit(s, template, 2);
+	if (rv != 0) {
+		return rv;
+	}
+	rv = f->C_FindObjects(s, objs, max, count);
+	f->C_FindObjectsFinal(s);
+	return rv;
+}
+
+// Reads one attribute into a buffer of malloc, NULL and 0 where the object lacks it.
+static CK_RV p11_attribute(CK_FUNCTION_LIST *f, CK_SESSION_HANDLE s, CK_OBJECT_HANDLE obj,
+	CK_ULONG type, void **value, CK_ULONG *len) {
+	CK_ATTRIBUTE a = {type, NULL, 0};
+	CK_RV rv = f->C_GetAttributeValue(s, obj, &a, 1);
+	*value = NULL;
+	*len = 0;
+	if (rv == CKR_ATTRIBUTE_SENSITIVE || rv == CKR_ATTRIBUTE_TYPE_INVALID || a.ulValueLen == (CK_ULONG)-1) {
+		return 0;
+	}
+	if (rv != 0 || a.ulValueLen == 0) {
+		return rv;
+	}
+	a.pValue = malloc(a.ulValueLen);
+	if (a.pValue == NULL) {
+		return 2;
+	}
+	rv = f->C_GetAttributeValue(s, obj, &a, 1);
+	if (rv != 0) {
+		free(a.pValue);
+		return rv;
+	}
+	*value = a.pValue;
+	*len = a.ulValueLen;
+	return 0;
+}
+
+static CK_RV p11_sign(CK_FUNCTION_LIST *f, CK_SESSION_HANDLE s, CK_OBJECT_HANDLE key,
+	CK_BYTE *digest, CK_ULONG digest_len, CK_BYTE *sig, CK_ULONG *sig_len) {
+	CK_MECHANISM m = {CKM_ECDSA, NULL, 0};
+	CK_RV rv = f->C_SignInit(s, &m, key);
+	if (rv != 0) {
+		return rv;
+	}
+	return f->C_Sign(s, digest, digest_len, sig, sig_len);
+}
+*/
+import "C"
+
+import (
+	"bytes"
+	"fmt"
+	"unsafe"
+)
+
+// Most key objects of a class that are listed, more than any token holds.
+const pkcs11MaxObjects = 256
+
+// CKA_ID, CKA_LABEL, CKA_EC_PARAMS and CKA_EC_POINT.
+const (
+	pkcs11AttrID       = 0x102
+	pkcs11AttrLabel    = 0x3
+	pkcs11AttrECParams = 0x180
+	pkcs11AttrECPoint  = 0x181
+)
+
+// A session on a token of a module loaded with dlopen.
+type cgoPKCS11Module struct {
+	lib     unsafe.Pointer
+	f       *C.CK_FUNCTION_LIST
+	session C.CK_SESSION_HANDLE
+}
+
+func open_pkcs11_module(path, token string, pin []byte) (pkcs11Module, error) {
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	m := &cgoPKCS11Module{}
+	m.f = C.p11_load(cpath, &m.lib)
+	if m.f == nil {
+		return nil, fmt.Errorf("pkcs11: cannot load module %s", path)
+	}
+	rv := C.p11_initialize(m.f)
+	if rv != 0 && rv != C.CKR_CRYPTOKI_ALREADY_INITIALIZED {
+		C.dlclose(m.lib)
+		return nil, pkcs11_error("C_Initialize", rv)
+	}
+	slot, err := m.find_slot(token)
+	if err == nil {
+		err = m.login(slot, pin)
+	}
+	if err != nil {
+		C.p11_finalize(m.f)
+		C.dlclose(m.lib)
+		return nil, err
+	}
+	return m, nil
+}
+
+// The slot holding the token labelled token; labels are blank padded to 32 bytes.
+func (m *cgoPKCS11Module) find_slot(token string) (C.CK_SLOT_ID, error) {
+	var count C.CK_ULONG
+	if rv := C.p11_slots(m.f, nil, &count); rv != 0 {
+		return 0, pkcs11_error("C_GetSlotList", rv)
+	}
+	if count == 0 {
+		return 0, ErrPKCS11Token
+	}
+	slots := make([]C.CK_SLOT_ID, count)
+	if rv := C.p11_slots(m.f, &slots[0], &count); rv != 0 {
+		return 0, pkcs11_error("C_GetSlotList", rv)
+	}
+	label := (*C.CK_BYTE)(C.malloc(32))
+	defer C.free(unsafe.Pointer(label))
+	for _, slot := range slots[:count] {
+		if C.p11_token_label(m.f, slot, label) != 0 {
+			continue
+		}
+		if string(bytes.TrimRight(C.GoBytes(unsafe.Pointer(label), 32), " ")) == token {
+			return slot, nil
+		}
+	}
+	return 0, ErrPKCS11Token
+}
+
+// Opens a session on slot and logs in; the PIN is copied to C memory and wiped there.
+func (m *cgoPKCS11Module) login(slot C.CK_SLOT_ID, pin []byte) error {
+	cpin := (*C.CK_BYTE)(C.malloc(C.size_t(len(pin) + 1)))
+	defer C.free(unsafe.Pointer(cpin))
+	defer C.memset(unsafe.Pointer(cpin), 0, C.size_t(len(pin)+1))
+	if len(pin) > 0 {
+		C.memcpy(unsafe.Pointer(cpin), unsafe.Pointer(&pin[0]), C.size_t(len(pin)))
+	}
+	rv := C.p11_open(m.f, slot, cpin, C.CK_ULONG(len(pin)), &m.session)
+	switch rv {
+	case 0:
+		return nil
+	case C.CKR_PIN_INCORRECT, C.CKR_PIN_LOCKED:
+		return ErrPKCS11PIN
+	}
+	return pkcs11_error("C_Login", rv)
+}
+
+func (m *cgoPKCS11Module) objects(class uint) ([]pkcs11Object, error) {
+	handles := make([]C.CK_OBJECT_HANDLE, pkcs11MaxObjects)
+	var count C.CK_ULONG
+	if rv := C.p11_find(m.f, m.session, C.CK_ULONG(class), &handles[0], pkcs11MaxObjects, &count); rv != 0 {
+		return nil, pkcs11_error("C_FindObjects", rv)
+	}
+	objs := make([]pkcs11Object, count)
+	for i, h := range handles[:count] {
+		obj := &objs[i]
+		obj.handle = uint(h)
+		for _, attr := range []struct {
+			typ uint
+			out *[]byte
+		}{{pkcs11AttrID, &obj.id}, {pkcs11AttrECParams, &obj.params}, {pkcs11AttrECPoint, &obj.point}} {
+			v, err := m.attribute(h, attr.typ)
+			if err != nil {
+				return nil, err
+			}
+			*attr.out = v
+		}
+		label, err := m.attribute(h, pkcs11AttrLabel)
+		if err != nil {
+			return nil, err
+		}
+		obj.label = string(label)
+	}
+	return objs, nil
+}
+
+func (m *cgoPKCS11Module) attribute(obj C.CK_OBJECT_HANDLE, typ uint) ([]byte, error) {
+	var value unsafe.Pointer
+	var length C.CK_ULONG
+	if rv := C.p11_attribute(m.f, m.session, obj, C.CK_ULONG(typ), &value, &length); rv != 0 {
+		return nil, pkcs11_error("C_GetAttributeValue", rv)
+	}
+	if value == nil {
+		return nil, nil
+	}
+	defer C.free(value)
+	return C.GoBytes(value, C.int(length)), nil
+}
+
+func (m *cgoPKCS11Module) sign(handle uint, digest []byte) ([]byte, error) {
+	buf := (*C.CK_BYTE)(C.malloc(C.size_t(len(digest) + 132)))
+	defer C.free(unsafe.Pointer(buf))
+	C.memcpy(unsafe.Pointer(buf), unsafe.Pointer(&digest[0]), C.size_t(len(digest)))
+	sig := (*C.CK_BYTE)(unsafe.Add(unsafe.Pointer(buf), len(digest)))
+	length := C.CK_ULONG(132)
+	rv := C.p11_sign(m.f, m.session, C.CK_OBJECT_HANDLE(handle), buf, C.CK_ULONG(len(digest)), sig, &length)
+	if rv != 0 {
+		return nil, pkcs11_error("C_Sign", rv)
+	}
+	return C.GoBytes(unsafe.Pointer(sig), C.int(length)), nil
+}
+
+func (m *cgoPKCS11Module) close() error {
+	C.p11_close(m.f, m.session)
+	rv := C.p11_finalize(m.f)
+	C.dlclose(m.lib)
+	if rv != 0 {
+		return pkcs11_error("C_Finalize", rv)
+	}
+	return nil
+}
+
+func pkcs11_error(function string, rv C.CK_RV) error {
+	return fmt.Errorf("pkcs11: %s failed with CKR 0x%x", function, uint64(rv))
+}
@@ -0,0 +1,7 @@
+//go:build !verifyonly && !(cgo && unix)
+
+package sig
+
+func open_pkcs11_module(path, token string, pin []byte) (pkcs11Module, error) {
+	return nil, ErrPKCS11Unavailable
+}
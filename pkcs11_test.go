@@ -0,0 +1,167 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// A pkcs11Module in memory, standing in for a token; it signs with high s and, if faulty, flips a bit.
+type memoryToken struct {
+	keys   []*ecdsa.PrivateKey
+	faulty bool
+	closed bool
+}
+
+func (m *memoryToken) objects(class uint) ([]pkcs11Object, error) {
+	var objs []pkcs11Object
+	for i, key := range m.keys {
+		params, _ := asn1.Marshal(ecdsa_curve_oid(key.Curve))
+		obj := pkcs11Object{handle: uint(i), id: []byte{byte(i)}, label: fmt.Sprint("token key ", i), params: params}
+		if class == pkcs11PublicKey {
+			obj.point, _ = asn1.Marshal(uncompressed_point(key.X, key.Y, curve_width(key.Curve)))
+		}
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
+
+func (m *memoryToken) sign(handle uint, digest []byte) ([]byte, error) {
+	key := m.keys[handle]
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest)
+	if err != nil {
+		return nil, err
+	}
+	if is_low_s(key.Curve, s) {
+		s.Sub(key.Curve.Params().N, s)
+	}
+	sig := append(toFixedBytes(r, 32), toFixedBytes(s, 32)...)
+	if m.faulty {
+		sig[40] ^= 1
+	}
+	return sig, nil
+}
+
+func (m *memoryToken) close() error {
+	m.closed = true
+	return nil
+}
+
+// Token keys list with their public keys and sign as crypto.Signers whose signatures verify.
+func TestPKCS11TokenKeys(t *testing.T) {
+
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	module := &memoryToken{keys: []*ecdsa.PrivateKey{p384, p256}}
+	token, err := open_pkcs11_token(module)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(token.Keys()); n != 1 {
+		t.Fatalf("token lists %d keys, want the P-256 key alone", n)
+	}
+	key := token.Keys()[0]
+	pub, ok := key.Public().(*ecdsa.PublicKey)
+	if !ok || !pub.Equal(&p256.PublicKey) {
+		t.Fatalf("token key's public key is %v", key.Public())
+	}
+	if key.Label != "token key 1" || !bytes.Equal(key.ID, []byte{1}) {
+		t.Fatalf("token key labelled %q with ID %x", key.Label, key.ID)
+	}
+	want, err := PublicKeyFingerprint(&p256.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.Fingerprint() != want {
+		t.Fatalf("fingerprint %v, want %v", key.Fingerprint(), want)
+	}
+
+	// signatures verify here and in the standard library, low s on request
+	var signer crypto.Signer = key
+	digest := sha256.Sum256([]byte("signed on the token"))
+	der, err := signer.Sign(nil, digest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ecdsa.VerifyASN1(pub, digest[:], der) {
+		t.Fatal("token signature does not verify in crypto/ecdsa")
+	}
+	key.LowS = true
+	der, err = signer.Sign(nil, digest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sig ecdsaDERSignature
+	if _, err := asn1.Unmarshal(der, &sig); err != nil {
+		t.Fatal(err)
+	}
+	if !is_low_s(elliptic.P256(), sig.S) {
+		t.Fatalf("s = %v is high", sig.S)
+	}
+	if !verify_ecdsa_digest(pub, digest[:], sig.R, sig.S) {
+		t.Fatal("low s signature does not verify")
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "token key"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, pub, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := x509.ParseCertificate(cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := parsed.CheckSignatureFrom(parsed); err != nil {
+		t.Fatal(err)
+	}
+
+	// other hashes, faulty tokens and closed tokens fail
+	if _, err := signer.Sign(nil, digest[:], crypto.SHA384); err != ErrSignerHash {
+		t.Fatalf("SHA-384: %v, want %v", err, ErrSignerHash)
+	}
+	module.faulty = true
+	_, err = signer.Sign(nil, digest[:], crypto.SHA256)
+	module.faulty = false
+	if err != ErrPKCS11Signature {
+		t.Fatalf("faulty token: %v, want %v", err, ErrPKCS11Signature)
+	}
+	if err := token.Close(); err != nil || !module.closed {
+		t.Fatalf("close: %v, module closed %v", err, module.closed)
+	}
+	if err := token.Close(); err != nil {
+		t.Fatalf("second close: %v", err)
+	}
+	if _, err := signer.Sign(nil, digest[:], crypto.SHA256); err == nil {
+		t.Fatal("closed token signs")
+	}
+	if _, err := OpenPKCS11Token(filepath.Join(os.TempDir(), "no-such-pkcs11-module.so"), "token", []byte("1234")); err == nil {
+		t.Fatal("missing module opens")
+	}
+}
@@ -0,0 +1,182 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+)
+
+// PrivateKeyInfo of RFC 5208 Section 5, version 0.
+type pkcs8PrivateKey struct {
+	Version    int
+	Algorithm  pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+/*
+ECPrivateKey of RFC 5915 Section 3. The curve is left to the enclosing
+PrivateKeyInfo, as RFC 5915 Section 2 allows and crypto/x509 does.
+*/
+type ecPrivateKey struct {
+	Version    int
+	PrivateKey []byte
+	Parameters asn1.RawValue  `asn1:"optional,explicit,tag:0"`
+	PublicKey  asn1.BitString `asn1:"optional,explicit,tag:1"`
+}
+
+/*
+Encodes the key as a DER PKCS#8 PrivateKeyInfo holding an RFC 5915
+ECPrivateKey with its public key, as "openssl pkey" reads and writes on
+P-256, P-384, P-521 and secp256k1. The result holds the secret scalar in
+the clear: the caller wipes it once written.
+*/
+func (key *ECDSAPrivateKey) MarshalPKCS8() ([]byte, error) {
+	key.mu.Lock()
+	wiped := key.wiped
+	key.mu.Unlock()
+	if wiped {
+		return nil, ErrDestroyedSecret
+	}
+	curve := ecdsa_curve(key.Curve)
+	if err := check_ecdsa_scalar(curve, key.D); err != nil {
+		return nil, err
+	}
+	oid := ecdsa_curve_oid(curve)
+	if oid == nil {
+		return nil, ErrUnsupportedAlgorithm
+	}
+	params, _ := asn1.Marshal(oid)
+	width := (curve.Params().N.BitLen() + 7) / 8
+	pub := key.PublicKey()
+	return marshal_pkcs8(params, key.D, width, uncompressed_point(pub.X, pub.Y, curve_width(curve)))
+}
+
+/*
+Encodes the E521 secret key x as MarshalPKCS8 does ECDSA keys, under the
+curve OID of oidE521DER, with the public key x × G. Only this package
+reads the result.
+*/
+func MarshalE521PKCS8(x *SecretScalar) ([]byte, error) {
+	s, err := x.Int()
+	if err != nil {
+		return nil, err
+	}
+	defer wipe_int(s)
+	s.Mod(s, e521Curve.Order())
+	if s.Sign() == 0 {
+		return nil, ErrZeroScalar
+	}
+	Y := e521Curve.BaseMul(s)
+	width := (e521Curve.Order().BitLen() + 7) / 8
+	return marshal_pkcs8(oidE521DER, s, width, uncompressed_point(Y.X(), Y.Y(), e521Curve.EncodedLen()))
+}
+
+func marshal_pkcs8(params []byte, d *big.Int, width int, point []byte) ([]byte, error) {
+	scalar := toFixedBytes(d, width)
+	defer wipe_bytes(scalar)
+	inner, err := asn1.Marshal(ecPrivateKey{
+		Version:    1,
+		PrivateKey: scalar,
+		PublicKey:  asn1.BitString{Bytes: point, BitLength: 8 * len(point)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer wipe_bytes(inner)
+	return asn1.Marshal(pkcs8PrivateKey{Algorithm: ec_algorithm(params), PrivateKey: inner})
+}
+
+/*
+Decodes a DER PKCS#8 PrivateKeyInfo of MarshalPKCS8 or MarshalE521PKCS8,
+returning an *ECDSAPrivateKey with the nonce cache enabled or, for E521,
+a *SecretScalar. The scalar must lie in [1, n−1], and a public key, if
+present, must match it; malformed and unsupported encodings fail with
+ErrKeyEncoding.
+*/
+func ParsePKCS8PrivateKey(der []byte) (interface{}, error) {
+	var info pkcs8PrivateKey
+	if rest, err := asn1.Unmarshal(der, &info); err != nil || len(rest) > 0 || info.Version != 0 {
+		return nil, ErrKeyEncoding
+	}
+	defer wipe_bytes(info.PrivateKey)
+	if !info.Algorithm.Algorithm.Equal(oidPublicKeyEC) {
+		return nil, ErrKeyEncoding
+	}
+	var inner ecPrivateKey
+	if rest, err := asn1.Unmarshal(info.PrivateKey, &inner); err != nil || len(rest) > 0 || inner.Version != 1 {
+		return nil, ErrKeyEncoding
+	}
+	defer wipe_bytes(inner.PrivateKey)
+	if len(inner.Parameters.FullBytes) > 0 && string(inner.Parameters.FullBytes) != string(info.Algorithm.Parameters.FullBytes) {
+		return nil, ErrKeyEncoding
+	}
+	d := fromFixedBytes(inner.PrivateKey)
+
+	if is_e521_params(info.Algorithm.Parameters) {
+		defer wipe_int(d)
+		if d.Sign() == 0 || d.Cmp(e521Curve.Order()) >= 0 || len(inner.PrivateKey) != (e521Curve.Order().BitLen()+7)/8 {
+			return nil, ErrKeyEncoding
+		}
+		if len(inner.PublicKey.Bytes) > 0 {
+			Y := e521Curve.BaseMul(d)
+			if string(inner.PublicKey.Bytes) != string(uncompressed_point(Y.X(), Y.Y(), e521Curve.EncodedLen())) {
+				return nil, ErrKeyEncoding
+			}
+		}
+		return NewSecretScalar(d), nil
+	}
+	curve := ecdsa_curve_from_oid(info.Algorithm.Parameters)
+	if curve == nil || len(inner.PrivateKey) != (curve.Params().N.BitLen()+7)/8 {
+		wipe_int(d)
+		return nil, ErrKeyEncoding
+	}
+	if err := check_ecdsa_scalar(curve, d); err != nil {
+		wipe_int(d)
+		return nil, ErrKeyEncoding
+	}
+	key := NewECDSAPrivateKey(d)
+	key.Curve = curve
+	if len(inner.PublicKey.Bytes) > 0 {
+		pub := key.PublicKey()
+		if string(inner.PublicKey.Bytes) != string(uncompressed_point(pub.X, pub.Y, curve_width(curve))) {
+			key.Wipe()
+			return nil, ErrKeyEncoding
+		}
+	}
+	return key, nil
+}
+
+/*
+Armors a private key as a "PRIVATE KEY" PEM block: an *ECDSAPrivateKey
+with MarshalPKCS8, or an E521 *SecretScalar with MarshalE521PKCS8.
+*/
+func PrivateKeyToPEM(key interface{}) ([]byte, error) {
+	var der []byte
+	var err error
+	switch k := key.(type) {
+	case *ECDSAPrivateKey:
+		der, err = k.MarshalPKCS8()
+	case *SecretScalar:
+		der, err = MarshalE521PKCS8(k)
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer wipe_bytes(der)
+	return pem.EncodeToMemory(&pem.Block{Type: privateKeyPEMType, Bytes: der}), nil
+}
+
+// Reads the first "PRIVATE KEY" PEM block of data with ParsePKCS8PrivateKey.
+func PrivateKeyFromPEM(data []byte) (interface{}, error) {
+	der, err := pem_block(data, privateKeyPEMType)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe_bytes(der)
+	return ParsePKCS8PrivateKey(der)
+}
@@ -0,0 +1,221 @@
+package sig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"math/big"
+
+	"sig/edwards"
+	"sig/secp256k1"
+)
+
+const (
+	publicKeyPEMType  = "PUBLIC KEY"
+	privateKeyPEMType = "PRIVATE KEY"
+)
+
+var ErrKeyEncoding = errors.New("pkix: malformed or unsupported key encoding")
+
+// id-ecPublicKey of RFC 5480 Section 2.1.1 and the named curves of Section 2.1.1.1 and SEC 2.
+var (
+	oidPublicKeyEC = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidP256        = asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}
+	oidP384        = asn1.ObjectIdentifier{1, 3, 132, 0, 34}
+	oidP521        = asn1.ObjectIdentifier{1, 3, 132, 0, 35}
+	oidSecp256k1   = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+)
+
+/*
+The DER encoding of the OID naming E521 in place of a named curve:
+
+	2.25.147685396933228888518284815923099114292
+
+an OID under the arc 2.25 of ITU-T X.667, whose last component is the
+integer value of the UUID 6f1b2c3e-9a4d-4e8b-b1f7-2c5d8e0a9b34, which
+needs no registration. No standard assigns E521 an OID; this one is this
+package's own, and other tools read such keys as of an unknown curve.
+Its component exceeds an int, so it is kept encoded rather than as an
+asn1.ObjectIdentifier.
+*/
+var oidE521DER = []byte{
+	0x06, 0x14, 0x69, 0x81, 0xde, 0x9b, 0x96, 0x8f, 0xd3, 0xa4, 0xea, 0xba,
+	0x97, 0xb1, 0xfb, 0xcb, 0x8b, 0xd8, 0xf0, 0xaa, 0xb6, 0x34,
+}
+
+// SubjectPublicKeyInfo of RFC 5280 Section 4.1.2.7.
+type subjectPublicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// The curve OID of a supported ECDSA curve, nil for any other.
+func ecdsa_curve_oid(curve elliptic.Curve) asn1.ObjectIdentifier {
+	switch curve.Params().Name {
+	case "P-256":
+		return oidP256
+	case "P-384":
+		return oidP384
+	case "P-521":
+		return oidP521
+	case "secp256k1":
+		return oidSecp256k1
+	}
+	return nil
+}
+
+// The ECDSA curve named by the parameters of an id-ecPublicKey AlgorithmIdentifier, or nil.
+func ecdsa_curve_from_oid(params asn1.RawValue) elliptic.Curve {
+	var oid asn1.ObjectIdentifier
+	if rest, err := asn1.Unmarshal(params.FullBytes, &oid); err != nil || len(rest) > 0 {
+		return nil
+	}
+	switch {
+	case oid.Equal(oidP256):
+		return elliptic.P256()
+	case oid.Equal(oidP384):
+		return elliptic.P384()
+	case oid.Equal(oidP521):
+		return elliptic.P521()
+	case oid.Equal(oidSecp256k1):
+		return secp256k1.Curve()
+	}
+	return nil
+}
+
+// True iff params name E521, see oidE521DER.
+func is_e521_params(params asn1.RawValue) bool {
+	return string(params.FullBytes) == string(oidE521DER)
+}
+
+// The AlgorithmIdentifier id-ecPublicKey with the curve OID DER encoded in params.
+func ec_algorithm(params []byte) pkix.AlgorithmIdentifier {
+	return pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyEC, Parameters: asn1.RawValue{FullBytes: params}}
+}
+
+/*
+Encodes pub as a DER SubjectPublicKeyInfo of RFC 5480, as OpenSSL reads
+with "openssl pkey -pubin". pub is one of
+
+	*ecdsa.PublicKey on P-256, P-384, P-521 or secp256k1
+	*edwards.Point    on E521, under the curve OID of oidE521DER
+
+the point in the uncompressed form 04 ‖ x ‖ y of SEC 1 Section 2.3.3
+either way. Other keys fail with ErrUnsupportedAlgorithm.
+*/
+func MarshalPKIX(pub interface{}) ([]byte, error) {
+	var params, point []byte
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		if key == nil || key.Curve == nil || key.X == nil || key.Y == nil || !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, edwards.ErrInvalidPoint
+		}
+		oid := ecdsa_curve_oid(key.Curve)
+		if oid == nil {
+			return nil, ErrUnsupportedAlgorithm
+		}
+		params, _ = asn1.Marshal(oid)
+		point = uncompressed_point(key.X, key.Y, curve_width(key.Curve))
+	case *edwards.Point:
+		if key == nil || key.Curve() != e521Curve {
+			return nil, ErrUnsupportedAlgorithm
+		}
+		if err := validate_public_key(key); err != nil {
+			return nil, err
+		}
+		params = oidE521DER
+		point = uncompressed_point(key.X(), key.Y(), e521Curve.EncodedLen())
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+	return asn1.Marshal(subjectPublicKeyInfo{
+		Algorithm: ec_algorithm(params),
+		PublicKey: asn1.BitString{Bytes: point, BitLength: 8 * len(point)},
+	})
+}
+
+/*
+Decodes a DER SubjectPublicKeyInfo of MarshalPKIX, returning an
+*ecdsa.PublicKey or, for E521, an *edwards.Point of the prime order
+subgroup. Points not on their curve fail with edwards.ErrInvalidPoint, any other
+malformed or unsupported encoding with ErrKeyEncoding.
+*/
+func ParsePKIXPublicKey(der []byte) (interface{}, error) {
+	var info subjectPublicKeyInfo
+	if rest, err := asn1.Unmarshal(der, &info); err != nil || len(rest) > 0 {
+		return nil, ErrKeyEncoding
+	}
+	if !info.Algorithm.Algorithm.Equal(oidPublicKeyEC) || info.PublicKey.BitLength != 8*len(info.PublicKey.Bytes) {
+		return nil, ErrKeyEncoding
+	}
+	if is_e521_params(info.Algorithm.Parameters) {
+		x, y, err := parse_uncompressed_point(info.PublicKey.Bytes, e521Curve.EncodedLen())
+		if err != nil {
+			return nil, err
+		}
+		P := e521Curve.NewPointXY(*x, *y)
+		if validate_public_key(P) != nil || !P.InPrimeSubgroup() {
+			return nil, edwards.ErrInvalidPoint
+		}
+		return P, nil
+	}
+	curve := ecdsa_curve_from_oid(info.Algorithm.Parameters)
+	if curve == nil {
+		return nil, ErrKeyEncoding
+	}
+	x, y, err := parse_uncompressed_point(info.PublicKey.Bytes, curve_width(curve))
+	if err != nil {
+		return nil, err
+	}
+	if !curve.IsOnCurve(x, y) {
+		return nil, edwards.ErrInvalidPoint
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// 04 ‖ x ‖ y with coordinates of width bytes.
+func uncompressed_point(x, y *big.Int, width int) []byte {
+	return append(append([]byte{4}, toFixedBytes(x, width)...), toFixedBytes(y, width)...)
+}
+
+func parse_uncompressed_point(b []byte, width int) (*big.Int, *big.Int, error) {
+	if len(b) != 1+2*width || b[0] != 4 {
+		return nil, nil, ErrKeyEncoding
+	}
+	return fromFixedBytes(b[1 : 1+width]), fromFixedBytes(b[1+width:]), nil
+}
+
+// Armors a public key of MarshalPKIX as a "PUBLIC KEY" PEM block.
+func PublicKeyToPEM(pub interface{}) ([]byte, error) {
+	der, err := MarshalPKIX(pub)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: publicKeyPEMType, Bytes: der}), nil
+}
+
+// Reads the first "PUBLIC KEY" PEM block of data with ParsePKIXPublicKey.
+func PublicKeyFromPEM(data []byte) (interface{}, error) {
+	der, err := pem_block(data, publicKeyPEMType)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePKIXPublicKey(der)
+}
+
+// The bytes of the first PEM block of type typ in data, skipping others.
+func pem_block(data []byte, typ string) ([]byte, error) {
+	for {
+		block, rest := pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("no " + typ + " PEM block found")
+		}
+		if block.Type == typ {
+			return block.Bytes, nil
+		}
+		data = rest
+	}
+}
@@ -0,0 +1,125 @@
+package sig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"errors"
+	"math/big"
+
+	"sig/secp256k1"
+)
+
+// Offset of the recovery id in the first byte of a recoverable signature, as in Bitcoin and Ethereum.
+const recoveryIDOffset = 27
+
+var ErrRecoverableSignature = errors.New("malformed recoverable signature")
+
+/*
+Encodes (r, s) with recovery id recid as v || r || s, v = 27 + recid, with
+r and s at the width of curve: 65 bytes on the 256-bit curves. Bit 0 of
+recid is the parity of the y coordinate of R = k × G and bit 1 is set if
+the x coordinate of R was reduced mod n to give r.
+*/
+func encode_recoverable(curve elliptic.Curve, recid byte, r, s *big.Int) []byte {
+	width := curve_width(curve)
+	out := []byte{recoveryIDOffset + recid}
+	out = append(out, toFixedBytes(r, width)...)
+	return append(out, toFixedBytes(s, width)...)
+}
+
+// Splits a recoverable signature into recovery id, r and s.
+func decode_recoverable(curve elliptic.Curve, sig []byte) (byte, *big.Int, *big.Int, error) {
+	width := curve_width(curve)
+	if len(sig) != 1+2*width || sig[0] < recoveryIDOffset || sig[0] > recoveryIDOffset+3 {
+		return 0, nil, nil, ErrRecoverableSignature
+	}
+	return sig[0] - recoveryIDOffset, fromFixedBytes(sig[1 : 1+width]), fromFixedBytes(sig[1+width:]), nil
+}
+
+/*
+y coordinate with the given parity of the point of curve with x coordinate
+x, solving y² = x³ + ax + b with a = 0 for secp256k1 and a = −3 for the
+NIST curves, or nil if no point has this x.
+*/
+func curve_y(curve elliptic.Curve, x *big.Int, parity uint) *big.Int {
+	p := curve.Params().P
+	if x.Sign() < 0 || x.Cmp(p) >= 0 {
+		return nil
+	}
+	a := new(big.Int).Exp(x, big.NewInt(3), p)
+	if curve != secp256k1.Curve() {
+		a.Sub(a, new(big.Int).Mul(big.NewInt(3), x))
+	}
+	a.Add(a, curve.Params().B).Mod(a, p)
+	y := new(big.Int).ModSqrt(a, p)
+	if y == nil {
+		return nil
+	}
+	if y.Bit(0) != parity {
+		y.Sub(p, y)
+	}
+	return y
+}
+
+// Public key that makes (r, s) a valid signature of the hash e on curve, given recid.
+func recover_ecdsa_key(curve elliptic.Curve, recid byte, e []byte, r, s *big.Int) (*ecdsa.PublicKey, error) {
+	n := curve.Params().N
+	if r.Sign() <= 0 || r.Cmp(n) >= 0 || s.Sign() <= 0 || s.Cmp(n) >= 0 {
+		return nil, ErrRecoverableSignature
+	}
+	// R = (r + jn, y) with j and the parity of y from recid
+	x := new(big.Int).Set(r)
+	if recid&2 != 0 {
+		x.Add(x, n)
+	}
+	y := curve_y(curve, x, uint(recid&1))
+	if y == nil {
+		return nil, ErrRecoverableSignature
+	}
+	R := &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+
+	// Q = r⁻¹(sR − zG) = (−z r⁻¹) × G + (s r⁻¹) × R
+	width := curve_width(curve)
+	r_inv := new(big.Int).ModInverse(r, n)
+	u1 := new(big.Int).Mul(ecdsa_bits2int(curve, e), r_inv)
+	u1.Neg(u1).Mod(u1, n)
+	u2 := new(big.Int).Mul(s, r_inv)
+	u2.Mod(u2, n)
+	Q := &ecdsa.PublicKey{Curve: curve}
+	Q.X, Q.Y = ecdsa_combined_mult(curve, R, toFixedBytes(u1, width), toFixedBytes(u2, width))
+	if err := ValidatePublicKey(curve, Q.X, Q.Y); err != nil {
+		return nil, err
+	}
+	return Q, nil
+}
+
+/*
+Recovers the public key that signed msg in domain from a recoverable
+signature on curve, so that signatures need not ship the key. The
+recovered key is checked to verify the signature. Any signature yields some key: callers must compare the
+result with the key or address they expect, as VerifyRecoverable does.
+*/
+func RecoverPublicKey(curve elliptic.Curve, domain string, sig []byte, msg []byte) (*ecdsa.PublicKey, error) {
+	recid, r, s, err := decode_recoverable(curve, sig)
+	if err != nil {
+		return nil, err
+	}
+	e := ecdsa_digest(curve, domain, msg)
+	Q, err := recover_ecdsa_key(curve, recid, e, r, s)
+	if err != nil {
+		return nil, err
+	}
+	if !verify_ecdsa_digest(Q, e, r, s) {
+		return nil, ErrRecoverableSignature
+	}
+	return Q, nil
+}
+
+// True iff the recoverable signature sig on msg in domain recovers to Q.
+func VerifyRecoverable(Q *ecdsa.PublicKey, domain string, sig []byte, msg []byte) bool {
+	if Q == nil {
+		return false
+	}
+	got, err := RecoverPublicKey(ecdsa_curve(Q.Curve), domain, sig, msg)
+	return err == nil && got.X.Cmp(Q.X) == 0 && got.Y.Cmp(Q.Y) == 0
+}
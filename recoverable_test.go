@@ -0,0 +1,71 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"sig/secp256k1"
+)
+
+// Keys recovered from v || r || s signatures match the signer, on P-256 and secp256k1.
+func TestRecoverableSignatures(t *testing.T) {
+
+	for _, curve := range []elliptic.Curve{elliptic.P256(), secp256k1.Curve(), elliptic.P384()} {
+		name := curve.Params().Name
+		d, err := rand.Int(rand.Reader, curve.Params().N)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key := NewECDSAPrivateKey(d)
+		key.Curve, key.LowS = curve, curve == secp256k1.Curve()
+		Q := key.PublicKey()
+		for i := 0; i < 4; i++ {
+			msg := []byte(fmt.Sprintf("transfer %d", i))
+			sig, err := key.SignRecoverable("", &msg)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if len(sig) != 1+2*curve_width(curve) {
+				t.Fatalf("%s: signature is %d bytes, want %d", name, len(sig), 1+2*curve_width(curve))
+			}
+			got, err := RecoverPublicKey(curve, "", sig, msg)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if got.X.Cmp(Q.X) != 0 || got.Y.Cmp(Q.Y) != 0 {
+				t.Fatalf("%s: recovered (%x, %x), want (%x, %x)", name, got.X, got.Y, Q.X, Q.Y)
+			}
+			if !VerifyRecoverable(Q, "", sig, msg) {
+				t.Fatalf("%s: signature does not verify", name)
+			}
+			if VerifyRecoverable(Q, "", sig, append(msg, '!')) || VerifyRecoverable(Q, "elsewhere", sig, msg) {
+				t.Fatalf("%s: signature verifies another message or domain", name)
+			}
+		}
+	}
+
+	// 65 bytes on the 256-bit curves; bad ids and lengths are refused
+	key := NewECDSAPrivateKey(new(big.Int).Mod(generateRandomBigInt(), elliptic.P256().Params().N))
+	msg := []byte("compact")
+	sig, err := key.SignRecoverable("", &msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sig) != 65 {
+		t.Fatalf("P-256 signature is %d bytes, want 65", len(sig))
+	}
+	if _, err := RecoverPublicKey(elliptic.P256(), "", append([]byte{31}, sig[1:]...), msg); err != ErrRecoverableSignature {
+		t.Fatalf("recovery id 31: %v, want %v", err, ErrRecoverableSignature)
+	}
+	if _, err := RecoverPublicKey(elliptic.P256(), "", sig[:64], msg); err != ErrRecoverableSignature {
+		t.Fatalf("64 byte signature: %v, want %v", err, ErrRecoverableSignature)
+	}
+	if flipped := append([]byte{sig[0] ^ 1}, sig[1:]...); VerifyRecoverable(key.PublicKey(), "", flipped, msg) {
+		t.Fatal("signature verifies with the other recovery id")
+	}
+}
@@ -0,0 +1,95 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto/elliptic"
+	"crypto/hmac"
+	"math/big"
+)
+
+/*
+Signs msg in domain under d_a on curve with the nonce derived per RFC 6979,
+so the same key and message always give the same signature and no
+randomness is needed at signing time.
+*/
+func sign_ecdsa_deterministic(curve elliptic.Curve, msg *[]byte, d_a *big.Int, domain string) (*big.Int, *big.Int) {
+	return sign_ecdsa_deterministic_digest(curve, ecdsa_digest(curve, domain, *msg), d_a)
+}
+
+// Signs the message hash e under d_a on curve with the RFC 6979 nonce for e.
+func sign_ecdsa_deterministic_digest(curve elliptic.Curve, e []byte, d_a *big.Int) (*big.Int, *big.Int) {
+	return sign_ecdsa_hedged_digest(curve, e, d_a, nil)
+}
+
+/*
+Signs the message hash e under d_a on curve with a hedged nonce: fresh
+random bytes are passed to the RFC 6979 derivation as the additional
+data k' of Section 3.6, so k is derived from random || d || H(m). A
+failed random source degrades to plain RFC 6979, and a fault during a
+deterministic signature no longer repeats on the next one. With nil
+random this is sign_ecdsa_deterministic_digest.
+*/
+func sign_ecdsa_hedged_digest(curve elliptic.Curve, e []byte, d_a *big.Int, random []byte) (*big.Int, *big.Int) {
+	z := ecdsa_bits2int(curve, e)
+	k := rfc6979_nonce(curve, d_a, e, random)
+	defer wipe_int(k)
+	return sign_ecdsa_with_nonce(curve, z, d_a, k)
+}
+
+/*
+Derives the nonce k for the key x on curve and the message hash h1 with
+HMAC_DRBG as in RFC 6979 Section 3.2, HMAC using the hash that hashed the
+message, ecdsa_hash(curve). When qlen exceeds hlen, as for P-521 with
+SHA-512, each candidate is built from several HMAC blocks.
+extra is the additional data k' of Section 3.6, nil for none.
+*/
+func rfc6979_nonce(curve elliptic.Curve, x *big.Int, h1 []byte, extra []byte) *big.Int {
+	q := curve.Params().N
+	rlen := (q.BitLen() + 7) / 8
+	H := ecdsa_hash(curve)
+	// HMAC into dst, so that K and V are overwritten in place rather than
+	// left behind in discarded slices
+	mac := func(dst, key []byte, parts ...[]byte) []byte {
+		m := hmac.New(H.New, key)
+		for _, p := range parts {
+			m.Write(p)
+		}
+		return m.Sum(dst[:0])
+	}
+
+	x_octets := toFixedBytes(x, rlen)
+	defer wipe_bytes(x_octets)
+	h_octets := toFixedBytes(new(big.Int).Mod(ecdsa_bits2int(curve, h1), q), rlen) // bits2octets
+
+	// b, c. V = 0x01 0x01 ..., K = 0x00 0x00 ...
+	V := make([]byte, H.Size())
+	for i := range V {
+		V[i] = 0x01
+	}
+	K := make([]byte, H.Size())
+	defer wipe_bytes(K)
+	defer wipe_bytes(V)
+
+	// d - g.
+	K = mac(K, K, V, []byte{0x00}, x_octets, h_octets, extra)
+	V = mac(V, K, V)
+	K = mac(K, K, V, []byte{0x01}, x_octets, h_octets, extra)
+	V = mac(V, K, V)
+
+	// h. concatenate blocks until there are qlen bits
+	for {
+		var T []byte
+		for len(T) < rlen {
+			V = mac(V, K, V)
+			T = append(T, V...)
+		}
+		k := ecdsa_bits2int(curve, T)
+		wipe_bytes(T)
+		if k.Sign() > 0 && k.Cmp(q) < 0 {
+			return k
+		}
+		K = mac(K, K, V, []byte{0x00})
+		V = mac(V, K, V)
+	}
+}
@@ -0,0 +1,52 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto/elliptic"
+	"crypto/sha256"
+	"testing"
+)
+
+// RFC 6979 A.2.5 nonces and signatures for P-256 with SHA-256.
+func TestDeterministicECDSA(t *testing.T) {
+
+	nonces := []string{
+		"a6e3c57dd01abe90086538398355dd4c3b17aa873382b0f24d6129493d8aad60",
+		"d16b6ae827f17175e040871a1c7ec3500192c4c92677336ec2537acaee0008e0",
+	}
+	for i, v := range ecdsaKnownAnswers {
+		msg := []byte(v.msg)
+		h := sha256.Sum256(msg)
+		if k := rfc6979_nonce(elliptic.P256(), hexInt(v.d), h[:], nil); k.Cmp(hexInt(nonces[i])) != 0 {
+			t.Fatalf("%q: k = %x, want %s", v.msg, k, nonces[i])
+		}
+
+		key := NewECDSAPrivateKey(hexInt(v.d))
+		key.Deterministic = true
+		r1, s1, err := key.Sign("", &msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r1.Cmp(hexInt(v.r)) != 0 || s1.Cmp(hexInt(v.s)) != 0 {
+			t.Fatalf("%q: (r, s) = (%x, %x), want (%s, %s)", v.msg, r1, s1, v.r, v.s)
+		}
+		r2, s2, err := key.Sign("", &msg) // repeating a signature is not nonce reuse
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r1.Cmp(r2) != 0 || s1.Cmp(s2) != 0 {
+			t.Fatalf("%q: signing twice gives (%x, %x) and (%x, %x)", v.msg, r1, s1, r2, s2)
+		}
+	}
+
+	// the nonce depends on the message and, through the digest, on the domain
+	d := hexInt(ecdsaKnownAnswers[0].d)
+	msg := []byte("sample")
+	rA, _ := sign_ecdsa_deterministic(elliptic.P256(), &msg, d, "a")
+	rB, _ := sign_ecdsa_deterministic(elliptic.P256(), &msg, d, "b")
+	r0, _ := sign_ecdsa_deterministic(elliptic.P256(), &msg, d, "")
+	if rA.Cmp(rB) == 0 || rA.Cmp(r0) == 0 {
+		t.Fatalf("r = %x in domain a, %x in b and %x in none", rA, rB, r0)
+	}
+}
@@ -1,4 +1,6 @@
-package main
+//go:build !verifyonly
+
+package sig
 
 func test() {
 
@@ -0,0 +1,213 @@
+// Package secp256k1 implements the secp256k1 curve as an elliptic.Curve.
+package secp256k1
+
+import (
+	"crypto/elliptic"
+	"math/big"
+)
+
+/*
+secp256k1, y² = x³ + 7 over the prime field of p = 2²⁵⁶ − 2³² − 977, as
+used by Bitcoin and Ethereum (SEC 2 Section 2.4.1). The standard library
+only implements curves with a = −3, so the group law is implemented here
+in Jacobian coordinates (X, Y, Z) ↦ (X/Z², Y/Z³) and the type satisfies
+elliptic.Curve, which lets the ECDSA code sign and verify on it unchanged.
+The point at infinity is (0, 0) in affine form, as in crypto/elliptic.
+
+Security Remark: the arithmetic uses big.Int and is not constant time.
+*/
+type secp256k1Curve struct {
+	params *elliptic.CurveParams
+}
+
+var secp256k1 = &secp256k1Curve{params: &elliptic.CurveParams{
+	P:       hex_int("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f"),
+	N:       hex_int("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141"),
+	B:       big.NewInt(7),
+	Gx:      hex_int("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"),
+	Gy:      hex_int("483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8"),
+	BitSize: 256,
+	Name:    "secp256k1",
+}}
+
+/*
+The secp256k1 curve. Besides elliptic.Curve it implements
+CombinedMult(x, y, baseScalar, scalar), the sum baseScalar × G +
+scalar × (x, y) computed in one interleaved pass.
+*/
+func Curve() elliptic.Curve { return secp256k1 }
+
+func hex_int(s string) *big.Int {
+	v, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("secp256k1: bad constant " + s)
+	}
+	return v
+}
+
+func (c *secp256k1Curve) Params() *elliptic.CurveParams { return c.params }
+
+// True iff (x, y) is an affine point of the curve with coordinates in [0, p−1].
+func (c *secp256k1Curve) IsOnCurve(x, y *big.Int) bool {
+	p := c.params.P
+	if x.Sign() < 0 || x.Cmp(p) >= 0 || y.Sign() < 0 || y.Cmp(p) >= 0 {
+		return false
+	}
+	lhs := new(big.Int).Mul(y, y)
+	rhs := new(big.Int).Mul(x, x)
+	rhs.Mul(rhs, x).Add(rhs, c.params.B)
+	return lhs.Sub(lhs, rhs).Mod(lhs, p).Sign() == 0
+}
+
+func (c *secp256k1Curve) Add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	return c.to_affine(c.add(c.to_jacobian(x1, y1), c.to_jacobian(x2, y2)))
+}
+
+func (c *secp256k1Curve) Double(x1, y1 *big.Int) (*big.Int, *big.Int) {
+	return c.to_affine(c.double(c.to_jacobian(x1, y1)))
+}
+
+// k × (x1, y1) for the big endian scalar k, by double-and-add.
+func (c *secp256k1Curve) ScalarMult(x1, y1 *big.Int, k []byte) (*big.Int, *big.Int) {
+	P := c.to_jacobian(x1, y1)
+	R := jacobianPoint{new(big.Int), new(big.Int), new(big.Int)}
+	for _, b := range k {
+		for bit := 7; bit >= 0; bit-- {
+			R = c.double(R)
+			if b>>uint(bit)&1 == 1 {
+				R = c.add(R, P)
+			}
+		}
+	}
+	return c.to_affine(R)
+}
+
+func (c *secp256k1Curve) ScalarBaseMult(k []byte) (*big.Int, *big.Int) {
+	return c.ScalarMult(c.params.Gx, c.params.Gy, k)
+}
+
+/*
+baseScalar × G + scalar × (x1, y1) by Strauss–Shamir interleaving with
+joint 2-bit windows: the 15 sums iG + jQ for i, j ∈ [0, 3] are tabulated,
+then both scalars are walked two bits at a time from the top, doubling
+twice and adding one table entry per step. This shares the doublings of
+the two multiplications and needs one addition per window instead of up
+to four, which is what ECDSA verification spends its time on.
+*/
+func (c *secp256k1Curve) CombinedMult(x1, y1 *big.Int, baseScalar, scalar []byte) (*big.Int, *big.Int) {
+	G := c.to_jacobian(c.params.Gx, c.params.Gy)
+	Q := c.to_jacobian(x1, y1)
+	var table [4][4]jacobianPoint
+	table[0][0] = jacobianPoint{new(big.Int), new(big.Int), new(big.Int)}
+	for i := 0; i < 4; i++ {
+		if i > 0 {
+			table[i][0] = c.add(table[i-1][0], G)
+		}
+		for j := 1; j < 4; j++ {
+			table[i][j] = c.add(table[i][j-1], Q)
+		}
+	}
+
+	// left pad the shorter scalar so that the windows line up
+	n := len(baseScalar)
+	if len(scalar) > n {
+		n = len(scalar)
+	}
+	a := append(make([]byte, n-len(baseScalar)), baseScalar...)
+	b := append(make([]byte, n-len(scalar)), scalar...)
+
+	R := table[0][0]
+	for i := 0; i < n; i++ {
+		for shift := 6; shift >= 0; shift -= 2 {
+			R = c.double(c.double(R))
+			if w := table[a[i]>>uint(shift)&3][b[i]>>uint(shift)&3]; w.Z.Sign() != 0 {
+				R = c.add(R, w)
+			}
+		}
+	}
+	return c.to_affine(R)
+}
+
+// Point in Jacobian coordinates, Z = 0 for the point at infinity.
+type jacobianPoint struct {
+	X, Y, Z *big.Int
+}
+
+func (c *secp256k1Curve) to_jacobian(x, y *big.Int) jacobianPoint {
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return jacobianPoint{new(big.Int), new(big.Int), new(big.Int)}
+	}
+	return jacobianPoint{new(big.Int).Set(x), new(big.Int).Set(y), big.NewInt(1)}
+}
+
+func (c *secp256k1Curve) to_affine(P jacobianPoint) (*big.Int, *big.Int) {
+	p := c.params.P
+	if P.Z.Sign() == 0 {
+		return new(big.Int), new(big.Int)
+	}
+	z_inv := new(big.Int).ModInverse(P.Z, p)
+	z_inv2 := new(big.Int).Mul(z_inv, z_inv)
+	x := new(big.Int).Mul(P.X, z_inv2)
+	y := new(big.Int).Mul(P.Y, z_inv2.Mul(z_inv2, z_inv))
+	return x.Mod(x, p), y.Mod(y, p)
+}
+
+// 2P by the a = 0 doubling formulas dbl-2009-l.
+func (c *secp256k1Curve) double(P jacobianPoint) jacobianPoint {
+	p := c.params.P
+	if P.Z.Sign() == 0 || P.Y.Sign() == 0 {
+		return jacobianPoint{new(big.Int), new(big.Int), new(big.Int)}
+	}
+	mod := func(v *big.Int) *big.Int { return v.Mod(v, p) }
+	A := mod(new(big.Int).Mul(P.X, P.X))
+	B := mod(new(big.Int).Mul(P.Y, P.Y))
+	C := mod(new(big.Int).Mul(B, B))
+	D := new(big.Int).Add(P.X, B)
+	D = mod(D.Mul(D, D).Sub(D, A).Sub(D, C).Lsh(D, 1))
+	E := mod(new(big.Int).Mul(A, big.NewInt(3)))
+	F := mod(new(big.Int).Mul(E, E))
+	X3 := mod(new(big.Int).Sub(F, new(big.Int).Lsh(D, 1)))
+	Y3 := new(big.Int).Sub(D, X3)
+	Y3 = mod(Y3.Mul(Y3, E).Sub(Y3, new(big.Int).Lsh(C, 3)))
+	Z3 := new(big.Int).Mul(P.Y, P.Z)
+	Z3 = mod(Z3.Lsh(Z3, 1))
+	return jacobianPoint{X3, Y3, Z3}
+}
+
+// P + Q by the general addition formulas add-2007-bl.
+func (c *secp256k1Curve) add(P, Q jacobianPoint) jacobianPoint {
+	p := c.params.P
+	if P.Z.Sign() == 0 {
+		return Q
+	}
+	if Q.Z.Sign() == 0 {
+		return P
+	}
+	mod := func(v *big.Int) *big.Int { return v.Mod(v, p) }
+	Z1Z1 := mod(new(big.Int).Mul(P.Z, P.Z))
+	Z2Z2 := mod(new(big.Int).Mul(Q.Z, Q.Z))
+	U1 := mod(new(big.Int).Mul(P.X, Z2Z2))
+	U2 := mod(new(big.Int).Mul(Q.X, Z1Z1))
+	S1 := mod(new(big.Int).Mul(P.Y, new(big.Int).Mul(Q.Z, Z2Z2)))
+	S2 := mod(new(big.Int).Mul(Q.Y, new(big.Int).Mul(P.Z, Z1Z1)))
+	H := mod(new(big.Int).Sub(U2, U1))
+	r := new(big.Int).Sub(S2, S1)
+	r = mod(r.Lsh(r, 1))
+	if H.Sign() == 0 {
+		if r.Sign() == 0 {
+			return c.double(P)
+		}
+		return jacobianPoint{new(big.Int), new(big.Int), new(big.Int)}
+	}
+	I := new(big.Int).Lsh(H, 1)
+	I = mod(I.Mul(I, I))
+	J := mod(new(big.Int).Mul(H, I))
+	V := mod(new(big.Int).Mul(U1, I))
+	X3 := new(big.Int).Mul(r, r)
+	X3 = mod(X3.Sub(X3, J).Sub(X3, new(big.Int).Lsh(V, 1)))
+	Y3 := new(big.Int).Sub(V, X3)
+	Y3 = mod(Y3.Mul(Y3, r).Sub(Y3, new(big.Int).Lsh(new(big.Int).Mul(S1, J), 1)))
+	Z3 := new(big.Int).Add(P.Z, Q.Z)
+	Z3 = mod(Z3.Mul(Z3, Z3).Sub(Z3, Z1Z1).Sub(Z3, Z2Z2).Mul(Z3, H))
+	return jacobianPoint{X3, Y3, Z3}
+}
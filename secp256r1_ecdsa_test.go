@@ -0,0 +1,61 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"io"
+	"math/big"
+	"testing"
+)
+
+// Out of range nonce candidates are redrawn, up to maxNonceAttempts times.
+func TestNonceRetryLoop(t *testing.T) {
+
+	n := elliptic.P256().Params().N
+	key := NewECDSAPrivateKey(new(big.Int).Mod(generateRandomBigInt(), n))
+	Q := &ecdsa.PublicKey{Curve: elliptic.P256()}
+	Q.X, Q.Y = elliptic.P256().ScalarBaseMult(toFixedBytes(key.D, p256Width))
+	msg := []byte("retry")
+	high := bytes.Repeat([]byte{0xff}, p256Width) // 2²⁵⁶ − 1 > n − 2
+
+	// two rejected candidates, then k = 0x5a5a... + 1
+	key.Rand = io.MultiReader(bytes.NewReader(append(high, high...)), stuckReader{})
+	r, s, err := key.Sign("", &msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stuck := make([]byte, p256Width)
+	stuckReader{}.Read(stuck)
+	k := new(big.Int).Add(new(big.Int).SetBytes(stuck), big.NewInt(1))
+	e := sha256.Sum256(msg)
+	r2, s2 := sign_ecdsa_with_nonce(elliptic.P256(), new(big.Int).SetBytes(e[:]), key.D, k)
+	if r.Cmp(r2) != 0 || s.Cmp(s2) != 0 {
+		t.Fatalf("(r, s) = (%x, %x), want (%x, %x) from the third candidate", r, s, r2, s2)
+	}
+	if !verify_ecdsa_sig(Q, r, s, &msg) {
+		t.Fatal("signature does not verify")
+	}
+
+	// the largest accepted candidate gives k = n − 1
+	c, err := ecdsa_nonce_candidate(bytes.NewReader(toFixedBytes(new(big.Int).Sub(n, big.NewInt(2)), p256Width)), n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := new(big.Int).Sub(n, big.NewInt(1)); c.Cmp(want) != 0 {
+		t.Fatalf("candidate n − 2 gives k = %x, want %x", c, want)
+	}
+
+	// a source stuck above n − 2, or failing, yields an error
+	key.Rand = bytes.NewReader(bytes.Repeat([]byte{0xff}, p256Width*maxNonceAttempts+1))
+	if _, _, err := key.Sign("", &msg); err != ErrNonceAttempts {
+		t.Fatalf("source stuck above n − 2: %v, want %v", err, ErrNonceAttempts)
+	}
+	key.Rand = bytes.NewReader(high[:10])
+	if _, _, err := key.Sign("", &msg); err != io.ErrUnexpectedEOF {
+		t.Fatalf("short source: %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
@@ -1,71 +1,28 @@
-package main
+package sig
 
 import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
-	"crypto/rand"
 	"crypto/sha256"
-	"fmt"
 	"math/big"
-	"time"
 )
 
-func run_secp256_schnorr() {
-	total := 0
-	loops := 100
-	rnd := rand.Reader
-	data := make([]byte, 5242880) //5mb random data
-
-	rnd.Read(data)
-	for i := 0; i < loops; i++ {
-		start := time.Now()
-		key, sig, e := sign_message_secp256(&data)
-		verify_sig_secp256(&key, sig, e, &data)
-		elapsed := time.Since(start)
-		total += int(elapsed.Microseconds())
-	}
-	fmt.Println("avg μs to sign and verify secp256: ", total/loops)
-}
-
-func sign_message_secp256(msg *[]byte) (ecdsa.PublicKey, *big.Int, *big.Int) {
-	secp256r1 := elliptic.P256() // aka secp256r1
-	n := secp256r1.Params().Params().N
-
-	g := ecdsa.PublicKey{
-		Curve: elliptic.P256(),
-		X:     secp256r1.Params().Gx,
-		Y:     secp256r1.Params().Gy,
+/*
+Computes the challenge e = SHA256(r || M) for the given signature version.
+Version 1 hashed the variable length r_x.Bytes(), version 2 hashes the
+fixed width encoding of r. Outside the empty domain cSHAKE256 customized
+by domain replaces SHA-256.
+*/
+func secp256_challenge(version byte, domain string, r_x *big.Int, msg *[]byte) *big.Int {
+	if domain != "" {
+		return fromFixedBytes(domain_hash(domain, toFixedBytes(r_x, p256Width), *msg))
 	}
-
-	// the secret key generated by the user
-	rnd := rand.Reader
-	x_bytes := make([]byte, 32)
-	rnd.Read(x_bytes)
-	pub_x, pub_y := g.ScalarBaseMult(x_bytes)
-	y := ecdsa.PublicKey{
-		Curve: elliptic.P256(),
-		X:     pub_x,
-		Y:     pub_y,
+	if version == signatureVersionVariableWidth {
+		e_hash := sha256.Sum256(append(r_x.Bytes(), *msg...))
+		return fromFixedBytes(e_hash[:])
 	}
-	x := big.NewInt(0).SetBytes(x_bytes)
-
-	// random k from allowed set [1..n-1]
-	k_read := rand.Reader
-	k_bytes := make([]byte, 32)
-	k_read.Read(k_bytes)
-	k := big.NewInt(0).SetBytes(k_bytes)
-	k.Add(k, big.NewInt(1))
-	k = k.Mod(k, n)
-
-	r_x, _ := g.ScalarBaseMult(k.Bytes())
-	e_hash := sha256.Sum256(append(r_x.Bytes(), *msg...))
-
-	e := big.NewInt(0).SetBytes(e_hash[:32])
-	xe := big.NewInt(0).Mul(x, e)
-
-	s := k.Sub(k, xe)
-	s = s.Mod(s, n)
-	return y, s, e
+	e_hash := sha256.Sum256(append(toFixedBytes(r_x, p256Width), *msg...))
+	return fromFixedBytes(e_hash[:])
 }
 
 /*
@@ -75,7 +32,21 @@ let e_v Hash(r_v || M)
 return true iff e_v = e
 */
 func verify_sig_secp256(y *ecdsa.PublicKey, s, e *big.Int, msg *[]byte) bool {
+	return verify_sig_secp256_version(signatureVersion, "", y, s, e, msg)
+}
+
+/*
+Verifies a signature made under the given signature version. Version 1
+compared against e.Bytes(), which rejects valid signatures whenever e
+has a leading zero byte.
+*/
+func verify_sig_secp256_version(version byte, domain string, y *ecdsa.PublicKey, s, e *big.Int, msg *[]byte) bool {
 	curve := elliptic.P256() // aka secp256r1
+	// the identity has no affine encoding, so an on-curve key is not the identity
+	if y == nil || y.X == nil || y.Y == nil || !curve.IsOnCurve(y.X, y.Y) ||
+		new(big.Int).Mod(e, curve.Params().N).Sign() == 0 || s.Sign() < 0 || s.Cmp(curve.Params().N) >= 0 {
+		return false
+	}
 
 	g := ecdsa.PublicKey{
 		Curve: elliptic.P256(),
@@ -83,13 +54,16 @@ func verify_sig_secp256(y *ecdsa.PublicKey, s, e *big.Int, msg *[]byte) bool {
 		Y:     curve.Params().Gy,
 	}
 
-	gs_x, gs_y := g.ScalarBaseMult(s.Bytes())
-	gy_x, gy_y := y.ScalarMult(y.X, y.Y, e.Bytes())
+	gs_x, gs_y := g.ScalarBaseMult(toFixedBytes(s, p256Width))
+	gy_x, gy_y := y.ScalarMult(y.X, y.Y, toFixedBytes(e, p256Width))
 
 	r_x, _ := g.Add(gs_x, gs_y, gy_x, gy_y)
 
-	e_v := sha256.Sum256(append(r_x.Bytes(), *msg...))
-	return Equal(e_v[:32], e.Bytes())
+	if version == signatureVersionVariableWidth && domain == "" {
+		e_v := sha256.Sum256(append(r_x.Bytes(), *msg...))
+		return Equal(e_v[:32], e.Bytes())
+	}
+	return secp256_challenge(version, domain, r_x, msg).Cmp(e) == 0
 }
 
 // Compare byte arrays for equality
@@ -0,0 +1,79 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+func run_secp256_schnorr() {
+	total := 0
+	loops := 100
+	rnd := rand.Reader
+	data := make([]byte, 5242880) //5mb random data
+
+	rnd.Read(data)
+	for i := 0; i < loops; i++ {
+		start := time.Now()
+		key, sig, e := sign_message_secp256(&data)
+		verify_sig_secp256(&key, sig, e, &data)
+		elapsed := time.Since(start)
+		total += int(elapsed.Microseconds())
+	}
+	fmt.Println("avg μs to sign and verify secp256: ", total/loops)
+}
+
+func sign_message_secp256(msg *[]byte) (ecdsa.PublicKey, *big.Int, *big.Int) {
+	secp256r1 := elliptic.P256() // aka secp256r1
+
+	g := ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     secp256r1.Params().Gx,
+		Y:     secp256r1.Params().Gy,
+	}
+
+	// the secret key generated by the user
+	rnd := rand.Reader
+	x_bytes := make([]byte, 32)
+	rnd.Read(x_bytes)
+	pub_x, pub_y := g.ScalarBaseMult(x_bytes)
+	y := ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     pub_x,
+		Y:     pub_y,
+	}
+	x := big.NewInt(0).SetBytes(x_bytes)
+	s, e := sign_with_key_secp256(x, "", msg)
+	return y, s, e
+}
+
+// Signs msg in domain under the secp256 secret key x, returning (s, e).
+func sign_with_key_secp256(x *big.Int, domain string, msg *[]byte) (*big.Int, *big.Int) {
+	secp256r1 := elliptic.P256() // aka secp256r1
+	n := secp256r1.Params().Params().N
+
+	// random k from allowed set [1..n-1]
+	k_read := rand.Reader
+	k_bytes := make([]byte, 32)
+	k_read.Read(k_bytes)
+	k := big.NewInt(0).SetBytes(k_bytes)
+	wipe_bytes(k_bytes)
+	k.Add(k, big.NewInt(1))
+	k = k.Mod(k, n)
+
+	k_fixed := toFixedBytes(k, p256Width)
+	r_x, _ := secp256r1.ScalarBaseMult(k_fixed)
+	wipe_bytes(k_fixed)
+	e := secp256_challenge(signatureVersion, domain, r_x, msg)
+	xe := big.NewInt(0).Mul(x, e)
+	defer wipe_int(xe)
+
+	s := k.Sub(k, xe) // overwrites k
+	s = s.Mod(s, n)
+	return s, e
+}
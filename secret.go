@@ -0,0 +1,94 @@
+package sig
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+)
+
+var ErrDestroyedSecret = errors.New("secret has been wiped")
+
+/*
+Holds secret key material, such as keys derived with KMAC, in a byte
+slice that is zeroed by Wipe once the secret is no longer needed, so that
+it does not linger in heap memory that is later reused. A wiped secret
+refuses to be used rather than silently acting as zeros.
+*/
+type SecretBytes struct {
+	b     []byte
+	wiped bool
+}
+
+// Wraps b, which the SecretBytes owns from now on and zeroes on Wipe.
+func NewSecretBytes(b []byte) *SecretBytes {
+	return &SecretBytes{b: b}
+}
+
+/*
+Returns the secret itself, not a copy, so that Wipe also clears what the
+caller reads. Callers must not keep the slice past Wipe.
+*/
+func (s *SecretBytes) Bytes() ([]byte, error) {
+	if s.wiped {
+		return nil, ErrDestroyedSecret
+	}
+	return s.b, nil
+}
+
+// Zeroes the secret. Any later use returns ErrDestroyedSecret.
+func (s *SecretBytes) Wipe() {
+	wipe_bytes(s.b)
+	s.wiped = true
+}
+
+/*
+Holds a secret scalar such as a private key or nonce as SecretBytes, so
+that it can be wiped once no longer needed.
+*/
+type SecretScalar struct {
+	SecretBytes
+}
+
+// Copies v into a new SecretScalar. The caller remains responsible for v.
+func NewSecretScalar(v *big.Int) *SecretScalar {
+	return &SecretScalar{SecretBytes{b: v.Bytes()}}
+}
+
+// Reads a random secret scalar of size bytes from the system CSPRNG.
+func RandomSecretScalar(size int) (*SecretScalar, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return &SecretScalar{SecretBytes{b: b}}, nil
+}
+
+// Returns the value as a fresh big.Int, which the caller should wipe_int after use.
+func (s *SecretScalar) Int() (*big.Int, error) {
+	b, err := s.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// Wipes the scalar, see SecretBytes.Wipe.
+func (s *SecretScalar) Destroy() {
+	s.Wipe()
+}
+
+// Zeroes the words backing v and sets it to 0.
+func wipe_int(v *big.Int) {
+	words := v.Bits()
+	for i := range words {
+		words[i] = 0
+	}
+	v.SetInt64(0)
+}
+
+// Zeroes b.
+func wipe_bytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
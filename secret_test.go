@@ -0,0 +1,103 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"testing"
+
+	"sig/secp256k1"
+)
+
+// A destroyed scalar is zeroed and refuses to sign.
+func TestDestroyedScalar(t *testing.T) {
+
+	msg := []byte("short message")
+	x := NewSecretScalar(generateRandomBigInt())
+	if _, _, err := sign_with_key_e222(x, "", &msg); err != nil {
+		t.Fatal(err)
+	}
+	x.Destroy()
+
+	for i, b := range x.b {
+		if b != 0 {
+			t.Fatalf("byte %d of a destroyed scalar is %#x", i, b)
+		}
+	}
+	if _, err := x.Int(); err != ErrDestroyedSecret {
+		t.Fatalf("Int of a destroyed scalar: %v, want %v", err, ErrDestroyedSecret)
+	}
+	if s, _, err := sign_with_key_e222(x, "", &msg); s != nil || err != ErrDestroyedSecret {
+		t.Fatalf("signing with a destroyed scalar: s = %v, %v, want %v", s, err, ErrDestroyedSecret)
+	}
+}
+
+func TestSecretWiping(t *testing.T) {
+
+	b := NewSecretBytes([]byte{1, 2, 3, 4})
+	raw, err := b.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(raw, []byte{1, 2, 3, 4}) {
+		t.Fatalf("secret bytes %x, want 01020304", raw)
+	}
+	b.Wipe()
+	if !bytes.Equal(raw, make([]byte, 4)) {
+		t.Fatalf("wiped secret bytes %x", raw)
+	}
+	if _, err := b.Bytes(); !errors.Is(err, ErrDestroyedSecret) {
+		t.Fatalf("Bytes after Wipe: %v, want %v", err, ErrDestroyedSecret)
+	}
+
+	x := NewSecretScalar(big.NewInt(12345))
+	x.Destroy()
+	if _, err := x.Int(); !errors.Is(err, ErrDestroyedSecret) {
+		t.Fatalf("Int after Destroy: %v, want %v", err, ErrDestroyedSecret)
+	}
+
+	// a wiped ECDSA key zeroes D and refuses to sign
+	d, err := rand.Int(rand.Reader, elliptic.P256().Params().N)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := NewECDSAPrivateKey(d)
+	msg := []byte("wipe me")
+	if _, _, err := key.Sign("", &msg); err != nil {
+		t.Fatal(err)
+	}
+	key.Wipe()
+	if d.Sign() != 0 {
+		t.Fatalf("wiped key has D = %x", d)
+	}
+	if _, _, err := key.Sign("", &msg); !errors.Is(err, ErrDestroyedSecret) {
+		t.Fatalf("signing with a wiped key: %v, want %v", err, ErrDestroyedSecret)
+	}
+
+	// deterministic nonces are unchanged by wiping the DRBG state
+	d2 := big.NewInt(1)
+	r1, s1 := sign_ecdsa_deterministic(secp256k1.Curve(), &msg, d2, "")
+	r2, s2 := sign_ecdsa_deterministic(secp256k1.Curve(), &msg, d2, "")
+	if r1.Cmp(r2) != 0 || s1.Cmp(s2) != 0 {
+		t.Fatalf("deterministic signatures (%x, %x) and (%x, %x)", r1, s1, r2, s2)
+	}
+
+	// KMAC-derived keys are wiped after use without breaking encryption
+	pw := []byte("correct horse battery staple")
+	_, V := e222_keypair_from_passphrase(pw)
+	ct, err := encrypt_to_recipients([]*E222{V}, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt, err := decrypt_as_recipient(pw, ct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pt, msg) {
+		t.Fatalf("decrypted %q, want %q", pt, msg)
+	}
+}
@@ -0,0 +1,158 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Pass/fail counts for one category of interop checks.
+type SelfTestResult struct {
+	Name   string
+	Passed int
+	Failed int
+}
+
+// Outcome of SelfTest, one entry per category.
+type SelfTestReport struct {
+	Results []SelfTestResult
+}
+
+// True iff no check in any category failed.
+func (r SelfTestReport) OK() bool {
+	for _, res := range r.Results {
+		if res.Failed > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (r SelfTestReport) String() string {
+	var sb strings.Builder
+	for _, res := range r.Results {
+		status := "PASS"
+		if res.Failed > 0 {
+			status = "FAIL"
+		}
+		fmt.Fprintf(&sb, "%-28s %s  (%d passed, %d failed)\n", res.Name, status, res.Passed, res.Failed)
+	}
+	return sb.String()
+}
+
+func (r *SelfTestReport) record(name string, ok bool) {
+	for i := range r.Results {
+		if r.Results[i].Name == name {
+			if ok {
+				r.Results[i].Passed++
+			} else {
+				r.Results[i].Failed++
+			}
+			return
+		}
+	}
+	res := SelfTestResult{Name: name}
+	if ok {
+		res.Passed++
+	} else {
+		res.Failed++
+	}
+	r.Results = append(r.Results, res)
+}
+
+// ASN.1 structure of a DER encoded ECDSA signature.
+type ecdsaDERSignature struct {
+	R, S *big.Int
+}
+
+// Encodes (r, s) as an ASN.1 DER SEQUENCE of two INTEGERs.
+func marshal_ecdsa_der(r, s *big.Int) ([]byte, error) {
+	return asn1.Marshal(ecdsaDERSignature{R: r, S: s})
+}
+
+// Decodes a DER encoded signature, rejecting trailing data.
+func parse_ecdsa_der(der []byte) (*big.Int, *big.Int, error) {
+	var sig ecdsaDERSignature
+	rest, err := asn1.Unmarshal(der, &sig)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rest) != 0 {
+		return nil, nil, errors.New("trailing data after DER signature")
+	}
+	return sig.R, sig.S, nil
+}
+
+/*
+Checks that this ECDSA implementation agrees with crypto/ecdsa. For n
+random keys and messages it signs here and verifies with the standard
+library, signs with the standard library and verifies here, and round
+trips signatures through the DER and raw encodings in both directions.
+It finally checks the fixed known answer vectors.
+*/
+func SelfTest(n int) SelfTestReport {
+	var report SelfTestReport
+	curve := elliptic.P256()
+
+	for i := 0; i < n; i++ {
+		priv, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			report.record("key generation", false)
+			continue
+		}
+		msg := make([]byte, 64+i)
+		rand.Read(msg)
+		digest := sha256.Sum256(msg)
+
+		// ours → crypto/ecdsa
+		r, s := sign_message_ecdsa(elliptic.P256(), &msg, priv.D)
+		report.record("sign here, verify stdlib", ecdsa.Verify(&priv.PublicKey, digest[:], r, s))
+
+		// crypto/ecdsa → ours
+		r2, s2, err := ecdsa.Sign(rand.Reader, priv, digest[:])
+		report.record("sign stdlib, verify here", err == nil && verify_ecdsa_sig(&priv.PublicKey, r2, s2, &msg))
+
+		// DER both directions
+		der, err := marshal_ecdsa_der(r, s)
+		report.record("DER here → stdlib", err == nil && ecdsa.VerifyASN1(&priv.PublicKey, digest[:], der))
+		std_der, err := ecdsa.SignASN1(rand.Reader, priv, digest[:])
+		ok := err == nil
+		if ok {
+			dr, ds, err := parse_ecdsa_der(std_der)
+			ok = err == nil && verify_ecdsa_sig(&priv.PublicKey, dr, ds, &msg)
+		}
+		report.record("DER stdlib → here", ok)
+
+		// raw r || s both directions
+		raw := append(toFixedBytes(r, p256Width), toFixedBytes(s, p256Width)...)
+		rr, rs := fromFixedBytes(raw[:p256Width]), fromFixedBytes(raw[p256Width:])
+		report.record("raw here → stdlib", ecdsa.Verify(&priv.PublicKey, digest[:], rr, rs))
+		raw2 := append(toFixedBytes(r2, p256Width), toFixedBytes(s2, p256Width)...)
+		rr2, rs2 := fromFixedBytes(raw2[:p256Width]), fromFixedBytes(raw2[p256Width:])
+		report.record("raw stdlib → here", verify_ecdsa_sig(&priv.PublicKey, rr2, rs2, &msg))
+
+		// a modified message must be rejected by both
+		msg[0] ^= 1
+		flipped := sha256.Sum256(msg)
+		report.record("bit flip rejected", !verify_ecdsa_sig(&priv.PublicKey, r, s, &msg) &&
+			!ecdsa.Verify(&priv.PublicKey, flipped[:], r, s))
+	}
+
+	for _, v := range ecdsaKnownAnswers {
+		msg := []byte(v.msg)
+		Q_a := ecdsa.PublicKey{Curve: curve, X: hexInt(v.qx), Y: hexInt(v.qy)}
+		qx, qy := curve.ScalarBaseMult(hexInt(v.d).Bytes())
+		ok := qx.Cmp(Q_a.X) == 0 && qy.Cmp(Q_a.Y) == 0 &&
+			verify_ecdsa_sig(&Q_a, hexInt(v.r), hexInt(v.s), &msg)
+		report.record("known answer vectors", ok)
+	}
+	return report
+}
@@ -0,0 +1,14 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"testing"
+)
+
+func TestECDSASelfTest(t *testing.T) {
+
+	if report := SelfTest(20); !report.OK() {
+		t.Fatalf("self test failed:\n%v", report)
+	}
+}
@@ -0,0 +1,312 @@
+/*
+Package sig implements the signature schemes of this module: Schnorr
+signatures on E222 and secp256r1, ECDSA on the NIST curves and secp256k1,
+Ed25519, Ed448 and BIP-340, together with their armored containers,
+detached signatures, keys, keystores and the signing agent. The curve
+arithmetic lives in packages edwards and secp256k1, KMACXOF256 in package
+kmac; cmd/secp256r1_ecdsa is the command line front end.
+
+Built with the verifyonly tag the package leaves out everything that
+needs a secret key, so that a verify-only binary links no signing code.
+*/
+package sig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"errors"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+
+	"sig/edwards"
+)
+
+// Curve identifiers recorded in encoded Schnorr signatures so that
+// DecodeSignature can dispatch to the verifier for the right curve.
+// They double as the AlgorithmID of the Schnorr scheme on that curve.
+const (
+	curveE222    byte = 0x01
+	curveSecp256 byte = 0x02
+)
+
+/*
+Versions of the canonical signature encoding. Version 1 signatures hash
+curve points via big.Int.Bytes(), which drops leading zeros; version 2
+hashes fixed width encodings. EncodeSignature emits signatureVersion
+unless the signature records an older one.
+*/
+const (
+	signatureVersionVariableWidth byte = 0x01
+	signatureVersion              byte = 0x02
+)
+
+// Width of e, the 256 bit hash output, in encoded signatures.
+const hashWidth = 32
+
+// Optional type-length-value metadata carried in an encoded signature.
+type SignatureField struct {
+	Tag   byte
+	Value []byte
+}
+
+// Metadata tags.
+const (
+	// Present when the signed message was text in canonical form, see canonicalize_text.
+	metaCanonicalText byte = 0x01
+	// Domain the signature was made in, informational only: verifiers use their own.
+	metaDomain byte = 0x02
+)
+
+/*
+Signing domains separate signatures made for different purposes with the
+same key: a signature made in one domain fails verification in any other.
+Schnorr challenges use the domain as the cSHAKE256 customization string,
+ECDSA prefixes it to the input of the curve's hash, see ecdsa_digest. The
+empty domain is the original, unseparated scheme and remains the default
+of the sign_message_* and verify_sig_* conveniences.
+
+Returns the 256 bit cSHAKE256 digest of the parts with customization string domain.
+*/
+func domain_hash(domain string, parts ...[]byte) []byte {
+	h := sha3.NewCShake256(nil, []byte(domain))
+	for _, part := range parts {
+		h.Write(part)
+	}
+	out := make([]byte, hashWidth)
+	h.Read(out)
+	return out
+}
+
+// Domain prefix of the signed input in canonical text mode. It binds the
+// mode into the signature so that the flag cannot be added or removed.
+var canonicalTextPrefix = []byte("canonical-text\x00")
+
+/*
+Normalizes text so that signatures survive platform line ending
+conversions: CRLF and CR become LF and trailing spaces and tabs are
+stripped from every line.
+*/
+func canonicalize_text(msg []byte) []byte {
+	text := strings.ReplaceAll(string(msg), "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// The bytes actually signed for msg in canonical text mode.
+func canonical_text_input(msg []byte) []byte {
+	return append(append([]byte{}, canonicalTextPrefix...), canonicalize_text(msg)...)
+}
+
+// Domain label recorded in sig, or "" if none.
+func (sig *SchnorrSignature) domain_label() string {
+	for _, f := range sig.Meta {
+		if f.Tag == metaDomain {
+			return string(f.Value)
+		}
+	}
+	return ""
+}
+
+// True iff sig carries the canonical text flag.
+func (sig *SchnorrSignature) is_canonical_text() bool {
+	for _, f := range sig.Meta {
+		if f.Tag == metaCanonicalText {
+			return true
+		}
+	}
+	return false
+}
+
+// A Schnorr signature (s, e) together with the curve it was made on.
+type SchnorrSignature struct {
+	Version byte // zero means signatureVersion
+	Curve   byte
+	S       *big.Int
+	E       *big.Int
+	Meta    []SignatureField
+}
+
+// Fixed width of s for each curve, the byte length of the group order.
+func scalar_width(curve byte) int {
+	switch curve {
+	case curveE222:
+		return 28
+	case curveSecp256:
+		return 32
+	}
+	return 0
+}
+
+/*
+Order of the generator that s is reduced by for each curve. Signers emit
+s < order, and decoders and verifiers reject larger values: s + order
+verifies like s, so accepting it would give each signature several
+encodings.
+*/
+func scalar_order(curve byte) *big.Int {
+	switch curve {
+	case curveE222:
+		return e222Params.R
+	case curveSecp256:
+		return elliptic.P256().Params().N
+	}
+	return nil
+}
+
+/*
+Encodes sig in the canonical layout:
+
+	version (1 byte) || curve id (1 byte) || s (fixed width, big endian) ||
+	e (32 bytes, big endian) || metadata
+
+where s is as wide as the curve order and each metadata field is
+
+	tag (1 byte) || len (2 bytes, big endian) || value
+
+The same logical signature always encodes to the same bytes.
+*/
+func EncodeSignature(sig *SchnorrSignature) []byte {
+	width := scalar_width(sig.Curve)
+	out := make([]byte, 2+width+hashWidth)
+	out[0] = sig.Version
+	if out[0] == 0 {
+		out[0] = signatureVersion
+	}
+	out[1] = sig.Curve
+	copy(out[2:], toFixedBytes(sig.S, width))
+	copy(out[2+width:], toFixedBytes(sig.E, hashWidth))
+	for _, f := range sig.Meta {
+		out = append(out, f.Tag, byte(len(f.Value)>>8), byte(len(f.Value)))
+		out = append(out, f.Value...)
+	}
+	return out
+}
+
+/*
+Decodes a signature produced by EncodeSignature. Unknown versions and
+curves, truncated input, malformed metadata and s at or above the curve
+order are rejected.
+*/
+func DecodeSignature(b []byte) (*SchnorrSignature, error) {
+	return decode_signature_canonical(b)
+}
+
+/*
+Decodes as DecodeSignature does, falling back to the unversioned layout
+
+	curve id (1 byte) || len(s) (2 bytes, big endian) || s || e
+
+used before the canonical encoding was introduced. Legacy signatures
+decode as version 1, so EncodeSignature turns them into canonical
+encodings that VerifySignature accepts, including those made under the
+unscaled key x before signers used 4x. Only callers holding such old
+signatures should use it.
+*/
+func DecodeSignatureLegacy(b []byte) (*SchnorrSignature, error) {
+	sig, err := decode_signature_canonical(b)
+	if err != nil {
+		if legacy, legacy_err := decode_signature_legacy(b); legacy_err == nil {
+			return legacy, nil
+		}
+	}
+	return sig, err
+}
+
+func decode_signature_canonical(b []byte) (*SchnorrSignature, error) {
+	if len(b) < 2 {
+		return nil, errors.New("signature too short")
+	}
+	if b[0] != signatureVersionVariableWidth && b[0] != signatureVersion {
+		return nil, errors.New("unsupported signature version")
+	}
+	width := scalar_width(b[1])
+	if width == 0 {
+		return nil, ErrUnsupportedAlgorithm
+	}
+	if len(b) < 2+width+hashWidth {
+		return nil, errors.New("signature truncated")
+	}
+	sig := &SchnorrSignature{
+		Version: b[0],
+		Curve:   b[1],
+		S:       fromFixedBytes(b[2 : 2+width]),
+		E:       fromFixedBytes(b[2+width : 2+width+hashWidth]),
+	}
+	if sig.S.Cmp(scalar_order(sig.Curve)) >= 0 {
+		return nil, errors.New("signature scalar out of range")
+	}
+	rest := b[2+width+hashWidth:]
+	for len(rest) > 0 {
+		if len(rest) < 3 {
+			return nil, errors.New("malformed signature metadata")
+		}
+		length := int(rest[1])<<8 | int(rest[2])
+		if len(rest) < 3+length {
+			return nil, errors.New("malformed signature metadata")
+		}
+		value := append([]byte{}, rest[3:3+length]...)
+		sig.Meta = append(sig.Meta, SignatureField{Tag: rest[0], Value: value})
+		rest = rest[3+length:]
+	}
+	return sig, nil
+}
+
+/*
+Decodes the unversioned layout. Signers of that layout reduced E222
+responses mod 4r rather than r; s is reduced mod the order here so that
+their signatures still verify.
+*/
+func decode_signature_legacy(b []byte) (*SchnorrSignature, error) {
+	if len(b) < 3 {
+		return nil, errors.New("signature too short")
+	}
+	if scalar_width(b[0]) == 0 {
+		return nil, errors.New("unknown signature curve")
+	}
+	s_len := int(b[1])<<8 | int(b[2])
+	if len(b) < 3+s_len {
+		return nil, errors.New("signature truncated")
+	}
+	s := new(big.Int).SetBytes(b[3 : 3+s_len])
+	return &SchnorrSignature{
+		Version: signatureVersionVariableWidth,
+		Curve:   b[0],
+		S:       s.Mod(s, scalar_order(b[0])),
+		E:       new(big.Int).SetBytes(b[3+s_len:]),
+	}, nil
+}
+
+/*
+Verifies an encoded Schnorr signature against key, which must be an *E222
+or *ecdsa.PublicKey matching the curve recorded in the signature.
+A signature presented against a key of the other curve fails verification.
+Signatures flagged as canonical text are checked against the canonical
+form of msg. The signature is checked in the caller's domain; any domain
+label recorded in it is ignored. With edwards.RequirePrimeOrder, E222
+keys with a small order component are rejected.
+*/
+func VerifySignature(key interface{}, domain string, encoded []byte, msg *[]byte, opts ...edwards.DecodeOption) bool {
+	sig, err := DecodeSignature(encoded)
+	if err != nil {
+		return false
+	}
+	if sig.is_canonical_text() {
+		canonical := canonical_text_input(*msg)
+		msg = &canonical
+	}
+	switch sig.Curve {
+	case curveE222:
+		y, ok := key.(*E222)
+		return ok && verify_sig_e222_version(sig.Version, domain, y, sig.S, sig.E, msg, opts...)
+	case curveSecp256:
+		y, ok := key.(*ecdsa.PublicKey)
+		return ok && verify_sig_secp256_version(sig.Version, domain, y, sig.S, sig.E, msg)
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package sig
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Text renderings of a signature.
+const (
+	formatArmor  = "armor"
+	formatHex    = "hex"
+	formatBase64 = "base64"
+	formatJSON   = "json"
+)
+
+var ErrUnknownSignatureFormat = errors.New("text is not a signature in any known format")
+
+// JSON rendering of a SignatureContainer, binary fields in hex.
+type signatureJSON struct {
+	Algorithm   string `json:"algorithm,omitempty"`
+	Scheme      string `json:"scheme"`
+	PublicKey   string `json:"public_key"`
+	Signature   string `json:"signature"`
+	Filename    string `json:"filename,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Domain      string `json:"domain,omitempty"`
+}
+
+/*
+Renders c in format. Armor and JSON carry the whole container, hex and
+base64 only the signature bytes, so the verifier must know the key.
+*/
+func render_signature(c *SignatureContainer, format string) (string, error) {
+	switch format {
+	case formatArmor:
+		return EncodeContainer(c), nil
+	case formatHex:
+		return hex.EncodeToString(c.Signature), nil
+	case formatBase64:
+		return base64.StdEncoding.EncodeToString(c.Signature), nil
+	case formatJSON:
+		id, _ := SchemeAlgorithm(c.Scheme)
+		out, err := json.Marshal(signatureJSON{
+			Algorithm:   id.String(),
+			Scheme:      c.Scheme,
+			PublicKey:   hex.EncodeToString(c.PublicKey),
+			Signature:   hex.EncodeToString(c.Signature),
+			Filename:    c.Filename,
+			Fingerprint: c.Fingerprint,
+			Domain:      c.Domain,
+		})
+		return string(out), err
+	}
+	return "", errors.New("unknown signature format: " + format)
+}
+
+/*
+Parses a signature rendered by render_signature and reports its format.
+Formats are tried in the order armor, JSON, hex, base64; text made only
+of hex digits is read as hex even though it is valid base64 too. For hex
+and base64 only the Signature field of the result is set.
+*/
+func DetectSignature(text string) (*SignatureContainer, string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, "", ErrUnknownSignatureFormat
+	}
+	if strings.HasPrefix(text, "-----BEGIN ") {
+		c, err := DecodeContainer(text)
+		return c, formatArmor, err
+	}
+	if strings.HasPrefix(text, "{") {
+		var j signatureJSON
+		if err := json.Unmarshal([]byte(text), &j); err != nil {
+			return nil, formatJSON, err
+		}
+		key, err1 := hex.DecodeString(j.PublicKey)
+		sig, err2 := hex.DecodeString(j.Signature)
+		if err1 != nil || err2 != nil || j.Scheme == "" || len(sig) == 0 {
+			return nil, formatJSON, errors.New("malformed JSON signature")
+		}
+		id, err := SchemeAlgorithm(j.Scheme)
+		if err != nil {
+			return nil, formatJSON, err
+		}
+		if j.Algorithm != "" {
+			if named, err := parse_algorithm(j.Algorithm); err != nil || named != id {
+				return nil, formatJSON, ErrUnsupportedAlgorithm
+			}
+		}
+		return &SignatureContainer{
+			Scheme:      j.Scheme,
+			PublicKey:   key,
+			Signature:   sig,
+			Filename:    j.Filename,
+			Fingerprint: j.Fingerprint,
+			Domain:      j.Domain,
+		}, formatJSON, nil
+	}
+	compact := strings.Join(strings.Fields(text), "")
+	if sig, err := hex.DecodeString(compact); err == nil {
+		return &SignatureContainer{Signature: sig}, formatHex, nil
+	}
+	if sig, err := base64.StdEncoding.DecodeString(compact); err == nil {
+		return &SignatureContainer{Signature: sig}, formatBase64, nil
+	}
+	return nil, "", ErrUnknownSignatureFormat
+}
@@ -0,0 +1,68 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Every rendering is detected as its own format; ambiguous and corrupted text is handled.
+func TestSignatureFormats(t *testing.T) {
+
+	msg := []byte("format me")
+	y, s, e := sign_message_e222(&msg)
+	c := e222_container(y, s, e)
+	c.Domain = "formats"
+
+	for _, format := range []string{formatArmor, formatJSON, formatHex, formatBase64} {
+		text, err := render_signature(c, format)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		got, detected, err := DetectSignature("\n  " + text + "\n")
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if detected != format {
+			t.Fatalf("%s detected as %s", format, detected)
+		}
+		if !bytes.Equal(got.Signature, c.Signature) {
+			t.Fatalf("%s: signature %x, want %x", format, got.Signature, c.Signature)
+		}
+		if format == formatArmor || format == formatJSON {
+			if ok, err := VerifyContainer(got, "", &msg); !ok {
+				t.Fatalf("%s: container does not verify: %v", format, err)
+			}
+			if got.Domain != "formats" {
+				t.Fatalf("%s: domain %q, want formats", format, got.Domain)
+			}
+		}
+	}
+	if _, err := render_signature(c, "yaml"); err == nil {
+		t.Fatal("yaml renders")
+	}
+
+	cases := []struct {
+		text   string
+		format string
+		ok     bool
+	}{
+		{"deadbeef", formatHex, true},      // also valid base64, hex wins
+		{"dead beef\n00", formatHex, true}, // whitespace is ignored
+		{"3q2+7w==", formatBase64, true},
+		{"", "", false},
+		{"not a signature!", "", false},
+		{"deadbee", "", false}, // odd length hex, invalid base64
+		{"-----BEGIN SIGNATURE-----\ngarbage", formatArmor, false},
+		{"-----BEGIN OTHER-----\nAA==\n-----END OTHER-----\n", formatArmor, false},
+		{`{"scheme": "schnorr-e222"`, formatJSON, false},
+		{`{"scheme": "schnorr-e222", "public_key": "zz", "signature": "00"}`, formatJSON, false},
+		{`{"public_key": "00", "signature": "00"}`, formatJSON, false},
+	}
+	for _, tc := range cases {
+		if _, format, err := DetectSignature(tc.text); format != tc.format || (err == nil) != tc.ok {
+			t.Fatalf("%q detected as %q, %v, want %q", tc.text, format, err, tc.format)
+		}
+	}
+}
@@ -0,0 +1,371 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+func TestEncodedSigRoundTrip(t *testing.T) {
+
+	msg := []byte("short message")
+	for i := 0; i < 10; i++ {
+		y, s, e := sign_message_e222(&msg)
+		sig := EncodeSignature(&SchnorrSignature{Curve: curveE222, S: s, E: e})
+		if !VerifySignature(y, "", sig, &msg) {
+			t.Fatalf("encoded signature %x does not verify", sig)
+		}
+	}
+}
+
+// An E222 signature presented against a secp256 key (and vice versa) must
+// fail cleanly rather than panic.
+func TestCrossCurveSignature(t *testing.T) {
+
+	msg := []byte("short message")
+	e222_key, e222_s, e222_e := sign_message_e222(&msg)
+	p256_key, p256_s, p256_e := sign_message_secp256(&msg)
+
+	e222_sig := EncodeSignature(&SchnorrSignature{Curve: curveE222, S: e222_s, E: e222_e})
+	p256_sig := EncodeSignature(&SchnorrSignature{Curve: curveSecp256, S: p256_s, E: p256_e})
+
+	if VerifySignature(&p256_key, "", e222_sig, &msg) {
+		t.Fatal("E222 signature verifies against a secp256r1 key")
+	}
+	if VerifySignature(e222_key, "", p256_sig, &msg) {
+		t.Fatal("secp256r1 signature verifies against an E222 key")
+	}
+}
+
+// The canonical encoding must never drift silently.
+func TestSignatureGoldenBytes(t *testing.T) {
+
+	sig := &SchnorrSignature{
+		Curve: curveE222,
+		S:     big.NewInt(0x0102),
+		E:     big.NewInt(0x03),
+		Meta:  []SignatureField{{Tag: 0x07, Value: []byte("ab")}},
+	}
+	golden := "0201" +
+		"00000000000000000000000000000000000000000000000000000102" +
+		"0000000000000000000000000000000000000000000000000000000000000003" +
+		"0700026162"
+	if encoded := hex.EncodeToString(EncodeSignature(sig)); encoded != golden {
+		t.Fatalf("EncodeSignature = %s, want %s", encoded, golden)
+	}
+}
+
+func TestSignatureEncodeDecodeIdentity(t *testing.T) {
+
+	for i := 0; i < 100; i++ {
+		curve := []byte{curveE222, curveSecp256}[i%2]
+		s := new(big.Int).Mod(generateRandomBigInt(), scalar_order(curve))
+		e := new(big.Int).Rsh(generateRandomBigInt(), 512-8*hashWidth)
+		meta := []SignatureField{{Tag: byte(i), Value: generateRandomBigInt().Bytes()[:i%8]}}
+		encoded := EncodeSignature(&SchnorrSignature{Curve: curve, S: s, E: e, Meta: meta})
+		decoded, err := DecodeSignature(encoded)
+		if err != nil {
+			t.Fatalf("DecodeSignature(%x): %v", encoded, err)
+		}
+		if decoded.S.Cmp(s) != 0 || decoded.E.Cmp(e) != 0 {
+			t.Fatalf("%x decodes to s = %v, e = %v, want s = %v, e = %v", encoded, decoded.S, decoded.E, s, e)
+		}
+		if again := EncodeSignature(decoded); !bytes.Equal(again, encoded) {
+			t.Fatalf("%x re-encodes as %x", encoded, again)
+		}
+	}
+}
+
+// The legacy layout is only accepted by DecodeSignatureLegacy.
+// Adding multiples of r to s gives encodings that decode and verification both refuse.
+func TestMalleatedSignatureRejected(t *testing.T) {
+
+	x := NewSecretScalar(generateRandomBigInt())
+	x_int, err := x.Int()
+	if err != nil {
+		t.Fatal(err)
+	}
+	y := e222Curve.BaseMul(x_int)
+	msg := []byte("short message")
+	s, e, err := sign_with_key_e222(x, "", &msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded := EncodeSignature(&SchnorrSignature{Curve: curveE222, S: s, E: e})
+	if !VerifySignature(y, "", encoded, &msg) {
+		t.Fatal("signature does not verify")
+	}
+	for i := int64(1); i < 4; i++ {
+		s_i := new(big.Int).Add(s, new(big.Int).Mul(e222Params.R, big.NewInt(i)))
+		malleated := EncodeSignature(&SchnorrSignature{Curve: curveE222, S: s_i, E: e})
+		if bytes.Equal(malleated, encoded) {
+			t.Fatalf("s + %d r encodes the same as s", i)
+		}
+		if _, err := DecodeSignature(malleated); err == nil {
+			t.Fatalf("s + %d r decodes", i)
+		}
+		if VerifySignature(y, "", malleated, &msg) || verify_sig_e222(y, s_i, e, &msg) {
+			t.Fatalf("s + %d r verifies", i)
+		}
+		if VerifyBatchE222("", []SchnorrSignature{{Curve: curveE222, S: s_i, E: e}}, []*E222{y}, [][]byte{msg}) {
+			t.Fatalf("s + %d r verifies in a batch", i)
+		}
+	}
+}
+
+func TestLegacySignatureCompat(t *testing.T) {
+
+	// sign with version 1 hashing, as signatures in the legacy layout were,
+	// under the unscaled key x as before signers used 4x and under 4x as after
+	msg := []byte("short message")
+	x := generateRandomBigInt()
+	y := e222Curve.GenPoint().SecMul(x)
+	n := e222Curve.Params().N
+	for _, key := range []*big.Int{x, cofactor_scalar(x)} {
+		k := generateRandomBigInt()
+		e := e222_challenge(signatureVersionVariableWidth, "", e222Curve.GenPoint().SecMul(k).X(), &msg)
+		s := new(big.Int).Sub(k, new(big.Int).Mul(key, e))
+		s.Mod(s, n)
+
+		legacy := append([]byte{curveE222, 0, byte(len(s.Bytes()))}, s.Bytes()...)
+		legacy = append(legacy, e.Bytes()...)
+
+		if VerifySignature(y, "", legacy, &msg) {
+			t.Fatal("legacy layout verifies without DecodeSignatureLegacy")
+		}
+		if _, err := DecodeSignature(legacy); err == nil {
+			t.Fatal("DecodeSignature accepts the legacy layout")
+		}
+		decoded, err := DecodeSignatureLegacy(legacy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded.Version != signatureVersionVariableWidth {
+			t.Fatalf("legacy signature decodes as version %d, want %d", decoded.Version, signatureVersionVariableWidth)
+		}
+		if !VerifySignature(y, "", EncodeSignature(decoded), &msg) {
+			t.Fatalf("decoded legacy signature %x does not verify", legacy)
+		}
+		decoded.S.Add(decoded.S, big.NewInt(1))
+		if VerifySignature(y, "", EncodeSignature(decoded), &msg) {
+			t.Fatal("altered legacy signature verifies")
+		}
+	}
+
+	// a signature in the legacy layout made by the signer of that time, under x
+	golden_x, _ := new(big.Int).SetString("1234567890abcdef1234567890abcdef1234567890abcdef", 16)
+	golden_msg := []byte("signed before the cofactor scaled key")
+	golden, _ := hex.DecodeString("01001c15539b39efcb133cb2ef6433d198d5a67c8c0cdeb367b5fdba7666ef24105ec011606b5ce7f6e1c013f422932c0a8cd3a4adca09c25a58e47369676e")
+	golden_y := e222Curve.GenPoint().SecMul(golden_x)
+	decoded, err := DecodeSignatureLegacy(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifySignature(golden_y, "", EncodeSignature(decoded), &golden_msg) {
+		t.Fatalf("legacy signature %x under x does not verify", golden)
+	}
+	if VerifySignature(golden_y, "", EncodeSignature(decoded), &msg) {
+		t.Fatal("legacy signature under x verifies another message")
+	}
+
+	// current signatures pass through unchanged
+	current, err := sign_text_e222(NewSecretScalar(x), "", msg, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	same, err := DecodeSignatureLegacy(current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again := EncodeSignature(same); !bytes.Equal(again, current) {
+		t.Fatalf("DecodeSignatureLegacy(%x) re-encodes as %x", current, again)
+	}
+}
+
+/*
+Version 1 challenges depend on whether a value has a leading zero byte;
+version 2 challenges use fixed width encodings and verify regardless.
+*/
+func TestFixedWidthChallenge(t *testing.T) {
+
+	msg := []byte("short message")
+
+	// r_x with a leading zero byte hashes differently under the two versions
+	r_x := new(big.Int).Rsh(generateRandomBigInt(), 512-248)
+	if b := toFixedBytes(r_x, p256Width); len(b) != p256Width || fromFixedBytes(b).Cmp(r_x) != 0 {
+		t.Fatalf("toFixedBytes(%v) = %x", r_x, b)
+	}
+	fixed := sha256.Sum256(append(toFixedBytes(r_x, p256Width), msg...))
+	v1 := secp256_challenge(signatureVersionVariableWidth, "", r_x, &msg)
+	v2 := secp256_challenge(signatureVersion, "", r_x, &msg)
+	if v1.Cmp(v2) == 0 {
+		t.Fatalf("version 1 and 2 challenges are both %v", v1)
+	}
+	if v2.Cmp(fromFixedBytes(fixed[:])) != 0 {
+		t.Fatalf("version 2 challenge = %v, want %x", v2, fixed)
+	}
+
+	// find a signature whose challenge e has a leading zero byte
+	var key ecdsa.PublicKey
+	var s, e *big.Int
+	for e == nil || e.BitLen() > 248 {
+		key, s, e = sign_message_secp256(&msg)
+	}
+	if !verify_sig_secp256(&key, s, e, &msg) {
+		t.Fatalf("signature with e = %v does not verify", e)
+	}
+	encoded := EncodeSignature(&SchnorrSignature{Curve: curveSecp256, S: s, E: e})
+	if encoded[0] != signatureVersion {
+		t.Fatalf("signature encodes as version %d, want %d", encoded[0], signatureVersion)
+	}
+	if !VerifySignature(&key, "", encoded, &msg) {
+		t.Fatalf("encoded signature with e = %v does not verify", e)
+	}
+}
+
+func TestCanonicalTextSignatures(t *testing.T) {
+
+	x := NewSecretScalar(generateRandomBigInt())
+	x_int, err := x.Int()
+	if err != nil {
+		t.Fatal(err)
+	}
+	y := e222Curve.GenPoint().SecMul(x_int)
+
+	crlf := []byte("first line  \r\nsecond line\t\r\n")
+	lf := []byte("first line\nsecond line\n")
+	changed := []byte("first line\nsecond line changed\n")
+
+	sigCRLF, err := sign_text_e222(x, "", crlf, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigLF, err := sign_text_e222(x, "", lf, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifySignature(y, "", sigCRLF, &lf) || !VerifySignature(y, "", sigLF, &crlf) {
+		t.Fatal("canonical text signatures depend on line endings")
+	}
+	if VerifySignature(y, "", sigLF, &changed) {
+		t.Fatal("canonical text signature verifies a changed text")
+	}
+
+	// file mode stays byte exact
+	sigFile, err := sign_text_e222(x, "", crlf, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifySignature(y, "", sigFile, &crlf) {
+		t.Fatal("file mode signature does not verify")
+	}
+	if VerifySignature(y, "", sigFile, &lf) {
+		t.Fatal("file mode signature ignores line endings")
+	}
+
+	// stripping the canonical flag breaks the signature
+	decoded, err := DecodeSignature(sigLF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded.Meta = nil
+	if stripped := EncodeSignature(decoded); VerifySignature(y, "", stripped, &lf) {
+		t.Fatal("signature verifies without its canonical text flag")
+	}
+}
+
+// Signatures made in one domain fail in every other, whatever label they carry.
+func TestDomainSeparation(t *testing.T) {
+
+	msg := []byte("transfer 10 coins")
+	x := NewSecretScalar(generateRandomBigInt())
+	x_int, err := x.Int()
+	if err != nil {
+		t.Fatal(err)
+	}
+	y := e222Curve.GenPoint().SecMul(x_int)
+
+	sig, err := sign_text_e222(x, "app-a", msg, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeSignature(sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if label := decoded.domain_label(); label != "app-a" {
+		t.Fatalf("domain label %q, want app-a", label)
+	}
+	if !VerifySignature(y, "app-a", sig, &msg) {
+		t.Fatal("E222 signature does not verify in its domain")
+	}
+	for _, domain := range []string{"app-b", ""} {
+		if VerifySignature(y, domain, sig, &msg) {
+			t.Fatalf("E222 signature from app-a verifies in domain %q", domain)
+		}
+	}
+
+	// relabelling the signature does not move it to another domain
+	decoded.Meta = []SignatureField{{Tag: metaDomain, Value: []byte("app-b")}}
+	relabelled := EncodeSignature(decoded)
+	if VerifySignature(y, "app-b", relabelled, &msg) || !VerifySignature(y, "app-a", relabelled, &msg) {
+		t.Fatal("relabelling the signature moved it to app-b")
+	}
+
+	// empty domain signatures stay valid only in the empty domain
+	plain, err := sign_text_e222(x, "", msg, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifySignature(y, "", plain, &msg) || VerifySignature(y, "app-a", plain, &msg) {
+		t.Fatal("signature without a domain verifies in app-a")
+	}
+
+	// secp256 Schnorr
+	curve := elliptic.P256()
+	d := new(big.Int).Mod(generateRandomBigInt(), curve.Params().N)
+	Q := &ecdsa.PublicKey{Curve: curve}
+	Q.X, Q.Y = curve.ScalarBaseMult(toFixedBytes(d, p256Width))
+	s, e := sign_with_key_secp256(d, "app-a", &msg)
+	if !verify_sig_secp256_version(signatureVersion, "app-a", Q, s, e, &msg) {
+		t.Fatal("secp256r1 signature does not verify in its domain")
+	}
+	if verify_sig_secp256_version(signatureVersion, "app-b", Q, s, e, &msg) || verify_sig_secp256(Q, s, e, &msg) {
+		t.Fatal("secp256r1 signature from app-a verifies in another domain")
+	}
+
+	// ECDSA, also through the armored container
+	key := NewECDSAPrivateKey(d)
+	r, s2, err := key.Sign("app-a", &msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verify_ecdsa_sig_domain(Q, "app-a", r, s2, &msg) {
+		t.Fatal("ECDSA signature does not verify in its domain")
+	}
+	if verify_ecdsa_sig_domain(Q, "app-b", r, s2, &msg) || verify_ecdsa_sig(Q, r, s2, &msg) {
+		t.Fatal("ECDSA signature from app-a verifies in another domain")
+	}
+	c, err := ecdsa_container(Q, r, s2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Domain = "app-a"
+	armored, err := DecodeContainer(EncodeContainer(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := VerifyContainer(armored, "app-a", &msg); !ok || err != nil {
+		t.Fatalf("container in app-a: %v, %v", ok, err)
+	}
+	armored.Domain = "app-b"
+	if ok, _ := VerifyContainer(armored, "app-b", &msg); ok {
+		t.Fatal("container relabelled to app-b verifies")
+	}
+}
@@ -0,0 +1,65 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"errors"
+	"io"
+)
+
+var ErrSignerHash = errors.New("unsupported hash for this signer")
+
+/*
+Adapts key to crypto.Signer, producing ASN.1 DER signatures as
+crypto/ecdsa does, so that it can be used with crypto/tls,
+x509.CreateCertificate and other code written against the standard
+interface. Digests must be of the curve's hash, see ecdsa_hash: SHA-256
+for P-256 and secp256k1, SHA-384 for P-384 and SHA-512 for P-521. Nonces follow the key's own settings
+and the cache of Sign, so the rand passed to the signer is not used.
+*/
+func (key *ECDSAPrivateKey) Signer() crypto.Signer {
+	return &ecdsaSigner{key: key, pub: key.PublicKey()}
+}
+
+type ecdsaSigner struct {
+	key *ECDSAPrivateKey
+	pub *ecdsa.PublicKey
+}
+
+func (s *ecdsaSigner) Public() crypto.PublicKey { return s.pub }
+
+func (s *ecdsaSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if opts.HashFunc() != ecdsa_hash(s.key.Curve) {
+		return nil, ErrSignerHash
+	}
+	r, sig_s, err := s.key.SignDigest(digest)
+	if err != nil {
+		return nil, err
+	}
+	return marshal_ecdsa_der(r, sig_s)
+}
+
+/*
+Adapts ctx to crypto.Signer. Like ed25519, Schnorr signatures here are
+over the whole message, so opts must be crypto.Hash(0) and the "digest"
+is the message itself, signed in the empty domain. Public returns the
+*E222 public key and signatures are encoded as by EncodeSignature.
+*/
+func (ctx *SigningContext) Signer() crypto.Signer {
+	return &e222Signer{ctx: ctx}
+}
+
+type e222Signer struct {
+	ctx *SigningContext
+}
+
+func (s *e222Signer) Public() crypto.PublicKey { return s.ctx.PublicKey() }
+
+func (s *e222Signer) Sign(_ io.Reader, message []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if opts.HashFunc() != crypto.Hash(0) {
+		return nil, ErrSignerHash
+	}
+	return s.ctx.Sign("", message)
+}
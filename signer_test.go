@@ -0,0 +1,67 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// The package keys work where the standard library expects a crypto.Signer.
+func TestCryptoSigners(t *testing.T) {
+
+	key := NewECDSAPrivateKey(new(big.Int).Mod(generateRandomBigInt(), elliptic.P256().Params().N))
+	var signer crypto.Signer = key.Signer()
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "signer test"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, signer.Public(), signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.CheckSignatureFrom(cert); err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte("digest"))
+	if _, err := signer.Sign(rand.Reader, digest[:], crypto.SHA384); err != ErrSignerHash {
+		t.Fatalf("ECDSA signer with SHA-384: %v, want %v", err, ErrSignerHash)
+	}
+
+	ctx, err := NewSigningContextFromScalar(NewSecretScalar(new(big.Int).Mod(generateRandomBigInt(), e222Params.R)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer = ctx.Signer()
+	msg := []byte("whole message")
+	sig, err := signer.Sign(nil, msg, crypto.Hash(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	V, ok := signer.Public().(*E222)
+	if !ok {
+		t.Fatalf("E222 signer has a %T public key", signer.Public())
+	}
+	if !VerifySignature(V, "", sig, &msg) {
+		t.Fatal("E222 signer signature does not verify")
+	}
+	if _, err := signer.Sign(nil, digest[:], crypto.SHA256); err != ErrSignerHash {
+		t.Fatalf("E222 signer with SHA-256: %v, want %v", err, ErrSignerHash)
+	}
+}
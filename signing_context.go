@@ -0,0 +1,64 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"sync"
+)
+
+/*
+Signing key derived once and reused across messages, so that signing many
+messages does not repeat the passphrase KDF and public key computation of
+sign_with_passphrase_e222. Safe for concurrent use: every signature draws
+its own nonce, so signatures made in parallel stay independent.
+*/
+type SigningContext struct {
+	mu  sync.RWMutex
+	s   *SecretScalar
+	pub *E222
+}
+
+// Derives the signing key from pw.
+func NewSigningContext(pw []byte) *SigningContext {
+	s, V := e222_keypair_from_passphrase(pw)
+	return &SigningContext{s: s, pub: V}
+}
+
+// Wraps a copy of s, which the caller remains responsible for.
+func NewSigningContextFromScalar(s *SecretScalar) (*SigningContext, error) {
+	s_int, err := s.Int()
+	if err != nil {
+		return nil, err
+	}
+	defer wipe_int(s_int)
+	if err := check_scalar(s_int); err != nil {
+		return nil, err
+	}
+	return &SigningContext{s: NewSecretScalar(s_int), pub: e222Curve.BaseMul(s_int)}, nil
+}
+
+// Public key matching the cached scalar.
+func (ctx *SigningContext) PublicKey() *E222 {
+	return ctx.pub
+}
+
+// Signs msg in domain and returns the encoded signature, or ErrDestroyedSecret after Destroy.
+func (ctx *SigningContext) Sign(domain string, msg []byte) ([]byte, error) {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+	return sign_text_e222(ctx.s, domain, msg, false)
+}
+
+// Wipes the cached scalar, waiting for signatures in progress to finish.
+func (ctx *SigningContext) Destroy() {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.s.Destroy()
+}
+
+// Signs msg in domain with the key derived from pw, deriving it afresh on every call.
+func sign_with_passphrase_e222(pw []byte, domain string, msg []byte) ([]byte, error) {
+	s, _ := e222_keypair_from_passphrase(pw)
+	defer s.Destroy()
+	return sign_text_e222(s, domain, msg, false)
+}
@@ -0,0 +1,50 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// 16 goroutines share one SigningContext; run under -race to check it.
+func TestSigningContextConcurrent(t *testing.T) {
+
+	pw := []byte("correct horse battery staple")
+	ctx := NewSigningContext(pw)
+	_, V := e222_keypair_from_passphrase(pw)
+	if !ctx.PublicKey().Equals(V) {
+		t.Fatal("context public key differs from the passphrase key")
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16*8)
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 8; i++ {
+				msg := []byte(fmt.Sprintf("message %d from goroutine %d", i, g))
+				sig, err := ctx.Sign("", msg)
+				if err == nil && !VerifySignature(V, "", sig, &msg) {
+					err = fmt.Errorf("signature of %q does not verify", msg)
+				}
+				errs <- err
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx.Destroy()
+	if _, err := ctx.Sign("", []byte("after destroy")); !errors.Is(err, ErrDestroyedSecret) {
+		t.Fatalf("signing after Destroy: %v, want %v", err, ErrDestroyedSecret)
+	}
+}
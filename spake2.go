@@ -0,0 +1,214 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"sig/edwards"
+	"sig/kmac"
+)
+
+// Lengths in bytes of the session key and of the confirmation MACs of SPAKE2.
+const (
+	SPAKE2KeySize   = 32
+	SPAKE2MACLength = 32
+)
+
+var (
+	ErrSPAKE2State        = errors.New("spake2: protocol step out of order")
+	ErrSPAKE2Confirmation = errors.New("spake2: key confirmation failed")
+)
+
+/*
+One side of a SPAKE2 exchange, the password-authenticated key exchange of
+RFC 9382, over any Edwards curve of this package. Two parties who share
+only a password pw, possibly a short one, agree on a strong session key;
+an eavesdropper learns nothing about pw and an active attacker can test
+one password guess per exchange. With w the password scalar, see
+spake2_password, and M and N points of unknown discrete logarithm, see
+spake2_points:
+
+	A: x ← random, pA ← x × G + w × M
+	B: y ← random, pB ← y × G + w × N
+	A: K ← h × x × (pB − w × N)
+	B: K ← h × y × (pA − w × M)
+
+with h the cofactor. Both sides hash the transcript
+
+	TT ← A || B || pA || pB || K || w
+
+each item preceded by its length in 8 bytes little endian, as in Section
+3.3, and derive
+
+	(Ke || Ka) ← KMACXOF256("", TT, 512, "SPAKE2")
+	(KcA || KcB) ← KMACXOF256(Ka, aad, 512, "SPAKE2 CONFIRM")
+	cA ← KMACXOF256(KcA, TT, 256, "SPAKE2 MAC")
+	cB ← KMACXOF256(KcB, TT, 256, "SPAKE2 MAC")
+
+A sends cA and B sends cB; the session key Ke is released only once the
+peer's MAC checks. Use: Message to the peer, Finish with the peer's
+message, which returns the own MAC, and Confirm with the peer's MAC.
+*/
+type SPAKE2 struct {
+	curve    *edwards.Curve
+	isA      bool
+	idA, idB []byte
+	aad      []byte
+	w, x     *big.Int
+	M, N     *edwards.Point
+	msg      []byte       // the own share pA or pB, encoded by Bytes
+	ke       *SecretBytes // the session key, once Finish has run
+	cA, cB   []byte       // the confirmation MACs, once Finish has run
+	done     bool
+}
+
+// Starts the exchange as party A, identified by idA, with party B, identified by idB.
+func NewSPAKE2A(c *edwards.Curve, pw, idA, idB, aad []byte) (*SPAKE2, error) {
+	return new_spake2(c, true, pw, idA, idB, aad)
+}
+
+// Starts the exchange as party B, identified by idB, with party A, identified by idA.
+func NewSPAKE2B(c *edwards.Curve, pw, idA, idB, aad []byte) (*SPAKE2, error) {
+	return new_spake2(c, false, pw, idA, idB, aad)
+}
+
+func new_spake2(c *edwards.Curve, isA bool, pw, idA, idB, aad []byte) (*SPAKE2, error) {
+	x, err := rand.Int(rand.Reader, new(big.Int).Sub(c.Order(), big.NewInt(1)))
+	if err != nil {
+		return nil, err
+	}
+	x.Add(x, big.NewInt(1))
+	M, N := spake2_points(c)
+	s := &SPAKE2{
+		curve: c, isA: isA,
+		idA: append([]byte(nil), idA...), idB: append([]byte(nil), idB...), aad: append([]byte(nil), aad...),
+		w: spake2_password(c, pw), x: x, M: M, N: N,
+	}
+	own := M
+	if !isA {
+		own = N
+	}
+	s.msg = c.BaseMul(x).Add(own.SecMul(s.w)).Bytes()
+	return s, nil
+}
+
+/*
+M and N of the curve c, each hashed to the curve from its name as
+HashToPoint does, so that nobody knows their discrete logarithms to G or
+to each other. RFC 9382 fixes M and N for other curves only.
+*/
+func spake2_points(c *edwards.Curve) (*edwards.Point, *edwards.Point) {
+	M, _ := c.HashToPoint([]byte(c.Name()), []byte("SPAKE2 M"))
+	N, _ := c.HashToPoint([]byte(c.Name()), []byte("SPAKE2 N"))
+	return M, N
+}
+
+/*
+The password scalar w ← KMACXOF256(NFC(pw), "", |r| + 128, "SPAKE2 W")
+mod r, 128 extra bits keeping its bias negligible. RFC 9382 recommends a
+memory hard function here; KMAC alone leaves offline guessing to whoever
+captures w, which this package never stores.
+*/
+func spake2_password(c *edwards.Curve, pw []byte) *big.Int {
+	derived := NewSecretBytes(kmac.XOF256(normalize_passphrase(pw), nil, 8*((c.Order().BitLen()+7)/8)+128, "SPAKE2 W"))
+	defer derived.Wipe()
+	w := fromFixedBytes(derived.b)
+	return w.Mod(w, c.Order())
+}
+
+// The own share pA or pB to send to the peer, encoded by Bytes.
+func (s *SPAKE2) Message() []byte { return append([]byte(nil), s.msg...) }
+
+/*
+Takes the peer's share and returns the own confirmation MAC to send back.
+The share must decode with FromBytes; its small order component, if any,
+is removed by h. A K that is the identity fails with ErrNoSharedSecret.
+The scalars x and w are wiped whether or not it succeeds, so Finish runs
+once, and again fails with ErrSPAKE2State.
+*/
+func (s *SPAKE2) Finish(peer []byte) ([]byte, error) {
+	if s.x == nil {
+		return nil, ErrSPAKE2State
+	}
+	defer s.wipe_scalars()
+	c := s.curve
+	P, err := c.FromBytes(peer)
+	if err != nil {
+		return nil, err
+	}
+	other, pA, pB := s.N, s.msg, peer
+	if !s.isA {
+		other, pA, pB = s.M, peer, s.msg
+	}
+	K := P.Add(other.SecMul(s.w).Neg()).ClearCofactor().SecMul(s.x)
+	if K.IsIdentity() {
+		return nil, ErrNoSharedSecret
+	}
+	shared := K.Bytes()
+	defer wipe_bytes(shared)
+	w := toFixedBytes(s.w, (c.Params().P.BitLen()+7)/8)
+	defer wipe_bytes(w)
+
+	var tt []byte
+	for _, item := range [][]byte{s.idA, s.idB, pA, pB, shared, w} {
+		tt = binary.LittleEndian.AppendUint64(tt, uint64(len(item)))
+		tt = append(tt, item...)
+	}
+	defer wipe_bytes(tt)
+	keys := NewSecretBytes(kmac.XOF256(nil, tt, 512, "SPAKE2"))
+	defer keys.Wipe()
+	ke, ka := split_keys(keys.b)
+	confirm := NewSecretBytes(kmac.XOF256(ka, s.aad, 512, "SPAKE2 CONFIRM"))
+	defer confirm.Wipe()
+	kcA, kcB := split_keys(confirm.b)
+	s.cA = kmac.XOF256(kcA, tt, 8*SPAKE2MACLength, "SPAKE2 MAC")
+	s.cB = kmac.XOF256(kcB, tt, 8*SPAKE2MACLength, "SPAKE2 MAC")
+	s.ke = NewSecretBytes(append([]byte(nil), ke...))
+	if s.isA {
+		return append([]byte(nil), s.cA...), nil
+	}
+	return append([]byte(nil), s.cB...), nil
+}
+
+/*
+Checks the peer's confirmation MAC and returns the session key Ke for the
+caller to wipe. A wrong MAC, which a wrong password on either side gives,
+fails with ErrSPAKE2Confirmation and destroys the session's keys, so that
+a failed exchange cannot be retried with another MAC.
+*/
+func (s *SPAKE2) Confirm(peerMAC []byte) (*SecretBytes, error) {
+	if s.ke == nil || s.done {
+		return nil, ErrSPAKE2State
+	}
+	defer s.Destroy()
+	expected := s.cB
+	if !s.isA {
+		expected = s.cA
+	}
+	if subtle.ConstantTimeCompare(peerMAC, expected) != 1 {
+		return nil, ErrSPAKE2Confirmation
+	}
+	return NewSecretBytes(append([]byte(nil), s.ke.b...)), nil
+}
+
+func (s *SPAKE2) wipe_scalars() {
+	if s.x != nil {
+		wipe_int(s.x)
+		wipe_int(s.w)
+		s.x, s.w = nil, nil
+	}
+}
+
+// Wipes the session's scalars and keys; the exchange cannot continue after it.
+func (s *SPAKE2) Destroy() {
+	s.wipe_scalars()
+	if s.ke != nil {
+		s.ke.Wipe()
+	}
+	s.done = true
+}
@@ -0,0 +1,125 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"testing"
+
+	"sig/edwards"
+)
+
+/*
+Tests SPAKE2 on every Edwards curve: both parties derive the same key and
+confirm each other, a wrong password, identity or AAD fails confirmation
+on both sides, malformed shares are refused, and steps out of order fail.
+*/
+func TestSPAKE2Exchange(t *testing.T) {
+	pw := []byte("1234")
+	idA, idB := []byte("alice"), []byte("bob")
+	exchange := func(c *edwards.Curve, pwB, idBofB, aadB []byte) (*SecretBytes, *SecretBytes, error, error) {
+		A, err := NewSPAKE2A(c, pw, idA, idB, []byte("aad"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		B, err := NewSPAKE2B(c, pwB, idA, idBofB, aadB)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cA, errA := A.Finish(B.Message())
+		cB, errB := B.Finish(A.Message())
+		if errA != nil || errB != nil {
+			return nil, nil, errA, errB
+		}
+		keyA, errA := A.Confirm(cB)
+		keyB, errB := B.Confirm(cA)
+		return keyA, keyB, errA, errB
+	}
+	for _, c := range []*edwards.Curve{e222Curve, e521Curve, ed25519Curve, ed448Curve} {
+		keyA, keyB, errA, errB := exchange(c, pw, idB, []byte("aad"))
+		if errA != nil || errB != nil {
+			t.Fatalf("%s: %v, %v", c.Name(), errA, errB)
+		}
+		if len(keyA.b) != SPAKE2KeySize || !bytes.Equal(keyA.b, keyB.b) {
+			t.Fatalf("%s: keys %x and %x", c.Name(), keyA.b, keyB.b)
+		}
+
+		mismatches := []struct {
+			name           string
+			pwB, idB, aadB []byte
+		}{
+			{"password", []byte("1235"), idB, []byte("aad")},
+			{"identity", pw, []byte("mallory"), []byte("aad")},
+			{"AAD", pw, idB, []byte("other")},
+		}
+		for _, m := range mismatches {
+			_, _, errA, errB := exchange(c, m.pwB, m.idB, m.aadB)
+			if errA != ErrSPAKE2Confirmation || errB != ErrSPAKE2Confirmation {
+				t.Fatalf("%s wrong %s: %v, %v, want %v", c.Name(), m.name, errA, errB, ErrSPAKE2Confirmation)
+			}
+		}
+
+		// shares differ between sessions and between the two roles
+		A1, err := NewSPAKE2A(c, pw, idA, idB, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		A2, err := NewSPAKE2A(c, pw, idA, idB, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Equal(A1.Message(), A2.Message()) {
+			t.Fatalf("%s: two sessions send the same share", c.Name())
+		}
+		M, N := spake2_points(c)
+		if !M.InPrimeSubgroup() || !N.InPrimeSubgroup() || M.Equals(N) {
+			t.Fatalf("%s: M and N are equal or outside the prime order subgroup", c.Name())
+		}
+	}
+
+	// malformed shares, and steps out of order or repeated
+	A, err := NewSPAKE2A(e222Curve, pw, idA, idB, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	B, err := NewSPAKE2B(e222Curve, pw, idA, idB, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := A.Confirm(make([]byte, SPAKE2MACLength)); err != ErrSPAKE2State {
+		t.Fatalf("confirm before finish: %v, want %v", err, ErrSPAKE2State)
+	}
+	if _, err := A.Finish(B.Message()[1:]); err != edwards.ErrNonCanonicalPoint {
+		t.Fatalf("short share: %v, want %v", err, edwards.ErrNonCanonicalPoint)
+	}
+	if _, err := A.Finish(B.Message()); err != ErrSPAKE2State {
+		t.Fatalf("finish after a failure: %v, want %v", err, ErrSPAKE2State)
+	}
+
+	A, err = NewSPAKE2A(e222Curve, pw, idA, idB, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cA, err := A.Finish(B.Message())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cB, err := B.Finish(A.Message())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyB, err := B.Confirm(cA); err != nil || keyB == nil {
+		t.Fatalf("confirm: %v, %v", keyB, err)
+	}
+	if _, err := B.Confirm(cA); err != ErrSPAKE2State {
+		t.Fatalf("second confirm: %v, want %v", err, ErrSPAKE2State)
+	}
+	bad := append([]byte(nil), cB...)
+	bad[0] ^= 1
+	if _, err := A.Confirm(bad); err != ErrSPAKE2Confirmation {
+		t.Fatalf("altered confirmation: %v, want %v", err, ErrSPAKE2Confirmation)
+	}
+	if _, err := A.Confirm(cB); err != ErrSPAKE2State {
+		t.Fatalf("confirm after a failure: %v, want %v", err, ErrSPAKE2State)
+	}
+}
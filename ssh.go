@@ -0,0 +1,319 @@
+package sig
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"strings"
+
+	"sig/edwards"
+)
+
+const (
+	sshKeyEd25519      = "ssh-ed25519"
+	sshKeyECDSAP256    = "ecdsa-sha2-nistp256"
+	sshSignatureMagic  = "SSHSIG"
+	sshSignatureType   = "SSH SIGNATURE"
+	sshSignatureV1     = 1
+	sshDefaultHashName = "sha512"
+)
+
+var (
+	ErrSSHEncoding  = errors.New("ssh: malformed or unsupported key or signature")
+	ErrSSHSignature = errors.New("ssh: signature verification failed")
+)
+
+/*
+Encodes a public key as a line of an OpenSSH authorized_keys file,
+
+	type base64(blob) comment
+
+with the key blob of RFC 5656 Section 3.1 for an *ecdsa.PublicKey on
+P-256, "ecdsa-sha2-nistp256", or of RFC 8709 Section 4 for an
+*edwards.Point on edwards25519, "ssh-ed25519". Other curves fail with
+ErrUnsupportedAlgorithm: SSH pairs P-384 and P-521 with SHA-384 and
+SHA-512, which the package's ECDSA code does not sign with.
+*/
+func MarshalAuthorizedKey(pub interface{}, comment string) ([]byte, error) {
+	if strings.ContainsAny(comment, "\r\n") {
+		return nil, errors.New("ssh: comment contains a line break")
+	}
+	blob, err := ssh_public_blob(pub)
+	if err != nil {
+		return nil, err
+	}
+	line := ssh_key_type(blob) + " " + base64.StdEncoding.EncodeToString(blob)
+	if comment != "" {
+		line += " " + comment
+	}
+	return []byte(line + "\n"), nil
+}
+
+/*
+Decodes the first key line of an authorized_keys file, skipping blank
+lines and comments, into an *ecdsa.PublicKey or *edwards.Point and the
+comment. Leading options, as in from="…" ssh-ed25519 …, are not read.
+*/
+func ParseAuthorizedKey(data []byte) (interface{}, string, error) {
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, "", ErrSSHEncoding
+		}
+		blob, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil || ssh_key_type(blob) != fields[0] {
+			return nil, "", ErrSSHEncoding
+		}
+		pub, err := parse_ssh_public_blob(blob)
+		if err != nil {
+			return nil, "", err
+		}
+		return pub, strings.Join(fields[2:], " "), nil
+	}
+	return nil, "", ErrSSHEncoding
+}
+
+// The key blob of a supported public key, see MarshalAuthorizedKey.
+func ssh_public_blob(pub interface{}) ([]byte, error) {
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		if key == nil || key.Curve != elliptic.P256() {
+			return nil, ErrUnsupportedAlgorithm
+		}
+		if key.X == nil || key.Y == nil || !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, edwards.ErrInvalidPoint
+		}
+		b := ssh_string(nil, []byte(sshKeyECDSAP256))
+		b = ssh_string(b, []byte("nistp256"))
+		return ssh_string(b, uncompressed_point(key.X, key.Y, p256Width)), nil
+	case *edwards.Point:
+		if key == nil || key.Curve() != ed25519Curve {
+			return nil, ErrUnsupportedAlgorithm
+		}
+		if err := validate_public_key(key); err != nil {
+			return nil, err
+		}
+		return ssh_string(ssh_string(nil, []byte(sshKeyEd25519)), key.EncodeRFC8032()), nil
+	}
+	return nil, ErrUnsupportedAlgorithm
+}
+
+func parse_ssh_public_blob(blob []byte) (interface{}, error) {
+	r := &sshReader{b: blob}
+	switch string(r.string()) {
+	case sshKeyECDSAP256:
+		curve, point := r.string(), r.string()
+		if !r.done() || string(curve) != "nistp256" {
+			return nil, ErrSSHEncoding
+		}
+		x, y, err := parse_uncompressed_point(point, p256Width)
+		if err != nil {
+			return nil, ErrSSHEncoding
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, edwards.ErrInvalidPoint
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	case sshKeyEd25519:
+		raw := r.string()
+		if !r.done() {
+			return nil, ErrSSHEncoding
+		}
+		A, err := ed25519Curve.DecodeRFC8032(raw)
+		if err != nil {
+			return nil, err
+		}
+		if err := validate_public_key(A); err != nil {
+			return nil, err
+		}
+		return A, nil
+	}
+	return nil, ErrSSHEncoding
+}
+
+// The leading key type string of a key blob, "" if there is none.
+func ssh_key_type(blob []byte) string {
+	return string((&sshReader{b: blob}).string())
+}
+
+/*
+An SSH signature of the format of ssh-keygen -Y sign, PROTOCOL.sshsig of
+OpenSSH. The signer signs, with SSH's signature for its key type,
+
+	"SSHSIG" || string(namespace) || string("") ||
+	string(hash algorithm) || string(H(message))
+
+and the signature is armored as "SSH SIGNATURE" from
+
+	"SSHSIG" || uint32(1) || string(public key blob) ||
+	string(namespace) || string("") || string(hash algorithm) ||
+	string(signature)
+
+with string a 4 byte big endian length and its bytes. The namespace,
+"file" or "git" for instance, keeps signatures made for one purpose from
+being taken for another.
+*/
+type sshSignature struct {
+	publicKey []byte
+	namespace string
+	hash      string
+	signature []byte
+}
+
+// The bytes the key signs, see sshSignature.
+func ssh_signed_data(namespace, hash string, msg []byte) ([]byte, error) {
+	var digest []byte
+	switch hash {
+	case "sha512":
+		d := sha512.Sum512(msg)
+		digest = d[:]
+	case "sha256":
+		d := sha256.Sum256(msg)
+		digest = d[:]
+	default:
+		return nil, ErrSSHEncoding
+	}
+	b := ssh_string([]byte(sshSignatureMagic), []byte(namespace))
+	b = ssh_string(b, nil)
+	b = ssh_string(b, []byte(hash))
+	return ssh_string(b, digest), nil
+}
+
+func (sig *sshSignature) armor() []byte {
+	b := binary.BigEndian.AppendUint32([]byte(sshSignatureMagic), sshSignatureV1)
+	b = ssh_string(b, sig.publicKey)
+	b = ssh_string(b, []byte(sig.namespace))
+	b = ssh_string(b, nil)
+	b = ssh_string(b, []byte(sig.hash))
+	b = ssh_string(b, sig.signature)
+	return pem.EncodeToMemory(&pem.Block{Type: sshSignatureType, Bytes: b})
+}
+
+func parse_ssh_signature(armored []byte) (*sshSignature, error) {
+	block, _ := pem.Decode(armored)
+	if block == nil || block.Type != sshSignatureType || !bytes.HasPrefix(block.Bytes, []byte(sshSignatureMagic)) {
+		return nil, ErrSSHEncoding
+	}
+	r := &sshReader{b: block.Bytes[len(sshSignatureMagic):]}
+	version := r.uint32()
+	sig := &sshSignature{publicKey: r.string(), namespace: string(r.string())}
+	reserved := r.string()
+	sig.hash = string(r.string())
+	sig.signature = r.string()
+	if !r.done() || version != sshSignatureV1 || len(reserved) != 0 {
+		return nil, ErrSSHEncoding
+	}
+	return sig, nil
+}
+
+/*
+Verifies an armored SSH signature over msg, as ssh-keygen -Y verify does:
+the signature must be by pub, an *ecdsa.PublicKey or *edwards.Point as
+MarshalAuthorizedKey takes, and made in namespace. Returns nil if it
+holds, ErrSSHSignature if it does not, and ErrSSHEncoding for malformed
+signatures.
+*/
+func VerifySSHSignature(pub interface{}, namespace string, msg, armored []byte) error {
+	sig, err := parse_ssh_signature(armored)
+	if err != nil {
+		return err
+	}
+	blob, err := ssh_public_blob(pub)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(blob, sig.publicKey) || sig.namespace != namespace {
+		return ErrSSHSignature
+	}
+	signed, err := ssh_signed_data(sig.namespace, sig.hash, msg)
+	if err != nil {
+		return err
+	}
+
+	r := &sshReader{b: sig.signature}
+	format, raw := string(r.string()), r.string()
+	if !r.done() || format != ssh_key_type(blob) {
+		return ErrSSHEncoding
+	}
+	ok := false
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		rs := &sshReader{b: raw}
+		R, S := rs.mpint(), rs.mpint()
+		if !rs.done() {
+			return ErrSSHEncoding
+		}
+		digest := sha256.Sum256(signed)
+		ok = VerifyDigest(key, digest[:], R, S)
+	case *edwards.Point:
+		ok = VerifyEd25519(key.EncodeRFC8032(), signed, raw)
+	}
+	if !ok {
+		return ErrSSHSignature
+	}
+	return nil
+}
+
+// Appends the SSH string of s, a 4 byte big endian length and s.
+func ssh_string(b, s []byte) []byte {
+	return append(binary.BigEndian.AppendUint32(b, uint32(len(s))), s...)
+}
+
+// Appends the SSH mpint of v ≥ 0 of RFC 4251 Section 5: big endian, a zero byte before a set top bit.
+func ssh_mpint(b []byte, v *big.Int) []byte {
+	m := v.Bytes()
+	if len(m) > 0 && m[0]&0x80 != 0 {
+		m = append([]byte{0}, m...)
+	}
+	return ssh_string(b, m)
+}
+
+// Reads SSH wire encoded values; after the first error every read returns nothing and done is false.
+type sshReader struct {
+	b   []byte
+	err bool
+}
+
+func (r *sshReader) uint32() uint32 {
+	if r.err || len(r.b) < 4 {
+		r.err = true
+		return 0
+	}
+	v := binary.BigEndian.Uint32(r.b)
+	r.b = r.b[4:]
+	return v
+}
+
+func (r *sshReader) string() []byte {
+	n := r.uint32()
+	if r.err || uint32(len(r.b)) < n {
+		r.err = true
+		return nil
+	}
+	s := r.b[:n]
+	r.b = r.b[n:]
+	return s
+}
+
+// A non-negative mpint in its minimal encoding; anything else sets the error.
+func (r *sshReader) mpint() *big.Int {
+	m := r.string()
+	if len(m) > 0 && (m[0]&0x80 != 0 || (m[0] == 0 && (len(m) == 1 || m[1]&0x80 == 0))) {
+		r.err = true
+	}
+	return new(big.Int).SetBytes(m)
+}
+
+// True iff everything was read without error.
+func (r *sshReader) done() bool { return !r.err && len(r.b) == 0 }
@@ -0,0 +1,105 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+
+	"golang.org/x/crypto/ssh"
+)
+
+/*
+Signs msg in namespace as ssh-keygen -Y sign -n namespace does, with
+SHA-512 as the message hash, and returns the armored signature, which
+ssh-keygen -Y verify accepts. The key must be on P-256; the
+"ecdsa-sha2-nistp256" signature is the mpints r and s over the SHA-256
+of the signed data.
+*/
+func (key *ECDSAPrivateKey) SignSSH(namespace string, msg []byte) ([]byte, error) {
+	blob, err := ssh_public_blob(key.PublicKey())
+	if err != nil {
+		return nil, err
+	}
+	signed, err := ssh_signed_data(namespace, sshDefaultHashName, msg)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(signed)
+	r, s, err := key.SignDigest(digest[:])
+	if err != nil {
+		return nil, err
+	}
+	raw := ssh_mpint(ssh_mpint(nil, r), s)
+	return ssh_sign(blob, namespace, ssh_string(ssh_string(nil, []byte(sshKeyECDSAP256)), raw))
+}
+
+// Signs msg in namespace as SignSSH does, with the Ed25519 key of seed.
+func SignSSHEd25519(seed []byte, namespace string, msg []byte) ([]byte, error) {
+	pub, err := Ed25519PublicKey(seed)
+	if err != nil {
+		return nil, err
+	}
+	A, err := ed25519_decode(pub)
+	if err != nil {
+		return nil, err
+	}
+	blob, err := ssh_public_blob(A)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := ssh_signed_data(namespace, sshDefaultHashName, msg)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := SignEd25519(seed, signed)
+	if err != nil {
+		return nil, err
+	}
+	return ssh_sign(blob, namespace, ssh_string(ssh_string(nil, []byte(sshKeyEd25519)), sig))
+}
+
+func ssh_sign(blob []byte, namespace string, signature []byte) ([]byte, error) {
+	if namespace == "" {
+		return nil, errors.New("ssh: signatures need a namespace")
+	}
+	sig := &sshSignature{publicKey: blob, namespace: namespace, hash: sshDefaultHashName, signature: signature}
+	return sig.armor(), nil
+}
+
+/*
+Reads an OpenSSH private key, the "OPENSSH PRIVATE KEY" format of
+ssh-keygen or a PEM EC key, decrypting it with passphrase if it is
+protected; passphrase is ignored otherwise. Returns an *ECDSAPrivateKey
+with the nonce cache enabled, on any curve ssh-keygen writes, or, for
+ssh-ed25519, the 32 byte Ed25519 seed SignEd25519 and SignSSHEd25519
+take. The caller wipes either once done. Other key types fail with
+ErrUnsupportedAlgorithm, a wrong passphrase with ErrDecryption.
+*/
+func ParseOpenSSHPrivateKey(data, passphrase []byte) (interface{}, error) {
+	raw, err := ssh.ParseRawPrivateKey(data)
+	if _, missing := err.(*ssh.PassphraseMissingError); missing {
+		if len(passphrase) == 0 {
+			return nil, ErrDecryption
+		}
+		raw, err = ssh.ParseRawPrivateKeyWithPassphrase(data, passphrase)
+		if errors.Is(err, x509.IncorrectPasswordError) {
+			return nil, ErrDecryption
+		}
+	}
+	if err != nil {
+		return nil, ErrSSHEncoding
+	}
+	switch key := raw.(type) {
+	case *ecdsa.PrivateKey:
+		return FromECDSAPrivateKey(key)
+	case *ed25519.PrivateKey:
+		return append([]byte(nil), key.Seed()...), nil
+	case ed25519.PrivateKey:
+		return append([]byte(nil), key.Seed()...), nil
+	}
+	return nil, ErrUnsupportedAlgorithm
+}
@@ -0,0 +1,115 @@
+package sig
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/big"
+	"os"
+	"strconv"
+
+	"golang.org/x/crypto/sha3"
+
+	"sig/edwards"
+)
+
+// Bytes hashed between progress reports and cancellation checks.
+const verifyChunkSize = 1 << 20
+
+/*
+Verifies an encoded E222 Schnorr signature over the message read from r
+in a single pass. Only the challenge hash depends on the message, and its
+commitment r = s × G + e × 4y is known before the first byte is read, so
+the message is hashed as it streams past. prefix is hashed before the
+stream, as detached_input does. total, the expected message size, is
+passed to reporter.Progress after every chunk; cancelling ctx stops the
+read after the current chunk and returns ctx.Err().
+
+Legacy version 1 and canonical text signatures need the whole message
+and are refused. opts are checked on y as VerifySignature does.
+*/
+func verify_stream_e222(ctx context.Context, y *E222, domain string, encoded []byte, prefix []byte, r io.Reader, total int64, reporter UIReporter, opts ...edwards.DecodeOption) (bool, error) {
+	sig, err := DecodeSignature(encoded)
+	if err != nil {
+		return false, err
+	}
+	if sig.Curve != curveE222 {
+		return false, errors.New("not an E222 signature")
+	}
+	if sig.Version != signatureVersion || sig.is_canonical_text() {
+		return false, errors.New("signature cannot be verified from a stream")
+	}
+	if err := validate_public_key(y); err != nil {
+		return false, err
+	}
+	if err := y.Check(opts...); err != nil {
+		return false, err
+	}
+	if new(big.Int).Mod(sig.E, e222Params.R).Sign() == 0 {
+		return false, nil
+	}
+	R := e222Curve.BaseMul(sig.S).Add(y.ClearCofactor().SecMul(sig.E))
+
+	h, sum := e222_challenge_hasher(domain)
+	h.Write(toFixedBytes(R.X(), e222Width))
+	h.Write(prefix)
+
+	buf := make([]byte, verifyChunkSize)
+	done := int64(0)
+	for {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		n, err := io.ReadFull(r, buf)
+		h.Write(buf[:n])
+		done += int64(n)
+		if n > 0 {
+			reporter.Progress(done, total)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	return fromFixedBytes(sum()).Cmp(sig.E) == 0, nil
+}
+
+/*
+The hash of the version 2 challenge in domain, as e222_challenge computes
+it, for input written a piece at a time: SHA3-256 in the empty domain and
+cSHAKE256 customized by domain otherwise. sum returns the 32 byte digest.
+*/
+func e222_challenge_hasher(domain string) (h io.Writer, sum func() []byte) {
+	if domain == "" {
+		d := sha3.New256()
+		return d, func() []byte { return d.Sum(nil) }
+	}
+	d := sha3.NewCShake256(nil, []byte(domain))
+	return d, func() []byte {
+		out := make([]byte, hashWidth)
+		d.Read(out)
+		return out
+	}
+}
+
+// Verifies an encoded E222 signature over the file at path with verify_stream_e222.
+func verify_file_e222(ctx context.Context, y *E222, domain string, encoded []byte, path string, reporter UIReporter) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	ok, err := verify_stream_e222(ctx, y, domain, encoded, nil, f, info.Size(), reporter)
+	result := strconv.FormatBool(ok)
+	if err != nil {
+		result = err.Error()
+	}
+	reporter.Record(LogEntry{Operation: "verify", Fingerprint: key_id(y), Subject: path, Result: result})
+	return ok, err
+}
@@ -0,0 +1,144 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Streaming verification agrees with VerifySignature, in and out of a domain.
+func TestStreamVerify(t *testing.T) {
+
+	msg := make([]byte, 3*verifyChunkSize+123)
+	rand.Read(msg)
+	x := NewSecretScalar(generateRandomBigInt())
+	x_int, err := x.Int()
+	if err != nil {
+		t.Fatal(err)
+	}
+	y := e222Curve.GenPoint().SecMul(x_int)
+
+	for _, domain := range []string{"", "stream"} {
+		sig, err := sign_text_e222(x, domain, msg, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reporter := &progressCounter{}
+		ok, err := verify_stream_e222(context.Background(), y, domain, sig, nil, bytes.NewReader(msg), int64(len(msg)), reporter)
+		if !ok || err != nil {
+			t.Fatalf("domain %q: %v, %v", domain, ok, err)
+		}
+		if reporter.calls != 4 || reporter.last != int64(len(msg)) {
+			t.Fatalf("domain %q: %d progress calls, last at %d, want 4 up to %d", domain, reporter.calls, reporter.last, len(msg))
+		}
+
+		tampered := append([]byte{}, msg...)
+		tampered[len(tampered)-1] ^= 1
+		ok, err = verify_stream_e222(context.Background(), y, domain, sig, nil, bytes.NewReader(tampered), int64(len(msg)), reporter)
+		if ok || err != nil {
+			t.Fatalf("domain %q, tampered: %v, %v", domain, ok, err)
+		}
+	}
+
+	// detached signatures stream their metadata as prefix
+	input := detached_input("doc.bin", "fp", msg)
+	s, e, err := sign_with_key_e222(x, "", &input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := EncodeSignature(&SchnorrSignature{Curve: curveE222, S: s, E: e})
+	ok, err := verify_stream_e222(context.Background(), y, "", sig, detached_input("doc.bin", "fp", nil), bytes.NewReader(msg), 0, &progressCounter{})
+	if !ok || err != nil {
+		t.Fatalf("detached: %v, %v", ok, err)
+	}
+
+	// canonical text needs the whole message
+	text, err := sign_text_e222(x, "", []byte("text"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verify_stream_e222(context.Background(), y, "", text, nil, strings.NewReader("text"), 4, &progressCounter{}); err == nil {
+		t.Fatal("canonical text signature streams")
+	}
+}
+
+// Reporter cancelling its context after the first progress report.
+type cancellingReporter struct {
+	progressCounter
+	cancel context.CancelFunc
+}
+
+func (c *cancellingReporter) Progress(done, total int64) {
+	c.progressCounter.Progress(done, total)
+	c.cancel()
+}
+
+// A 1 GB sparse file is read exactly once, and cancellation stops within one chunk.
+func TestStreamVerifyLargeFile(t *testing.T) {
+
+	f, err := os.CreateTemp(t.TempDir(), "large")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	const size = 1 << 30
+	if err := f.Truncate(size); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := []byte("unrelated")
+	y, s, e := sign_message_e222(&msg)
+	sig := EncodeSignature(&SchnorrSignature{Curve: curveE222, S: s, E: e})
+
+	counter := &countingReader{r: f}
+	reporter := &progressCounter{}
+	ok, err := verify_stream_e222(context.Background(), y, "", sig, nil, counter, size, reporter)
+	if ok || err != nil {
+		t.Fatalf("unrelated signature: %v, %v", ok, err)
+	}
+	if counter.n != size || reporter.last != size || reporter.calls != size/verifyChunkSize {
+		t.Fatalf("read %d bytes, %d progress calls, last at %d, want %d bytes in %d calls",
+			counter.n, reporter.calls, reporter.last, size, size/verifyChunkSize)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	counter = &countingReader{r: f}
+	cancelling := &cancellingReporter{cancel: cancel}
+	if _, err := verify_stream_e222(ctx, y, "", sig, nil, counter, size, cancelling); !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled: %v, want %v", err, context.Canceled)
+	}
+	if counter.n != verifyChunkSize || cancelling.calls != 1 {
+		t.Fatalf("cancelled after %d bytes and %d progress calls, want %d and 1", counter.n, cancelling.calls, verifyChunkSize)
+	}
+
+	// through the file helper, which logs the outcome
+	ok, err = verify_file_e222(context.Background(), y, "", sig, f.Name(), reporter)
+	if ok || err != nil {
+		t.Fatalf("verify_file_e222 = %v, %v", ok, err)
+	}
+	if n := len(reporter.Entries()); n != 1 {
+		t.Fatalf("%d log entries, want 1", n)
+	}
+}
@@ -0,0 +1,78 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+
+	"sig/kmac"
+)
+
+const (
+	symmetricVersion     = 0x01
+	symmetricNonceLength = 64 // bytes of z
+	symmetricTagLength   = 64 // bytes of t
+)
+
+/*
+Encrypts msg under the passphrase pw with the KMACXOF256 authenticated
+encryption scheme, which needs no key pair:
+
+	z ← random 512 bits
+	(ke || ka) ← KMACXOF256(z || NFC(pw), "", 1024, "S")
+	c ← KMACXOF256(ke, "", |m|, "SKE") ⊕ m
+	t ← KMACXOF256(ka, m, 512, "SKA")
+
+and returns version (1 byte) || z || c || t. The fresh z gives every
+message its own keys, so a passphrase can protect any number of files.
+Weak passphrases are rejected with ErrWeakPassphrase, see GenerateKeyPair.
+*/
+func EncryptWithPassphrase(pw, msg []byte) ([]byte, error) {
+	if err := check_passphrase(pw, DefaultMinPassphraseLength); err != nil {
+		return nil, err
+	}
+	z := make([]byte, symmetricNonceLength)
+	if _, err := rand.Read(z); err != nil {
+		return nil, err
+	}
+	keys := symmetric_keys(z, pw)
+	defer keys.Wipe()
+	ke, ka := split_keys(keys.b)
+
+	out := append([]byte{symmetricVersion}, z...)
+	out = append(out, xor_bytes(kmac.XOF256(ke, nil, 8*len(msg), "SKE"), msg)...)
+	return append(out, kmac.XOF256(ka, msg, 8*symmetricTagLength, "SKA")...), nil
+}
+
+/*
+Decrypts a cryptogram of EncryptWithPassphrase under pw. A wrong
+passphrase and any change to the cryptogram fail alike with
+ErrDecryption, and no plaintext is released.
+*/
+func DecryptWithPassphrase(pw, cryptogram []byte) ([]byte, error) {
+	if len(cryptogram) < 1+symmetricNonceLength+symmetricTagLength || cryptogram[0] != symmetricVersion {
+		return nil, errors.New("unsupported cryptogram format")
+	}
+	z := cryptogram[1 : 1+symmetricNonceLength]
+	c := cryptogram[1+symmetricNonceLength : len(cryptogram)-symmetricTagLength]
+	t := cryptogram[len(cryptogram)-symmetricTagLength:]
+
+	keys := symmetric_keys(z, pw)
+	defer keys.Wipe()
+	ke, ka := split_keys(keys.b)
+	m := xor_bytes(kmac.XOF256(ke, nil, 8*len(c), "SKE"), c)
+	if subtle.ConstantTimeCompare(t, kmac.XOF256(ka, m, 8*symmetricTagLength, "SKA")) != 1 {
+		wipe_bytes(m)
+		return nil, ErrDecryption
+	}
+	return m, nil
+}
+
+// ke || ka ← KMACXOF256(z || NFC(pw), "", 1024, "S") as SecretBytes for the caller to wipe.
+func symmetric_keys(z, pw []byte) *SecretBytes {
+	key := append(append([]byte{}, z...), normalize_passphrase(pw)...)
+	defer wipe_bytes(key)
+	return NewSecretBytes(kmac.XOF256(key, nil, 1024, "S"))
+}
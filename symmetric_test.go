@@ -0,0 +1,80 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestPassphraseEncryption(t *testing.T) {
+
+	pw := []byte("correct horse battery staple")
+	for _, msg := range [][]byte{nil, []byte("the file contents"), make([]byte, 5000)} {
+		ct, err := EncryptWithPassphrase(pw, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := 1 + symmetricNonceLength + len(msg) + symmetricTagLength; len(ct) != want {
+			t.Fatalf("cryptogram of %d bytes, want %d", len(ct), want)
+		}
+		pt, err := DecryptWithPassphrase(pw, ct)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(pt, msg) {
+			t.Fatalf("decrypted %x, want %x", pt, msg)
+		}
+	}
+
+	msg := []byte("the file contents")
+	ct, err := EncryptWithPassphrase(pw, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := EncryptWithPassphrase(pw, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(ct, again) {
+		t.Fatal("encryption is deterministic")
+	}
+
+	// the passphrase is normalized: composed and decomposed forms agree
+	composed, decomposed := []byte("caf\u00e9 au lait, merci"), []byte("cafe\u0301 au lait, merci")
+	ct2, err := EncryptWithPassphrase(composed, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt, err := DecryptWithPassphrase(decomposed, ct2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pt, msg) {
+		t.Fatalf("decrypted %q, want %q", pt, msg)
+	}
+
+	// wrong passphrases and changes anywhere fail to authenticate
+	if _, err := DecryptWithPassphrase([]byte("incorrect horse battery staple"), ct); err != ErrDecryption {
+		t.Fatalf("wrong passphrase: %v, want %v", err, ErrDecryption)
+	}
+	for _, i := range []int{1, 1 + symmetricNonceLength, len(ct) - 1} {
+		tampered := append([]byte(nil), ct...)
+		tampered[i] ^= 1
+		if _, err := DecryptWithPassphrase(pw, tampered); err != ErrDecryption {
+			t.Fatalf("byte %d changed: %v, want %v", i, err, ErrDecryption)
+		}
+	}
+
+	// weak passphrases, other versions and truncation are refused
+	if _, err := EncryptWithPassphrase([]byte("short"), msg); !errors.Is(err, ErrWeakPassphrase) {
+		t.Fatalf("weak passphrase: %v, want %v", err, ErrWeakPassphrase)
+	}
+	if _, err := DecryptWithPassphrase(pw, append([]byte{0x02}, ct[1:]...)); err == nil || err == ErrDecryption {
+		t.Fatalf("other version: %v, want a format error", err)
+	}
+	if _, err := DecryptWithPassphrase(pw, ct[:symmetricNonceLength+symmetricTagLength]); err == nil || err == ErrDecryption {
+		t.Fatalf("truncated cryptogram: %v, want a format error", err)
+	}
+}
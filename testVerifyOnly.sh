@@ -0,0 +1,13 @@
+#!/bin/bash
+# Builds the verify-only configuration, checks that no signing code was
+# linked in, and runs the verification tests in both configurations.
+set -e
+go build -tags verifyonly -o secp256r1_ecdsa_verifyonly ./cmd/secp256r1_ecdsa
+if go tool nm secp256r1_ecdsa_verifyonly | grep -E 'sig\.(sign_|generate_key|encrypt_|SignJWS)'; then
+	echo "signing symbols found in verify-only build"
+	rm -f secp256r1_ecdsa_verifyonly
+	exit 1
+fi
+rm -f secp256r1_ecdsa_verifyonly
+go test -tags verifyonly ./...
+go test ./...
@@ -0,0 +1,352 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+)
+
+var (
+	ErrTPMUnavailable = errors.New("tpm: no TPM 2.0 device available")
+	ErrTPMAuth        = errors.New("tpm: owner hierarchy authorization failed")
+	ErrTPMSignature   = errors.New("tpm: TPM returned an invalid signature")
+	ErrTPMResponse    = errors.New("tpm: malformed response")
+)
+
+// Constants of the TPM 2.0 library specification, part 2.
+const (
+	tpmSTNoSessions = 0x8001
+	tpmSTSessions   = 0x8002
+	tpmSTHashcheck  = 0x8024
+
+	tpmCCCreatePrimary = 0x131
+	tpmCCSign          = 0x15d
+	tpmCCFlushContext  = 0x165
+
+	tpmRHOwner = 0x40000001
+	tpmRHNull  = 0x40000007
+	tpmRSPW    = 0x40000009
+
+	tpmAlgECC    = 0x0023
+	tpmAlgSHA256 = 0x000b
+	tpmAlgNull   = 0x0010
+	tpmAlgECDSA  = 0x0018
+	tpmECCP256   = 0x0003
+
+	// fixedTPM | fixedParent | sensitiveDataOrigin | userWithAuth | sign
+	tpmKeyAttributes = 1<<1 | 1<<4 | 1<<5 | 1<<6 | 1<<18
+)
+
+// Longest response read from the device, TPM_MAX_COMMAND_SIZE of common TPMs.
+const tpmMaxResponse = 4096
+
+/*
+The link to a TPM: transmit sends one marshalled command and returns the
+response, whose header it has checked for length only.
+*/
+type tpmTransport interface {
+	transmit(cmd []byte) ([]byte, error)
+	close() error
+}
+
+/*
+A TPM 2.0 holding machine identity keys: ECDSA P-256 keys that are
+created inside the TPM and never leave it, not even encrypted. Each key
+is a primary key of the owner hierarchy, derived by the TPM from its
+secret owner seed and the key's name, so the same name gives the same key
+on the same TPM, surviving reboots, with nothing stored on disk, and
+no other TPM can produce it. Clearing the TPM destroys them.
+*/
+type TPM struct {
+	mu        sync.Mutex
+	transport tpmTransport
+	ownerAuth []byte
+	keys      []*TPMKey
+}
+
+/*
+A key of TPM.Key, implementing crypto.Signer like ECDSAPrivateKey.Signer:
+digests are SHA-256 and signatures ASN.1 DER.
+*/
+type TPMKey struct {
+	Name   string
+	LowS   bool // emit n − s for s > n/2, as VerifyOptions.RejectHighS requires
+	tpm    *TPM
+	handle uint32
+	pub    *ecdsa.PublicKey
+}
+
+/*
+Opens the TPM of this machine, /dev/tpmrm0 on Linux, with the
+authorization value of its owner hierarchy, empty unless the machine's
+owner has set one. Fails with ErrTPMUnavailable where there is none or
+the platform is not supported; access to the device usually takes
+membership of the tss group.
+*/
+func OpenTPM(ownerAuth []byte) (*TPM, error) {
+	transport, err := platform_tpm()
+	if err != nil {
+		return nil, err
+	}
+	return &TPM{transport: transport, ownerAuth: append([]byte{}, ownerAuth...)}, nil
+}
+
+/*
+Loads the key called name, creating it in the TPM the first time: a
+primary ECDSA P-256 signing key whose template carries SHA-256 of name
+as its unique field. Wrong owner authorization fails with ErrTPMAuth.
+The key stays loaded until Close.
+*/
+func (t *TPM) Key(name string) (*TPMKey, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.transport == nil {
+		return nil, errors.New("tpm: closed")
+	}
+	for _, key := range t.keys {
+		if key.Name == name {
+			return key, nil
+		}
+	}
+	unique := sha256.Sum256([]byte(name))
+	params := tpm2b(nil, []byte{0, 0, 0, 0}) // TPM2B_SENSITIVE_CREATE, empty auth and data
+	params = tpm2b(params, tpm_key_template(unique[:]))
+	params = tpm2b(params, nil)                       // outsideInfo
+	params = binary.BigEndian.AppendUint32(params, 0) // creationPCR, no PCRs
+	resp, err := t.command(tpmCCCreatePrimary, []uint32{tpmRHOwner}, t.ownerAuth, params)
+	if err != nil {
+		return nil, err
+	}
+	handle := resp.uint32()
+	resp.uint32() // parameterSize
+	pub := tpm_public_key(&tpmReader{b: resp.tpm2b()})
+	if resp.err || pub == nil {
+		t.flush(handle)
+		return nil, ErrTPMResponse
+	}
+	key := &TPMKey{Name: name, tpm: t, handle: handle, pub: pub}
+	t.keys = append(t.keys, key)
+	return key, nil
+}
+
+/*
+The TPMT_PUBLIC template of identity keys: an unrestricted ECDSA
+SHA-256 signing key on P-256, without a policy, whose unique x is the
+hash of its name.
+*/
+func tpm_key_template(unique []byte) []byte {
+	out := binary.BigEndian.AppendUint16(nil, tpmAlgECC)
+	out = binary.BigEndian.AppendUint16(out, tpmAlgSHA256)
+	out = binary.BigEndian.AppendUint32(out, tpmKeyAttributes)
+	out = tpm2b(out, nil) // authPolicy
+	for _, v := range []uint16{tpmAlgNull, tpmAlgECDSA, tpmAlgSHA256, tpmECCP256, tpmAlgNull} {
+		out = binary.BigEndian.AppendUint16(out, v) // symmetric, scheme and hash, curve, kdf
+	}
+	return tpm2b(tpm2b(out, unique), nil)
+}
+
+/*
+The P-256 public key in a TPMT_PUBLIC, nil unless it is an ECC key on
+that curve with a point on it.
+*/
+func tpm_public_key(r *tpmReader) *ecdsa.PublicKey {
+	if r.uint16() != tpmAlgECC {
+		return nil
+	}
+	r.uint16() // nameAlg
+	r.uint32() // objectAttributes
+	r.tpm2b()  // authPolicy
+	if sym := r.uint16(); sym != tpmAlgNull {
+		r.uint16() // keyBits
+		r.uint16() // mode
+	}
+	if scheme := r.uint16(); scheme != tpmAlgNull {
+		r.uint16() // hashAlg
+	}
+	curve := r.uint16()
+	if kdf := r.uint16(); kdf != tpmAlgNull {
+		r.uint16() // hashAlg
+	}
+	x, y := r.tpm2b(), r.tpm2b()
+	if r.err || curve != tpmECCP256 || len(x) > 32 || len(y) > 32 {
+		return nil
+	}
+	X, Y := new(big.Int).SetBytes(x), new(big.Int).SetBytes(y)
+	if !elliptic.P256().IsOnCurve(X, Y) {
+		return nil
+	}
+	return &ecdsa.PublicKey{Curve: elliptic.P256(), X: X, Y: Y}
+}
+
+// Flushes the loaded keys and closes the device; the keys no longer sign.
+func (t *TPM) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.transport == nil {
+		return nil
+	}
+	for _, key := range t.keys {
+		t.flush(key.handle)
+	}
+	t.keys = nil
+	wipe_bytes(t.ownerAuth)
+	err := t.transport.close()
+	t.transport = nil
+	return err
+}
+
+func (t *TPM) flush(handle uint32) {
+	t.command(tpmCCFlushContext, nil, nil, binary.BigEndian.AppendUint32(nil, handle))
+}
+
+/*
+Sends a command with handles and parameters and returns the reader at
+the response's handle area. Commands with an auth value, nil for none,
+carry one password session for the first handle.
+*/
+func (t *TPM) command(code uint32, handles []uint32, auth []byte, params []byte) (*tpmReader, error) {
+	tag := uint16(tpmSTNoSessions)
+	body := binary.BigEndian.AppendUint32(nil, code)
+	for _, h := range handles {
+		body = binary.BigEndian.AppendUint32(body, h)
+	}
+	if auth != nil {
+		tag = tpmSTSessions
+		session := binary.BigEndian.AppendUint32(nil, tpmRSPW)
+		session = tpm2b(session, nil) // nonce
+		session = append(session, 0)  // sessionAttributes
+		session = tpm2b(session, auth)
+		body = binary.BigEndian.AppendUint32(body, uint32(len(session)))
+		body = append(body, session...)
+		wipe_bytes(session)
+	}
+	body = append(body, params...)
+	cmd := binary.BigEndian.AppendUint16(nil, tag)
+	cmd = binary.BigEndian.AppendUint32(cmd, uint32(6+len(body)))
+	cmd = append(cmd, body...)
+	wipe_bytes(body)
+	defer wipe_bytes(cmd)
+	resp, err := t.transport.transmit(cmd)
+	if err != nil {
+		return nil, err
+	}
+	r := &tpmReader{b: resp}
+	r.uint16() // tag
+	r.uint32() // responseSize
+	rc := r.uint32()
+	if r.err {
+		return nil, ErrTPMResponse
+	}
+	if rc != 0 {
+		return nil, tpm_error(code, rc)
+	}
+	return r, nil
+}
+
+/*
+The error of response code rc. TPM_RC_AUTH_FAIL and TPM_RC_BAD_AUTH,
+format-one codes that also carry the session number, become ErrTPMAuth.
+*/
+func tpm_error(code, rc uint32) error {
+	if rc&0x80 != 0 && (rc&0xbf == 0x8e || rc&0xbf == 0xa2) {
+		return ErrTPMAuth
+	}
+	return fmt.Errorf("tpm: command 0x%x failed with TPM_RC 0x%x", code, rc)
+}
+
+func (key *TPMKey) Public() crypto.PublicKey { return key.pub }
+
+// The fingerprint of the key's public key, see Fingerprint.
+func (key *TPMKey) Fingerprint() Fingerprint {
+	return fingerprint_of(uncompressed_point(key.pub.X, key.pub.Y, 32))
+}
+
+/*
+Has the TPM sign the SHA-256 digest. The signature is checked against the
+public key before it is returned, so a faulty TPM fails with
+ErrTPMSignature rather than handing out a bad signature.
+*/
+func (key *TPMKey) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if opts.HashFunc() != crypto.SHA256 || len(digest) != 32 {
+		return nil, ErrSignerHash
+	}
+	params := tpm2b(nil, digest)
+	params = binary.BigEndian.AppendUint16(params, tpmAlgNull) // the key's own scheme
+	params = binary.BigEndian.AppendUint16(params, tpmSTHashcheck)
+	params = binary.BigEndian.AppendUint32(params, tpmRHNull) // no ticket, the key is unrestricted
+	params = tpm2b(params, nil)
+	t := key.tpm
+	t.mu.Lock()
+	if t.transport == nil {
+		t.mu.Unlock()
+		return nil, errors.New("tpm: closed")
+	}
+	resp, err := t.command(tpmCCSign, []uint32{key.handle}, []byte{}, params)
+	t.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	resp.uint32() // parameterSize
+	alg := resp.uint16()
+	resp.uint16() // hash
+	sig_r, sig_s := resp.tpm2b(), resp.tpm2b()
+	if resp.err || alg != tpmAlgECDSA || len(sig_r) > 32 || len(sig_s) > 32 {
+		return nil, ErrTPMResponse
+	}
+	r, s := new(big.Int).SetBytes(sig_r), new(big.Int).SetBytes(sig_s)
+	if key.LowS && !is_low_s(elliptic.P256(), s) {
+		s.Sub(elliptic.P256().Params().N, s)
+	}
+	if !verify_ecdsa_digest(key.pub, digest, r, s) {
+		return nil, ErrTPMSignature
+	}
+	return marshal_ecdsa_der(r, s)
+}
+
+// Appends b as a TPM2B, with a 2 byte length prefix.
+func tpm2b(out, b []byte) []byte {
+	return append(binary.BigEndian.AppendUint16(out, uint16(len(b))), b...)
+}
+
+// Reads big-endian TPM structures; like sshReader, it records the first overrun.
+type tpmReader struct {
+	b   []byte
+	err bool
+}
+
+func (r *tpmReader) next(n int) []byte {
+	if r.err || len(r.b) < n {
+		r.err = true
+		return nil
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *tpmReader) uint16() uint16 {
+	if v := r.next(2); v != nil {
+		return binary.BigEndian.Uint16(v)
+	}
+	return 0
+}
+
+func (r *tpmReader) uint32() uint32 {
+	if v := r.next(4); v != nil {
+		return binary.BigEndian.Uint32(v)
+	}
+	return 0
+}
+
+func (r *tpmReader) tpm2b() []byte { return r.next(int(r.uint16())) }
+
+func (r *tpmReader) done() bool { return !r.err && len(r.b) == 0 }
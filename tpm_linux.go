@@ -0,0 +1,43 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"errors"
+	"os"
+)
+
+// The kernel's TPM device, through its resource manager where there is one.
+type deviceTPM struct{ f *os.File }
+
+func platform_tpm() (tpmTransport, error) {
+	for _, path := range []string{"/dev/tpmrm0", "/dev/tpm0"} {
+		f, err := os.OpenFile(path, os.O_RDWR, 0)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &deviceTPM{f: f}, nil
+	}
+	return nil, ErrTPMUnavailable
+}
+
+// The device takes one command per write and answers it with one read.
+func (d *deviceTPM) transmit(cmd []byte) ([]byte, error) {
+	if _, err := d.f.Write(cmd); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, tpmMaxResponse)
+	n, err := d.f.Read(resp)
+	if err != nil {
+		return nil, err
+	}
+	if n < 10 {
+		return nil, ErrTPMResponse
+	}
+	return resp[:n], nil
+}
+
+func (d *deviceTPM) close() error { return d.f.Close() }
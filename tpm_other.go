@@ -0,0 +1,7 @@
+//go:build !verifyonly && !linux
+
+package sig
+
+func platform_tpm() (tpmTransport, error) {
+	return nil, ErrTPMUnavailable
+}
@@ -0,0 +1,244 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// A tpmTransport in memory, standing in for a TPM; its primary keys derive from seed and the template's unique field.
+type memoryTPM struct {
+	seed      []byte
+	ownerAuth []byte
+	keys      map[uint32]*ecdsa.PrivateKey
+	next      uint32
+	faulty    bool
+	closed    bool
+}
+
+func (m *memoryTPM) transmit(cmd []byte) ([]byte, error) {
+	r := &tpmReader{b: cmd}
+	tag, size, code := r.uint16(), r.uint32(), r.uint32()
+	if int(size) != len(cmd) || (code == tpmCCFlushContext) != (tag == tpmSTNoSessions) {
+		return tpm_test_response(0x1e, nil), nil // TPM_RC_BAD_TAG
+	}
+	if code == tpmCCFlushContext {
+		handle := r.uint32()
+		if _, ok := m.keys[handle]; !ok || !r.done() {
+			return tpm_test_response(0x18b, nil), nil // TPM_RC_HANDLE
+		}
+		delete(m.keys, handle)
+		return tpm_test_response(0, nil), nil
+	}
+	handle := r.uint32()
+	r.uint32()                                        // authorizationSize
+	session, _, _ := r.uint32(), r.tpm2b(), r.next(1) // handle, nonce, attributes
+	auth := r.tpm2b()
+	out := binary.BigEndian.AppendUint32(nil, 0)
+	switch {
+	case session != tpmRSPW:
+		return tpm_test_response(0x1e, nil), nil
+	case code == tpmCCCreatePrimary:
+		r.tpm2b() // inSensitive
+		template := r.tpm2b()
+		if handle != tpmRHOwner || len(template) < 20 {
+			return tpm_test_response(0x1c4, nil), nil // TPM_RC_VALUE
+		}
+		if !bytes.Equal(auth, m.ownerAuth) {
+			return tpm_test_response(0x98e, nil), nil
+		}
+		unique := (&tpmReader{b: template[20:]}).tpm2b()
+		h := sha256.Sum256(append(append([]byte(nil), m.seed...), unique...))
+		key := new(ecdsa.PrivateKey)
+		key.Curve = elliptic.P256()
+		key.D = new(big.Int).Mod(new(big.Int).SetBytes(h[:]), key.Curve.Params().N)
+		key.X, key.Y = key.Curve.ScalarBaseMult(key.D.Bytes())
+		m.next++
+		m.keys[0x80000000+m.next] = key
+		public := tpm2b(tpm2b(append([]byte(nil), template[:20]...), key.X.Bytes()), key.Y.Bytes())
+		params := tpm2b(nil, public)
+		params = tpm2b(tpm2b(params, nil), nil) // creationData, creationHash
+		params = binary.BigEndian.AppendUint16(params, 0x8021)
+		params = tpm2b(binary.BigEndian.AppendUint32(params, tpmRHOwner), nil) // creationTicket
+		params = tpm2b(params, nil)                                            // name
+		out = binary.BigEndian.AppendUint32(nil, 0x80000000+m.next)
+		out = append(binary.BigEndian.AppendUint32(out, uint32(len(params))), params...)
+	case code == tpmCCSign:
+		key, ok := m.keys[handle]
+		if !ok {
+			return tpm_test_response(0x18b, nil), nil
+		}
+		r_, s_, _ := ecdsa.Sign(rand.Reader, key, r.tpm2b())
+		if m.faulty {
+			s_.Add(s_, big.NewInt(1))
+		}
+		params := binary.BigEndian.AppendUint16(nil, tpmAlgECDSA)
+		params = binary.BigEndian.AppendUint16(params, tpmAlgSHA256)
+		params = tpm2b(tpm2b(params, r_.Bytes()), s_.Bytes())
+		out = append(binary.BigEndian.AppendUint32(nil, uint32(len(params))), params...)
+	default:
+		return tpm_test_response(0x143, nil), nil // TPM_RC_COMMAND_CODE
+	}
+	out = tpm2b(append(tpm2b(out, nil), 1), nil) // password session response
+	return tpm_test_response(0, out), nil
+}
+
+func (m *memoryTPM) close() error {
+	m.closed = true
+	return nil
+}
+
+func tpm_test_response(rc uint32, body []byte) []byte {
+	tag := uint16(tpmSTNoSessions)
+	if body != nil {
+		tag = tpmSTSessions
+	}
+	out := binary.BigEndian.AppendUint16(nil, tag)
+	out = binary.BigEndian.AppendUint32(out, uint32(10+len(body)))
+	return append(binary.BigEndian.AppendUint32(out, rc), body...)
+}
+
+// TPM identity keys are stable per name, sign as crypto.Signers and are flushed on close.
+func TestTPMIdentityKeys(t *testing.T) {
+
+	seed := make([]byte, 32)
+	rand.Read(seed)
+	device := &memoryTPM{seed: seed, ownerAuth: []byte("owner"), keys: map[uint32]*ecdsa.PrivateKey{}}
+	tpm := &TPM{transport: device, ownerAuth: []byte("wrong")}
+	if _, err := tpm.Key("machine identity"); err != ErrTPMAuth {
+		t.Fatalf("wrong owner authorization: %v, want %v", err, ErrTPMAuth)
+	}
+	tpm = &TPM{transport: device, ownerAuth: []byte("owner")}
+	key, err := tpm.Key("machine identity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := tpm.Key("machine identity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := tpm.Key("other identity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != again {
+		t.Fatal("the same name loads a second key")
+	}
+	if key.Public().(*ecdsa.PublicKey).Equal(other.Public()) {
+		t.Fatal("two names give the same key")
+	}
+	if n := len(device.keys); n != 2 {
+		t.Fatalf("TPM holds %d keys, want 2", n)
+	}
+
+	// signatures verify here, in the standard library and in certificates
+	pub := key.Public().(*ecdsa.PublicKey)
+	want, err := PublicKeyFingerprint(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.Fingerprint() != want {
+		t.Fatalf("fingerprint %v, want %v", key.Fingerprint(), want)
+	}
+	var signer crypto.Signer = key
+	digest := sha256.Sum256([]byte("signed in the TPM"))
+	der, err := signer.Sign(nil, digest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ecdsa.VerifyASN1(pub, digest[:], der) {
+		t.Fatal("TPM signature does not verify in crypto/ecdsa")
+	}
+	key.LowS = true
+	for i := 0; i < 8; i++ {
+		der, err = signer.Sign(nil, digest[:], crypto.SHA256)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sig ecdsaDERSignature
+		if _, err := asn1.Unmarshal(der, &sig); err != nil {
+			t.Fatal(err)
+		}
+		if !is_low_s(elliptic.P256(), sig.S) {
+			t.Fatalf("s = %v is high", sig.S)
+		}
+		if !verify_ecdsa_digest(pub, digest[:], sig.R, sig.S) {
+			t.Fatal("low s signature does not verify")
+		}
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "machine identity"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, pub, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := x509.ParseCertificate(cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := parsed.CheckSignatureFrom(parsed); err != nil {
+		t.Fatal(err)
+	}
+
+	// the same name gives the same key after reopening; other hashes and faulty TPMs fail
+	if _, err := signer.Sign(nil, digest[:], crypto.SHA384); err != ErrSignerHash {
+		t.Fatalf("SHA-384: %v, want %v", err, ErrSignerHash)
+	}
+	device.faulty = true
+	_, err = signer.Sign(nil, digest[:], crypto.SHA256)
+	device.faulty = false
+	if err != ErrTPMSignature {
+		t.Fatalf("faulty TPM: %v, want %v", err, ErrTPMSignature)
+	}
+	if err := tpm.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !device.closed || len(device.keys) != 0 {
+		t.Fatalf("after close: device closed %v, %d keys loaded", device.closed, len(device.keys))
+	}
+	if err := tpm.Close(); err != nil {
+		t.Fatalf("second close: %v", err)
+	}
+	if _, err := signer.Sign(nil, digest[:], crypto.SHA256); err == nil {
+		t.Fatal("key of a closed TPM signs")
+	}
+	tpm = &TPM{transport: device, ownerAuth: []byte("owner")}
+	reopened, err := tpm.Key("machine identity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reopened.Public().(*ecdsa.PublicKey).Equal(pub) {
+		t.Fatal("the name gives another key after reopening")
+	}
+	_, err = (&TPMKey{tpm: tpm, handle: 0x80ffffff, pub: pub}).Sign(nil, digest[:], crypto.SHA256)
+	if err == nil || err == ErrTPMSignature {
+		t.Fatalf("unknown handle: %v, want a TPM error", err)
+	}
+	if err := tpm.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if local, err := OpenTPM(nil); err == nil {
+		if err := local.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
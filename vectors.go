@@ -0,0 +1,111 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"math/rand"
+
+	"sig/kmac"
+)
+
+/*
+One test vector of the E222 Schnorr scheme, following a signature from
+the passphrase to its encoding. All byte strings are hex, integers are
+fixed width big-endian hex. KMAC intermediates are the raw outputs before
+reduction mod r:
+
+	key_kmac   = KMACXOF256(passphrase, "", 448, "SK"),    scalar = key_kmac mod r
+	nonce_kmac = KMACXOF256(scalar, message, 448, "N"),    nonce  = nonce_kmac mod r
+	response   = (nonce - 4·scalar·challenge) mod r
+
+In a signing domain the nonce customization is "N" ‖ 0x00 ‖ domain, see
+e222_kmac_nonce, and the challenge is cSHAKE256 customized by the domain.
+*/
+type TestVector struct {
+	Passphrase string `json:"passphrase"` // printable ASCII
+	KeyKMAC    string `json:"key_kmac"`
+	Scalar     string `json:"scalar"`
+	PublicX    string `json:"public_x"`
+	PublicY    string `json:"public_y"`
+	Message    string `json:"message"`
+	NonceKMAC  string `json:"nonce_kmac"`
+	Nonce      string `json:"nonce"`
+	CommitX    string `json:"commit_x"`  // x coordinate of nonce × G
+	Challenge  string `json:"challenge"` // e, the h of the signature
+	Response   string `json:"response"`  // s, the z of the signature
+	Signature  string `json:"signature"` // EncodeSignature
+}
+
+type TestVectorFile struct {
+	Scheme  string       `json:"scheme"`
+	Domain  string       `json:"domain,omitempty"` // signing domain, empty for none
+	Seed    int64        `json:"seed"`
+	Vectors []TestVector `json:"vectors"`
+}
+
+/*
+Generates count test vectors from seed as indented JSON. Passphrases and
+messages come from a math/rand source seeded with seed and nonces are
+derived from the key and message with KMAC, so the output depends on
+nothing but its arguments and the scheme itself.
+*/
+func GenerateTestVectors(seed int64, count int) ([]byte, error) {
+	return GenerateDomainTestVectors(seed, count, "")
+}
+
+// Generates test vectors as GenerateTestVectors does, for signatures made in domain.
+func GenerateDomainTestVectors(seed int64, count int, domain string) ([]byte, error) {
+	if count < 0 {
+		return nil, errors.New("negative test vector count")
+	}
+	rnd := rand.New(rand.NewSource(seed))
+	file := TestVectorFile{Scheme: "schnorr-e222-sha3-256", Domain: domain, Seed: seed, Vectors: []TestVector{}}
+	r := e222Params.R
+
+	for i := 0; i < count; i++ {
+		pw := make([]byte, 12+rnd.Intn(20))
+		for j := range pw {
+			pw[j] = byte(' ' + 1 + rnd.Intn(94)) // printable ASCII, unchanged by NFC
+		}
+		msg := make([]byte, rnd.Intn(64))
+		rnd.Read(msg)
+
+		key_kmac := kmac.XOF256(normalize_passphrase(pw), nil, 448, "SK")
+		x := new(big.Int).Mod(fromFixedBytes(key_kmac), r)
+		if err := check_scalar(x); err != nil {
+			return nil, err
+		}
+		V := e222Curve.BaseMul(x)
+
+		nonce_kmac, k := e222_kmac_nonce(x, domain, msg)
+		s, e := sign_with_nonce_e222(x, k, domain, &msg)
+		sig := &SchnorrSignature{Curve: curveE222, S: s, E: e}
+		if domain != "" {
+			sig.Meta = []SignatureField{{Tag: metaDomain, Value: []byte(domain)}}
+		}
+
+		file.Vectors = append(file.Vectors, TestVector{
+			Passphrase: hex.EncodeToString(pw),
+			KeyKMAC:    hex.EncodeToString(key_kmac),
+			Scalar:     hex.EncodeToString(toFixedBytes(x, e222Width)),
+			PublicX:    hex.EncodeToString(toFixedBytes(V.X(), e222Width)),
+			PublicY:    hex.EncodeToString(toFixedBytes(V.Y(), e222Width)),
+			Message:    hex.EncodeToString(msg),
+			NonceKMAC:  hex.EncodeToString(nonce_kmac),
+			Nonce:      hex.EncodeToString(toFixedBytes(k, e222Width)),
+			CommitX:    hex.EncodeToString(toFixedBytes(e222Curve.BaseMul(k).X(), e222Width)),
+			Challenge:  hex.EncodeToString(toFixedBytes(e, hashWidth)),
+			Response:   hex.EncodeToString(toFixedBytes(s, e222Width)),
+			Signature:  hex.EncodeToString(EncodeSignature(sig)),
+		})
+	}
+	out, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
@@ -0,0 +1,50 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+// Regenerates testdata/vectors.json, which must match byte for byte and verify.
+func TestVectorFileReproducible(t *testing.T) {
+
+	want, err := os.ReadFile("testdata/vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := GenerateTestVectors(1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(want, got) {
+		t.Fatalf("regenerated vectors differ from testdata/vectors.json:\n%s", got)
+	}
+
+	var file TestVectorFile
+	if err := json.Unmarshal(got, &file); err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Vectors) != 3 {
+		t.Fatalf("%d vectors, want 3", len(file.Vectors))
+	}
+	for i, v := range file.Vectors {
+		var fields [4][]byte
+		for j, h := range []string{v.PublicX, v.PublicY, v.Signature, v.Message} {
+			if fields[j], err = hex.DecodeString(h); err != nil {
+				t.Fatalf("vector %d: %v", i, err)
+			}
+		}
+		V, err := e222_from_bytes(append(fields[0], fields[1]...))
+		if err != nil {
+			t.Fatalf("vector %d: %v", i, err)
+		}
+		if !VerifySignature(V, "", fields[2], &fields[3]) {
+			t.Fatalf("vector %d does not verify", i)
+		}
+	}
+}
@@ -0,0 +1,422 @@
+package sig
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"sig/secp256k1"
+)
+
+func TestECDSAKnownAnswerVectors(t *testing.T) {
+
+	for i, v := range ecdsaKnownAnswers {
+		msg := []byte(v.msg)
+		Q_a := ecdsa.PublicKey{Curve: elliptic.P256(), X: hexInt(v.qx), Y: hexInt(v.qy)}
+		if !verify_ecdsa_sig(&Q_a, hexInt(v.r), hexInt(v.s), &msg) {
+			t.Fatalf("vector %d does not verify", i)
+		}
+		msg[0] ^= 1
+		if verify_ecdsa_sig(&Q_a, hexInt(v.r), hexInt(v.s), &msg) {
+			t.Fatalf("vector %d verifies for an altered message", i)
+		}
+	}
+}
+
+// A version 2 E222 Schnorr signature over "verify-only fixture".
+const (
+	e222FixtureKey = "1ea99d73ee7376fd81d863eb079411c99b23920bbd7f25b913256de0" +
+		"1d83df77ed5a6fe1260f78f28c179a077f510275188c1d461f867d0e"
+	e222FixtureSig = "0201" +
+		"0b87ab8fd41c8f9c6f8c777ed68bda1a2e2a35e3e5621cfa99af5f5f" +
+		"f6018c68a174062f421ec0be894237a5ea7fcfbb777b1ae3f8d94f3e417ba299"
+)
+
+func TestE222SignatureFixture(t *testing.T) {
+
+	key_bytes, _ := hex.DecodeString(e222FixtureKey)
+	sig, _ := hex.DecodeString(e222FixtureSig)
+	y, err := e222_from_bytes(key_bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("verify-only fixture")
+	other := []byte("verify-only fixturf")
+	if !VerifySignature(y, "", sig, &msg) {
+		t.Fatal("fixture does not verify")
+	}
+	if VerifySignature(y, "", sig, &other) {
+		t.Fatal("fixture verifies for an altered message")
+	}
+}
+
+// RFC 7515 Appendix A.3 ES256 example.
+const (
+	rfc7515JWK = `{"kty":"EC","crv":"P-256",` +
+		`"x":"f83OJ3D2xF1Bg8vub9tLe1gHMzV76e8Tus9uPHvRVEU",` +
+		`"y":"x_FEzRu9m36HLN_tue659LNpXW6pCyStikYjKIWI5a0"}`
+	rfc7515Token = "eyJhbGciOiJFUzI1NiJ9" +
+		".eyJpc3MiOiJqb2UiLA0KICJleHAiOjEzMDA4MTkzODAsDQogImh0dHA6Ly9leGFtcGxlLmNvbS9pc19yb290Ijp0cnVlfQ" +
+		".DtEhU3ljbEg8L38VWAfUAqOyKAM6-Xx-F4GawxaepmXFCgfTjDxw5djxLa8ISlSApmWQxfKTUJqPP3-Kg6NU1Q"
+)
+
+func TestJWSFixture(t *testing.T) {
+
+	pub, err := UnmarshalJWK([]byte(rfc7515JWK))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := VerifyJWS(rfc7515Token, pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(payload), `{"iss":"joe"`) {
+		t.Fatalf("payload %q", payload)
+	}
+	jwk, err := MarshalJWK(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reparsed, err := UnmarshalJWK(jwk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reparsed.X.Cmp(pub.X) != 0 || reparsed.Y.Cmp(pub.Y) != 0 {
+		t.Fatalf("JWK %s does not round trip", jwk)
+	}
+
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." +
+		strings.Split(rfc7515Token, ".")[1] + "."
+	if _, err := VerifyJWS(none, pub); err != ErrUnsupportedJWSAlgorithm {
+		t.Fatalf("alg none: %v, want %v", err, ErrUnsupportedJWSAlgorithm)
+	}
+	hs := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256"}`)) +
+		rfc7515Token[strings.Index(rfc7515Token, "."):]
+	if _, err := VerifyJWS(hs, pub); err != ErrUnsupportedJWSAlgorithm {
+		t.Fatalf("alg HS256: %v, want %v", err, ErrUnsupportedJWSAlgorithm)
+	}
+	if _, err := UnmarshalJWK([]byte(strings.Replace(rfc7515JWK, "P-256", "P-384", 1))); err == nil {
+		t.Fatal("P-384 JWK with P-256 coordinates parses")
+	}
+}
+
+// ValidatePublicKey names what is wrong with a key, and verification refuses such keys.
+func TestPublicKeyValidation(t *testing.T) {
+
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521(), secp256k1.Curve()} {
+		params := curve.Params()
+		for _, c := range []struct {
+			name string
+			x, y *big.Int
+			want error
+		}{
+			{"generator", params.Gx, params.Gy, nil},
+			{"(0, 0)", big.NewInt(0), big.NewInt(0), ErrPublicKeyInfinity},
+			{"y + p", params.Gx, new(big.Int).Add(params.Gy, params.P), ErrPublicKeyRange},
+			{"-y", params.Gx, new(big.Int).Neg(params.Gy), ErrPublicKeyRange},
+			{"y = 1", params.Gx, big.NewInt(1), ErrPublicKeyOffCurve},
+			{"no x", nil, params.Gy, ErrPublicKeyMissing},
+		} {
+			if err := ValidatePublicKey(curve, c.x, c.y); err != c.want {
+				t.Fatalf("%s %s: %v, want %v", params.Name, c.name, err, c.want)
+			}
+		}
+	}
+	if err := ValidatePublicKey(nil, big.NewInt(1), big.NewInt(1)); err != ErrPublicKeyMissing {
+		t.Fatalf("no curve: %v, want %v", err, ErrPublicKeyMissing)
+	}
+	if !errors.Is(ErrPublicKeyOffCurve, ErrInvalidPublicKey) {
+		t.Fatal("ErrPublicKeyOffCurve is not an ErrInvalidPublicKey")
+	}
+
+	// the key of the first known answer vector, and copies of the neutral point
+	v := ecdsaKnownAnswers[0]
+	msg := []byte(v.msg)
+	infinity := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int), Y: new(big.Int)}
+	Q := &ecdsa.PublicKey{Curve: elliptic.P256(), X: hexInt(v.qx), Y: hexInt(v.qy)}
+	if verify_ecdsa_sig(infinity, hexInt(v.r), hexInt(v.s), &msg) {
+		t.Fatal("signature verifies under the point at infinity")
+	}
+	if !verify_ecdsa_sig(Q, hexInt(v.r), hexInt(v.s), &msg) {
+		t.Fatal("known answer does not verify")
+	}
+}
+
+// Test vectors from RFC 8032 Section 7.4 (Ed448).
+var ed448KnownAnswers = []struct {
+	seed, pub, msg, ctx, sig string
+}{
+	{
+		seed: "6c82a562cb808d10d632be89c8513ebf6c929f34ddfa8c9f63c9960ef6e348a3528c8a3fcc2f044e39a3fc5b94492f8f032e7549a20098f95b",
+		pub:  "5fd7449b59b461fd2ce787ec616ad46a1da1342485a70e1f8a0ea75d80e96778edf124769b46c7061bd6783df1e50f6cd1fa1abeafe8256180",
+		sig: "533a37f6bbe457251f023c0d88f976ae2dfb504a843e34d2074fd823d41a591f2b233f034f628281f2fd7a22ddd47d7828c59bd0a21bfd3980" +
+			"ff0d2028d4b18a9df63e006c5d1c2d345b925d8dc00b4104852db99ac5c7cdda8530a113a0f4dbb61149f05a7363268c71d95808ff2e652600",
+	},
+	{
+		seed: "c4eab05d357007c632f3dbb48489924d552b08fe0c353a0d4a1f00acda2c463afbea67c5e8d2877c5e3bc397a659949ef8021e954e0a12274e",
+		pub:  "43ba28f430cdff456ae531545f7ecd0ac834a55d9358c0372bfa0c6c6798c0866aea01eb00742802b8438ea4cb82169c235160627b4c3a9480",
+		msg:  "03",
+		ctx:  "666f6f",
+		sig: "d4f8f6131770dd46f40867d6fd5d5055de43541f8c5e35abbcd001b32a89f7d2151f7647f11d8ca2ae279fb842d607217fce6e042f6815ea00" +
+			"0c85741de5c8da1144a6a1aba7f96de42505d7a7298524fda538fccbbb754f578c1cad10d54d0d5428407e85dcbc98a49155c13764e66c3c00",
+	},
+}
+
+// RFC 8032 Ed448 signatures verify, and altered ones do not.
+func TestEd448KnownAnswerVectors(t *testing.T) {
+
+	for i, v := range ed448KnownAnswers {
+		pub, _ := hex.DecodeString(v.pub)
+		msg, _ := hex.DecodeString(v.msg)
+		ctx, _ := hex.DecodeString(v.ctx)
+		sig, _ := hex.DecodeString(v.sig)
+		if !VerifyEd448(pub, msg, sig, ctx) {
+			t.Fatalf("vector %d does not verify", i)
+		}
+		if VerifyEd448(pub, append(msg, 0), sig, ctx) {
+			t.Fatalf("vector %d verifies for an altered message", i)
+		}
+		if VerifyEd448(pub, msg, sig, append(ctx, 0)) {
+			t.Fatalf("vector %d verifies for an altered context", i)
+		}
+
+		A, err := ed448_decode(pub)
+		if err != nil {
+			t.Fatalf("vector %d: %v", i, err)
+		}
+		if got := ed448_encode(A); !bytes.Equal(got, pub) {
+			t.Fatalf("vector %d: key encodes as %x, want %x", i, got, pub)
+		}
+
+		// S ≥ r is rejected even though S − r would verify
+		S := fromLittleEndian(sig[Ed448PublicKeySize:])
+		high := append(append([]byte(nil), sig[:Ed448PublicKeySize]...),
+			toLittleEndian(S.Add(S, ed448Curve.Params().R), Ed448PublicKeySize)...)
+		if VerifyEd448(pub, msg, high, ctx) {
+			t.Fatalf("vector %d verifies with S + r", i)
+		}
+	}
+}
+
+// Test vectors from RFC 8032 Section 7.1 (Ed25519).
+var ed25519KnownAnswers = []struct {
+	seed, pub, msg, sig string
+}{
+	{
+		seed: "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60",
+		pub:  "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a",
+		sig:  "e5564300c360ac729086e2cc806e828a84877f1eb8e5d974d873e065224901555fb8821590a33bacc61e39701cf9b46bd25bf5f0595bbe24655141438e7a100b",
+	},
+	{
+		seed: "4ccd089b28ff96da9db6c346ec114e0f5b8a319f35aba624da8cf6ed4fb8a6fb",
+		pub:  "3d4017c3e843895a92b70aa74d1b7ebc9c982ccf2ec4968cc0cd55f12af4660c",
+		msg:  "72",
+		sig:  "92a009a9f0d4cab8720e820b5f642540a2b27b5416503f8fb3762223ebdb69da085ac1e43e15996e458f3613d0f11d8c387b2eaeb4302aeeb00d291612bb0c00",
+	},
+}
+
+// RFC 8032 Ed25519 signatures verify, and altered ones or bad encodings do not.
+func TestEd25519KnownAnswerVectors(t *testing.T) {
+
+	for i, v := range ed25519KnownAnswers {
+		pub, _ := hex.DecodeString(v.pub)
+		msg, _ := hex.DecodeString(v.msg)
+		sig, _ := hex.DecodeString(v.sig)
+		if !VerifyEd25519(pub, msg, sig) {
+			t.Fatalf("vector %d does not verify", i)
+		}
+		if VerifyEd25519(pub, append(msg, 0), sig) {
+			t.Fatalf("vector %d verifies for an altered message", i)
+		}
+
+		A, err := ed25519_decode(pub)
+		if err != nil {
+			t.Fatalf("vector %d: %v", i, err)
+		}
+		if got := ed25519_encode(A); !bytes.Equal(got, pub) {
+			t.Fatalf("vector %d: key encodes as %x, want %x", i, got, pub)
+		}
+	}
+
+	// y = p is not canonical, and y = 2 is not on the curve
+	p := ed25519Curve.Params().P
+	if _, err := ed25519_decode(toLittleEndian(p, Ed25519PublicKeySize)); err != ErrEd25519Encoding {
+		t.Fatalf("y = p: %v, want %v", err, ErrEd25519Encoding)
+	}
+	if _, err := ed25519_decode(toLittleEndian(big.NewInt(2), Ed25519PublicKeySize)); err != ErrEd25519Encoding {
+		t.Fatalf("y = 2: %v, want %v", err, ErrEd25519Encoding)
+	}
+}
+
+// Test vectors 0 – 2 and 4 from the BIP-340 test-vectors.csv, all valid; vector 4 has no secret key.
+var bip340KnownAnswers = []struct {
+	seckey, pub, aux, msg, sig string
+}{
+	{
+		seckey: "0000000000000000000000000000000000000000000000000000000000000003",
+		pub:    "f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9",
+		aux:    "0000000000000000000000000000000000000000000000000000000000000000",
+		msg:    "0000000000000000000000000000000000000000000000000000000000000000",
+		sig:    "e907831f80848d1069a5371b402410364bdf1c5f8307b0084c55f1ce2dca821525f66a4a85ea8b71e482a74f382d2ce5ebeee8fdb2172f477df4900d310536c0",
+	},
+	{
+		seckey: "b7e151628aed2a6abf7158809cf4f3c762e7160f38b4da56a784d9045190cfef",
+		pub:    "dff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659",
+		aux:    "0000000000000000000000000000000000000000000000000000000000000001",
+		msg:    "243f6a8885a308d313198a2e03707344a4093822299f31d0082efa98ec4e6c89",
+		sig:    "6896bd60eeae296db48a229ff71dfe071bde413e6d43f917dc8dcf8c78de33418906d11ac976abccb20b091292bff4ea897efcb639ea871cfa95f6de339e4b0a",
+	},
+	{
+		seckey: "c90fdaa22168c234c4c6628b80dc1cd129024e088a67cc74020bbea63b14e5c9",
+		pub:    "dd308afec5777e13121fa72b9cc1b7cc0139715309b086c960e18fd969774eb8",
+		aux:    "c87aa53824b4d7ae2eb035a2b5bbbccc080e76cdc6d1692c4b0b62d798e6d906",
+		msg:    "7e2d58d8b3bcdf1abadec7829054f90dda9805aab56c77333024b9d0a508b75c",
+		sig:    "5831aaeed7b44bb74e5eab94ba9d4294c49bcf2a60728d8b4c200f50dd313c1bab745879a5ad954a72c45a91c3a51d3c7adea98d82f8481e0e1e03674a6f3fb7",
+	},
+	{
+		pub: "d69c3509bb99e412e68b0fe8544e72837dfa30746d8be2aa65975f29d22dc7b9",
+		msg: "4df3c3f68fcc83b27e9d42c90431a72499f17875c81a599b566c9889b9696703",
+		sig: "00000000000000000000003b78ce563f89a0ed9414f5aa28ad0d96d6795f9c6376afb1548af603b3eb45c9f8207dee1060cb71c04e80f593060b07d28308d7f4",
+	},
+}
+
+// BIP-340 signatures verify, and altered ones, out of range values and keys off the curve do not.
+func TestBIP340KnownAnswerVectors(t *testing.T) {
+
+	for i, v := range bip340KnownAnswers {
+		pub, _ := hex.DecodeString(v.pub)
+		msg, _ := hex.DecodeString(v.msg)
+		sig, _ := hex.DecodeString(v.sig)
+		if !VerifyBIP340(pub, msg, sig) {
+			t.Fatalf("vector %d does not verify", i)
+		}
+		if VerifyBIP340(pub, append(msg, 0), sig) {
+			t.Fatalf("vector %d verifies for an altered message", i)
+		}
+
+		bad := append([]byte(nil), sig...)
+		bad[63] ^= 1
+		if VerifyBIP340(pub, msg, bad) {
+			t.Fatalf("vector %d verifies with an altered s", i)
+		}
+		if VerifyBIP340(pub, msg, sig[:63]) {
+			t.Fatalf("vector %d verifies truncated", i)
+		}
+	}
+
+	v := bip340KnownAnswers[1]
+	pub, _ := hex.DecodeString(v.pub)
+	msg, _ := hex.DecodeString(v.msg)
+	sig, _ := hex.DecodeString(v.sig)
+	params := secp256k1.Curve().Params()
+
+	// vector 5: a public key that is not on the curve; then x = p, r = p, s = n
+	offCurve, _ := hex.DecodeString("eefdea4cdb677750a420fee807eacf21eb9898ae79b9768766e4faa04a2d4a34")
+	rp := append(toFixedBytes(params.P, 32), sig[32:]...)
+	sn := append(append([]byte(nil), sig[:32]...), toFixedBytes(params.N, 32)...)
+	for _, c := range []struct {
+		name     string
+		pub, sig []byte
+	}{
+		{"key off the curve", offCurve, sig},
+		{"x = p", toFixedBytes(params.P, 32), sig},
+		{"r = p", pub, rp},
+		{"s = n", pub, sn},
+	} {
+		if VerifyBIP340(c.pub, msg, c.sig) {
+			t.Fatalf("%s verifies", c.name)
+		}
+	}
+
+	// lift_x picks the even y
+	x, y := bip340_lift_x(fromFixedBytes(pub))
+	if x == nil {
+		t.Fatal("lift_x fails for a valid key")
+	}
+	if y.Bit(0) != 0 || !secp256k1.Curve().IsOnCurve(x, y) {
+		t.Fatalf("lift_x gives (%x, %x)", x, y)
+	}
+}
+
+// An ECVRF proof on E222 over "verify-only fixture", for x = 0x5a…5a, with its output.
+const (
+	vrfFixtureKey   = "02c19c37311f43169af48517453a26e3d749503c398285c795810493"
+	vrfFixtureProof = "b561206383fd578f8a7b4945af9b382ed7c2ff4432692894f8f191b3" +
+		"4c8997a5e66e759fcf3f804145110a86" +
+		"06bbae94ce760e24d69c8e446cc1090e43ad3c99bc87a4e10fbb"
+	vrfFixtureOutput = "55c3405477a2554e84ee939389fe04beeb21bbf57f1c1b4f080d4cc25805e99b" +
+		"34ec598c4047fd8f0758bd4f5a48acafdc72e966146c347352312aba4356eafe"
+)
+
+func TestVRFFixture(t *testing.T) {
+
+	key_bytes, _ := hex.DecodeString(vrfFixtureKey)
+	proof_bytes, _ := hex.DecodeString(vrfFixtureProof)
+	Y, err := e222Curve.FromBytes(key_bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := ParseVRFProof(e222Curve, proof_bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	beta, ok := VerifyVRF(Y, []byte("verify-only fixture"), proof)
+	if !ok {
+		t.Fatal("fixture does not verify")
+	}
+	if got := hex.EncodeToString(beta); got != vrfFixtureOutput {
+		t.Fatalf("output %s, want %s", got, vrfFixtureOutput)
+	}
+	if _, ok := VerifyVRF(Y, []byte("verify-only fixturf"), proof); ok {
+		t.Fatal("fixture verifies for an altered input")
+	}
+}
+
+// An ssh-keygen -Y sign signature per key over "hello ssh\n" in the namespace "file".
+const (
+	sshFixtureEd25519 = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIA7UenHJ9PdX/IsVEDGzkTw8JMX9fW3HVu1xZT8E1sI7 alice"
+	sshFixtureECDSA   = "ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTYAAABBBIV179EZiB+qTUA+" +
+		"aUwqawwjv+srWIon/9vsOi+ObK3gfFGn+1Z5UiotMRHxY6uJnFYosvFGI3IfltOiMHaotEY= bob"
+	sshFixtureEd25519Sig = "-----BEGIN SSH SIGNATURE-----\n" +
+		"U1NIU0lHAAAAAQAAADMAAAALc3NoLWVkMjU1MTkAAAAgDtR6ccn091f8ixUQMbORPDwkxf\n" +
+		"19bcdW7XFlPwTWwjsAAAAEZmlsZQAAAAAAAAAGc2hhNTEyAAAAUwAAAAtzc2gtZWQyNTUx\n" +
+		"OQAAAEDy8FZbwi5Ri3Ze9py6vzZefYiNn+VqXUQs+jplj310iafgH6EINE0EOFgakQRmSL\n" +
+		"iNLcxJdV5zmelULr1eHPgH\n" +
+		"-----END SSH SIGNATURE-----\n"
+	sshFixtureECDSASig = "-----BEGIN SSH SIGNATURE-----\n" +
+		"U1NIU0lHAAAAAQAAAGgAAAATZWNkc2Etc2hhMi1uaXN0cDI1NgAAAAhuaXN0cDI1NgAAAE\n" +
+		"EEhXXv0RmIH6pNQD5pTCprDCO/6ytYiif/2+w6L45sreB8Uaf7VnlSKi0xEfFjq4mcViiy\n" +
+		"8UYjch+W06Iwdqi0RgAAAARmaWxlAAAAAAAAAAZzaGE1MTIAAABlAAAAE2VjZHNhLXNoYT\n" +
+		"ItbmlzdHAyNTYAAABKAAAAIQDw6xSDM2P0FMEAX8y8BFHY9b+sMcobO002pn3ABfDMIQAA\n" +
+		"ACEAohiuYz7Qe9sa1rrnvDgok9Tt2S124rVDEYlUU1v48w0=\n" +
+		"-----END SSH SIGNATURE-----\n"
+)
+
+func TestSSHSignatureFixture(t *testing.T) {
+
+	msg := []byte("hello ssh\n")
+	for _, f := range []struct{ key, sig string }{
+		{sshFixtureEd25519, sshFixtureEd25519Sig},
+		{sshFixtureECDSA, sshFixtureECDSASig},
+	} {
+		pub, comment, err := ParseAuthorizedKey([]byte(f.key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := VerifySSHSignature(pub, "file", msg, []byte(f.sig)); err != nil {
+			t.Fatalf("%s's fixture: %v", comment, err)
+		}
+		if err := VerifySSHSignature(pub, "git", msg, []byte(f.sig)); err != ErrSSHSignature {
+			t.Fatalf("%s's fixture in another namespace: %v, want %v", comment, err, ErrSSHSignature)
+		}
+		if err := VerifySSHSignature(pub, "file", []byte("hello ssh"), []byte(f.sig)); err != ErrSSHSignature {
+			t.Fatalf("%s's fixture for an altered message: %v, want %v", comment, err, ErrSSHSignature)
+		}
+	}
+}
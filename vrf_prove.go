@@ -0,0 +1,57 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"math/big"
+
+	"sig/edwards"
+	"sig/kmac"
+)
+
+/*
+Proves the VRF output of alpha under the secret key x on curve c,
+ECVRF_prove of RFC 9381 Section 5.1, see VRFProof. The public key is
+x × G and the output is the proof's Output. Proofs are deterministic,
+the same x and alpha always giving the same proof, like beta itself.
+*/
+func ProveVRF(c *edwards.Curve, x *SecretScalar, alpha []byte) (*VRFProof, error) {
+	a, err := x.Int()
+	if err != nil {
+		return nil, err
+	}
+	defer wipe_int(a)
+	a.Mod(a, c.Order())
+	if a.Sign() == 0 {
+		return nil, ErrZeroScalar
+	}
+	Y := c.BaseMul(a)
+	H := vrf_hash_point(Y, alpha)
+	Gamma := H.SecMul(a)
+	k := vrf_nonce(c, a, H)
+	defer wipe_int(k)
+
+	ch := vrf_challenge(Y, H, Gamma, c.BaseMul(k), H.SecMul(k))
+	cx := new(big.Int).Mul(ch, a)
+	defer wipe_int(cx)
+	s := new(big.Int).Add(k, cx)
+	return &VRFProof{Gamma: Gamma, C: ch, S: s.Mod(s, c.Order())}, nil
+}
+
+/*
+The nonce k ← KMACXOF256(x, H, 2·|r|, "ECVRF NONCE") mod r, keyed by the
+secret key as the nonce generation of RFC 9381 Section 5.4.2.2 is, so
+that k never repeats for distinct inputs and no randomness is needed.
+*/
+func vrf_nonce(c *edwards.Curve, x *big.Int, H *edwards.Point) *big.Int {
+	key := toFixedBytes(x, c.EncodedLen())
+	defer wipe_bytes(key)
+	derived := NewSecretBytes(kmac.XOF256(key, H.Bytes(), 2*c.Order().BitLen(), "ECVRF NONCE"))
+	defer derived.Wipe()
+	k := fromFixedBytes(derived.b)
+	k.Mod(k, c.Order())
+	if k.Sign() == 0 {
+		k.SetInt64(1)
+	}
+	return k
+}
@@ -0,0 +1,89 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"sig/edwards"
+)
+
+// VRF proofs verify to the prover's output, which is deterministic and bound to the key and input.
+func TestVRFProofs(t *testing.T) {
+
+	alpha := []byte("round 42")
+	for _, c := range []*edwards.Curve{e222Curve, e521Curve, ed25519Curve, ed448Curve} {
+		a, _ := rand.Int(rand.Reader, c.Params().R)
+		x := NewSecretScalar(a)
+		Y := c.BaseMul(a)
+		proof, err := ProveVRF(c, x, alpha)
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name(), err)
+		}
+		beta, ok := VerifyVRF(Y, alpha, proof)
+		if !ok {
+			t.Fatalf("%s: proof does not verify", c.Name())
+		}
+		if len(beta) != VRFOutputSize || !bytes.Equal(beta, proof.Output()) {
+			t.Fatalf("%s: verified output %x, proof output %x", c.Name(), beta, proof.Output())
+		}
+
+		// the output is a function of key and input alone
+		again, err := ProveVRF(c, x, alpha)
+		if err != nil {
+			t.Fatal(err)
+		}
+		other, err := ProveVRF(c, x, []byte("round 43"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(again.Bytes(), proof.Bytes()) {
+			t.Fatalf("%s: proofs differ for the same input", c.Name())
+		}
+		if bytes.Equal(other.Output(), beta) {
+			t.Fatalf("%s: output does not depend on the input", c.Name())
+		}
+		if _, ok := VerifyVRF(Y, []byte("round 43"), proof); ok {
+			t.Fatalf("%s: proof verifies for another input", c.Name())
+		}
+		if _, ok := VerifyVRF(c.BaseMul(new(big.Int).Add(a, big.NewInt(1))), alpha, proof); ok {
+			t.Fatalf("%s: proof verifies under another key", c.Name())
+		}
+
+		decoded, err := ParseVRFProof(c, proof.Bytes())
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name(), err)
+		}
+		if _, ok := VerifyVRF(Y, alpha, decoded); !ok {
+			t.Fatalf("%s: decoded proof does not verify", c.Name())
+		}
+		if _, err := ParseVRFProof(c, proof.Bytes()[1:]); err != ErrVRFProofEncoding {
+			t.Fatalf("%s short proof: %v, want %v", c.Name(), err, ErrVRFProofEncoding)
+		}
+
+		// altered parts, small order keys and missing parts are rejected
+		T := small_order_points(c)[1]
+		for i, bad := range []*VRFProof{
+			{Gamma: proof.Gamma.Add(c.GenPoint()), C: proof.C, S: proof.S},
+			{Gamma: proof.Gamma, C: new(big.Int).Add(proof.C, big.NewInt(1)), S: proof.S},
+			{Gamma: proof.Gamma, C: proof.C, S: new(big.Int).Add(proof.S, big.NewInt(1))},
+			{Gamma: proof.Gamma, C: proof.C, S: new(big.Int).Add(proof.S, c.Params().R)},
+			nil,
+		} {
+			if _, ok := VerifyVRF(Y, alpha, bad); ok {
+				t.Fatalf("%s: altered proof %d verifies", c.Name(), i)
+			}
+		}
+		if _, ok := VerifyVRF(Y.Add(T), alpha, proof); ok {
+			t.Fatalf("%s: proof verifies under a key with a small order component", c.Name())
+		}
+
+		x.Destroy()
+		if _, err := ProveVRF(c, x, alpha); err != ErrDestroyedSecret {
+			t.Fatalf("%s destroyed key: %v, want %v", c.Name(), err, ErrDestroyedSecret)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"errors"
+	"math/big"
+)
+
+// Lengths in bytes of the scalars and u-coordinates of X25519 and X448.
+const (
+	X25519Size = 32
+	X448Size   = 56
+)
+
+var ErrMontgomeryInput = errors.New("x25519: scalar or u-coordinate of the wrong length")
+
+/*
+Curve25519 and Curve448 in Montgomery form v² = u³ + A·u² + u, the curves
+of the X25519 and X448 functions of RFC 7748, birationally equivalent to
+edwards25519 and Ed448. Only u-coordinates are used: a scalar multiple
+of a point is determined by the multiple's u alone, so key agreement
+never needs v, point validation or the Edwards signature code.
+*/
+type montgomeryCurve struct {
+	p    *big.Int
+	a24  *big.Int // (A − 2) / 4
+	bits int      // ladder steps, the bit length of clamped scalars
+	size int      // bytes of scalars and u-coordinates
+	base byte     // u of the base point
+}
+
+var montgomery25519, montgomery448 *montgomeryCurve
+
+func init() {
+	P25519 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+	montgomery25519 = &montgomeryCurve{p: P25519, a24: big.NewInt(121665), bits: 255, size: X25519Size, base: 9}
+	P448 := new(big.Int).Lsh(big.NewInt(1), 448)
+	P448.Sub(P448, new(big.Int).Lsh(big.NewInt(1), 224)).Sub(P448, big.NewInt(1))
+	montgomery448 = &montgomeryCurve{p: P448, a24: big.NewInt(39081), bits: 448, size: X448Size, base: 5}
+}
+
+/*
+The X25519 function of RFC 7748 Section 5: the u-coordinate of the
+clamped scalar times the point with u-coordinate u, both 32 bytes little
+endian. Pass X25519Basepoint as u for the public key of scalar, and the
+peer's public key for the shared secret. An all zero result, which a
+small order u gives, fails with ErrNoSharedSecret as Section 6.1 allows.
+*/
+func X25519(scalar, u []byte) ([]byte, error) {
+	return montgomery25519.scalar_mult(scalar, u)
+}
+
+// The X448 function of RFC 7748 Section 5, as X25519 with 56 byte inputs.
+func X448(scalar, u []byte) ([]byte, error) {
+	return montgomery448.scalar_mult(scalar, u)
+}
+
+// The u-coordinate 9 of the Curve25519 base point.
+func X25519Basepoint() []byte { return montgomery25519.base_u() }
+
+// The u-coordinate 5 of the Curve448 base point.
+func X448Basepoint() []byte { return montgomery448.base_u() }
+
+func (m *montgomeryCurve) base_u() []byte {
+	out := make([]byte, m.size)
+	out[0] = m.base
+	return out
+}
+
+/*
+Clamps a scalar as decodeScalar25519 and decodeScalar448 do: clears the
+cofactor bits at the bottom, 3 for Curve25519 and 2 for Curve448, clears
+everything above the top bit and sets the top bit, so that every scalar
+takes the same number of ladder steps.
+*/
+func (m *montgomeryCurve) clamp(scalar []byte) *big.Int {
+	k := append([]byte(nil), scalar...)
+	defer wipe_bytes(k)
+	if m == montgomery25519 {
+		k[0] &= 248
+		k[31] &= 127
+		k[31] |= 64
+	} else {
+		k[0] &= 252
+		k[55] |= 128
+	}
+	return fromLittleEndian(k)
+}
+
+// decodeUCoordinate: the unused top bit of X25519 input is masked, and u ≥ p is reduced, not refused.
+func (m *montgomeryCurve) decode_u(u []byte) *big.Int {
+	b := append([]byte(nil), u...)
+	if m.bits%8 != 0 {
+		b[len(b)-1] &= 1<<(m.bits%8) - 1
+	}
+	v := fromLittleEndian(b)
+	return v.Mod(v, m.p)
+}
+
+func (m *montgomeryCurve) scalar_mult(scalar, u []byte) ([]byte, error) {
+	if len(scalar) != m.size || len(u) != m.size {
+		return nil, ErrMontgomeryInput
+	}
+	k := m.clamp(scalar)
+	defer wipe_int(k)
+	x := m.ladder(k, m.decode_u(u))
+	if x.Sign() == 0 {
+		return nil, ErrNoSharedSecret
+	}
+	return toLittleEndian(x, m.size), nil
+}
+
+/*
+The Montgomery ladder of RFC 7748 Section 5 over projective (X : Z): the
+same bits steps for every k, each one differential addition and one
+doubling, with the operands exchanged by cswap rather than
+chosen by branches on the bits of k. One inversion at the end.
+*/
+func (m *montgomeryCurve) ladder(k, u *big.Int) *big.Int {
+	p := m.p
+	x1 := u
+	x2, z2 := big.NewInt(1), big.NewInt(0)
+	x3, z3 := new(big.Int).Set(u), big.NewInt(1)
+	mod := func(v *big.Int) *big.Int { return v.Mod(v, p) }
+
+	swap := uint(0)
+	for t := m.bits - 1; t >= 0; t-- {
+		bit := k.Bit(t)
+		swap ^= bit
+		m.cswap(x2, x3, swap)
+		m.cswap(z2, z3, swap)
+		swap = bit
+
+		A := mod(new(big.Int).Add(x2, z2))
+		AA := mod(new(big.Int).Mul(A, A))
+		B := mod(new(big.Int).Sub(x2, z2))
+		BB := mod(new(big.Int).Mul(B, B))
+		E := mod(new(big.Int).Sub(AA, BB))
+		C := mod(new(big.Int).Add(x3, z3))
+		D := mod(new(big.Int).Sub(x3, z3))
+		DA := mod(new(big.Int).Mul(D, A))
+		CB := mod(new(big.Int).Mul(C, B))
+		x3.Add(DA, CB)
+		mod(x3.Mul(x3, x3))
+		z3.Sub(DA, CB)
+		mod(z3.Mul(z3, z3))
+		mod(z3.Mul(z3, x1))
+		mod(x2.Mul(AA, BB))
+		z2.Mul(m.a24, E)
+		z2.Add(z2, AA)
+		mod(z2.Mul(z2, E))
+	}
+	m.cswap(x2, x3, swap)
+	m.cswap(z2, z3, swap)
+
+	// z2^(p−2) = z2⁻¹, and 0 for z2 = 0, a small order input
+	z_inv := new(big.Int).Exp(z2, new(big.Int).Sub(p, big.NewInt(2)), p)
+	return mod(x2.Mul(x2, z_inv))
+}
+
+// Swaps a and b, both in [0, p−1], if swap is 1, as extended_cswap does for points.
+func (m *montgomeryCurve) cswap(a, b *big.Int, swap uint) {
+	ab := make([]byte, m.size)
+	bb := make([]byte, m.size)
+	defer wipe_bytes(ab)
+	defer wipe_bytes(bb)
+	a.FillBytes(ab)
+	b.FillBytes(bb)
+	mask := -byte(swap & 1)
+	for i := range ab {
+		t := mask & (ab[i] ^ bb[i])
+		ab[i] ^= t
+		bb[i] ^= t
+	}
+	a.SetBytes(ab)
+	b.SetBytes(bb)
+}
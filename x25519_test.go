@@ -0,0 +1,118 @@
+//go:build !verifyonly
+
+package sig
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+)
+
+// X25519 and X448 reproduce RFC 7748 Sections 5.2 and 6 and agree on shared secrets.
+func TestMontgomeryLadderDH(t *testing.T) {
+
+	h := func(s string) []byte {
+		b, _ := hex.DecodeString(s)
+		return b
+	}
+	vectors := []struct {
+		f                  func(scalar, u []byte) ([]byte, error)
+		scalar, u, product string
+	}{
+		{X25519, "a546e36bf0527c9d3b16154b82465edd62144c0ac1fc5a18506a2244ba449ac4",
+			"e6db6867583030db3594c1a424b15f7c726624ec26b3353b10a903a6d0ab1c4c",
+			"c3da55379de9c6908e94ea4df28d084f32eccf03491c71f754b4075577a28552"},
+		{X25519, "4b66e9d4d1b4673c5ad22691957d6af5c11b6421e0ea01d42ca4169e7918ba0d",
+			"e5210f12786811d3f4b7959d0538ae2c31dbe7106fc03c3efc4cd549c715a493",
+			"95cbde9476e8907d7aade45cb4b873f88b595a68799fa152e6f8f7647aac7957"},
+		{X448, "3d262fddf9ec8e88495266fea19a34d28882acef045104d0d1aae121700a779c984c24f8cdd78fbff44943eba368f54b29259a4f1c600ad3",
+			"06fce640fa3487bfda5f6cf2d5263f8aad88334cbd07437f020f08f9814dc031ddbdc38c19c6da2583fa5429db94ada18aa7a7fb4ef8a086",
+			"ce3e4ff95a60dc6697da1db1d85e6afbdf79b50a2412d7546d5f239fe14fbaadeb445fc66a01b0779d98223961111e21766282f73dd96b6f"},
+		{X448, "9a8f4925d1519f5775cf46b04b5800d4ee9ee8bae8bc5565d498c28dd9c9baf574a9419744897391006382a6f127ab1d9ac2d8c0a598726b",
+			"3eb7a829b0cd20f5bcfc0b599b6feccf6da4627107bdb0d4f345b43027d8b972fc3e34fb4232a13ca706dcb57aec3dae07bdc1c67bf33609",
+			"07fff4181ac6cc95ec1c16a94a0f74d12da232ce40a77552281d282bb60c0b56fd2464c335543936521c24403085d59a449a5037514a879d"},
+	}
+	for _, v := range vectors {
+		out, err := v.f(h(v.scalar), h(v.u))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(out); got != v.product {
+			t.Fatalf("scalar %s: got %s, want %s", v.scalar, got, v.product)
+		}
+	}
+
+	// Section 6.1: public keys from the base point and the shared secret
+	alice := h("77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a")
+	bob := h("5dab087e624a8a4b79e17f8b83800ee66f3bb1292618b6fd1c2f8b27ff88e0eb")
+	alicePub, err := X25519(alice, X25519Basepoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	bobPub, err := X25519(bob, X25519Basepoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	k1, err := X25519(alice, bobPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k2, err := X25519(bob, alicePub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := hex.EncodeToString(alicePub), "8520f0098930a754748b7ddcb43ef75a0dbf3a0d26381af4eba4a98eaa9b4e6a"; got != want {
+		t.Fatalf("Alice's public key: %s, want %s", got, want)
+	}
+	if got, want := hex.EncodeToString(bobPub), "de9edb7d7b7dc1b4d35b61c2ece435373f8343c85b78674dadfc7e146f882b4f"; got != want {
+		t.Fatalf("Bob's public key: %s, want %s", got, want)
+	}
+	if !bytes.Equal(k1, k2) {
+		t.Fatalf("parties derive %x and %x", k1, k2)
+	}
+	if got, want := hex.EncodeToString(k1), "4a5d9d5ba4ce2de1728e3bf480350f25e07e21c947d19e3376f09b3c1e161742"; got != want {
+		t.Fatalf("shared secret: %s, want %s", got, want)
+	}
+
+	// random X448 key pairs agree
+	a := make([]byte, X448Size)
+	b := make([]byte, X448Size)
+	rand.Read(a)
+	rand.Read(b)
+	aPub, err := X448(a, X448Basepoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	bPub, err := X448(b, X448Basepoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	k1, err = X448(a, bPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k2, err = X448(b, aPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(k1, k2) {
+		t.Fatalf("X448 parties derive %x and %x", k1, k2)
+	}
+
+	// small order u-coordinates give no secret, wrong lengths are refused
+	if _, err := X25519(alice, make([]byte, X25519Size)); err != ErrNoSharedSecret {
+		t.Fatalf("X25519 small order u: %v, want %v", err, ErrNoSharedSecret)
+	}
+	one := make([]byte, X448Size)
+	one[0] = 1
+	if _, err := X448(a, one); err != ErrNoSharedSecret {
+		t.Fatalf("X448 small order u: %v, want %v", err, ErrNoSharedSecret)
+	}
+	if _, err := X25519(alice[:31], bobPub); err != ErrMontgomeryInput {
+		t.Fatalf("short X25519 scalar: %v, want %v", err, ErrMontgomeryInput)
+	}
+	if _, err := X448(a, bobPub); err != ErrMontgomeryInput {
+		t.Fatalf("X25519 u for X448: %v, want %v", err, ErrMontgomeryInput)
+	}
+}